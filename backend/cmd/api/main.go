@@ -19,6 +19,12 @@ func main() {
 		logger.Fatal("Failed to load configuration", err)
 	}
 
+	// Apply database migrations instead of serving when asked to
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(cfg, os.Args[2:])
+		return
+	}
+
 	// Create and initialize application
 	application := app.New(cfg)
 	if err := application.Initialize(); err != nil {
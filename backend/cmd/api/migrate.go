@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/flutterninja9/mental-math-app/config"
+	"github.com/flutterninja9/mental-math-app/internal/app"
+	"github.com/flutterninja9/mental-math-app/pkg/logger"
+)
+
+// runMigrate applies pending database migrations, or lists them with -dry-run
+func runMigrate(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "list pending migrations without applying them")
+	flags.Parse(args)
+
+	logger.Initialize(cfg.App.Env)
+
+	steps, err := app.Migrate(context.Background(), cfg, *dryRun)
+	verb := "Applied"
+	if *dryRun {
+		verb = "Pending"
+	}
+	for _, step := range steps {
+		logger.Info(fmt.Sprintf("%s %s migration %s %s", verb, step.Store, step.Version, step.Description))
+	}
+	if err != nil {
+		logger.Fatal("Migration failed", err)
+	}
+	if len(steps) == 0 {
+		logger.Info("Database is up to date")
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/flutterninja9/mental-math-app/config"
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/service"
+)
+
+// runImportExercises imports exercises from a file, or from standard input,
+// and prints the import report
+func runImportExercises(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("import-exercises", flag.ExitOnError)
+	format := flags.String("format", service.TransferFormatJSONL, "file format: jsonl, csv, qti or gift")
+	file := flags.String("file", "-", "file to import; - reads standard input")
+	author := flags.String("author", "", "email address of the user recorded as the author of the revisions")
+	var opts service.ImportOptions
+	flags.BoolVar(&opts.DryRun, "dry-run", false, "validate and report without writing")
+	flags.BoolVar(&opts.Upsert, "upsert", false, "update exercises whose external key already exists")
+	flags.StringVar(&opts.Category, "category", "", "category for rows that leave it empty")
+	flags.StringVar(&opts.Difficulty, "difficulty", "", "difficulty for rows that leave it empty")
+	flags.Parse(args)
+
+	var r io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			fail("failed to open the import file", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	backend := openBackend(cfg)
+	defer backend.Close()
+
+	ctx := context.Background()
+	if *author != "" {
+		user, err := backend.Users.GetByEmail(ctx, *author)
+		if err != nil {
+			fail("failed to find the author", err)
+		}
+		opts.AuthorID = user.ID
+	}
+
+	report, err := backend.ExerciseTransfer.Import(ctx, *format, r, opts)
+	if err != nil {
+		fail("failed to import exercises", err)
+	}
+
+	printJSON(report)
+}
+
+// runExportExercises exports exercises to a file. Without -output the export
+// itself is written to standard output instead of a JSON result.
+func runExportExercises(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("export-exercises", flag.ExitOnError)
+	format := flags.String("format", service.TransferFormatJSONL, "file format: jsonl, csv, qti or gift")
+	output := flags.String("output", "", "file to write; standard output when empty")
+	tags := flags.String("tags", "", "comma separated tags, any of which must match")
+	var query model.ExerciseQuery
+	flags.StringVar(&query.Category, "category", "", "only export this category")
+	flags.StringVar(&query.Difficulty, "difficulty", "", "only export this difficulty")
+	flags.StringVar(&query.Status, "status", "", "only export this status; implies -include-unpublished")
+	flags.BoolVar(&query.IncludeUnpublished, "include-unpublished", false, "also export drafts, exercises in review and archived exercises")
+	flags.Parse(args)
+
+	if *tags != "" {
+		query.Tags = strings.Split(*tags, ",")
+	}
+	if query.Status != "" {
+		query.IncludeUnpublished = true
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fail("failed to create the export file", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	backend := openBackend(cfg)
+	defer backend.Close()
+
+	if err := backend.ExerciseTransfer.Export(context.Background(), *format, query, w); err != nil {
+		fail("failed to export exercises", err)
+	}
+
+	if *output != "" {
+		printJSON(struct {
+			Format string `json:"format"`
+			Output string `json:"output"`
+		}{*format, *output})
+	}
+}
+
+// runRecomputeStats rebuilds progress summaries and statistics for one user, or
+// for every user when no user is given
+func runRecomputeStats(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("recompute-stats", flag.ExitOnError)
+	email := flags.String("email", "", "email address of the user; every user when empty")
+	id := flags.String("id", "", "ID of the user, instead of -email")
+	flags.Parse(args)
+
+	backend := openBackend(cfg)
+	defer backend.Close()
+
+	ctx := context.Background()
+	if *email == "" && *id == "" {
+		summary, err := backend.Progress.RecomputeAllStatistics(ctx)
+		if err != nil {
+			fail("failed to recompute statistics", err)
+		}
+		printJSON(summary)
+		return
+	}
+
+	user, err := findUser(ctx, backend, *email, *id)
+	if err != nil {
+		fail("failed to find user", err)
+	}
+	result, err := backend.Progress.RecomputeStatistics(ctx, user.ID)
+	if err != nil {
+		fail("failed to recompute statistics", err)
+	}
+	printJSON(result)
+}
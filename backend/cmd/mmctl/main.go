@@ -0,0 +1,108 @@
+// Command mmctl runs operational tasks against the same storage and cache as
+// the API, configured the same way. Every command prints its result to
+// standard output as JSON; errors are printed to standard error as JSON and
+// exit with status 1.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/flutterninja9/mental-math-app/config"
+	"github.com/flutterninja9/mental-math-app/internal/app"
+	"github.com/flutterninja9/mental-math-app/pkg/logger"
+)
+
+// command is a subcommand of mmctl
+type command struct {
+	name    string
+	summary string
+	run     func(cfg *config.Config, args []string)
+}
+
+var commands = []command{
+	{"create-user", "create a user, optionally with an editor or admin role", runCreateUser},
+	{"set-role", "change a user's role and sign them out", runSetRole},
+	{"purge-sessions", "delete expired sessions", runPurgeSessions},
+	{"import-exercises", "import exercises from a jsonl, csv, qti or gift file", runImportExercises},
+	{"export-exercises", "export exercises to a jsonl, csv, qti or gift file", runExportExercises},
+	{"recompute-stats", "rebuild progress summaries and user statistics from attempts", runRecomputeStats},
+	{"seed", "fill a development database with sample learning paths", runSeed},
+	{"migrate", "apply pending database migrations", runMigrate},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == os.Args[1] {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		usage()
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fail("failed to load configuration", err)
+	}
+
+	// Logs go to standard error so standard output only holds the result
+	logger.InitializeOutput(cfg.App.Env, os.Stderr)
+
+	cmd.run(cfg, os.Args[2:])
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: mmctl <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-18s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run mmctl <command> -h for the flags of a command.")
+	os.Exit(2)
+}
+
+// openBackend connects to the configured storage and cache
+func openBackend(cfg *config.Config) *app.Backend {
+	backend, err := app.Open(cfg)
+	if err != nil {
+		fail("failed to open the backend", err)
+	}
+	return backend
+}
+
+// printJSON writes the result of a command to standard output
+func printJSON(v interface{}) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		fail("failed to write the result", err)
+	}
+}
+
+// commandError is the JSON written to standard error when a command fails
+type commandError struct {
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// fail reports an error and exits
+func fail(message string, err error) {
+	if err != nil {
+		message += ": " + err.Error()
+	}
+	exit(commandError{Error: message})
+}
+
+func exit(e commandError) {
+	json.NewEncoder(os.Stderr).Encode(e)
+	os.Exit(1)
+}
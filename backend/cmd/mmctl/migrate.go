@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/flutterninja9/mental-math-app/config"
+	"github.com/flutterninja9/mental-math-app/internal/app"
+)
+
+// runMigrate applies pending database migrations, or lists them with -dry-run.
+// The steps applied before a failure are printed along with the error.
+func runMigrate(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "list pending migrations without applying them")
+	flags.Parse(args)
+
+	steps, err := app.Migrate(context.Background(), cfg, *dryRun)
+	if steps == nil {
+		steps = []app.MigrationStep{}
+	}
+	printJSON(struct {
+		DryRun bool                `json:"dry_run"`
+		Steps  []app.MigrationStep `json:"steps"`
+	}{*dryRun, steps})
+	if err != nil {
+		fail("migration failed", err)
+	}
+}
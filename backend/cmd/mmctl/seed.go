@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/config"
+	"github.com/flutterninja9/mental-math-app/internal/app"
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// seedMeta is recorded on the revisions of seeded content
+var seedMeta = model.RevisionMeta{Note: "Seeded sample content"}
+
+// seedStage is a stage of a sample path, with the operands of its exercises
+type seedStage struct {
+	title       string
+	description string
+	operands    [][2]int
+}
+
+// seedPath is a sample learning path
+type seedPath struct {
+	title       string
+	description string
+	difficulty  string
+	category    string
+	operator    string
+	stages      []seedStage
+}
+
+// seedPaths are created in order, each path requiring the one before it
+var seedPaths = []seedPath{
+	{
+		title:       "Addition Foundations",
+		description: "Add single and two digit numbers in your head",
+		difficulty:  "easy",
+		category:    "addition",
+		operator:    "+",
+		stages: []seedStage{
+			{"Single digits", "Sums of two single digit numbers", [][2]int{{3, 4}, {5, 2}, {6, 3}, {7, 8}, {9, 6}}},
+			{"Two digits", "Sums of two digit numbers", [][2]int{{12, 15}, {23, 34}, {38, 17}, {46, 29}, {57, 36}}},
+		},
+	},
+	{
+		title:       "Times Tables",
+		description: "Learn the multiplication tables from 2 to 9",
+		difficulty:  "medium",
+		category:    "multiplication",
+		operator:    "×",
+		stages: []seedStage{
+			{"Tables of 2 to 5", "Products with a factor from 2 to 5", [][2]int{{2, 7}, {3, 6}, {4, 8}, {5, 9}, {4, 6}}},
+			{"Tables of 6 to 9", "Products with a factor from 6 to 9", [][2]int{{6, 7}, {7, 8}, {8, 9}, {9, 6}, {7, 7}}},
+		},
+	},
+}
+
+// seededPath is the result for a created path
+type seededPath struct {
+	ID     primitive.ObjectID `json:"id"`
+	Title  string             `json:"title"`
+	Stages int                `json:"stages"`
+}
+
+// runSeed fills an empty development database with published sample paths
+// and their exercises. It refuses to run in production, and on a database
+// that already has learning paths unless -force is set.
+func runSeed(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	force := flags.Bool("force", false, "seed even when learning paths already exist")
+	flags.Parse(args)
+
+	if cfg.App.Env == "production" {
+		fail("refusing to seed a production database", nil)
+	}
+
+	backend := openBackend(cfg)
+	defer backend.Close()
+
+	ctx := context.Background()
+	if !*force {
+		existing, err := backend.Paths.GetAll(ctx, true, pagination.Params{Limit: 1})
+		if err != nil {
+			fail("failed to check for existing learning paths", err)
+		}
+		if existing.Meta.Total > 0 {
+			fail("the database already has learning paths; use -force to seed anyway", nil)
+		}
+	}
+
+	var result struct {
+		Paths     []seededPath `json:"paths"`
+		Exercises int          `json:"exercises"`
+	}
+	var previous *model.LearningPath
+	for _, sp := range seedPaths {
+		path, exercises, err := seed(ctx, backend, sp, previous)
+		result.Exercises += exercises
+		if err != nil {
+			fail("failed to seed "+sp.title, err)
+		}
+		result.Paths = append(result.Paths, seededPath{ID: path.ID, Title: path.Title, Stages: len(path.Stages)})
+		previous = path
+	}
+
+	printJSON(result)
+}
+
+// seed creates a sample path and its exercises, returning the path and the
+// number of exercises created
+func seed(ctx context.Context, backend *app.Backend, sp seedPath, prerequisite *model.LearningPath) (*model.LearningPath, int, error) {
+	path := &model.LearningPath{
+		Title:          sp.title,
+		Description:    sp.description,
+		Difficulty:     sp.difficulty,
+		Categories:     []string{sp.category},
+		Status:         model.StatusPublished,
+		RequiredSkills: []string{},
+	}
+	if prerequisite != nil {
+		path.PrerequisitePathIDs = []primitive.ObjectID{prerequisite.ID}
+	}
+
+	created := 0
+	for _, stage := range sp.stages {
+		ps := model.PathStage{
+			Title:              stage.title,
+			Description:        stage.description,
+			CompletionCriteria: model.CompletionCriteria{MinAccuracy: 80},
+		}
+		for _, operands := range stage.operands {
+			exercise := seedExercise(sp, operands)
+			if err := backend.Exercises.Create(ctx, exercise, seedMeta); err != nil {
+				return nil, created, err
+			}
+			created++
+			ps.ExerciseIDs = append(ps.ExerciseIDs, exercise.ID)
+		}
+		path.Stages = append(path.Stages, ps)
+	}
+
+	if err := backend.Paths.Create(ctx, path, seedMeta); err != nil {
+		return nil, created, err
+	}
+	return path, created, nil
+}
+
+// seedExercise builds a published fill in exercise for a pair of operands
+func seedExercise(sp seedPath, operands [2]int) *model.Exercise {
+	a, b := operands[0], operands[1]
+	answer := a + b
+	if sp.category == "multiplication" {
+		answer = a * b
+	}
+	problem := fmt.Sprintf("%d %s %d", a, sp.operator, b)
+
+	return &model.Exercise{
+		Title:       problem,
+		Description: "Work out " + problem + " in your head",
+		Type:        "fill_in",
+		Category:    sp.category,
+		Difficulty:  sp.difficulty,
+		Content: model.ExerciseContent{
+			Problem:       problem + " = ?",
+			Options:       []string{},
+			CorrectAnswer: strconv.Itoa(answer),
+			Explanation:   fmt.Sprintf("%s = %d", problem, answer),
+		},
+		Metadata: model.ExerciseMetadata{
+			GeneratedBy: "seed",
+			CreatedAt:   time.Now(),
+		},
+		Tags:   []string{sp.category},
+		Status: model.StatusPublished,
+	}
+}
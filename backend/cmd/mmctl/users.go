@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/flutterninja9/mental-math-app/config"
+	"github.com/flutterninja9/mental-math-app/internal/app"
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/handler"
+	"github.com/flutterninja9/mental-math-app/pkg/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// runCreateUser registers a user the way the API does, then gives them a role.
+// The password is read from the first line of standard input unless -password
+// is set, so it does not have to appear in the process list.
+func runCreateUser(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("create-user", flag.ExitOnError)
+	var req handler.RegisterRequest
+	flags.StringVar(&req.Email, "email", "", "email address")
+	flags.StringVar(&req.Username, "username", "", "username")
+	flags.StringVar(&req.Password, "password", "", "password; read from standard input when empty")
+	flags.StringVar(&req.FirstName, "first-name", "", "first name")
+	flags.StringVar(&req.LastName, "last-name", "", "last name")
+	flags.StringVar(&req.Timezone, "timezone", "", "IANA timezone, UTC when empty")
+	role := flags.String("role", model.RoleUser, "role: user, editor or admin")
+	flags.Parse(args)
+
+	if req.Password == "" {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			fail("failed to read the password from standard input", err)
+		}
+		req.Password = strings.TrimRight(line, "\r\n")
+	}
+
+	valErrors := utils.NewValidator().Validate(req)
+	if valErrors.HasErrors() {
+		exit(commandError{Error: "invalid user", Fields: valErrors.Errors})
+	}
+	if valid, msg := utils.ValidatePassword(req.Password); !valid {
+		exit(commandError{Error: "invalid user", Fields: map[string]string{"password": msg}})
+	}
+
+	backend := openBackend(cfg)
+	defer backend.Close()
+
+	ctx := context.Background()
+	user, err := backend.Users.Register(ctx, req.Email, req.Username, req.Password, req.FirstName, req.LastName, req.Timezone)
+	if err != nil {
+		fail("failed to create user", err)
+	}
+	if *role != model.RoleUser {
+		if user, err = backend.Users.UpdateRole(ctx, user.ID, *role); err != nil {
+			fail("user created but their role was not set", err)
+		}
+	}
+
+	printJSON(user)
+}
+
+// runSetRole promotes or demotes a user. Sessions carry the role they were
+// created with, so the user is signed out everywhere for the change to apply.
+func runSetRole(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("set-role", flag.ExitOnError)
+	email := flags.String("email", "", "email address of the user")
+	id := flags.String("id", "", "ID of the user, instead of -email")
+	role := flags.String("role", "", "role: user, editor or admin")
+	flags.Parse(args)
+
+	backend := openBackend(cfg)
+	defer backend.Close()
+
+	ctx := context.Background()
+	user, err := findUser(ctx, backend, *email, *id)
+	if err != nil {
+		fail("failed to find user", err)
+	}
+	if user, err = backend.Users.UpdateRole(ctx, user.ID, *role); err != nil {
+		fail("failed to set role", err)
+	}
+	if err := backend.Auth.InvalidateAllUserTokens(user.ID); err != nil {
+		fail("role set but the user could not be signed out", err)
+	}
+
+	printJSON(user)
+}
+
+// runPurgeSessions deletes the sessions that have expired
+func runPurgeSessions(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("purge-sessions", flag.ExitOnError)
+	flags.Parse(args)
+
+	backend := openBackend(cfg)
+	defer backend.Close()
+
+	deleted, err := backend.Auth.PurgeExpiredSessions(context.Background())
+	if err != nil {
+		fail("failed to purge sessions", err)
+	}
+
+	printJSON(struct {
+		Deleted int64 `json:"deleted"`
+	}{deleted})
+}
+
+// findUser looks a user up by email, or by ID when no email is given
+func findUser(ctx context.Context, backend *app.Backend, email, id string) (*model.User, error) {
+	if email != "" {
+		return backend.Users.GetByEmail(ctx, email)
+	}
+	if id == "" {
+		return nil, errors.New("-email or -id is required")
+	}
+	userID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+	return backend.Users.GetByID(ctx, userID)
+}
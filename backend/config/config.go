@@ -14,6 +14,11 @@ type Config struct {
 	MongoDB MongoDBConfig
 	JWT     JWTConfig
 	LLM     LLMConfig
+	Content ContentConfig
+	Account AccountConfig
+	Trash   TrashConfig
+	Storage StorageConfig
+	Cache   CacheConfig
 }
 
 type AppConfig struct {
@@ -40,10 +45,59 @@ type LLMConfig struct {
 	APIURL string
 }
 
+// Exercise delete policies
+const (
+	DeletePolicyBlock   = "block"   // refuse to delete exercises used by learning paths
+	DeletePolicyCascade = "cascade" // remove the exercise from learning path stages
+	DeletePolicySoft    = "soft"    // hide the exercise but keep path references valid
+)
+
+type ContentConfig struct {
+	ExerciseDeletePolicy string
+}
+
+type AccountConfig struct {
+	// DeletionGracePeriod delays account deletion so it can be cancelled; zero deletes immediately
+	DeletionGracePeriod time.Duration
+}
+
+type TrashConfig struct {
+	// Retention is how long deleted exercises, learning paths and users can be
+	// restored before they are purged
+	Retention time.Duration
+}
+
+// Storage backends for users, sessions, exercises, progress, attempts and learning paths
+const (
+	StorageMongo    = "mongo"
+	StoragePostgres = "postgres"
+)
+
+type StorageConfig struct {
+	Backend     string
+	PostgresURL string
+}
+
+// Cache backends for exercises, learning paths and sessions
+const (
+	CacheNone   = "none"
+	CacheMemory = "memory"
+	CacheRedis  = "redis"
+)
+
+type CacheConfig struct {
+	Backend  string
+	RedisURL string
+	// TTL is how long exercises, learning paths and sessions stay cached
+	TTL time.Duration
+	// SessionMissTTL is how long an unknown session token is remembered as unknown
+	SessionMissTTL time.Duration
+}
+
 func LoadConfig() (*Config, error) {
 	// Load environment variables from .env file
 	if err := godotenv.Load(); err != nil {
-		fmt.Printf("Warning: .env file not found or cannot be loaded: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Warning: .env file not found or cannot be loaded: %v\n", err)
 	}
 
 	// Parse application port with default
@@ -59,6 +113,55 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("invalid JWT_EXPIRATION value: %w", err)
 	}
 
+	deletePolicy := getEnv("EXERCISE_DELETE_POLICY", DeletePolicyBlock)
+	switch deletePolicy {
+	case DeletePolicyBlock, DeletePolicyCascade, DeletePolicySoft:
+	default:
+		return nil, fmt.Errorf("invalid EXERCISE_DELETE_POLICY value: %s", deletePolicy)
+	}
+
+	gracePeriod, err := time.ParseDuration(getEnv("ACCOUNT_DELETION_GRACE_PERIOD", "0s"))
+	if err != nil || gracePeriod < 0 {
+		return nil, fmt.Errorf("invalid ACCOUNT_DELETION_GRACE_PERIOD value: %s", getEnv("ACCOUNT_DELETION_GRACE_PERIOD", ""))
+	}
+
+	retention, err := time.ParseDuration(getEnv("TRASH_RETENTION", "720h"))
+	if err != nil || retention < 0 {
+		return nil, fmt.Errorf("invalid TRASH_RETENTION value: %s", getEnv("TRASH_RETENTION", ""))
+	}
+
+	storageBackend := getEnv("STORAGE_BACKEND", StorageMongo)
+	switch storageBackend {
+	case StorageMongo:
+	case StoragePostgres:
+		if getEnv("POSTGRES_URL", "") == "" {
+			return nil, fmt.Errorf("POSTGRES_URL is required when STORAGE_BACKEND is %s", StoragePostgres)
+		}
+	default:
+		return nil, fmt.Errorf("invalid STORAGE_BACKEND value: %s", storageBackend)
+	}
+
+	cacheBackend := getEnv("CACHE_BACKEND", CacheMemory)
+	switch cacheBackend {
+	case CacheNone, CacheMemory:
+	case CacheRedis:
+		if getEnv("REDIS_URL", "") == "" {
+			return nil, fmt.Errorf("REDIS_URL is required when CACHE_BACKEND is %s", CacheRedis)
+		}
+	default:
+		return nil, fmt.Errorf("invalid CACHE_BACKEND value: %s", cacheBackend)
+	}
+
+	cacheTTL, err := time.ParseDuration(getEnv("CACHE_TTL", "5m"))
+	if err != nil || cacheTTL <= 0 {
+		return nil, fmt.Errorf("invalid CACHE_TTL value: %s", getEnv("CACHE_TTL", ""))
+	}
+
+	sessionMissTTL, err := time.ParseDuration(getEnv("SESSION_MISS_TTL", "30s"))
+	if err != nil || sessionMissTTL < 0 {
+		return nil, fmt.Errorf("invalid SESSION_MISS_TTL value: %s", getEnv("SESSION_MISS_TTL", ""))
+	}
+
 	return &Config{
 		App: AppConfig{
 			Name: getEnv("APP_NAME", "mental-math-api"),
@@ -80,6 +183,25 @@ func LoadConfig() (*Config, error) {
 			APIKEY: getEnv("LLM_API_KEY", ""),
 			APIURL: getEnv("LLM_API_URL", "https://api.llm-provider.com/v1"),
 		},
+		Content: ContentConfig{
+			ExerciseDeletePolicy: deletePolicy,
+		},
+		Account: AccountConfig{
+			DeletionGracePeriod: gracePeriod,
+		},
+		Trash: TrashConfig{
+			Retention: retention,
+		},
+		Storage: StorageConfig{
+			Backend:     storageBackend,
+			PostgresURL: getEnv("POSTGRES_URL", ""),
+		},
+		Cache: CacheConfig{
+			Backend:        cacheBackend,
+			RedisURL:       getEnv("REDIS_URL", ""),
+			TTL:            cacheTTL,
+			SessionMissTTL: sessionMissTTL,
+		},
 	}, nil
 }
 
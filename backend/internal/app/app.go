@@ -1,26 +1,38 @@
 package app
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/flutterninja9/mental-math-app/config"
 	"github.com/flutterninja9/mental-math-app/internal/auth"
 	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository/postgres"
 	"github.com/flutterninja9/mental-math-app/internal/handler"
-	"github.com/flutterninja9/mental-math-app/internal/llm"
+	"github.com/flutterninja9/mental-math-app/internal/migration"
 	"github.com/flutterninja9/mental-math-app/internal/service"
-	"github.com/flutterninja9/mental-math-app/pkg/database"
 	"github.com/flutterninja9/mental-math-app/pkg/logger"
 	"github.com/flutterninja9/mental-math-app/pkg/middleware"
 	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// accountPurgeInterval is how often accounts scheduled for deletion are purged
+const accountPurgeInterval = time.Hour
+
+// trashPurgeInterval is how often records past the trash retention period are purged
+const trashPurgeInterval = time.Hour
+
 // App represents the application
 type App struct {
-	config *config.Config
-	db     *database.MongoDB
-	server *fiber.App
+	config  *config.Config
+	backend *Backend
+	server  *fiber.App
+
+	// stopJobs stops the background jobs started by Initialize
+	stopJobs context.CancelFunc
 }
 
 // New creates a new application instance
@@ -36,12 +48,35 @@ func (a *App) Initialize() error {
 	logger.Initialize(a.config.App.Env)
 	logger.Info(fmt.Sprintf("Starting application in %s mode", a.config.App.Env))
 
-	// Connect to database
-	db, err := database.NewMongoDB(a.config.MongoDB.URI, a.config.MongoDB.DBName)
+	// Connect to storage and cache
+	backend, err := Open(a.config)
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
+		return err
+	}
+	a.backend = backend
+
+	// Collection validators would reject writes of models that drifted from them
+	if err := migration.CheckSchemas(); err != nil {
+		return fmt.Errorf("models do not match the collection schemas: %w", err)
+	}
+
+	// Schema changes are applied by the migrate command, not at startup
+	pending, err := migration.NewMigrator(backend.db.Database).Pending(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to check migrations: %w", err)
+	}
+	if len(pending) > 0 {
+		logger.Warn(fmt.Sprintf("%d database migrations are pending; run the migrate command", len(pending)))
+	}
+	if backend.pg != nil {
+		versions, err := postgres.Pending(context.Background(), backend.pg.Pool)
+		if err != nil {
+			return fmt.Errorf("failed to check postgres migrations: %w", err)
+		}
+		if len(versions) > 0 {
+			logger.Warn(fmt.Sprintf("%d postgres migrations are pending; run the migrate command", len(versions)))
+		}
 	}
-	a.db = db
 
 	// Setup Fiber
 	a.server = fiber.New(fiber.Config{
@@ -53,7 +88,7 @@ func (a *App) Initialize() error {
 	a.registerMiddleware()
 
 	// Register routes
-	a.registerRoutes(db.Database)
+	a.registerRoutes(&backend.Services)
 
 	return nil
 }
@@ -65,34 +100,62 @@ func (a *App) registerMiddleware() {
 	a.server.Use(middleware.CorsMiddleware())
 }
 
-// registerRoutes sets up the API routes
-func (a *App) registerRoutes(db *mongo.Database) {
-	// Set up repositories
-	userRepo := repository.NewUserRepository(db)
-	sessionRepo := repository.NewSessionRepository(db)
-	exerciseRepo := repository.NewExerciseRepository(db)
-	progressRepo := repository.NewProgressRepository(db)
-	learningPathRepo := repository.NewLearningPathRepository(db)
-
-	// Set up services
-	authService := auth.NewAuthService(a.config, sessionRepo)
-	userService := service.NewUserService(userRepo)
-	exerciseService := service.NewExerciseService(exerciseRepo)
-	progressService := service.NewProgressService(progressRepo, exerciseRepo, userRepo)
-	learningPathService := service.NewLearningPathService(learningPathRepo)
-
-	// Set up LLM client and service
-	llmClient := llm.NewLLMClient(a.config)
-	llmService := llm.NewService(llmClient)
+// repositories holds the storage the services are built on
+type repositories struct {
+	users       repository.UserRepository
+	sessions    repository.SessionRepository
+	exercises   repository.ExerciseRepository
+	progress    repository.ProgressRepository
+	attempts    repository.AttemptRepository
+	paths       repository.LearningPathRepository
+	enrollments repository.EnrollmentRepository
+	skills      repository.SkillRepository
+	revisions   repository.RevisionRepository
+}
+
+// newMongoRepositories sets up the repositories on a Mongo database. Their
+// indexes are created by the migrate command.
+func newMongoRepositories(db *mongo.Database) *repositories {
+	return &repositories{
+		users:       repository.NewUserRepository(db),
+		sessions:    repository.NewSessionRepository(db),
+		exercises:   repository.NewExerciseRepository(db),
+		progress:    repository.NewProgressRepository(db),
+		attempts:    repository.NewAttemptRepository(db),
+		paths:       repository.NewLearningPathRepository(db),
+		enrollments: repository.NewEnrollmentRepository(db),
+		skills:      repository.NewSkillRepository(db),
+		revisions:   repository.NewRevisionRepository(db),
+	}
+}
 
+// usePostgresRepositories moves users, sessions, exercises, progress, attempts
+// and learning paths to Postgres. Attempts move with exercises, which analytics
+// join them with. The other repositories, including revisions, stay on Mongo.
+func usePostgresRepositories(repos *repositories, pool *pgxpool.Pool) {
+	repos.users = postgres.NewUserRepository(pool)
+	repos.sessions = postgres.NewSessionRepository(pool)
+	repos.exercises = postgres.NewExerciseRepository(pool, repos.revisions)
+	repos.progress = postgres.NewProgressRepository(pool)
+	repos.attempts = postgres.NewAttemptRepository(pool)
+	repos.paths = postgres.NewLearningPathRepository(pool, repos.revisions)
+}
+
+// registerRoutes sets up the API routes
+func (a *App) registerRoutes(services *Services) {
 	// Set up handlers
-	userHandler := handler.NewUserHandler(userService, authService)
-	exerciseHandler := handler.NewExerciseHandler(exerciseService, llmService)
-	progressHandler := handler.NewProgressHandler(progressService)
-	learningPathHandler := handler.NewLearningPathHandler(learningPathService)
+	userHandler := handler.NewUserHandler(services.Users, services.Accounts, services.Auth)
+	exerciseHandler := handler.NewExerciseHandler(services.Exercises, services.ExerciseTransfer, services.LLM)
+	progressHandler := handler.NewProgressHandler(services.Progress)
+	learningPathHandler := handler.NewLearningPathHandler(services.Paths, services.PathProgress, services.Recommendations, services.PathAuthoring, services.PathPackages)
+	skillHandler := handler.NewSkillHandler(services.Skills)
+	reviewHandler := handler.NewReviewHandler(services.Reviews)
+	revisionHandler := handler.NewRevisionHandler(services.Revisions)
+	adminHandler := handler.NewAdminHandler(services.Paths, services.Trash)
 
 	// Set up auth middleware
-	authMiddleware := auth.JWTMiddleware(authService)
+	authMiddleware := auth.JWTMiddleware(services.Auth)
+	optionalAuthMiddleware := auth.OptionalJWTMiddleware(services.Auth)
 
 	// API routes
 	api := a.server.Group("/api")
@@ -100,9 +163,20 @@ func (a *App) registerRoutes(db *mongo.Database) {
 
 	// Register resource routes
 	userHandler.RegisterRoutes(v1, authMiddleware)
-	exerciseHandler.RegisterRoutes(v1, authMiddleware)
+	exerciseHandler.RegisterRoutes(v1, authMiddleware, optionalAuthMiddleware)
 	progressHandler.RegisterRoutes(v1, authMiddleware)
-	learningPathHandler.RegisterRoutes(v1, authMiddleware)
+	learningPathHandler.RegisterRoutes(v1, authMiddleware, optionalAuthMiddleware)
+	skillHandler.RegisterRoutes(v1, authMiddleware)
+	reviewHandler.RegisterRoutes(v1, authMiddleware)
+	revisionHandler.RegisterRoutes(v1, authMiddleware)
+	adminHandler.RegisterRoutes(v1, authMiddleware)
+
+	// Carry out account deletions whose grace period has ended and empty the
+	// trash of records past their retention period
+	jobs, stopJobs := context.WithCancel(context.Background())
+	a.stopJobs = stopJobs
+	go a.purgeDeletedAccounts(jobs, services.Accounts)
+	go a.purgeTrash(jobs, services.Trash)
 
 	// Health check endpoint
 	api.Get("/health", func(c *fiber.Ctx) error {
@@ -117,6 +191,51 @@ func (a *App) registerRoutes(db *mongo.Database) {
 	})
 }
 
+// purgeDeletedAccounts periodically deletes accounts scheduled for deletion
+// until the context is cancelled
+func (a *App) purgeDeletedAccounts(ctx context.Context, accountService service.AccountService) {
+	ticker := time.NewTicker(accountPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := accountService.PurgeScheduledDeletions(ctx)
+			if err != nil {
+				logger.Error("Failed to purge scheduled account deletions", err)
+			}
+			if deleted > 0 {
+				logger.Info(fmt.Sprintf("Deleted %d accounts scheduled for deletion", deleted))
+			}
+		}
+	}
+}
+
+// purgeTrash periodically removes records that have been in the trash for
+// longer than the retention period until the context is cancelled
+func (a *App) purgeTrash(ctx context.Context, trashService service.TrashService) {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := trashService.Purge(ctx)
+			if err != nil {
+				logger.Error("Failed to purge the trash", err)
+			}
+			if purged.Total() > 0 {
+				logger.Info(fmt.Sprintf("Purged %d exercises, %d learning paths and %d users from the trash",
+					purged.Exercises, purged.Paths, purged.Users))
+			}
+		}
+	}
+}
+
 // Start runs the application server
 func (a *App) Start() error {
 	return a.server.Listen(fmt.Sprintf(":%d", a.config.App.Port))
@@ -124,11 +243,13 @@ func (a *App) Start() error {
 
 // Shutdown gracefully shuts down the application
 func (a *App) Shutdown() error {
-	// Close database connection
-	if a.db != nil {
-		if err := a.db.Close(); err != nil {
-			logger.Error("Error closing database connection", err)
-		}
+	if a.stopJobs != nil {
+		a.stopJobs()
+	}
+
+	// Close database connections
+	if a.backend != nil {
+		a.backend.Close()
 	}
 
 	// Shutdown server
@@ -0,0 +1,147 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/flutterninja9/mental-math-app/config"
+	"github.com/flutterninja9/mental-math-app/internal/auth"
+	"github.com/flutterninja9/mental-math-app/internal/llm"
+	"github.com/flutterninja9/mental-math-app/internal/service"
+	"github.com/flutterninja9/mental-math-app/pkg/cache"
+	"github.com/flutterninja9/mental-math-app/pkg/database"
+	"github.com/flutterninja9/mental-math-app/pkg/logger"
+)
+
+// Backend is the storage and cache the application runs on, with the services
+// built on them. The API serves it over HTTP, and command line tools open
+// their own to work on the same data.
+type Backend struct {
+	Services
+
+	db    *database.MongoDB
+	pg    *database.Postgres
+	redis *database.Redis
+}
+
+// Services are the application services of a backend
+type Services struct {
+	Auth             auth.Service
+	Users            service.UserService
+	Accounts         service.AccountService
+	Exercises        service.ExerciseService
+	ExerciseTransfer service.ExerciseTransferService
+	PathProgress     service.PathProgressService
+	Progress         service.ProgressService
+	Paths            service.LearningPathService
+	PathPackages     service.PathPackageService
+	PathAuthoring    service.PathAuthoringService
+	Skills           service.SkillService
+	Reviews          service.ReviewService
+	Revisions        service.RevisionService
+	Trash            service.TrashService
+	Recommendations  service.RecommendationService
+	LLM              llm.Service
+}
+
+// Open connects to the configured storage and cache and sets up the services
+// on them. The backend must be closed when no longer needed.
+func Open(cfg *config.Config) (*Backend, error) {
+	b := &Backend{}
+
+	db, err := database.NewMongoDB(cfg.MongoDB.URI, cfg.MongoDB.DBName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	b.db = db
+
+	repos := newMongoRepositories(db.Database)
+	if cfg.Storage.Backend == config.StoragePostgres {
+		pg, err := database.NewPostgres(cfg.Storage.PostgresURL)
+		if err != nil {
+			b.Close()
+			return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+		}
+		b.pg = pg
+		usePostgresRepositories(repos, pg.Pool)
+	}
+
+	store, err := b.openCache(cfg)
+	if err != nil {
+		b.Close()
+		return nil, err
+	}
+
+	b.Services = newServices(cfg, repos, store)
+	return b, nil
+}
+
+// openCache sets up the configured cache for exercises, learning paths and sessions
+func (b *Backend) openCache(cfg *config.Config) (cache.Cache, error) {
+	switch cfg.Cache.Backend {
+	case config.CacheNone:
+		return cache.Nop{}, nil
+	case config.CacheRedis:
+		rdb, err := database.NewRedis(cfg.Cache.RedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to redis: %w", err)
+		}
+		b.redis = rdb
+		return cache.NewRedis(rdb.Client), nil
+	default:
+		return cache.NewMemory(), nil
+	}
+}
+
+// newServices sets up the services on a set of repositories and a cache
+func newServices(cfg *config.Config, repos *repositories, store cache.Cache) Services {
+	contentCache := service.NewContentCache(store, cfg.Cache.TTL)
+	sessionCache := auth.NewSessionCache(store, cfg.Cache.TTL, cfg.Cache.SessionMissTTL)
+
+	var s Services
+	s.Auth = auth.NewAuthService(cfg, repos.sessions, sessionCache)
+	s.Users = service.NewUserService(repos.users)
+	s.Accounts = service.NewAccountService(
+		repos.users, repos.progress, repos.attempts, repos.enrollments, repos.sessions,
+		repos.exercises, repos.paths, repos.revisions, sessionCache, contentCache,
+		cfg.Account.DeletionGracePeriod,
+	)
+	s.Exercises = service.NewExerciseService(repos.exercises, repos.paths, cfg.Content.ExerciseDeletePolicy, contentCache)
+	s.ExerciseTransfer = service.NewExerciseTransferService(s.Exercises, repos.exercises)
+	s.PathProgress = service.NewPathProgressService(repos.enrollments, repos.paths, repos.progress, contentCache)
+	s.Progress = service.NewProgressService(repos.progress, repos.attempts, repos.exercises, repos.users, s.PathProgress)
+	s.Paths = service.NewLearningPathService(repos.paths, repos.exercises, contentCache)
+	s.PathPackages = service.NewPathPackageService(s.Paths, s.Exercises, repos.exercises)
+	s.Skills = service.NewSkillService(repos.skills)
+	s.Reviews = service.NewReviewService(repos.exercises, repos.paths, repos.users, contentCache)
+	s.Revisions = service.NewRevisionService(repos.revisions, s.Exercises, s.Paths)
+	s.Trash = service.NewTrashService(
+		repos.exercises, repos.paths, repos.enrollments, repos.users, repos.sessions, s.Accounts,
+		sessionCache, contentCache, cfg.Trash.Retention,
+	)
+	s.Recommendations = service.NewRecommendationService(repos.paths, repos.enrollments, repos.progress, repos.exercises, repos.skills, repos.users)
+
+	// Set up LLM client and service
+	s.LLM = llm.NewService(llm.NewLLMClient(cfg))
+	s.PathAuthoring = service.NewPathAuthoringService(s.LLM, s.Paths, repos.exercises, contentCache)
+
+	return s
+}
+
+// Close disconnects from the cache and storage
+func (b *Backend) Close() {
+	if b.redis != nil {
+		if err := b.redis.Close(); err != nil {
+			logger.Error("Error closing redis connection", err)
+		}
+	}
+	if b.pg != nil {
+		if err := b.pg.Close(); err != nil {
+			logger.Error("Error closing postgres connection", err)
+		}
+	}
+	if b.db != nil {
+		if err := b.db.Close(); err != nil {
+			logger.Error("Error closing database connection", err)
+		}
+	}
+}
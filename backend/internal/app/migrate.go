@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/flutterninja9/mental-math-app/config"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository/postgres"
+	"github.com/flutterninja9/mental-math-app/internal/migration"
+	"github.com/flutterninja9/mental-math-app/pkg/database"
+)
+
+// Stores a migration can apply to
+const (
+	MigrationStoreMongo    = "mongo"
+	MigrationStorePostgres = "postgres"
+)
+
+// MigrationStep is a migration that was applied, or would be on a dry run
+type MigrationStep struct {
+	Store       string `json:"store"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Migrate applies the pending Mongo migrations and, when Postgres storage is
+// configured, the pending Postgres migrations. With dryRun set nothing is
+// changed and the steps that would be applied are returned.
+func Migrate(ctx context.Context, cfg *config.Config, dryRun bool) ([]MigrationStep, error) {
+	db, err := database.NewMongoDB(cfg.MongoDB.URI, cfg.MongoDB.DBName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	var steps []MigrationStep
+	applied, err := migration.NewMigrator(db.Database).Up(ctx, dryRun)
+	for _, m := range applied {
+		steps = append(steps, MigrationStep{Store: MigrationStoreMongo, Version: strconv.Itoa(m.Version), Description: m.Description})
+	}
+	if err != nil {
+		return steps, err
+	}
+
+	if cfg.Storage.Backend != config.StoragePostgres {
+		return steps, nil
+	}
+
+	pg, err := database.NewPostgres(cfg.Storage.PostgresURL)
+	if err != nil {
+		return steps, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	defer pg.Close()
+
+	var versions []string
+	if dryRun {
+		versions, err = postgres.Pending(ctx, pg.Pool)
+	} else {
+		versions, err = postgres.Migrate(ctx, pg.Pool)
+	}
+	for _, version := range versions {
+		steps = append(steps, MigrationStep{Store: MigrationStorePostgres, Version: version})
+	}
+	return steps, err
+}
@@ -3,6 +3,7 @@ package auth
 import (
 	"strings"
 
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
 	"github.com/flutterninja9/mental-math-app/pkg/utils"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -34,11 +35,45 @@ func JWTMiddleware(authService Service) fiber.Handler {
 		// Set user ID in locals for use in handlers
 		c.Locals("userID", session.UserID)
 		c.Locals("sessionID", session.ID)
+		c.Locals("userRole", session.Role)
 
 		return c.Next()
 	}
 }
 
+// OptionalJWTMiddleware returns a middleware that authenticates the user when a valid
+// bearer token is present and otherwise lets the request through anonymously
+func OptionalJWTMiddleware(authService Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		parts := strings.Split(c.Get("Authorization"), " ")
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			return c.Next()
+		}
+
+		session, err := authService.ValidateToken(parts[1])
+		if err != nil {
+			return c.Next()
+		}
+
+		c.Locals("userID", session.UserID)
+		c.Locals("sessionID", session.ID)
+		c.Locals("userRole", session.Role)
+
+		return c.Next()
+	}
+}
+
+// RequireRole returns a middleware that only lets users with one of the given roles through.
+// It must be registered after JWTMiddleware.
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !HasRole(c, roles...) {
+			return utils.ErrorResponse(c, nil, "Forbidden", fiber.StatusForbidden)
+		}
+		return c.Next()
+	}
+}
+
 // GetUserID extracts the user ID from the request context
 func GetUserID(c *fiber.Ctx) (primitive.ObjectID, bool) {
 	userID, ok := c.Locals("userID").(primitive.ObjectID)
@@ -50,3 +85,23 @@ func GetSessionID(c *fiber.Ctx) (primitive.ObjectID, bool) {
 	sessionID, ok := c.Locals("sessionID").(primitive.ObjectID)
 	return sessionID, ok
 }
+
+// GetUserRole extracts the user's role from the request context
+func GetUserRole(c *fiber.Ctx) string {
+	role, _ := c.Locals("userRole").(string)
+	if role == "" {
+		return model.RoleUser
+	}
+	return role
+}
+
+// HasRole reports whether the authenticated user has one of the given roles
+func HasRole(c *fiber.Ctx, roles ...string) bool {
+	role := GetUserRole(c)
+	for _, r := range roles {
+		if role == r {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -8,6 +9,7 @@ import (
 	"github.com/flutterninja9/mental-math-app/config"
 	"github.com/flutterninja9/mental-math-app/internal/domain/model"
 	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -19,18 +21,22 @@ type Service interface {
 	ValidateToken(tokenString string) (*model.UserSession, error)
 	InvalidateToken(sessionID primitive.ObjectID) error
 	InvalidateAllUserTokens(userID primitive.ObjectID) error
+	ListSessions(ctx context.Context, userID primitive.ObjectID, page pagination.Params) (pagination.Page[*model.UserSession], error)
+	PurgeExpiredSessions(ctx context.Context) (int64, error)
 }
 
 type authService struct {
 	cfg         *config.Config
 	sessionRepo repository.SessionRepository
+	sessions    *SessionCache
 }
 
 // NewAuthService creates a new instance of auth service
-func NewAuthService(cfg *config.Config, sessionRepo repository.SessionRepository) Service {
+func NewAuthService(cfg *config.Config, sessionRepo repository.SessionRepository, sessions *SessionCache) Service {
 	return &authService{
 		cfg:         cfg,
 		sessionRepo: sessionRepo,
+		sessions:    sessions,
 	}
 }
 
@@ -52,6 +58,7 @@ func (s *authService) GenerateToken(user *model.User, ipAddress, deviceInfo stri
 	// Create session in database
 	session := &model.UserSession{
 		UserID:       user.ID,
+		Role:         user.Role,
 		SessionToken: sessionToken,
 		CreatedAt:    time.Now(),
 		ExpiresAt:    expirationTime,
@@ -104,8 +111,11 @@ func (s *authService) ValidateToken(tokenString string) (*model.UserSession, err
 		return nil, errors.New("invalid token claims")
 	}
 
-	// Get session from database
-	session, err := s.sessionRepo.GetByToken(nil, claims.SessionToken)
+	// Get session from the cache or database
+	ctx := context.Background()
+	session, err := s.sessions.get(ctx, claims.UserID, claims.SessionToken, func() (*model.UserSession, error) {
+		return s.sessionRepo.GetByToken(ctx, claims.SessionToken)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("session not found: %w", err)
 	}
@@ -120,11 +130,49 @@ func (s *authService) ValidateToken(tokenString string) (*model.UserSession, err
 
 // InvalidateToken invalidates a user session
 func (s *authService) InvalidateToken(sessionID primitive.ObjectID) error {
-	return s.sessionRepo.Delete(nil, sessionID)
+	ctx := context.Background()
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if errors.Is(err, repository.ErrSessionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.sessionRepo.Delete(ctx, sessionID); err != nil {
+		return err
+	}
+	return s.sessions.InvalidateUser(ctx, session.UserID)
 }
 
 // InvalidateAllUserTokens invalidates all sessions for a user
 func (s *authService) InvalidateAllUserTokens(userID primitive.ObjectID) error {
-	_, err := s.sessionRepo.DeleteAllForUser(nil, userID)
-	return err
+	ctx := context.Background()
+	if _, err := s.sessionRepo.DeleteAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	return s.sessions.InvalidateUser(ctx, userID)
+}
+
+// ListSessions returns a page of a user's sessions that have not expired
+func (s *authService) ListSessions(ctx context.Context, userID primitive.ObjectID, page pagination.Params) (pagination.Page[*model.UserSession], error) {
+	sessions, err := s.sessionRepo.ListActiveByUserID(ctx, userID, page)
+	if err != nil {
+		return pagination.Page[*model.UserSession]{}, err
+	}
+	total, err := s.sessionRepo.CountActiveByUserID(ctx, userID)
+	if err != nil {
+		return pagination.Page[*model.UserSession]{}, err
+	}
+
+	return pagination.NewPage(sessions, page, total, func(session *model.UserSession) pagination.Cursor {
+		return pagination.IDCursor(session.ID)
+	}), nil
+}
+
+// PurgeExpiredSessions deletes the sessions that have expired and returns how
+// many were deleted. Cached sessions are never used past their expiry, so the
+// cache needs no invalidation.
+func (s *authService) PurgeExpiredSessions(ctx context.Context) (int64, error) {
+	return s.sessionRepo.DeleteExpired(ctx)
 }
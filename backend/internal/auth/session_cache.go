@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/cache"
+	"github.com/flutterninja9/mental-math-app/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// userGenerationTTL is how long a user's session generation is kept. When it
+// expires a new one is started, which only drops the sessions cached under it.
+const userGenerationTTL = 24 * time.Hour
+
+// SessionCache caches sessions by token so requests are authenticated without
+// a storage lookup, and briefly remembers tokens that have no session. Sessions
+// are keyed by a generation per user, so deleting any of a user's sessions
+// invalidates everything cached for them. A nil *SessionCache caches nothing.
+type SessionCache struct {
+	cache   cache.Cache
+	ttl     time.Duration
+	missTTL time.Duration
+}
+
+// cachedSession is a cached lookup; a nil session means the token has no session
+type cachedSession struct {
+	Session *model.UserSession `bson:"session"`
+}
+
+// NewSessionCache creates a session cache keeping sessions for ttl, or until they
+// expire if that is sooner, and unknown tokens for missTTL
+func NewSessionCache(c cache.Cache, ttl, missTTL time.Duration) *SessionCache {
+	return &SessionCache{cache: c, ttl: ttl, missTTL: missTTL}
+}
+
+// InvalidateUser drops every session cached for a user. It is called after the
+// user's sessions are deleted, so a lookup racing with it cannot cache them again.
+func (c *SessionCache) InvalidateUser(ctx context.Context, userID primitive.ObjectID) error {
+	if c == nil {
+		return nil
+	}
+	_, err := cache.NextGeneration(ctx, c.cache, userGenerationKey(userID), userGenerationTTL)
+	return err
+}
+
+// get returns the session for a token of a user, loading it on a miss. Cache
+// failures are logged before falling back to load.
+func (c *SessionCache) get(ctx context.Context, userID primitive.ObjectID, token string, load func() (*model.UserSession, error)) (*model.UserSession, error) {
+	if c == nil {
+		return load()
+	}
+
+	generation, err := cache.Generation(ctx, c.cache, userGenerationKey(userID), userGenerationTTL)
+	if err != nil {
+		logger.Error("Failed to read the session cache generation", err)
+		return load()
+	}
+	key := "sessions:" + userID.Hex() + ":" + generation + ":" + token
+
+	var cached cachedSession
+	err = cache.GetValue(ctx, c.cache, key, &cached)
+	if err == nil {
+		if cached.Session == nil {
+			return nil, repository.ErrSessionNotFound
+		}
+		return cached.Session, nil
+	}
+	if !errors.Is(err, cache.ErrMiss) {
+		logger.Error("Failed to read cached session", err)
+	}
+
+	session, err := load()
+	switch {
+	case errors.Is(err, repository.ErrSessionNotFound):
+		// Tokens are issued after their session is stored, so a token without a
+		// session never gets one
+		c.set(ctx, key, cachedSession{}, c.missTTL)
+	case err == nil:
+		ttl := time.Until(session.ExpiresAt)
+		if ttl > c.ttl {
+			ttl = c.ttl
+		}
+		c.set(ctx, key, cachedSession{Session: session}, ttl)
+	}
+	return session, err
+}
+
+func (c *SessionCache) set(ctx context.Context, key string, value cachedSession, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	if err := cache.SetValue(ctx, c.cache, key, value, ttl); err != nil {
+		logger.Error("Failed to cache session", err)
+	}
+}
+
+func userGenerationKey(userID primitive.ObjectID) string {
+	return "sessions:" + userID.Hex() + ":generation"
+}
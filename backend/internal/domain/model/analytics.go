@@ -0,0 +1,80 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Supported analytics granularities
+const (
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+)
+
+// AnalyticsQuery selects the attempts included in a performance report
+type AnalyticsQuery struct {
+	UserID      primitive.ObjectID
+	From        time.Time
+	To          time.Time
+	Granularity string
+	Timezone    string
+}
+
+// PerformanceSummary aggregates all attempts in the requested range
+type PerformanceSummary struct {
+	Attempts    int     `json:"attempts" bson:"attempts"`
+	Correct     int     `json:"correct" bson:"correct"`
+	Accuracy    float64 `json:"accuracy" bson:"accuracy"` // percentage
+	AverageTime float64 `json:"average_time" bson:"average_time"`
+	MedianTime  float64 `json:"median_time" bson:"median_time"`
+}
+
+// ResponseTimePercentiles describes the distribution of response times in seconds
+type ResponseTimePercentiles struct {
+	P50 float64 `json:"p50"`
+	P75 float64 `json:"p75"`
+	P90 float64 `json:"p90"`
+	P95 float64 `json:"p95"`
+}
+
+// PerformanceBucket aggregates the attempts made within one time period
+type PerformanceBucket struct {
+	Period     time.Time `json:"period" bson:"_id"`
+	Attempts   int       `json:"attempts" bson:"attempts"`
+	Correct    int       `json:"correct" bson:"correct"`
+	Accuracy   float64   `json:"accuracy" bson:"accuracy"`
+	MedianTime float64   `json:"median_time" bson:"median_time"`
+}
+
+// PerformanceBreakdown aggregates the attempts sharing a category, difficulty or tag
+type PerformanceBreakdown struct {
+	Key        string  `json:"key" bson:"_id"`
+	Attempts   int     `json:"attempts" bson:"attempts"`
+	Correct    int     `json:"correct" bson:"correct"`
+	Accuracy   float64 `json:"accuracy" bson:"accuracy"`
+	MedianTime float64 `json:"median_time" bson:"median_time"`
+}
+
+// PerformanceTrend describes how accuracy and speed change across the series
+type PerformanceTrend struct {
+	AccuracySlope   float64 `json:"accuracy_slope"`    // percentage points per period
+	MedianTimeSlope float64 `json:"median_time_slope"` // seconds per period
+	Direction       string  `json:"direction"`
+}
+
+// PerformanceReport is the full analytics report for a user and time range
+type PerformanceReport struct {
+	From         time.Time               `json:"from"`
+	To           time.Time               `json:"to"`
+	Granularity  string                  `json:"granularity"`
+	Timezone     string                  `json:"timezone"`
+	Summary      PerformanceSummary      `json:"summary"`
+	Percentiles  ResponseTimePercentiles `json:"percentiles"`
+	Series       []PerformanceBucket     `json:"series"`
+	ByCategory   []PerformanceBreakdown  `json:"by_category"`
+	ByDifficulty []PerformanceBreakdown  `json:"by_difficulty"`
+	ByTag        []PerformanceBreakdown  `json:"by_tag"`
+	Trend        PerformanceTrend        `json:"trend"`
+}
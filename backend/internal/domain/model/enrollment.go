@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Stage statuses within a learning path enrollment
+const (
+	StageStatusLocked     = "locked"
+	StageStatusInProgress = "in_progress"
+	StageStatusCompleted  = "completed"
+)
+
+// StageProgress tracks a user's progress through one stage of a learning path
+type StageProgress struct {
+	StageID            primitive.ObjectID `json:"stage_id" bson:"stage_id"`
+	Status             string             `json:"status" bson:"status"`
+	ExercisesAttempted int                `json:"exercises_attempted" bson:"exercises_attempted"`
+	Accuracy           float64            `json:"accuracy" bson:"accuracy"` // percentage
+	UnlockedAt         *time.Time         `json:"unlocked_at,omitempty" bson:"unlocked_at,omitempty"`
+	CompletedAt        *time.Time         `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
+
+// PathEnrollment records a user's enrollment in a learning path and their stage progression
+type PathEnrollment struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID      primitive.ObjectID `json:"user_id" bson:"user_id"`
+	PathID      primitive.ObjectID `json:"path_id" bson:"path_id"`
+	Stages      []StageProgress    `json:"stages" bson:"stages"`
+	EnrolledAt  time.Time          `json:"enrolled_at" bson:"enrolled_at"`
+	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+	CompletedAt *time.Time         `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
@@ -1,6 +1,9 @@
 package model
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -20,13 +23,36 @@ type ExerciseMetadata struct {
 }
 
 type Exercise struct {
-	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Title       string             `json:"title" bson:"title"`
-	Description string             `json:"description" bson:"description"`
-	Type        string             `json:"type" bson:"type"`
-	Category    string             `json:"category" bson:"category"`
-	Difficulty  string             `json:"difficulty" bson:"difficulty"`
-	Content     ExerciseContent    `json:"content" bson:"content"`
-	Metadata    ExerciseMetadata   `json:"metadata" bson:"metadata"`
-	Tags        []string           `json:"tags" bson:"tags"`
-}
\ No newline at end of file
+	ID          primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	ExternalKey string              `json:"external_key,omitempty" bson:"external_key,omitempty"` // set by bulk imports
+	ContentHash string              `json:"content_hash,omitempty" bson:"content_hash,omitempty"` // see ContentHash
+	Title       string              `json:"title" bson:"title"`
+	Description string              `json:"description" bson:"description"`
+	Type        string              `json:"type" bson:"type"`
+	Category    string              `json:"category" bson:"category"`
+	Difficulty  string              `json:"difficulty" bson:"difficulty"`
+	Content     ExerciseContent     `json:"content" bson:"content"`
+	Metadata    ExerciseMetadata    `json:"metadata" bson:"metadata"`
+	Tags        []string            `json:"tags" bson:"tags"`
+	Status      string              `json:"status" bson:"status"`
+	Review      Review              `json:"review" bson:"review"`
+	Revision    int                 `json:"revision" bson:"revision"`
+	DeletedAt   *time.Time          `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	DeletedBy   *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+}
+
+// ContentHash identifies an exercise by what it asks rather than by ID, so
+// copies in other environments can be recognised. Only the type, problem,
+// options and answer count; case and whitespace are ignored.
+func ContentHash(exercise *Exercise) string {
+	normalize := func(text string) string {
+		return strings.ToLower(strings.Join(strings.Fields(text), " "))
+	}
+
+	parts := []string{normalize(exercise.Type), normalize(exercise.Content.Problem), normalize(exercise.Content.CorrectAnswer)}
+	for _, option := range exercise.Content.Options {
+		parts = append(parts, normalize(option))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
@@ -6,16 +6,18 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// CompletionCriteria defines when a learner has completed a stage
 type CompletionCriteria struct {
-	MinAccuracy   float64 `json:"min_accuracy" bson:"min_accuracy"`
-	MinExercises  int     `json:"min_exercises" bson:"min_exercises"`
+	MinAccuracy  float64 `json:"min_accuracy" bson:"min_accuracy"`   // percentage of correct attempts
+	MinExercises int     `json:"min_exercises" bson:"min_exercises"` // distinct exercises attempted, 0 means all
 }
 
 type PathStage struct {
-	StageNumber       int                   `json:"stage_number" bson:"stage_number"`
-	Title             string                `json:"title" bson:"title"`
-	Description       string                `json:"description" bson:"description"`
-	ExerciseIDs       []primitive.ObjectID  `json:"exercise_ids" bson:"exercise_ids"`
+	ID                 primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
+	StageNumber        int                  `json:"stage_number" bson:"stage_number"`
+	Title              string               `json:"title" bson:"title"`
+	Description        string               `json:"description" bson:"description"`
+	ExerciseIDs        []primitive.ObjectID `json:"exercise_ids" bson:"exercise_ids"`
 	CompletionCriteria CompletionCriteria   `json:"completion_criteria" bson:"completion_criteria"`
 }
 
@@ -25,7 +27,19 @@ type LearningPath struct {
 	Description string             `json:"description" bson:"description"`
 	Difficulty  string             `json:"difficulty" bson:"difficulty"`
 	Categories  []string           `json:"categories" bson:"categories"`
+	Status      string             `json:"status" bson:"status"`
+	Review      Review             `json:"review" bson:"review"`
+	Revision    int                `json:"revision" bson:"revision"`
 	Stages      []PathStage        `json:"stages" bson:"stages"`
-	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
-}
\ No newline at end of file
+
+	// Prerequisites that must be satisfied before the path unlocks
+	PrerequisitePathIDs []primitive.ObjectID `json:"prerequisite_path_ids" bson:"prerequisite_path_ids"`
+	RequiredSkills      []string             `json:"required_skills" bson:"required_skills"`
+
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+
+	// Set while the path is in the trash
+	DeletedAt *time.Time          `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	DeletedBy *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+}
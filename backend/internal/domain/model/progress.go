@@ -6,18 +6,41 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// RecentAttemptLimit is the number of attempts kept on a progress summary
+const RecentAttemptLimit = 10
+
+// Attempt is a single answer submitted by a user, stored in the append-only attempts collection
 type Attempt struct {
-	Timestamp  time.Time `json:"timestamp" bson:"timestamp"`
-	UserAnswer string    `json:"user_answer" bson:"user_answer"`
-	IsCorrect  bool      `json:"is_correct" bson:"is_correct"`
-	TimeTaken  int       `json:"time_taken" bson:"time_taken"` // in seconds
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID     primitive.ObjectID `json:"user_id" bson:"user_id"`
+	ExerciseID primitive.ObjectID `json:"exercise_id" bson:"exercise_id"`
+	Timestamp  time.Time          `json:"timestamp" bson:"timestamp"`
+	UserAnswer string             `json:"user_answer" bson:"user_answer"`
+	IsCorrect  bool               `json:"is_correct" bson:"is_correct"`
+	TimeTaken  int                `json:"time_taken" bson:"time_taken"` // in seconds
+
+	// ExerciseRevision is the revision of the exercise the learner answered
+	ExerciseRevision int `json:"exercise_revision" bson:"exercise_revision"`
 }
 
+// AttemptTotals counts a user's attempts at one exercise
+type AttemptTotals struct {
+	ExerciseID    primitive.ObjectID `bson:"_id"`
+	Attempts      int                `bson:"attempts"`
+	Correct       int                `bson:"correct"`
+	TimeTaken     int                `bson:"time_taken"` // in seconds
+	LastAttempted time.Time          `bson:"last_attempted"`
+}
+
+// UserProgress is a rolling summary of a user's attempts at one exercise
 type UserProgress struct {
-	ID           primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
-	UserID       primitive.ObjectID   `json:"user_id" bson:"user_id"`
-	ExerciseID   primitive.ObjectID   `json:"exercise_id" bson:"exercise_id"`
-	Attempts     []Attempt            `json:"attempts" bson:"attempts"`
-	MasteryLevel float64              `json:"mastery_level" bson:"mastery_level"`
-	LastAttempted time.Time           `json:"last_attempted" bson:"last_attempted"`
-}
\ No newline at end of file
+	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID         primitive.ObjectID `json:"user_id" bson:"user_id"`
+	ExerciseID     primitive.ObjectID `json:"exercise_id" bson:"exercise_id"`
+	AttemptCount   int                `json:"attempt_count" bson:"attempt_count"`
+	CorrectCount   int                `json:"correct_count" bson:"correct_count"`
+	TotalTimeTaken int                `json:"total_time_taken" bson:"total_time_taken"`
+	RecentAttempts []Attempt          `json:"recent_attempts" bson:"recent_attempts"`
+	MasteryLevel   float64            `json:"mastery_level" bson:"mastery_level"`
+	LastAttempted  time.Time          `json:"last_attempted" bson:"last_attempted"`
+}
@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// Sort orders for exercise searches
+const (
+	ExerciseSortCreatedAt = "created_at"
+	ExerciseSortTitle     = "title"
+	ExerciseSortRelevance = "relevance" // text search score; requires Text
+)
+
+// ExerciseQuery combines the filters, free-text search and ordering of an exercise
+// search. Empty fields do not filter.
+type ExerciseQuery struct {
+	Category     string
+	Difficulty   string
+	Type         string
+	Tags         []string
+	MatchAllTags bool // require every tag rather than any of them
+	GeneratedBy  string
+	CreatedFrom  *time.Time
+	CreatedTo    *time.Time
+	Text         string
+	// Status only filters when IncludeUnpublished is set; otherwise only
+	// published exercises match
+	Status             string
+	Sort               string
+	Descending         bool
+	IncludeUnpublished bool
+	After              interface{} // sort value of the page cursor, in the sort field's type
+}
+
+// PathQuery filters learning paths. Empty fields do not filter.
+type PathQuery struct {
+	Difficulty string
+	Category   string
+	// Status only filters when IncludeUnpublished is set; otherwise only
+	// published paths match
+	Status             string
+	IncludeUnpublished bool
+}
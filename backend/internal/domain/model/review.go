@@ -0,0 +1,61 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Content statuses shared by exercises and learning paths. Content stored before
+// statuses existed has none and is treated as published.
+const (
+	StatusDraft     = "draft"
+	StatusInReview  = "in_review"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+// IsPublished reports whether content with the given status is visible to learners
+func IsPublished(status string) bool {
+	return status == "" || status == StatusPublished
+}
+
+// ReviewComment is a note left on content while it is being reviewed
+type ReviewComment struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"`
+	AuthorID  primitive.ObjectID `json:"author_id" bson:"author_id"`
+	Body      string             `json:"body" bson:"body"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// Review records who submitted, reviewed and approved a piece of content
+type Review struct {
+	SubmittedBy *primitive.ObjectID `json:"submitted_by,omitempty" bson:"submitted_by,omitempty"`
+	SubmittedAt *time.Time          `json:"submitted_at,omitempty" bson:"submitted_at,omitempty"`
+	ReviewerID  *primitive.ObjectID `json:"reviewer_id,omitempty" bson:"reviewer_id,omitempty"`
+	AssignedAt  *time.Time          `json:"assigned_at,omitempty" bson:"assigned_at,omitempty"`
+	ApprovedBy  *primitive.ObjectID `json:"approved_by,omitempty" bson:"approved_by,omitempty"`
+	ApprovedAt  *time.Time          `json:"approved_at,omitempty" bson:"approved_at,omitempty"`
+	Comments    []ReviewComment     `json:"comments" bson:"comments"`
+}
+
+// RemoveUser removes a deleted user from the review record. Their submission,
+// approval and comments are kept without an author, and an assignment to them
+// is dropped.
+func (r *Review) RemoveUser(userID primitive.ObjectID) {
+	if r.SubmittedBy != nil && *r.SubmittedBy == userID {
+		r.SubmittedBy = new(primitive.ObjectID)
+	}
+	if r.ApprovedBy != nil && *r.ApprovedBy == userID {
+		r.ApprovedBy = new(primitive.ObjectID)
+	}
+	if r.ReviewerID != nil && *r.ReviewerID == userID {
+		r.ReviewerID = nil
+		r.AssignedAt = nil
+	}
+	for i := range r.Comments {
+		if r.Comments[i].AuthorID == userID {
+			r.Comments[i].AuthorID = primitive.NilObjectID
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of content that are reviewed and keep a revision history
+const (
+	ContentKindExercise     = "exercise"
+	ContentKindLearningPath = "learning_path"
+)
+
+// AnyRevision skips the revision check on writes that do not need one
+const AnyRevision = -1
+
+// RevisionMeta describes who made a change and why. A zero author marks a
+// change made by the system.
+type RevisionMeta struct {
+	AuthorID primitive.ObjectID
+	Note     string
+}
+
+// Revision is an immutable snapshot of an exercise or learning path taken after a change
+type Revision struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Kind       string             `json:"kind" bson:"kind"`
+	DocumentID primitive.ObjectID `json:"document_id" bson:"document_id"`
+	Number     int                `json:"number" bson:"number"`
+	AuthorID   primitive.ObjectID `json:"author_id" bson:"author_id"`
+	Note       string             `json:"note" bson:"note"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+	Snapshot   bson.Raw           `json:"-" bson:"snapshot"`
+}
+
+// DecodeSnapshot decodes the revision's snapshot into the model of its kind
+func (r *Revision) DecodeSnapshot(v interface{}) error {
+	return bson.Unmarshal(r.Snapshot, v)
+}
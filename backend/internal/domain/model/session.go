@@ -9,6 +9,7 @@ import (
 type UserSession struct {
 	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	UserID       primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Role         string             `json:"role" bson:"role"`
 	SessionToken string             `json:"session_token" bson:"session_token"`
 	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
 	ExpiresAt    time.Time          `json:"expires_at" bson:"expires_at"`
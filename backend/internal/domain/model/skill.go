@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultMasteryThreshold is the mastery level at which a skill counts as mastered
+const DefaultMasteryThreshold = 0.8
+
+// Skill describes an exercise tag as a skill that can depend on other skills
+type Skill struct {
+	ID               primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Tag              string             `json:"tag" bson:"tag"`
+	Name             string             `json:"name" bson:"name"`
+	Description      string             `json:"description" bson:"description"`
+	Prerequisites    []string           `json:"prerequisites" bson:"prerequisites"`         // tags of prerequisite skills
+	MasteryThreshold float64            `json:"mastery_threshold" bson:"mastery_threshold"` // 0 to 1, like mastery_level
+	CreatedAt        time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at" bson:"updated_at"`
+}
@@ -0,0 +1,87 @@
+package model
+
+import "time"
+
+const (
+	// DayLayout is the format used to store calendar days on user statistics
+	DayLayout = "2006-01-02"
+
+	// A streak freeze is earned for every StreakFreezeInterval consecutive days,
+	// up to MaxStreakFreezes banked at once
+	StreakFreezeInterval = 7
+	MaxStreakFreezes     = 2
+)
+
+// StatisticsAttempt is an attempt as it counts towards a user's statistics
+type StatisticsAttempt struct {
+	IsCorrect bool
+	Timestamp time.Time
+	Day       string // calendar day of the attempt in the user's timezone, in DayLayout
+	DailyGoal int
+}
+
+// ApplyAttempt counts an attempt towards the answer totals and the daily goal,
+// advancing the streak when the goal is met for the first time on a calendar
+// day. Missed days are bridged by spending banked streak freezes. Repositories
+// that cannot call this apply the same rules in their own update.
+func (s *UserStatistics) ApplyAttempt(attempt StatisticsAttempt) {
+	if s.CorrectExercises == 0 && s.TotalExercisesCompleted > 0 {
+		// Statistics from before correct answers were counted only kept the average
+		s.CorrectExercises = int(float64(s.TotalExercisesCompleted)*s.AverageAccuracy/100 + 0.5)
+	}
+	s.TotalExercisesCompleted++
+	if attempt.IsCorrect {
+		s.CorrectExercises++
+	}
+	s.AverageAccuracy = float64(s.CorrectExercises) / float64(s.TotalExercisesCompleted) * 100
+	if attempt.Timestamp.After(s.LastActive) {
+		s.LastActive = attempt.Timestamp
+	}
+	s.Revision++
+
+	if s.DailyProgressDate != attempt.Day {
+		s.DailyProgressDate = attempt.Day
+		s.DailyProgressCount = 0
+	}
+	s.DailyProgressCount++
+
+	if s.DailyProgressCount < attempt.DailyGoal || s.LastGoalDate == attempt.Day {
+		return
+	}
+
+	gap, ok := DaysBetween(s.LastGoalDate, attempt.Day)
+	switch {
+	case ok && gap < 1:
+		// The user moved to an earlier timezone; this day was already counted
+		return
+	case ok && gap == 1:
+		s.StreakDays++
+	case ok && gap > 1 && gap-1 <= s.StreakFreezes:
+		s.StreakFreezes -= gap - 1
+		s.StreakDays++
+	default:
+		s.StreakDays = 1
+	}
+	s.LastGoalDate = attempt.Day
+
+	if s.StreakDays > s.LongestStreak {
+		s.LongestStreak = s.StreakDays
+	}
+	if s.StreakDays%StreakFreezeInterval == 0 && s.StreakFreezes < MaxStreakFreezes {
+		s.StreakFreezes++
+	}
+}
+
+// DaysBetween returns the number of calendar days from one day to another,
+// both in DayLayout
+func DaysBetween(from, to string) (int, bool) {
+	fromDay, err := time.Parse(DayLayout, from)
+	if err != nil {
+		return 0, false
+	}
+	toDay, err := time.Parse(DayLayout, to)
+	if err != nil {
+		return 0, false
+	}
+	return int(toDay.Sub(fromDay).Hours() / 24), true
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApplyAttemptBridgesMissedDaysWithFreezes(t *testing.T) {
+	stats := UserStatistics{StreakDays: 7, LongestStreak: 7, StreakFreezes: 1, LastGoalDate: "2026-03-01"}
+
+	// One missed day is covered by the banked freeze
+	stats.ApplyAttempt(StatisticsAttempt{IsCorrect: true, Timestamp: time.Now(), Day: "2026-03-03", DailyGoal: 1})
+	if stats.StreakDays != 8 || stats.StreakFreezes != 0 {
+		t.Fatalf("streak = %d, freezes = %d; want 8, 0", stats.StreakDays, stats.StreakFreezes)
+	}
+
+	// Meeting the goal again on the same day does not extend the streak
+	stats.ApplyAttempt(StatisticsAttempt{IsCorrect: false, Timestamp: time.Now(), Day: "2026-03-03", DailyGoal: 1})
+	if stats.StreakDays != 8 || stats.DailyProgressCount != 2 {
+		t.Errorf("streak = %d, daily progress = %d; want 8, 2", stats.StreakDays, stats.DailyProgressCount)
+	}
+
+	// Without freezes a missed day restarts the streak
+	stats.ApplyAttempt(StatisticsAttempt{IsCorrect: true, Timestamp: time.Now(), Day: "2026-03-05", DailyGoal: 1})
+	if stats.StreakDays != 1 || stats.LongestStreak != 8 {
+		t.Errorf("streak = %d, longest = %d; want 1, 8", stats.StreakDays, stats.LongestStreak)
+	}
+	if stats.TotalExercisesCompleted != 3 || stats.CorrectExercises != 2 || stats.Revision != 3 {
+		t.Errorf("totals = %d, %d correct, revision %d", stats.TotalExercisesCompleted, stats.CorrectExercises, stats.Revision)
+	}
+}
+
+func TestApplyAttemptBackfillsLegacyCorrectAnswers(t *testing.T) {
+	// Statistics from before correct answers were counted
+	stats := UserStatistics{TotalExercisesCompleted: 4, AverageAccuracy: 75}
+
+	stats.ApplyAttempt(StatisticsAttempt{IsCorrect: true, Timestamp: time.Now(), Day: "2026-03-01", DailyGoal: 10})
+	if stats.CorrectExercises != 4 || stats.AverageAccuracy != 80 {
+		t.Errorf("correct = %d, accuracy = %v; want 4, 80", stats.CorrectExercises, stats.AverageAccuracy)
+	}
+}
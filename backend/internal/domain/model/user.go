@@ -6,6 +6,13 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// User roles
+const (
+	RoleUser   = "user"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
 type NotificationSettings struct {
 	Enabled bool   `json:"enabled" bson:"enabled"`
 	Time    string `json:"time" bson:"time"`
@@ -20,9 +27,20 @@ type UserPreferences struct {
 
 type UserStatistics struct {
 	TotalExercisesCompleted int       `json:"total_exercises_completed" bson:"total_exercises_completed"`
+	CorrectExercises        int       `json:"correct_exercises" bson:"correct_exercises"`
 	StreakDays              int       `json:"streak_days" bson:"streak_days"`
+	LongestStreak           int       `json:"longest_streak" bson:"longest_streak"`
+	StreakFreezes           int       `json:"streak_freezes" bson:"streak_freezes"`
 	AverageAccuracy         float64   `json:"average_accuracy" bson:"average_accuracy"`
 	LastActive              time.Time `json:"last_active" bson:"last_active"`
+
+	// Calendar days are stored as YYYY-MM-DD in the user's timezone
+	LastGoalDate       string `json:"last_goal_date,omitempty" bson:"last_goal_date"`
+	DailyProgressDate  string `json:"daily_progress_date,omitempty" bson:"daily_progress_date"`
+	DailyProgressCount int    `json:"daily_progress_count" bson:"daily_progress_count"`
+
+	// Revision is incremented on every change and guards concurrent updates
+	Revision int64 `json:"-" bson:"revision"`
 }
 
 type User struct {
@@ -32,9 +50,18 @@ type User struct {
 	Username     string             `json:"username" bson:"username"`
 	FirstName    string             `json:"first_name" bson:"first_name"`
 	LastName     string             `json:"last_name" bson:"last_name"`
+	Timezone     string             `json:"timezone" bson:"timezone"`
+	Role         string             `json:"role" bson:"role"`
 	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at"`
 	LastLogin    time.Time          `json:"last_login" bson:"last_login"`
 	Preferences  UserPreferences    `json:"preferences" bson:"preferences"`
 	Statistics   UserStatistics     `json:"statistics" bson:"statistics"`
+
+	// DeletionScheduledAt is when a requested account deletion takes effect
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty" bson:"deletion_scheduled_at,omitempty"`
+
+	// Set while an administrator has the account in the trash
+	DeletedAt *time.Time          `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	DeletedBy *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
 }
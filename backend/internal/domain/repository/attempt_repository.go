@@ -0,0 +1,275 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AttemptRepository interface {
+	Create(ctx context.Context, attempt *model.Attempt) error
+	GetByUser(ctx context.Context, userID primitive.ObjectID, from, to time.Time) ([]*model.Attempt, error)
+	GetByUserAndExercise(ctx context.Context, userID, exerciseID primitive.ObjectID, limit int) ([]*model.Attempt, error)
+	AggregatePerformance(ctx context.Context, query model.AnalyticsQuery) (*model.PerformanceReport, error)
+	TotalsByExercise(ctx context.Context, userID primitive.ObjectID) ([]model.AttemptTotals, error)
+	AnonymizeUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
+
+type MongoAttemptRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAttemptRepository(db *mongo.Database) AttemptRepository {
+	return &MongoAttemptRepository{collection: db.Collection("attempts")}
+}
+
+func (r *MongoAttemptRepository) Create(ctx context.Context, attempt *model.Attempt) error {
+	attempt.ID = primitive.NewObjectID()
+	if attempt.Timestamp.IsZero() {
+		attempt.Timestamp = time.Now()
+	}
+
+	_, err := r.collection.InsertOne(ctx, attempt)
+	return err
+}
+
+func (r *MongoAttemptRepository) GetByUser(ctx context.Context, userID primitive.ObjectID, from, to time.Time) ([]*model.Attempt, error) {
+	filter := bson.M{
+		"user_id":   userID,
+		"timestamp": bson.M{"$gte": from, "$lt": to},
+	}
+	findOptions := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var attempts []*model.Attempt
+	if err := cursor.All(ctx, &attempts); err != nil {
+		return nil, err
+	}
+
+	return attempts, nil
+}
+
+// AnonymizeUser detaches a deleted user's attempts so they still count towards
+// exercise statistics. The attempts move to a new random ID that cannot be
+// traced back to the user, and free-text answers are cleared.
+func (r *MongoAttemptRepository) AnonymizeUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	update := bson.M{"$set": bson.M{"user_id": primitive.NewObjectID(), "user_answer": ""}}
+	result, err := r.collection.UpdateMany(ctx, bson.M{"user_id": userID}, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (r *MongoAttemptRepository) GetByUserAndExercise(ctx context.Context, userID, exerciseID primitive.ObjectID, limit int) ([]*model.Attempt, error) {
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "timestamp", Value: -1}})
+	findOptions.SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID, "exercise_id": exerciseID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var attempts []*model.Attempt
+	if err := cursor.All(ctx, &attempts); err != nil {
+		return nil, err
+	}
+
+	return attempts, nil
+}
+
+func (r *MongoAttemptRepository) AggregatePerformance(ctx context.Context, query model.AnalyticsQuery) (*model.PerformanceReport, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"user_id":   query.UserID,
+			"timestamp": bson.M{"$gte": query.From, "$lt": query.To},
+		}}},
+	}
+	pipeline = append(pipeline, performanceStages(query)...)
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var facets []performanceFacets
+	if err := cursor.All(ctx, &facets); err != nil {
+		return nil, err
+	}
+
+	return buildPerformanceReport(query, facets), nil
+}
+
+// TotalsByExercise counts a user's attempts at each exercise they answered, in
+// exercise ID order
+func (r *MongoAttemptRepository) TotalsByExercise(ctx context.Context, userID primitive.ObjectID) ([]model.AttemptTotals, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":            "$exercise_id",
+			"attempts":       bson.M{"$sum": 1},
+			"correct":        bson.M{"$sum": bson.M{"$cond": bson.A{"$is_correct", 1, 0}}},
+			"time_taken":     bson.M{"$sum": "$time_taken"},
+			"last_attempted": bson.M{"$max": "$timestamp"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	totals := []model.AttemptTotals{}
+	if err := cursor.All(ctx, &totals); err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// performanceFacets mirrors the output of the performance aggregation pipeline
+type performanceFacets struct {
+	Summary []struct {
+		model.PerformanceSummary `bson:",inline"`
+		Percentiles              []float64 `bson:"percentiles"`
+	} `bson:"summary"`
+	Series       []model.PerformanceBucket    `bson:"series"`
+	ByCategory   []model.PerformanceBreakdown `bson:"by_category"`
+	ByDifficulty []model.PerformanceBreakdown `bson:"by_difficulty"`
+	ByTag        []model.PerformanceBreakdown `bson:"by_tag"`
+}
+
+// performanceStages groups attempts into the summary, time series and breakdowns of a performance report
+func performanceStages(query model.AnalyticsQuery) mongo.Pipeline {
+	bucketStats := func(key interface{}) bson.D {
+		return bson.D{{Key: "$group", Value: bson.M{
+			"_id":         key,
+			"attempts":    bson.M{"$sum": 1},
+			"correct":     bson.M{"$sum": bson.M{"$cond": bson.A{"$is_correct", 1, 0}}},
+			"median_time": bson.M{"$median": bson.M{"input": "$time_taken", "method": "approximate"}},
+		}}}
+	}
+	byAttempts := bson.D{{Key: "$sort", Value: bson.D{{Key: "attempts", Value: -1}, {Key: "_id", Value: 1}}}}
+
+	return mongo.Pipeline{
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "exercises",
+			"localField":   "exercise_id",
+			"foreignField": "_id",
+			"as":           "exercise",
+		}}},
+		{{Key: "$unwind", Value: bson.M{"path": "$exercise", "preserveNullAndEmptyArrays": true}}},
+		{{Key: "$facet", Value: bson.M{
+			"summary": bson.A{
+				bson.D{{Key: "$group", Value: bson.M{
+					"_id":          nil,
+					"attempts":     bson.M{"$sum": 1},
+					"correct":      bson.M{"$sum": bson.M{"$cond": bson.A{"$is_correct", 1, 0}}},
+					"average_time": bson.M{"$avg": "$time_taken"},
+					"median_time":  bson.M{"$median": bson.M{"input": "$time_taken", "method": "approximate"}},
+					"percentiles": bson.M{"$percentile": bson.M{
+						"input":  "$time_taken",
+						"p":      bson.A{0.5, 0.75, 0.9, 0.95},
+						"method": "approximate",
+					}},
+				}}},
+			},
+			"series": bson.A{
+				bucketStats(bson.M{"$dateTrunc": bson.M{
+					"date":        "$timestamp",
+					"unit":        query.Granularity,
+					"timezone":    query.Timezone,
+					"startOfWeek": "monday",
+				}}),
+				bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+			},
+			"by_category": bson.A{
+				bucketStats(bson.M{"$ifNull": bson.A{"$exercise.category", "unknown"}}),
+				byAttempts,
+			},
+			"by_difficulty": bson.A{
+				bucketStats(bson.M{"$ifNull": bson.A{"$exercise.difficulty", "unknown"}}),
+				byAttempts,
+			},
+			"by_tag": bson.A{
+				bson.D{{Key: "$unwind", Value: "$exercise.tags"}},
+				bucketStats("$exercise.tags"),
+				byAttempts,
+				bson.D{{Key: "$limit", Value: 50}},
+			},
+		}}},
+	}
+}
+
+// buildPerformanceReport converts the aggregation output into a report and fills in accuracies
+func buildPerformanceReport(query model.AnalyticsQuery, facets []performanceFacets) *model.PerformanceReport {
+	report := &model.PerformanceReport{
+		From:         query.From,
+		To:           query.To,
+		Granularity:  query.Granularity,
+		Timezone:     query.Timezone,
+		Series:       []model.PerformanceBucket{},
+		ByCategory:   []model.PerformanceBreakdown{},
+		ByDifficulty: []model.PerformanceBreakdown{},
+		ByTag:        []model.PerformanceBreakdown{},
+	}
+	if len(facets) == 0 {
+		return report
+	}
+
+	result := facets[0]
+	if len(result.Summary) > 0 {
+		summary := result.Summary[0]
+		report.Summary = summary.PerformanceSummary
+		report.Summary.Accuracy = accuracy(summary.Correct, summary.Attempts)
+		if len(summary.Percentiles) == 4 {
+			report.Percentiles = model.ResponseTimePercentiles{
+				P50: summary.Percentiles[0],
+				P75: summary.Percentiles[1],
+				P90: summary.Percentiles[2],
+				P95: summary.Percentiles[3],
+			}
+		}
+	}
+
+	for _, bucket := range result.Series {
+		bucket.Accuracy = accuracy(bucket.Correct, bucket.Attempts)
+		report.Series = append(report.Series, bucket)
+	}
+	report.ByCategory = withAccuracy(result.ByCategory)
+	report.ByDifficulty = withAccuracy(result.ByDifficulty)
+	report.ByTag = withAccuracy(result.ByTag)
+
+	return report
+}
+
+func withAccuracy(breakdowns []model.PerformanceBreakdown) []model.PerformanceBreakdown {
+	result := make([]model.PerformanceBreakdown, 0, len(breakdowns))
+	for _, breakdown := range breakdowns {
+		breakdown.Accuracy = accuracy(breakdown.Correct, breakdown.Attempts)
+		result = append(result, breakdown)
+	}
+	return result
+}
+
+// accuracy returns the percentage of correct attempts
+func accuracy(correct, attempts int) float64 {
+	if attempts == 0 {
+		return 0
+	}
+	return float64(correct) / float64(attempts) * 100
+}
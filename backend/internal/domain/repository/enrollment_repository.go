@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type EnrollmentRepository interface {
+	Create(ctx context.Context, enrollment *model.PathEnrollment) error
+	GetByUserAndPath(ctx context.Context, userID, pathID primitive.ObjectID) (*model.PathEnrollment, error)
+	GetActiveByUserID(ctx context.Context, userID primitive.ObjectID) ([]*model.PathEnrollment, error)
+	GetCompletedByUserID(ctx context.Context, userID primitive.ObjectID) ([]*model.PathEnrollment, error)
+	GetByUserID(ctx context.Context, userID primitive.ObjectID) ([]*model.PathEnrollment, error)
+	DeleteByUserID(ctx context.Context, userID primitive.ObjectID) (int64, error)
+	DeleteByPathID(ctx context.Context, pathID primitive.ObjectID) (int64, error)
+	Update(ctx context.Context, enrollment *model.PathEnrollment) error
+}
+
+type MongoEnrollmentRepository struct {
+	collection *mongo.Collection
+}
+
+func NewEnrollmentRepository(db *mongo.Database) EnrollmentRepository {
+	return &MongoEnrollmentRepository{collection: db.Collection("path_enrollments")}
+}
+
+func (r *MongoEnrollmentRepository) Create(ctx context.Context, enrollment *model.PathEnrollment) error {
+	enrollment.ID = primitive.NewObjectID()
+	enrollment.EnrolledAt = time.Now()
+	enrollment.UpdatedAt = enrollment.EnrolledAt
+
+	_, err := r.collection.InsertOne(ctx, enrollment)
+	if mongo.IsDuplicateKeyError(err) {
+		return errors.New("already enrolled in learning path")
+	}
+	return err
+}
+
+func (r *MongoEnrollmentRepository) GetByUserAndPath(ctx context.Context, userID, pathID primitive.ObjectID) (*model.PathEnrollment, error) {
+	var enrollment model.PathEnrollment
+	err := r.collection.FindOne(ctx, bson.M{"user_id": userID, "path_id": pathID}).Decode(&enrollment)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("enrollment not found")
+		}
+		return nil, err
+	}
+	return &enrollment, nil
+}
+
+func (r *MongoEnrollmentRepository) GetActiveByUserID(ctx context.Context, userID primitive.ObjectID) ([]*model.PathEnrollment, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID, "completed_at": bson.M{"$exists": false}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var enrollments []*model.PathEnrollment
+	if err := cursor.All(ctx, &enrollments); err != nil {
+		return nil, err
+	}
+
+	return enrollments, nil
+}
+
+// GetByUserID returns all of a user's enrollments, active and completed
+func (r *MongoEnrollmentRepository) GetByUserID(ctx context.Context, userID primitive.ObjectID) ([]*model.PathEnrollment, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, options.Find().SetSort(bson.D{{Key: "enrolled_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var enrollments []*model.PathEnrollment
+	if err := cursor.All(ctx, &enrollments); err != nil {
+		return nil, err
+	}
+
+	return enrollments, nil
+}
+
+// DeleteByUserID removes all of a user's enrollments
+func (r *MongoEnrollmentRepository) DeleteByUserID(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// DeleteByPathID removes every enrollment in a learning path
+func (r *MongoEnrollmentRepository) DeleteByPathID(ctx context.Context, pathID primitive.ObjectID) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"path_id": pathID})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (r *MongoEnrollmentRepository) GetCompletedByUserID(ctx context.Context, userID primitive.ObjectID) ([]*model.PathEnrollment, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID, "completed_at": bson.M{"$exists": true}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var enrollments []*model.PathEnrollment
+	if err := cursor.All(ctx, &enrollments); err != nil {
+		return nil, err
+	}
+
+	return enrollments, nil
+}
+
+func (r *MongoEnrollmentRepository) Update(ctx context.Context, enrollment *model.PathEnrollment) error {
+	enrollment.UpdatedAt = time.Now()
+
+	filter := bson.M{"_id": enrollment.ID}
+	update := bson.M{"$set": enrollment}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	return err
+}
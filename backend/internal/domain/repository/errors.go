@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Errors shared by every implementation of the repositories, so callers do not
+// depend on a storage driver's errors
+var (
+	ErrUserNotFound     = errors.New("user not found")
+	ErrSessionNotFound  = errors.New("session not found")
+	ErrExerciseNotFound = errors.New("exercise not found")
+	ErrProgressNotFound = errors.New("progress record not found")
+	ErrPathNotFound     = errors.New("learning path not found")
+	ErrStageNotFound    = errors.New("stage not found")
+
+	// ErrDuplicateKey is returned when a write would break a uniqueness constraint
+	ErrDuplicateKey = errors.New("duplicate key")
+	// ErrVersionConflict is returned when a document changed since the caller read it
+	ErrVersionConflict = errors.New("document was modified by another update")
+)
+
+// translateWriteError maps a Mongo duplicate key error to ErrDuplicateKey
+func translateWriteError(err error) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateKey
+	}
+	return err
+}
@@ -3,8 +3,10 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -12,68 +14,165 @@ import (
 )
 
 type ExerciseRepository interface {
-	Create(ctx context.Context, exercise *model.Exercise) error
+	Create(ctx context.Context, exercise *model.Exercise, meta model.RevisionMeta) error
 	GetByID(ctx context.Context, id primitive.ObjectID) (*model.Exercise, error)
-	GetByCategory(ctx context.Context, category string, limit, offset int) ([]*model.Exercise, error)
-	GetByDifficulty(ctx context.Context, difficulty string, limit, offset int) ([]*model.Exercise, error)
-	GetByTags(ctx context.Context, tags []string, limit, offset int) ([]*model.Exercise, error)
-	Update(ctx context.Context, exercise *model.Exercise) error
+	GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*model.Exercise, error)
+	GetByExternalKey(ctx context.Context, key string) (*model.Exercise, error)
+	GetByContentHashes(ctx context.Context, hashes []string) ([]*model.Exercise, error)
+	GetByCategory(ctx context.Context, category string, includeUnpublished bool, page pagination.Params) ([]*model.Exercise, error)
+	GetByDifficulty(ctx context.Context, difficulty string, includeUnpublished bool, page pagination.Params) ([]*model.Exercise, error)
+	GetByTags(ctx context.Context, tags []string, includeUnpublished bool, page pagination.Params) ([]*model.Exercise, error)
+	Update(ctx context.Context, exercise *model.Exercise, meta model.RevisionMeta) error
 	Delete(ctx context.Context, id primitive.ObjectID) error
-	Count(ctx context.Context, filter bson.M) (int64, error)
+	SoftDelete(ctx context.Context, id, deletedBy primitive.ObjectID) error
+	Restore(ctx context.Context, id primitive.ObjectID) error
+	GetDeleted(ctx context.Context, before time.Time, page pagination.Params) ([]*model.Exercise, error)
+	CountDeleted(ctx context.Context) (int64, error)
+	GetByStatus(ctx context.Context, status string, page pagination.Params, after *time.Time) ([]*model.Exercise, error)
+	Count(ctx context.Context, query model.ExerciseQuery) (int64, error)
+	Search(ctx context.Context, query model.ExerciseQuery, page pagination.Params) ([]*model.Exercise, error)
+	ForEach(ctx context.Context, query model.ExerciseQuery, fn func(*model.Exercise) error) error
+	AnonymizeUser(ctx context.Context, userID primitive.ObjectID) error
+}
+
+// exerciseSortFields maps sort orders to the field they sort on
+var exerciseSortFields = map[string]string{
+	model.ExerciseSortCreatedAt: "metadata.created_at",
+	model.ExerciseSortTitle:     "title",
+}
+
+// exerciseFilter returns a query's filters as a Mongo filter, without the cursor
+func exerciseFilter(q model.ExerciseQuery) bson.M {
+	filter := bson.M{}
+	if q.Category != "" {
+		filter["category"] = q.Category
+	}
+	if q.Difficulty != "" {
+		filter["difficulty"] = q.Difficulty
+	}
+	if q.Type != "" {
+		filter["type"] = q.Type
+	}
+	if len(q.Tags) > 0 {
+		if q.MatchAllTags {
+			filter["tags"] = bson.M{"$all": q.Tags}
+		} else {
+			filter["tags"] = bson.M{"$in": q.Tags}
+		}
+	}
+	if q.GeneratedBy != "" {
+		filter["metadata.generated_by"] = q.GeneratedBy
+	}
+	if q.CreatedFrom != nil || q.CreatedTo != nil {
+		created := bson.M{}
+		if q.CreatedFrom != nil {
+			created["$gte"] = *q.CreatedFrom
+		}
+		if q.CreatedTo != nil {
+			created["$lte"] = *q.CreatedTo
+		}
+		filter["metadata.created_at"] = created
+	}
+	if q.Text != "" {
+		filter["$text"] = bson.M{"$search": q.Text}
+	}
+	if q.Status != "" && q.IncludeUnpublished {
+		filter["status"] = q.Status
+	}
+	return filter
 }
 
 type MongoExerciseRepository struct {
 	collection *mongo.Collection
+	revisions  *mongo.Collection
 }
 
 func NewExerciseRepository(db *mongo.Database) ExerciseRepository {
-	collection := db.Collection("exercises")
+	return &MongoExerciseRepository{
+		collection: db.Collection("exercises"),
+		revisions:  db.Collection(revisionsCollection),
+	}
+}
 
-	// Create indexes
-	indexes := []mongo.IndexModel{
-		{
-			Keys: bson.D{{Key: "category", Value: 1}},
-		},
-		{
-			Keys: bson.D{{Key: "difficulty", Value: 1}},
-		},
-		{
-			Keys: bson.D{{Key: "tags", Value: 1}},
-		},
+// Create inserts the exercise and records it as its first revision
+func (r *MongoExerciseRepository) Create(ctx context.Context, exercise *model.Exercise, meta model.RevisionMeta) error {
+	exercise.ID = primitive.NewObjectID()
+	exercise.Revision = 1
+	exercise.ContentHash = model.ContentHash(exercise)
+
+	if _, err := r.collection.InsertOne(ctx, exercise); err != nil {
+		return translateWriteError(err)
 	}
+	return saveRevision(ctx, r.revisions, model.ContentKindExercise, exercise.ID, exercise.Revision, exercise, meta)
+}
 
-	_, err := collection.Indexes().CreateMany(context.Background(), indexes)
+// GetByID returns an exercise that is not in the trash
+func (r *MongoExerciseRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*model.Exercise, error) {
+	var exercise model.Exercise
+	err := r.collection.FindOne(ctx, notDeleted(bson.M{"_id": id})).Decode(&exercise)
 	if err != nil {
-		panic(err)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrExerciseNotFound
+		}
+		return nil, err
 	}
-
-	return &MongoExerciseRepository{collection: collection}
+	return &exercise, nil
 }
 
-func (r *MongoExerciseRepository) Create(ctx context.Context, exercise *model.Exercise) error {
-	exercise.ID = primitive.NewObjectID()
-	_, err := r.collection.InsertOne(ctx, exercise)
-	return err
+// GetByIDs returns the exercises with the given IDs, including soft-deleted ones.
+// IDs that do not exist are silently skipped.
+func (r *MongoExerciseRepository) GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*model.Exercise, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var exercises []*model.Exercise
+	if err := cursor.All(ctx, &exercises); err != nil {
+		return nil, err
+	}
+
+	return exercises, nil
 }
 
-func (r *MongoExerciseRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*model.Exercise, error) {
+// GetByExternalKey returns the exercise imported under a key. Exercises in the
+// trash are skipped, since their keys can be reused.
+func (r *MongoExerciseRepository) GetByExternalKey(ctx context.Context, key string) (*model.Exercise, error) {
 	var exercise model.Exercise
-	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&exercise)
+	err := r.collection.FindOne(ctx, notDeleted(bson.M{"external_key": key})).Decode(&exercise)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("exercise not found")
+			return nil, ErrExerciseNotFound
 		}
 		return nil, err
 	}
 	return &exercise, nil
 }
 
-func (r *MongoExerciseRepository) GetByCategory(ctx context.Context, category string, limit, offset int) ([]*model.Exercise, error) {
+// GetByContentHashes returns the exercises, excluding soft-deleted ones, whose
+// content hash is one of the given hashes, oldest first
+func (r *MongoExerciseRepository) GetByContentHashes(ctx context.Context, hashes []string) ([]*model.Exercise, error) {
+	filter := bson.M{"content_hash": bson.M{"$in": hashes}, "deleted_at": bson.M{"$exists": false}}
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var exercises []*model.Exercise
+	if err := cursor.All(ctx, &exercises); err != nil {
+		return nil, err
+	}
+
+	return exercises, nil
+}
+
+func (r *MongoExerciseRepository) GetByCategory(ctx context.Context, category string, includeUnpublished bool, page pagination.Params) ([]*model.Exercise, error) {
 	findOptions := options.Find()
-	findOptions.SetLimit(int64(limit))
-	findOptions.SetSkip(int64(offset))
+	filter := page.Apply(listable(bson.M{"category": category}, includeUnpublished), findOptions)
 
-	cursor, err := r.collection.Find(ctx, bson.M{"category": category}, findOptions)
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -87,12 +186,11 @@ func (r *MongoExerciseRepository) GetByCategory(ctx context.Context, category st
 	return exercises, nil
 }
 
-func (r *MongoExerciseRepository) GetByDifficulty(ctx context.Context, difficulty string, limit, offset int) ([]*model.Exercise, error) {
+func (r *MongoExerciseRepository) GetByDifficulty(ctx context.Context, difficulty string, includeUnpublished bool, page pagination.Params) ([]*model.Exercise, error) {
 	findOptions := options.Find()
-	findOptions.SetLimit(int64(limit))
-	findOptions.SetSkip(int64(offset))
+	filter := page.Apply(listable(bson.M{"difficulty": difficulty}, includeUnpublished), findOptions)
 
-	cursor, err := r.collection.Find(ctx, bson.M{"difficulty": difficulty}, findOptions)
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -106,12 +204,11 @@ func (r *MongoExerciseRepository) GetByDifficulty(ctx context.Context, difficult
 	return exercises, nil
 }
 
-func (r *MongoExerciseRepository) GetByTags(ctx context.Context, tags []string, limit, offset int) ([]*model.Exercise, error) {
+func (r *MongoExerciseRepository) GetByTags(ctx context.Context, tags []string, includeUnpublished bool, page pagination.Params) ([]*model.Exercise, error) {
 	findOptions := options.Find()
-	findOptions.SetLimit(int64(limit))
-	findOptions.SetSkip(int64(offset))
+	filter := page.Apply(listable(bson.M{"tags": bson.M{"$in": tags}}, includeUnpublished), findOptions)
 
-	cursor, err := r.collection.Find(ctx, bson.M{"tags": bson.M{"$in": tags}}, findOptions)
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -125,12 +222,45 @@ func (r *MongoExerciseRepository) GetByTags(ctx context.Context, tags []string,
 	return exercises, nil
 }
 
-func (r *MongoExerciseRepository) Update(ctx context.Context, exercise *model.Exercise) error {
-	filter := bson.M{"_id": exercise.ID}
+// GetByStatus lists exercises in a status, oldest submission first. after is the
+// submission time of page.After in cursor mode.
+func (r *MongoExerciseRepository) GetByStatus(ctx context.Context, status string, page pagination.Params, after *time.Time) ([]*model.Exercise, error) {
+	findOptions := options.Find()
+	filter := page.ApplySorted(notDeleted(bson.M{"status": status}), findOptions, "review.submitted_at", after, false)
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var exercises []*model.Exercise
+	if err := cursor.All(ctx, &exercises); err != nil {
+		return nil, err
+	}
+
+	return exercises, nil
+}
+
+// Update replaces the exercise if it is still at the revision it was read at and
+// records the result as a new revision. A stale write returns ErrVersionConflict.
+func (r *MongoExerciseRepository) Update(ctx context.Context, exercise *model.Exercise, meta model.RevisionMeta) error {
+	expected := exercise.Revision
+	exercise.Revision++
+	exercise.ContentHash = model.ContentHash(exercise)
+
+	filter := bson.M{"_id": exercise.ID, "revision": matchRevision(expected)}
 	update := bson.M{"$set": exercise}
 
-	_, err := r.collection.UpdateOne(ctx, filter, update)
-	return err
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil || result.MatchedCount == 0 {
+		exercise.Revision = expected
+		if err != nil {
+			return translateWriteError(err)
+		}
+		return ErrVersionConflict
+	}
+	return saveRevision(ctx, r.revisions, model.ContentKindExercise, exercise.ID, exercise.Revision, exercise, meta)
 }
 
 func (r *MongoExerciseRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
@@ -138,6 +268,134 @@ func (r *MongoExerciseRepository) Delete(ctx context.Context, id primitive.Objec
 	return err
 }
 
-func (r *MongoExerciseRepository) Count(ctx context.Context, filter bson.M) (int64, error) {
-	return r.collection.CountDocuments(ctx, filter)
+// SoftDelete moves an exercise to the trash
+func (r *MongoExerciseRepository) SoftDelete(ctx context.Context, id, deletedBy primitive.ObjectID) error {
+	return softDelete(ctx, r.collection, id, deletedBy, ErrExerciseNotFound)
+}
+
+// Restore takes an exercise out of the trash
+func (r *MongoExerciseRepository) Restore(ctx context.Context, id primitive.ObjectID) error {
+	return restore(ctx, r.collection, id, ErrExerciseNotFound)
+}
+
+// GetDeleted lists the exercises moved to the trash before the given time
+func (r *MongoExerciseRepository) GetDeleted(ctx context.Context, before time.Time, page pagination.Params) ([]*model.Exercise, error) {
+	return findDeleted[model.Exercise](ctx, r.collection, before, page)
+}
+
+// CountDeleted counts the exercises in the trash
+func (r *MongoExerciseRepository) CountDeleted(ctx context.Context) (int64, error) {
+	return countDeleted(ctx, r.collection)
+}
+
+// Count counts the exercises matching a query's filters
+func (r *MongoExerciseRepository) Count(ctx context.Context, query model.ExerciseQuery) (int64, error) {
+	return r.collection.CountDocuments(ctx, listable(exerciseFilter(query), query.IncludeUnpublished))
+}
+
+// Search returns a page of the exercises matching a query in its sort order.
+// Relevance order only supports offset pages.
+func (r *MongoExerciseRepository) Search(ctx context.Context, query model.ExerciseQuery, page pagination.Params) ([]*model.Exercise, error) {
+	findOptions := options.Find()
+
+	var filter bson.M
+	if query.Sort == model.ExerciseSortRelevance {
+		filter = exerciseFilter(query)
+		findOptions.SetSort(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "_id", Value: 1}})
+		findOptions.SetLimit(int64(page.Limit))
+		findOptions.SetSkip(int64(page.Offset))
+	} else {
+		field, ok := exerciseSortFields[query.Sort]
+		if !ok {
+			field = exerciseSortFields[model.ExerciseSortCreatedAt]
+		}
+		filter = page.ApplySorted(exerciseFilter(query), findOptions, field, query.After, query.Descending)
+	}
+
+	cursor, err := r.collection.Find(ctx, listable(filter, query.IncludeUnpublished), findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var exercises []*model.Exercise
+	if err := cursor.All(ctx, &exercises); err != nil {
+		return nil, err
+	}
+
+	return exercises, nil
+}
+
+// ForEach calls fn with every exercise matching a query's filters in _id order,
+// without loading them all at once. It stops at the first error fn returns.
+func (r *MongoExerciseRepository) ForEach(ctx context.Context, query model.ExerciseQuery, fn func(*model.Exercise) error) error {
+	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, listable(exerciseFilter(query), query.IncludeUnpublished), findOptions)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var exercise model.Exercise
+		if err := cursor.Decode(&exercise); err != nil {
+			return err
+		}
+		if err := fn(&exercise); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// AnonymizeUser removes a deleted user from the review records of exercises
+func (r *MongoExerciseRepository) AnonymizeUser(ctx context.Context, userID primitive.ObjectID) error {
+	return anonymizeReviews(ctx, r.collection, userID)
+}
+
+// anonymizeReviews removes a user from the review records of content. Their
+// submissions, approvals and comments are kept without an author, and content
+// assigned to them for review becomes unassigned.
+func anonymizeReviews(ctx context.Context, collection *mongo.Collection, userID primitive.ObjectID) error {
+	updates := []struct {
+		filter bson.M
+		update bson.M
+		opts   *options.UpdateOptions
+	}{
+		{bson.M{"review.submitted_by": userID}, bson.M{"$set": bson.M{"review.submitted_by": primitive.NilObjectID}}, nil},
+		{bson.M{"review.approved_by": userID}, bson.M{"$set": bson.M{"review.approved_by": primitive.NilObjectID}}, nil},
+		{bson.M{"review.reviewer_id": userID}, bson.M{"$unset": bson.M{"review.reviewer_id": "", "review.assigned_at": ""}}, nil},
+		{
+			bson.M{"review.comments.author_id": userID},
+			bson.M{"$set": bson.M{"review.comments.$[comment].author_id": primitive.NilObjectID}},
+			options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"comment.author_id": userID}}}),
+		},
+	}
+
+	for _, u := range updates {
+		opts := u.opts
+		if opts == nil {
+			opts = options.Update()
+		}
+		if _, err := collection.UpdateMany(ctx, u.filter, u.update, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listable restricts a listing filter to exercises that are not soft-deleted and,
+// unless includeUnpublished is set, have been published
+func listable(filter bson.M, includeUnpublished bool) bson.M {
+	scoped := notDeleted(filter)
+	if !includeUnpublished {
+		scoped["status"] = publishedStatuses()
+	}
+	return scoped
+}
+
+// publishedStatuses matches published content and content stored before statuses existed
+func publishedStatuses() bson.M {
+	return bson.M{"$in": bson.A{model.StatusPublished, "", nil}}
 }
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -13,67 +14,93 @@ import (
 )
 
 type LearningPathRepository interface {
-	Create(ctx context.Context, path *model.LearningPath) error
+	Create(ctx context.Context, path *model.LearningPath, meta model.RevisionMeta) error
 	GetByID(ctx context.Context, id primitive.ObjectID) (*model.LearningPath, error)
-	GetAll(ctx context.Context, limit, offset int) ([]*model.LearningPath, error)
-	GetByDifficulty(ctx context.Context, difficulty string, limit, offset int) ([]*model.LearningPath, error)
-	GetByCategory(ctx context.Context, category string, limit, offset int) ([]*model.LearningPath, error)
-	Update(ctx context.Context, path *model.LearningPath) error
+	GetAll(ctx context.Context, includeUnpublished bool, page pagination.Params) ([]*model.LearningPath, error)
+	GetByDifficulty(ctx context.Context, difficulty string, includeUnpublished bool, page pagination.Params) ([]*model.LearningPath, error)
+	GetByCategory(ctx context.Context, category string, includeUnpublished bool, page pagination.Params) ([]*model.LearningPath, error)
+	GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*model.LearningPath, error)
+	GetByExerciseID(ctx context.Context, exerciseID primitive.ObjectID) ([]*model.LearningPath, error)
+	GetDependents(ctx context.Context, pathID primitive.ObjectID) ([]*model.LearningPath, error)
+	Update(ctx context.Context, path *model.LearningPath, meta model.RevisionMeta) error
+	InsertStage(ctx context.Context, pathID primitive.ObjectID, stage model.PathStage, position int, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error)
+	ReplaceStage(ctx context.Context, pathID primitive.ObjectID, stage model.PathStage, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error)
+	RemoveStage(ctx context.Context, pathID primitive.ObjectID, stageNumber int, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error)
+	ReorderStages(ctx context.Context, pathID primitive.ObjectID, order []int, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error)
+	AddStageExercise(ctx context.Context, pathID primitive.ObjectID, stageNumber int, exerciseID primitive.ObjectID, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error)
+	RemoveStageExercise(ctx context.Context, pathID primitive.ObjectID, stageNumber int, exerciseID primitive.ObjectID, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error)
 	Delete(ctx context.Context, id primitive.ObjectID) error
+	SoftDelete(ctx context.Context, id, deletedBy primitive.ObjectID) error
+	Restore(ctx context.Context, id primitive.ObjectID) error
+	GetDeleted(ctx context.Context, before time.Time, page pagination.Params) ([]*model.LearningPath, error)
+	CountDeleted(ctx context.Context) (int64, error)
+	RemoveExercise(ctx context.Context, exerciseID primitive.ObjectID, meta model.RevisionMeta) (int64, error)
+	RemovePrerequisite(ctx context.Context, pathID primitive.ObjectID, meta model.RevisionMeta) (int64, error)
+	GetByStatus(ctx context.Context, status string, page pagination.Params, after *time.Time) ([]*model.LearningPath, error)
+	Count(ctx context.Context, query model.PathQuery) (int64, error)
+	AnonymizeUser(ctx context.Context, userID primitive.ObjectID) error
 }
 
 type MongoLearningPathRepository struct {
 	collection *mongo.Collection
+	revisions  *mongo.Collection
 }
 
 func NewLearningPathRepository(db *mongo.Database) LearningPathRepository {
-	collection := db.Collection("learning_paths")
-
-	// Create indexes
-	indexes := []mongo.IndexModel{
-		{
-			Keys: bson.D{{Key: "difficulty", Value: 1}},
-		},
-		{
-			Keys: bson.D{{Key: "categories", Value: 1}},
-		},
+	return &MongoLearningPathRepository{
+		collection: db.Collection("learning_paths"),
+		revisions:  db.Collection(revisionsCollection),
 	}
-
-	_, err := collection.Indexes().CreateMany(context.Background(), indexes)
-	if err != nil {
-		panic(err)
-	}
-
-	return &MongoLearningPathRepository{collection: collection}
 }
 
-func (r *MongoLearningPathRepository) Create(ctx context.Context, path *model.LearningPath) error {
+// Create inserts the learning path and records it as its first revision
+func (r *MongoLearningPathRepository) Create(ctx context.Context, path *model.LearningPath, meta model.RevisionMeta) error {
 	path.ID = primitive.NewObjectID()
 	path.CreatedAt = time.Now()
 	path.UpdatedAt = time.Now()
+	path.Revision = 1
 
-	_, err := r.collection.InsertOne(ctx, path)
-	return err
+	if _, err := r.collection.InsertOne(ctx, path); err != nil {
+		return err
+	}
+	return saveRevision(ctx, r.revisions, model.ContentKindLearningPath, path.ID, path.Revision, path, meta)
 }
 
 func (r *MongoLearningPathRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*model.LearningPath, error) {
 	var path model.LearningPath
-	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&path)
+	err := r.collection.FindOne(ctx, notDeleted(bson.M{"_id": id})).Decode(&path)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("learning path not found")
+			return nil, ErrPathNotFound
 		}
 		return nil, err
 	}
 	return &path, nil
 }
 
-func (r *MongoLearningPathRepository) GetAll(ctx context.Context, limit, offset int) ([]*model.LearningPath, error) {
+func (r *MongoLearningPathRepository) GetAll(ctx context.Context, includeUnpublished bool, page pagination.Params) ([]*model.LearningPath, error) {
+	findOptions := options.Find()
+	filter := page.Apply(visible(bson.M{}, includeUnpublished), findOptions)
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var paths []*model.LearningPath
+	if err := cursor.All(ctx, &paths); err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+func (r *MongoLearningPathRepository) GetByDifficulty(ctx context.Context, difficulty string, includeUnpublished bool, page pagination.Params) ([]*model.LearningPath, error) {
 	findOptions := options.Find()
-	findOptions.SetLimit(int64(limit))
-	findOptions.SetSkip(int64(offset))
+	filter := page.Apply(visible(bson.M{"difficulty": difficulty}, includeUnpublished), findOptions)
 
-	cursor, err := r.collection.Find(ctx, bson.M{}, findOptions)
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -87,12 +114,11 @@ func (r *MongoLearningPathRepository) GetAll(ctx context.Context, limit, offset
 	return paths, nil
 }
 
-func (r *MongoLearningPathRepository) GetByDifficulty(ctx context.Context, difficulty string, limit, offset int) ([]*model.LearningPath, error) {
+func (r *MongoLearningPathRepository) GetByCategory(ctx context.Context, category string, includeUnpublished bool, page pagination.Params) ([]*model.LearningPath, error) {
 	findOptions := options.Find()
-	findOptions.SetLimit(int64(limit))
-	findOptions.SetSkip(int64(offset))
+	filter := page.Apply(visible(bson.M{"categories": category}, includeUnpublished), findOptions)
 
-	cursor, err := r.collection.Find(ctx, bson.M{"difficulty": difficulty}, findOptions)
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -106,12 +132,74 @@ func (r *MongoLearningPathRepository) GetByDifficulty(ctx context.Context, diffi
 	return paths, nil
 }
 
-func (r *MongoLearningPathRepository) GetByCategory(ctx context.Context, category string, limit, offset int) ([]*model.LearningPath, error) {
+// GetByStatus lists paths in a status, oldest submission first. after is the
+// submission time of page.After in cursor mode.
+func (r *MongoLearningPathRepository) GetByStatus(ctx context.Context, status string, page pagination.Params, after *time.Time) ([]*model.LearningPath, error) {
 	findOptions := options.Find()
-	findOptions.SetLimit(int64(limit))
-	findOptions.SetSkip(int64(offset))
+	filter := page.ApplySorted(notDeleted(bson.M{"status": status}), findOptions, "review.submitted_at", after, false)
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var paths []*model.LearningPath
+	if err := cursor.All(ctx, &paths); err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+// Count counts the paths matching a query
+func (r *MongoLearningPathRepository) Count(ctx context.Context, query model.PathQuery) (int64, error) {
+	filter := bson.M{}
+	if query.Difficulty != "" {
+		filter["difficulty"] = query.Difficulty
+	}
+	if query.Category != "" {
+		filter["categories"] = query.Category
+	}
+	if query.Status != "" && query.IncludeUnpublished {
+		filter["status"] = query.Status
+	}
+	return r.collection.CountDocuments(ctx, visible(filter, query.IncludeUnpublished))
+}
+
+func (r *MongoLearningPathRepository) GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*model.LearningPath, error) {
+	cursor, err := r.collection.Find(ctx, notDeleted(bson.M{"_id": bson.M{"$in": ids}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var paths []*model.LearningPath
+	if err := cursor.All(ctx, &paths); err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+func (r *MongoLearningPathRepository) GetByExerciseID(ctx context.Context, exerciseID primitive.ObjectID) ([]*model.LearningPath, error) {
+	cursor, err := r.collection.Find(ctx, notDeleted(bson.M{"stages.exercise_ids": exerciseID}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var paths []*model.LearningPath
+	if err := cursor.All(ctx, &paths); err != nil {
+		return nil, err
+	}
 
-	cursor, err := r.collection.Find(ctx, bson.M{"categories": category}, findOptions)
+	return paths, nil
+}
+
+// GetDependents returns the learning paths that list the given path as a prerequisite
+func (r *MongoLearningPathRepository) GetDependents(ctx context.Context, pathID primitive.ObjectID) ([]*model.LearningPath, error) {
+	cursor, err := r.collection.Find(ctx, notDeleted(bson.M{"prerequisite_path_ids": pathID}))
 	if err != nil {
 		return nil, err
 	}
@@ -125,17 +213,306 @@ func (r *MongoLearningPathRepository) GetByCategory(ctx context.Context, categor
 	return paths, nil
 }
 
-func (r *MongoLearningPathRepository) Update(ctx context.Context, path *model.LearningPath) error {
+// Update replaces the learning path if it is still at the revision it was read at
+// and records the result as a new revision. A stale write returns ErrVersionConflict.
+func (r *MongoLearningPathRepository) Update(ctx context.Context, path *model.LearningPath, meta model.RevisionMeta) error {
+	expected := path.Revision
 	path.UpdatedAt = time.Now()
+	path.Revision++
 
-	filter := bson.M{"_id": path.ID}
+	filter := bson.M{"_id": path.ID, "revision": matchRevision(expected)}
 	update := bson.M{"$set": path}
 
-	_, err := r.collection.UpdateOne(ctx, filter, update)
-	return err
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil || result.MatchedCount == 0 {
+		path.Revision = expected
+		if err != nil {
+			return err
+		}
+		return ErrVersionConflict
+	}
+	return saveRevision(ctx, r.revisions, model.ContentKindLearningPath, path.ID, path.Revision, path, meta)
+}
+
+// InsertStage adds a stage so that it becomes stage number position, moving the
+// stages from there on down. A position of zero or past the end appends the stage.
+func (r *MongoLearningPathRepository) InsertStage(ctx context.Context, pathID primitive.ObjectID, stage model.PathStage, position int, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
+	inserted := bson.A{bson.M{"$literal": stage}}
+
+	var stages bson.M
+	if position <= 0 {
+		stages = bson.M{"$concatArrays": bson.A{currentStages, inserted}}
+	} else {
+		stages = bson.M{"$concatArrays": bson.A{
+			stagesWhere(bson.M{"$lt": bson.A{"$$stage.stage_number", position}}),
+			inserted,
+			stagesWhere(bson.M{"$gte": bson.A{"$$stage.stage_number", position}}),
+		}}
+	}
+	return r.updateStages(ctx, pathID, nil, 0, expectedRevision, stages, meta)
+}
+
+// ReplaceStage replaces the content of a stage, keeping its identity and position
+func (r *MongoLearningPathRepository) ReplaceStage(ctx context.Context, pathID primitive.ObjectID, stage model.PathStage, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
+	stages := bson.M{"$map": bson.M{
+		"input": currentStages,
+		"as":    "stage",
+		"in": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{"$$stage.stage_number", stage.StageNumber}},
+			bson.M{"$mergeObjects": bson.A{"$$stage", bson.M{"$literal": bson.M{
+				"title":               stage.Title,
+				"description":         stage.Description,
+				"exercise_ids":        stage.ExerciseIDs,
+				"completion_criteria": stage.CompletionCriteria,
+			}}}},
+			"$$stage",
+		}},
+	}}
+	return r.updateStages(ctx, pathID, nil, stage.StageNumber, expectedRevision, stages, meta)
+}
+
+// RemoveStage removes a stage and renumbers the stages after it
+func (r *MongoLearningPathRepository) RemoveStage(ctx context.Context, pathID primitive.ObjectID, stageNumber int, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
+	stages := stagesWhere(bson.M{"$ne": bson.A{"$$stage.stage_number", stageNumber}})
+	return r.updateStages(ctx, pathID, nil, stageNumber, expectedRevision, stages, meta)
+}
+
+// ReorderStages puts the stages in a new order, given as the current stage numbers
+// in the order they should appear. The order must name every stage exactly once;
+// the write is rejected as a conflict if the number of stages changed meanwhile.
+func (r *MongoLearningPathRepository) ReorderStages(ctx context.Context, pathID primitive.ObjectID, order []int, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
+	stages := bson.M{"$map": bson.M{
+		"input": bson.M{"$literal": order},
+		"as":    "number",
+		"in":    bson.M{"$arrayElemAt": bson.A{currentStages, bson.M{"$subtract": bson.A{"$$number", 1}}}},
+	}}
+	conditions := bson.M{"stages": bson.M{"$size": len(order)}}
+	return r.updateStages(ctx, pathID, conditions, 0, expectedRevision, stages, meta)
+}
+
+// AddStageExercise appends an exercise to a stage unless the stage already has it
+func (r *MongoLearningPathRepository) AddStageExercise(ctx context.Context, pathID primitive.ObjectID, stageNumber int, exerciseID primitive.ObjectID, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
+	exerciseIDs := bson.M{"$ifNull": bson.A{"$$stage.exercise_ids", bson.A{}}}
+	updated := bson.M{"$cond": bson.A{
+		bson.M{"$in": bson.A{exerciseID, exerciseIDs}},
+		exerciseIDs,
+		bson.M{"$concatArrays": bson.A{exerciseIDs, bson.A{exerciseID}}},
+	}}
+	return r.updateStages(ctx, pathID, nil, stageNumber, expectedRevision, setStageExercises(stageNumber, updated), meta)
+}
+
+// RemoveStageExercise removes an exercise from a stage
+func (r *MongoLearningPathRepository) RemoveStageExercise(ctx context.Context, pathID primitive.ObjectID, stageNumber int, exerciseID primitive.ObjectID, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
+	updated := bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$$stage.exercise_ids", bson.A{}}},
+		"as":    "id",
+		"cond":  bson.M{"$ne": bson.A{"$$id", exerciseID}},
+	}}
+	return r.updateStages(ctx, pathID, nil, stageNumber, expectedRevision, setStageExercises(stageNumber, updated), meta)
+}
+
+// updateStages atomically replaces a path's stages with the result of an aggregation
+// expression, renumbers them and bumps the revision. Any conditions are added to
+// the filter. When stageNumber is set the stage must exist; when expectedRevision
+// is not model.AnyRevision the path must still be at that revision.
+func (r *MongoLearningPathRepository) updateStages(ctx context.Context, pathID primitive.ObjectID, conditions bson.M, stageNumber int, expectedRevision int, stages interface{}, meta model.RevisionMeta) (*model.LearningPath, error) {
+	filter := notDeleted(bson.M{"_id": pathID})
+	for key, value := range conditions {
+		filter[key] = value
+	}
+	if stageNumber > 0 {
+		filter["stages.stage_number"] = stageNumber
+	}
+	if expectedRevision != model.AnyRevision {
+		filter["revision"] = matchRevision(expectedRevision)
+	}
+
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"stages":     renumberStages(stages),
+			"revision":   bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$revision", 0}}, 1}},
+			"updated_at": "$$NOW",
+		}}},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var path model.LearningPath
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&path)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+
+		// Work out which condition failed
+		current, getErr := r.GetByID(ctx, pathID)
+		if getErr != nil {
+			return nil, getErr
+		}
+		if stageNumber > 0 && (stageNumber > len(current.Stages) || current.Stages[stageNumber-1].StageNumber != stageNumber) {
+			return nil, ErrStageNotFound
+		}
+		return nil, ErrVersionConflict
+	}
+
+	if err := saveRevision(ctx, r.revisions, model.ContentKindLearningPath, path.ID, path.Revision, &path, meta); err != nil {
+		return nil, err
+	}
+	return &path, nil
+}
+
+// currentStages is the path's stages in an aggregation expression
+var currentStages = bson.M{"$ifNull": bson.A{"$stages", bson.A{}}}
+
+// AnonymizeUser removes a deleted user from the review records of learning paths
+func (r *MongoLearningPathRepository) AnonymizeUser(ctx context.Context, userID primitive.ObjectID) error {
+	return anonymizeReviews(ctx, r.collection, userID)
+}
+
+// stagesWhere keeps the stages, bound to $$stage, for which cond holds
+func stagesWhere(cond bson.M) bson.M {
+	return bson.M{"$filter": bson.M{
+		"input": currentStages,
+		"as":    "stage",
+		"cond":  cond,
+	}}
+}
+
+// setStageExercises replaces the exercise IDs of one stage with an expression
+// evaluated against that stage, bound to $$stage
+func setStageExercises(stageNumber int, exerciseIDs bson.M) bson.M {
+	return bson.M{"$map": bson.M{
+		"input": currentStages,
+		"as":    "stage",
+		"in": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{"$$stage.stage_number", stageNumber}},
+			bson.M{"$mergeObjects": bson.A{"$$stage", bson.M{"exercise_ids": exerciseIDs}}},
+			"$$stage",
+		}},
+	}}
+}
+
+// renumberStages numbers the stages produced by an aggregation expression from one
+func renumberStages(stages interface{}) bson.M {
+	return bson.M{"$let": bson.M{
+		"vars": bson.M{"stages": stages},
+		"in": bson.M{"$map": bson.M{
+			"input": bson.M{"$range": bson.A{0, bson.M{"$size": "$$stages"}}},
+			"as":    "i",
+			"in": bson.M{"$mergeObjects": bson.A{
+				bson.M{"$arrayElemAt": bson.A{"$$stages", "$$i"}},
+				bson.M{"stage_number": bson.M{"$add": bson.A{"$$i", 1}}},
+			}},
+		}},
+	}}
 }
 
 func (r *MongoLearningPathRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
 	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
 	return err
 }
+
+// SoftDelete moves a learning path to the trash
+func (r *MongoLearningPathRepository) SoftDelete(ctx context.Context, id, deletedBy primitive.ObjectID) error {
+	return softDelete(ctx, r.collection, id, deletedBy, ErrPathNotFound)
+}
+
+// Restore takes a learning path out of the trash
+func (r *MongoLearningPathRepository) Restore(ctx context.Context, id primitive.ObjectID) error {
+	return restore(ctx, r.collection, id, ErrPathNotFound)
+}
+
+// GetDeleted lists the learning paths moved to the trash before the given time
+func (r *MongoLearningPathRepository) GetDeleted(ctx context.Context, before time.Time, page pagination.Params) ([]*model.LearningPath, error) {
+	return findDeleted[model.LearningPath](ctx, r.collection, before, page)
+}
+
+// CountDeleted counts the learning paths in the trash
+func (r *MongoLearningPathRepository) CountDeleted(ctx context.Context) (int64, error) {
+	return countDeleted(ctx, r.collection)
+}
+
+// RemoveExercise pulls an exercise out of every stage that references it, in
+// trashed paths too, and returns the number of learning paths that were changed.
+// Each path changed gets a new revision.
+func (r *MongoLearningPathRepository) RemoveExercise(ctx context.Context, exerciseID primitive.ObjectID, meta model.RevisionMeta) (int64, error) {
+	stages := bson.M{"$map": bson.M{
+		"input": currentStages,
+		"as":    "stage",
+		"in": bson.M{"$mergeObjects": bson.A{"$$stage", bson.M{"exercise_ids": bson.M{"$filter": bson.M{
+			"input": bson.M{"$ifNull": bson.A{"$$stage.exercise_ids", bson.A{}}},
+			"as":    "id",
+			"cond":  bson.M{"$ne": bson.A{"$$id", exerciseID}},
+		}}}}},
+	}}
+	return r.rewriteEach(ctx, bson.M{"stages.exercise_ids": exerciseID}, bson.M{"stages": stages}, meta)
+}
+
+// RemovePrerequisite drops a path from the prerequisites of every path that
+// depends on it, in trashed paths too, and returns the number of learning paths
+// that were changed. Each path changed gets a new revision.
+func (r *MongoLearningPathRepository) RemovePrerequisite(ctx context.Context, pathID primitive.ObjectID, meta model.RevisionMeta) (int64, error) {
+	prerequisites := bson.M{"$filter": bson.M{
+		"input": "$prerequisite_path_ids",
+		"as":    "id",
+		"cond":  bson.M{"$ne": bson.A{"$$id", pathID}},
+	}}
+	return r.rewriteEach(ctx, bson.M{"prerequisite_path_ids": pathID}, bson.M{"prerequisite_path_ids": prerequisites}, meta)
+}
+
+// rewriteEach applies an aggregation $set to every path matching filter, one
+// path at a time so each one's revision is bumped and recorded like an Update.
+// A path that stopped matching meanwhile is skipped.
+func (r *MongoLearningPathRepository) rewriteEach(ctx context.Context, filter bson.M, set bson.M, meta model.RevisionMeta) (int64, error) {
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return 0, err
+	}
+	var matched []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &matched); err != nil {
+		return 0, err
+	}
+
+	fields := bson.M{
+		"revision":   bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$revision", 0}}, 1}},
+		"updated_at": "$$NOW",
+	}
+	for key, value := range set {
+		fields[key] = value
+	}
+	update := mongo.Pipeline{{{Key: "$set", Value: fields}}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var changed int64
+	for _, m := range matched {
+		scoped := bson.M{"_id": m.ID}
+		for key, value := range filter {
+			scoped[key] = value
+		}
+
+		var path model.LearningPath
+		err := r.collection.FindOneAndUpdate(ctx, scoped, update, opts).Decode(&path)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		}
+		if err != nil {
+			return changed, err
+		}
+		if err := saveRevision(ctx, r.revisions, model.ContentKindLearningPath, path.ID, path.Revision, &path, meta); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// visible restricts a listing filter to learning paths that are not in the trash
+// and, unless includeUnpublished is set, have been published
+func visible(filter bson.M, includeUnpublished bool) bson.M {
+	scoped := notDeleted(filter)
+	if !includeUnpublished {
+		scoped["status"] = publishedStatuses()
+	}
+	return scoped
+}
@@ -0,0 +1,317 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxTagBreakdowns caps the tag breakdown of a performance report
+const maxTagBreakdowns = 50
+
+// AttemptRepository keeps attempts in memory. Performance reports look up the
+// answered exercises in exercises, which may be nil to report every attempt
+// under an unknown category and difficulty.
+type AttemptRepository struct {
+	mu        sync.RWMutex
+	attempts  map[primitive.ObjectID]*model.Attempt
+	exercises repository.ExerciseRepository
+}
+
+func NewAttemptRepository(exercises repository.ExerciseRepository) *AttemptRepository {
+	return &AttemptRepository{
+		attempts:  make(map[primitive.ObjectID]*model.Attempt),
+		exercises: exercises,
+	}
+}
+
+var _ repository.AttemptRepository = (*AttemptRepository)(nil)
+
+func (r *AttemptRepository) Create(ctx context.Context, attempt *model.Attempt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt.ID = primitive.NewObjectID()
+	if attempt.Timestamp.IsZero() {
+		attempt.Timestamp = time.Now()
+	}
+	r.attempts[attempt.ID] = clone(attempt)
+	return nil
+}
+
+func (r *AttemptRepository) GetByUser(ctx context.Context, userID primitive.ObjectID, from, to time.Time) ([]*model.Attempt, error) {
+	attempts := r.inRange(userID, from, to)
+	sort.SliceStable(attempts, func(i, j int) bool { return attempts[i].Timestamp.Before(attempts[j].Timestamp) })
+	return attempts, nil
+}
+
+func (r *AttemptRepository) GetByUserAndExercise(ctx context.Context, userID, exerciseID primitive.ObjectID, limit int) ([]*model.Attempt, error) {
+	attempts := r.filter(func(a *model.Attempt) bool { return a.UserID == userID && a.ExerciseID == exerciseID })
+	sort.SliceStable(attempts, func(i, j int) bool { return attempts[i].Timestamp.After(attempts[j].Timestamp) })
+	return window(attempts, 0, limit), nil
+}
+
+// AnonymizeUser moves a deleted user's attempts to a new random ID and clears
+// their free-text answers
+func (r *AttemptRepository) AnonymizeUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	anonymous := primitive.NewObjectID()
+	var modified int64
+	for _, attempt := range r.attempts {
+		if attempt.UserID == userID {
+			attempt.UserID = anonymous
+			attempt.UserAnswer = ""
+			modified++
+		}
+	}
+	return modified, nil
+}
+
+// TotalsByExercise counts a user's attempts at each exercise they answered, in
+// exercise ID order
+func (r *AttemptRepository) TotalsByExercise(ctx context.Context, userID primitive.ObjectID) ([]model.AttemptTotals, error) {
+	byExercise := make(map[primitive.ObjectID]*model.AttemptTotals)
+	for _, attempt := range r.filter(func(a *model.Attempt) bool { return a.UserID == userID }) {
+		totals, ok := byExercise[attempt.ExerciseID]
+		if !ok {
+			totals = &model.AttemptTotals{ExerciseID: attempt.ExerciseID}
+			byExercise[attempt.ExerciseID] = totals
+		}
+		totals.Attempts++
+		if attempt.IsCorrect {
+			totals.Correct++
+		}
+		totals.TimeTaken += attempt.TimeTaken
+		if attempt.Timestamp.After(totals.LastAttempted) {
+			totals.LastAttempted = attempt.Timestamp
+		}
+	}
+
+	all := make([]model.AttemptTotals, 0, len(byExercise))
+	for _, totals := range byExercise {
+		all = append(all, *totals)
+	}
+	sort.Slice(all, func(i, j int) bool { return compareIDs(all[i].ExerciseID, all[j].ExerciseID) < 0 })
+	return all, nil
+}
+
+// AggregatePerformance builds the same report as the Mongo aggregation, with
+// percentiles interpolated between the nearest response times
+func (r *AttemptRepository) AggregatePerformance(ctx context.Context, query model.AnalyticsQuery) (*model.PerformanceReport, error) {
+	location, err := time.LoadLocation(query.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := r.inRange(query.UserID, query.From, query.To)
+	exercises, err := r.answered(ctx, attempts)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.PerformanceReport{
+		From:         query.From,
+		To:           query.To,
+		Granularity:  query.Granularity,
+		Timezone:     query.Timezone,
+		Series:       []model.PerformanceBucket{},
+		ByCategory:   []model.PerformanceBreakdown{},
+		ByDifficulty: []model.PerformanceBreakdown{},
+		ByTag:        []model.PerformanceBreakdown{},
+	}
+	if len(attempts) == 0 {
+		return report, nil
+	}
+
+	all := &attemptGroup{}
+	periods := make(map[time.Time]*attemptGroup)
+	categories := make(map[string]*attemptGroup)
+	difficulties := make(map[string]*attemptGroup)
+	tags := make(map[string]*attemptGroup)
+	for _, attempt := range attempts {
+		all.add(attempt)
+		groupFor(periods, truncatePeriod(attempt.Timestamp, query.Granularity, location)).add(attempt)
+
+		category, difficulty := "unknown", "unknown"
+		if exercise, ok := exercises[attempt.ExerciseID]; ok {
+			category, difficulty = exercise.Category, exercise.Difficulty
+			for _, tag := range exercise.Tags {
+				groupFor(tags, tag).add(attempt)
+			}
+		}
+		groupFor(categories, category).add(attempt)
+		groupFor(difficulties, difficulty).add(attempt)
+	}
+
+	report.Summary = model.PerformanceSummary{
+		Attempts:    all.attempts,
+		Correct:     all.correct,
+		Accuracy:    all.accuracy(),
+		AverageTime: all.averageTime(),
+		MedianTime:  all.percentile(0.5),
+	}
+	report.Percentiles = model.ResponseTimePercentiles{
+		P50: all.percentile(0.5),
+		P75: all.percentile(0.75),
+		P90: all.percentile(0.9),
+		P95: all.percentile(0.95),
+	}
+
+	for period, group := range periods {
+		report.Series = append(report.Series, model.PerformanceBucket{
+			Period:     period,
+			Attempts:   group.attempts,
+			Correct:    group.correct,
+			Accuracy:   group.accuracy(),
+			MedianTime: group.percentile(0.5),
+		})
+	}
+	sort.Slice(report.Series, func(i, j int) bool { return report.Series[i].Period.Before(report.Series[j].Period) })
+
+	report.ByCategory = breakdowns(categories)
+	report.ByDifficulty = breakdowns(difficulties)
+	report.ByTag = window(breakdowns(tags), 0, maxTagBreakdowns)
+	return report, nil
+}
+
+// answered looks up the exercises of attempts, including trashed ones
+func (r *AttemptRepository) answered(ctx context.Context, attempts []*model.Attempt) (map[primitive.ObjectID]*model.Exercise, error) {
+	exercises := make(map[primitive.ObjectID]*model.Exercise)
+	if r.exercises == nil || len(attempts) == 0 {
+		return exercises, nil
+	}
+
+	var ids []primitive.ObjectID
+	for _, attempt := range attempts {
+		ids = append(ids, attempt.ExerciseID)
+	}
+	found, err := r.exercises.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, exercise := range found {
+		exercises[exercise.ID] = exercise
+	}
+	return exercises, nil
+}
+
+// inRange returns copies of a user's attempts made in [from, to)
+func (r *AttemptRepository) inRange(userID primitive.ObjectID, from, to time.Time) []*model.Attempt {
+	return r.filter(func(a *model.Attempt) bool {
+		return a.UserID == userID && !a.Timestamp.Before(from) && a.Timestamp.Before(to)
+	})
+}
+
+// filter returns copies of the attempts matching a condition, in ID order
+func (r *AttemptRepository) filter(match func(*model.Attempt) bool) []*model.Attempt {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var attempts []*model.Attempt
+	for _, attempt := range r.attempts {
+		if match(attempt) {
+			attempts = append(attempts, clone(attempt))
+		}
+	}
+	return sortByID(attempts, func(a *model.Attempt) primitive.ObjectID { return a.ID })
+}
+
+// attemptGroup accumulates the attempts sharing a period or breakdown key
+type attemptGroup struct {
+	attempts int
+	correct  int
+	times    []float64
+}
+
+func groupFor[K comparable](groups map[K]*attemptGroup, key K) *attemptGroup {
+	group, ok := groups[key]
+	if !ok {
+		group = &attemptGroup{}
+		groups[key] = group
+	}
+	return group
+}
+
+func (g *attemptGroup) add(attempt *model.Attempt) {
+	g.attempts++
+	if attempt.IsCorrect {
+		g.correct++
+	}
+	g.times = append(g.times, float64(attempt.TimeTaken))
+}
+
+func (g *attemptGroup) accuracy() float64 {
+	if g.attempts == 0 {
+		return 0
+	}
+	return float64(g.correct) / float64(g.attempts) * 100
+}
+
+func (g *attemptGroup) averageTime() float64 {
+	if len(g.times) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, t := range g.times {
+		total += t
+	}
+	return total / float64(len(g.times))
+}
+
+// percentile interpolates linearly between the closest ranks, like
+// percentile_cont in SQL
+func (g *attemptGroup) percentile(p float64) float64 {
+	if len(g.times) == 0 {
+		return 0
+	}
+	times := append([]float64(nil), g.times...)
+	sort.Float64s(times)
+
+	rank := p * float64(len(times)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return times[lower] + (times[upper]-times[lower])*(rank-float64(lower))
+}
+
+// breakdowns orders groups by attempts, most first, then by key
+func breakdowns(groups map[string]*attemptGroup) []model.PerformanceBreakdown {
+	result := make([]model.PerformanceBreakdown, 0, len(groups))
+	for key, group := range groups {
+		result = append(result, model.PerformanceBreakdown{
+			Key:        key,
+			Attempts:   group.attempts,
+			Correct:    group.correct,
+			Accuracy:   group.accuracy(),
+			MedianTime: group.percentile(0.5),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Attempts != result[j].Attempts {
+			return result[i].Attempts > result[j].Attempts
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// truncatePeriod returns the start, in UTC, of the day, week or month that
+// contains t in a location. Weeks start on Monday.
+func truncatePeriod(t time.Time, granularity string, location *time.Location) time.Time {
+	local := t.In(location)
+	year, month, day := local.Date()
+	switch granularity {
+	case model.GranularityWeek:
+		day -= (int(local.Weekday()) + 6) % 7
+	case model.GranularityMonth:
+		day = 1
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, location).UTC()
+}
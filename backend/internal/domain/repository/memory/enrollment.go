@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type EnrollmentRepository struct {
+	mu          sync.RWMutex
+	enrollments map[primitive.ObjectID]*model.PathEnrollment
+}
+
+func NewEnrollmentRepository() *EnrollmentRepository {
+	return &EnrollmentRepository{enrollments: make(map[primitive.ObjectID]*model.PathEnrollment)}
+}
+
+var _ repository.EnrollmentRepository = (*EnrollmentRepository)(nil)
+
+func (r *EnrollmentRepository) Create(ctx context.Context, enrollment *model.PathEnrollment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, other := range r.enrollments {
+		if other.UserID == enrollment.UserID && other.PathID == enrollment.PathID {
+			return errors.New("already enrolled in learning path")
+		}
+	}
+
+	enrollment.ID = primitive.NewObjectID()
+	enrollment.EnrolledAt = time.Now()
+	enrollment.UpdatedAt = enrollment.EnrolledAt
+	r.enrollments[enrollment.ID] = clone(enrollment)
+	return nil
+}
+
+func (r *EnrollmentRepository) GetByUserAndPath(ctx context.Context, userID, pathID primitive.ObjectID) (*model.PathEnrollment, error) {
+	enrollments := r.filter(func(e *model.PathEnrollment) bool { return e.UserID == userID && e.PathID == pathID })
+	if len(enrollments) == 0 {
+		return nil, errors.New("enrollment not found")
+	}
+	return enrollments[0], nil
+}
+
+func (r *EnrollmentRepository) GetActiveByUserID(ctx context.Context, userID primitive.ObjectID) ([]*model.PathEnrollment, error) {
+	return r.filter(func(e *model.PathEnrollment) bool { return e.UserID == userID && e.CompletedAt == nil }), nil
+}
+
+// GetByUserID returns all of a user's enrollments, active and completed
+func (r *EnrollmentRepository) GetByUserID(ctx context.Context, userID primitive.ObjectID) ([]*model.PathEnrollment, error) {
+	enrollments := r.filter(func(e *model.PathEnrollment) bool { return e.UserID == userID })
+	sort.SliceStable(enrollments, func(i, j int) bool { return enrollments[i].EnrolledAt.Before(enrollments[j].EnrolledAt) })
+	return enrollments, nil
+}
+
+// DeleteByUserID removes all of a user's enrollments
+func (r *EnrollmentRepository) DeleteByUserID(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, enrollment := range r.enrollments {
+		if enrollment.UserID == userID {
+			delete(r.enrollments, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// DeleteByPathID removes every enrollment in a learning path
+func (r *EnrollmentRepository) DeleteByPathID(ctx context.Context, pathID primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, enrollment := range r.enrollments {
+		if enrollment.PathID == pathID {
+			delete(r.enrollments, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *EnrollmentRepository) GetCompletedByUserID(ctx context.Context, userID primitive.ObjectID) ([]*model.PathEnrollment, error) {
+	return r.filter(func(e *model.PathEnrollment) bool { return e.UserID == userID && e.CompletedAt != nil }), nil
+}
+
+// Update replaces a stored enrollment; a missing enrollment is ignored
+func (r *EnrollmentRepository) Update(ctx context.Context, enrollment *model.PathEnrollment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	enrollment.UpdatedAt = time.Now()
+	if _, ok := r.enrollments[enrollment.ID]; ok {
+		r.enrollments[enrollment.ID] = clone(enrollment)
+	}
+	return nil
+}
+
+// filter returns copies of the enrollments matching a condition, in ID order
+func (r *EnrollmentRepository) filter(match func(*model.PathEnrollment) bool) []*model.PathEnrollment {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var enrollments []*model.PathEnrollment
+	for _, enrollment := range r.enrollments {
+		if match(enrollment) {
+			enrollments = append(enrollments, clone(enrollment))
+		}
+	}
+	return sortByID(enrollments, func(e *model.PathEnrollment) primitive.ObjectID { return e.ID })
+}
@@ -0,0 +1,416 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ExerciseRepository keeps exercises in memory
+type ExerciseRepository struct {
+	mu        sync.RWMutex
+	exercises map[primitive.ObjectID]*model.Exercise
+	revisions repository.RevisionRepository
+}
+
+// NewExerciseRepository creates an exercise repository that records revisions
+// in the given revision repository, or records none when it is nil
+func NewExerciseRepository(revisions repository.RevisionRepository) *ExerciseRepository {
+	return &ExerciseRepository{exercises: make(map[primitive.ObjectID]*model.Exercise), revisions: revisions}
+}
+
+var _ repository.ExerciseRepository = (*ExerciseRepository)(nil)
+
+// Create stores the exercise and records it as its first revision
+func (r *ExerciseRepository) Create(ctx context.Context, exercise *model.Exercise, meta model.RevisionMeta) error {
+	r.mu.Lock()
+	if r.keyTaken(exercise.ExternalKey, primitive.NilObjectID) {
+		r.mu.Unlock()
+		return repository.ErrDuplicateKey
+	}
+
+	exercise.ID = primitive.NewObjectID()
+	exercise.Revision = 1
+	exercise.ContentHash = model.ContentHash(exercise)
+	r.exercises[exercise.ID] = clone(exercise)
+	r.mu.Unlock()
+
+	return r.record(ctx, exercise, meta)
+}
+
+// GetByID returns an exercise that is not in the trash
+func (r *ExerciseRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*model.Exercise, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	exercise, ok := r.exercises[id]
+	if !ok || exercise.DeletedAt != nil {
+		return nil, repository.ErrExerciseNotFound
+	}
+	return clone(exercise), nil
+}
+
+// GetByIDs returns the exercises with the given IDs, including soft-deleted ones.
+// IDs that do not exist are silently skipped.
+func (r *ExerciseRepository) GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*model.Exercise, error) {
+	wanted := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	return sortByID(r.filter(func(e *model.Exercise) bool { return wanted[e.ID] }), exerciseID), nil
+}
+
+// GetByExternalKey returns the exercise imported under a key. Exercises in the
+// trash are skipped, since their keys can be reused.
+func (r *ExerciseRepository) GetByExternalKey(ctx context.Context, key string) (*model.Exercise, error) {
+	exercises := r.filter(func(e *model.Exercise) bool { return key != "" && e.ExternalKey == key && e.DeletedAt == nil })
+	if len(exercises) == 0 {
+		return nil, repository.ErrExerciseNotFound
+	}
+	return exercises[0], nil
+}
+
+// GetByContentHashes returns the exercises, excluding soft-deleted ones, whose
+// content hash is one of the given hashes, oldest first
+func (r *ExerciseRepository) GetByContentHashes(ctx context.Context, hashes []string) ([]*model.Exercise, error) {
+	wanted := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		wanted[hash] = true
+	}
+	exercises := r.filter(func(e *model.Exercise) bool { return e.DeletedAt == nil && wanted[e.ContentHash] })
+	return sortByID(exercises, exerciseID), nil
+}
+
+func (r *ExerciseRepository) GetByCategory(ctx context.Context, category string, includeUnpublished bool, page pagination.Params) ([]*model.Exercise, error) {
+	return r.list(model.ExerciseQuery{Category: category, IncludeUnpublished: includeUnpublished}, page), nil
+}
+
+func (r *ExerciseRepository) GetByDifficulty(ctx context.Context, difficulty string, includeUnpublished bool, page pagination.Params) ([]*model.Exercise, error) {
+	return r.list(model.ExerciseQuery{Difficulty: difficulty, IncludeUnpublished: includeUnpublished}, page), nil
+}
+
+func (r *ExerciseRepository) GetByTags(ctx context.Context, tags []string, includeUnpublished bool, page pagination.Params) ([]*model.Exercise, error) {
+	return r.list(model.ExerciseQuery{Tags: tags, IncludeUnpublished: includeUnpublished}, page), nil
+}
+
+// Update replaces the exercise if it is still at the revision it was read at and
+// records the result as a new revision. A stale write returns ErrVersionConflict.
+func (r *ExerciseRepository) Update(ctx context.Context, exercise *model.Exercise, meta model.RevisionMeta) error {
+	r.mu.Lock()
+	stored, ok := r.exercises[exercise.ID]
+	if !ok || !matchesRevision(stored.Revision, exercise.Revision) {
+		r.mu.Unlock()
+		return repository.ErrVersionConflict
+	}
+	if r.keyTaken(exercise.ExternalKey, exercise.ID) {
+		r.mu.Unlock()
+		return repository.ErrDuplicateKey
+	}
+
+	exercise.Revision++
+	exercise.ContentHash = model.ContentHash(exercise)
+	r.exercises[exercise.ID] = clone(exercise)
+	r.mu.Unlock()
+
+	return r.record(ctx, exercise, meta)
+}
+
+// record stores a snapshot of an exercise in the revision repository, if any
+func (r *ExerciseRepository) record(ctx context.Context, exercise *model.Exercise, meta model.RevisionMeta) error {
+	if r.revisions == nil {
+		return nil
+	}
+	return r.revisions.Record(ctx, model.ContentKindExercise, exercise.ID, exercise.Revision, exercise, meta)
+}
+
+func (r *ExerciseRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.exercises, id)
+	return nil
+}
+
+// SoftDelete moves an exercise to the trash
+func (r *ExerciseRepository) SoftDelete(ctx context.Context, id, deletedBy primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	exercise, ok := r.exercises[id]
+	if !ok || !trash(&exercise.DeletedAt, &exercise.DeletedBy, deletedBy) {
+		return repository.ErrExerciseNotFound
+	}
+	return nil
+}
+
+// Restore takes an exercise out of the trash
+func (r *ExerciseRepository) Restore(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	exercise, ok := r.exercises[id]
+	if !ok || exercise.DeletedAt == nil {
+		return repository.ErrExerciseNotFound
+	}
+	if r.keyTaken(exercise.ExternalKey, id) {
+		return repository.ErrDuplicateKey
+	}
+	untrash(&exercise.DeletedAt, &exercise.DeletedBy)
+	return nil
+}
+
+// GetDeleted lists the exercises moved to the trash before the given time
+func (r *ExerciseRepository) GetDeleted(ctx context.Context, before time.Time, page pagination.Params) ([]*model.Exercise, error) {
+	exercises := r.filter(func(e *model.Exercise) bool { return deletedBefore(e.DeletedAt, before) })
+	return paginateByID(exercises, page, exerciseID), nil
+}
+
+// CountDeleted counts the exercises in the trash
+func (r *ExerciseRepository) CountDeleted(ctx context.Context) (int64, error) {
+	return int64(len(r.filter(func(e *model.Exercise) bool { return e.DeletedAt != nil }))), nil
+}
+
+// GetByStatus lists exercises in a status, oldest submission first. after is the
+// submission time of page.After in cursor mode.
+func (r *ExerciseRepository) GetByStatus(ctx context.Context, status string, page pagination.Params, after *time.Time) ([]*model.Exercise, error) {
+	exercises := r.filter(func(e *model.Exercise) bool { return e.DeletedAt == nil && e.Status == status })
+	return paginateBySubmission(exercises, page, after, func(e *model.Exercise) (*time.Time, primitive.ObjectID) {
+		return e.Review.SubmittedAt, e.ID
+	}), nil
+}
+
+// Count counts the exercises matching a query's filters
+func (r *ExerciseRepository) Count(ctx context.Context, query model.ExerciseQuery) (int64, error) {
+	return int64(len(r.filter(matchExercise(query)))), nil
+}
+
+// Search returns a page of the exercises matching a query in its sort order.
+// Text matches any word of the query in the title, description or problem,
+// ignoring case, and relevance weighs the fields like the Mongo text index.
+func (r *ExerciseRepository) Search(ctx context.Context, query model.ExerciseQuery, page pagination.Params) ([]*model.Exercise, error) {
+	exercises := r.filter(matchExercise(query))
+
+	if query.Sort == model.ExerciseSortRelevance {
+		terms := searchTerms(query.Text)
+		compare := func(a, b *model.Exercise) int {
+			if scoreA, scoreB := textScore(a, terms), textScore(b, terms); scoreA != scoreB {
+				if scoreA > scoreB {
+					return -1
+				}
+				return 1
+			}
+			return compareIDs(a.ID, b.ID)
+		}
+		return paginate(exercises, pagination.Params{Limit: page.Limit, Offset: page.Offset}, compare, nil), nil
+	}
+
+	sortValue := func(e *model.Exercise) interface{} { return e.Metadata.CreatedAt }
+	if query.Sort == model.ExerciseSortTitle {
+		sortValue = func(e *model.Exercise) interface{} { return e.Title }
+	}
+	direction := 1
+	if query.Descending {
+		direction = -1
+	}
+	compare := func(a, b *model.Exercise) int {
+		if c := compareValues(sortValue(a), sortValue(b)); c != 0 {
+			return direction * c
+		}
+		return direction * compareIDs(a.ID, b.ID)
+	}
+	afterCursor := func(e *model.Exercise) bool {
+		c := compareValues(sortValue(e), query.After)
+		if c == 0 {
+			c = compareIDs(e.ID, page.After.ID)
+		}
+		return direction*c > 0
+	}
+	return paginate(exercises, page, compare, afterCursor), nil
+}
+
+// ForEach calls fn with every exercise matching a query's filters in ID order.
+// It stops at the first error fn returns.
+func (r *ExerciseRepository) ForEach(ctx context.Context, query model.ExerciseQuery, fn func(*model.Exercise) error) error {
+	for _, exercise := range sortByID(r.filter(matchExercise(query)), exerciseID) {
+		if err := fn(exercise); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AnonymizeUser removes a deleted user from the review records of exercises
+func (r *ExerciseRepository) AnonymizeUser(ctx context.Context, userID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, exercise := range r.exercises {
+		exercise.Review.RemoveUser(userID)
+	}
+	return nil
+}
+
+// list returns a page, in ID order, of the exercises matching a query
+func (r *ExerciseRepository) list(query model.ExerciseQuery, page pagination.Params) []*model.Exercise {
+	return paginateByID(r.filter(matchExercise(query)), page, exerciseID)
+}
+
+// filter returns copies of the exercises matching a condition
+func (r *ExerciseRepository) filter(match func(*model.Exercise) bool) []*model.Exercise {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var exercises []*model.Exercise
+	for _, exercise := range r.exercises {
+		if match(exercise) {
+			exercises = append(exercises, clone(exercise))
+		}
+	}
+	return exercises
+}
+
+// keyTaken reports whether an exercise other than self and outside the trash
+// was imported under a key. The caller holds the lock.
+func (r *ExerciseRepository) keyTaken(key string, self primitive.ObjectID) bool {
+	if key == "" {
+		return false
+	}
+	for id, exercise := range r.exercises {
+		if id != self && exercise.DeletedAt == nil && exercise.ExternalKey == key {
+			return true
+		}
+	}
+	return false
+}
+
+// matchExercise matches the exercises a query selects: not soft-deleted,
+// published unless the query includes unpublished ones, and passing its filters
+func matchExercise(q model.ExerciseQuery) func(*model.Exercise) bool {
+	terms := searchTerms(q.Text)
+	return func(e *model.Exercise) bool {
+		switch {
+		case e.DeletedAt != nil:
+			return false
+		case !q.IncludeUnpublished && !model.IsPublished(e.Status):
+			return false
+		case q.IncludeUnpublished && q.Status != "" && e.Status != q.Status:
+			return false
+		case q.Category != "" && e.Category != q.Category,
+			q.Difficulty != "" && e.Difficulty != q.Difficulty,
+			q.Type != "" && e.Type != q.Type,
+			q.GeneratedBy != "" && e.Metadata.GeneratedBy != q.GeneratedBy:
+			return false
+		case q.CreatedFrom != nil && e.Metadata.CreatedAt.Before(*q.CreatedFrom),
+			q.CreatedTo != nil && e.Metadata.CreatedAt.After(*q.CreatedTo):
+			return false
+		case len(q.Tags) > 0 && !matchTags(e.Tags, q.Tags, q.MatchAllTags):
+			return false
+		case len(terms) > 0 && textScore(e, terms) == 0:
+			return false
+		}
+		return true
+	}
+}
+
+// matchTags reports whether an exercise's tags contain any, or with all set
+// every, wanted tag
+func matchTags(tags, wanted []string, all bool) bool {
+	has := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		has[tag] = true
+	}
+	for _, tag := range wanted {
+		if has[tag] != all {
+			return !all
+		}
+	}
+	return all
+}
+
+// searchTerms splits a text query into lower-case words
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// textScore counts the query words in an exercise, weighted by field like the
+// Mongo text index
+func textScore(e *model.Exercise, terms []string) int {
+	fields := []struct {
+		text   string
+		weight int
+	}{
+		{e.Title, 5},
+		{e.Description, 2},
+		{e.Content.Problem, 1},
+	}
+
+	score := 0
+	for _, field := range fields {
+		for _, word := range searchTerms(field.text) {
+			for _, term := range terms {
+				if word == term {
+					score += field.weight
+				}
+			}
+		}
+	}
+	return score
+}
+
+// compareValues compares two sort values of the same type, times or strings
+func compareValues(a, b interface{}) int {
+	switch a := a.(type) {
+	case time.Time:
+		b, _ := b.(time.Time)
+		return a.Compare(b)
+	case string:
+		b, _ := b.(string)
+		return strings.Compare(a, b)
+	}
+	return 0
+}
+
+// paginateBySubmission pages content oldest submission first, as the review
+// queues are. after is the submission time of page.After in cursor mode.
+func paginateBySubmission[T any](items []T, page pagination.Params, after *time.Time, key func(T) (*time.Time, primitive.ObjectID)) []T {
+	submitted := func(item T) (time.Time, primitive.ObjectID) {
+		at, id := key(item)
+		if at == nil {
+			return time.Time{}, id
+		}
+		return *at, id
+	}
+	compare := func(a, b T) int {
+		atA, idA := submitted(a)
+		atB, idB := submitted(b)
+		if c := atA.Compare(atB); c != 0 {
+			return c
+		}
+		return compareIDs(idA, idB)
+	}
+	afterCursor := func(item T) bool {
+		at, id := submitted(item)
+		var cursorAt time.Time
+		if after != nil {
+			cursorAt = *after
+		}
+		if c := at.Compare(cursorAt); c != 0 {
+			return c > 0
+		}
+		return compareIDs(id, page.After.ID) > 0
+	}
+	return paginate(items, page, compare, afterCursor)
+}
+
+func exerciseID(exercise *model.Exercise) primitive.ObjectID {
+	return exercise.ID
+}
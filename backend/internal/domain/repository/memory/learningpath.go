@@ -0,0 +1,425 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LearningPathRepository keeps learning paths in memory
+type LearningPathRepository struct {
+	mu        sync.RWMutex
+	paths     map[primitive.ObjectID]*model.LearningPath
+	revisions repository.RevisionRepository
+}
+
+// NewLearningPathRepository creates a learning path repository that records
+// revisions in the given revision repository, or records none when it is nil
+func NewLearningPathRepository(revisions repository.RevisionRepository) *LearningPathRepository {
+	return &LearningPathRepository{paths: make(map[primitive.ObjectID]*model.LearningPath), revisions: revisions}
+}
+
+var _ repository.LearningPathRepository = (*LearningPathRepository)(nil)
+
+// Create stores the learning path and records it as its first revision
+func (r *LearningPathRepository) Create(ctx context.Context, path *model.LearningPath, meta model.RevisionMeta) error {
+	r.mu.Lock()
+	path.ID = primitive.NewObjectID()
+	path.CreatedAt = time.Now()
+	path.UpdatedAt = time.Now()
+	path.Revision = 1
+	r.paths[path.ID] = clone(path)
+	r.mu.Unlock()
+
+	return r.record(ctx, path, meta)
+}
+
+func (r *LearningPathRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*model.LearningPath, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	path, ok := r.paths[id]
+	if !ok || path.DeletedAt != nil {
+		return nil, repository.ErrPathNotFound
+	}
+	return clone(path), nil
+}
+
+func (r *LearningPathRepository) GetAll(ctx context.Context, includeUnpublished bool, page pagination.Params) ([]*model.LearningPath, error) {
+	return r.list(model.PathQuery{IncludeUnpublished: includeUnpublished}, page), nil
+}
+
+func (r *LearningPathRepository) GetByDifficulty(ctx context.Context, difficulty string, includeUnpublished bool, page pagination.Params) ([]*model.LearningPath, error) {
+	return r.list(model.PathQuery{Difficulty: difficulty, IncludeUnpublished: includeUnpublished}, page), nil
+}
+
+func (r *LearningPathRepository) GetByCategory(ctx context.Context, category string, includeUnpublished bool, page pagination.Params) ([]*model.LearningPath, error) {
+	return r.list(model.PathQuery{Category: category, IncludeUnpublished: includeUnpublished}, page), nil
+}
+
+func (r *LearningPathRepository) GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*model.LearningPath, error) {
+	wanted := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	return sortByID(r.filter(func(p *model.LearningPath) bool { return p.DeletedAt == nil && wanted[p.ID] }), pathID), nil
+}
+
+func (r *LearningPathRepository) GetByExerciseID(ctx context.Context, exerciseID primitive.ObjectID) ([]*model.LearningPath, error) {
+	paths := r.filter(func(p *model.LearningPath) bool { return p.DeletedAt == nil && usesExercise(p, exerciseID) })
+	return sortByID(paths, pathID), nil
+}
+
+// GetDependents returns the learning paths that list the given path as a prerequisite
+func (r *LearningPathRepository) GetDependents(ctx context.Context, id primitive.ObjectID) ([]*model.LearningPath, error) {
+	paths := r.filter(func(p *model.LearningPath) bool { return p.DeletedAt == nil && containsID(p.PrerequisitePathIDs, id) })
+	return sortByID(paths, pathID), nil
+}
+
+// Update replaces the learning path if it is still at the revision it was read at
+// and records the result as a new revision. A stale write returns ErrVersionConflict.
+func (r *LearningPathRepository) Update(ctx context.Context, path *model.LearningPath, meta model.RevisionMeta) error {
+	r.mu.Lock()
+	stored, ok := r.paths[path.ID]
+	if !ok || !matchesRevision(stored.Revision, path.Revision) {
+		r.mu.Unlock()
+		return repository.ErrVersionConflict
+	}
+
+	path.UpdatedAt = time.Now()
+	path.Revision++
+	r.paths[path.ID] = clone(path)
+	r.mu.Unlock()
+
+	return r.record(ctx, path, meta)
+}
+
+// InsertStage adds a stage so that it becomes stage number position, moving the
+// stages from there on down. A position of zero or past the end appends the stage.
+func (r *LearningPathRepository) InsertStage(ctx context.Context, pathID primitive.ObjectID, stage model.PathStage, position int, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
+	return r.updateStages(ctx, pathID, 0, expectedRevision, meta, func(stages []model.PathStage) ([]model.PathStage, bool) {
+		if position <= 0 || position > len(stages) {
+			return append(stages, stage), true
+		}
+		inserted := append([]model.PathStage{}, stages[:position-1]...)
+		inserted = append(inserted, stage)
+		return append(inserted, stages[position-1:]...), true
+	})
+}
+
+// ReplaceStage replaces the content of a stage, keeping its identity and position
+func (r *LearningPathRepository) ReplaceStage(ctx context.Context, pathID primitive.ObjectID, stage model.PathStage, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
+	return r.updateStages(ctx, pathID, stage.StageNumber, expectedRevision, meta, func(stages []model.PathStage) ([]model.PathStage, bool) {
+		current := &stages[stage.StageNumber-1]
+		current.Title = stage.Title
+		current.Description = stage.Description
+		current.ExerciseIDs = stage.ExerciseIDs
+		current.CompletionCriteria = stage.CompletionCriteria
+		return stages, true
+	})
+}
+
+// RemoveStage removes a stage and renumbers the stages after it
+func (r *LearningPathRepository) RemoveStage(ctx context.Context, pathID primitive.ObjectID, stageNumber int, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
+	return r.updateStages(ctx, pathID, stageNumber, expectedRevision, meta, func(stages []model.PathStage) ([]model.PathStage, bool) {
+		return append(stages[:stageNumber-1], stages[stageNumber:]...), true
+	})
+}
+
+// ReorderStages puts the stages in a new order, given as the current stage numbers
+// in the order they should appear. The write is rejected as a conflict if the
+// number of stages changed meanwhile.
+func (r *LearningPathRepository) ReorderStages(ctx context.Context, pathID primitive.ObjectID, order []int, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
+	return r.updateStages(ctx, pathID, 0, expectedRevision, meta, func(stages []model.PathStage) ([]model.PathStage, bool) {
+		if len(order) != len(stages) {
+			return nil, false
+		}
+		reordered := make([]model.PathStage, 0, len(order))
+		for _, number := range order {
+			if number < 1 || number > len(stages) {
+				return nil, false
+			}
+			reordered = append(reordered, stages[number-1])
+		}
+		return reordered, true
+	})
+}
+
+// AddStageExercise appends an exercise to a stage unless the stage already has it
+func (r *LearningPathRepository) AddStageExercise(ctx context.Context, pathID primitive.ObjectID, stageNumber int, exerciseID primitive.ObjectID, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
+	return r.updateStages(ctx, pathID, stageNumber, expectedRevision, meta, func(stages []model.PathStage) ([]model.PathStage, bool) {
+		stage := &stages[stageNumber-1]
+		if !containsID(stage.ExerciseIDs, exerciseID) {
+			stage.ExerciseIDs = append(stage.ExerciseIDs, exerciseID)
+		}
+		return stages, true
+	})
+}
+
+// RemoveStageExercise removes an exercise from a stage
+func (r *LearningPathRepository) RemoveStageExercise(ctx context.Context, pathID primitive.ObjectID, stageNumber int, exerciseID primitive.ObjectID, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
+	return r.updateStages(ctx, pathID, stageNumber, expectedRevision, meta, func(stages []model.PathStage) ([]model.PathStage, bool) {
+		stage := &stages[stageNumber-1]
+		stage.ExerciseIDs = withoutID(stage.ExerciseIDs, exerciseID)
+		return stages, true
+	})
+}
+
+// updateStages replaces a path's stages with the result of change, renumbers
+// them, bumps the revision and records it. When stageNumber is set the stage must exist;
+// when expectedRevision is not model.AnyRevision the path must still be at
+// that revision. change reports false to reject the write as a conflict.
+func (r *LearningPathRepository) updateStages(ctx context.Context, pathID primitive.ObjectID, stageNumber int, expectedRevision int, meta model.RevisionMeta, change func([]model.PathStage) ([]model.PathStage, bool)) (*model.LearningPath, error) {
+	updated, err := r.changeStages(pathID, stageNumber, expectedRevision, change)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.record(ctx, updated, meta); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// changeStages applies a stage change for updateStages under the lock
+func (r *LearningPathRepository) changeStages(pathID primitive.ObjectID, stageNumber int, expectedRevision int, change func([]model.PathStage) ([]model.PathStage, bool)) (*model.LearningPath, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path, ok := r.paths[pathID]
+	if !ok || path.DeletedAt != nil {
+		return nil, repository.ErrPathNotFound
+	}
+	if stageNumber > 0 && (stageNumber > len(path.Stages) || path.Stages[stageNumber-1].StageNumber != stageNumber) {
+		return nil, repository.ErrStageNotFound
+	}
+	if !matchesRevision(path.Revision, expectedRevision) {
+		return nil, repository.ErrVersionConflict
+	}
+
+	stages, ok := change(*clone(&path.Stages))
+	if !ok {
+		return nil, repository.ErrVersionConflict
+	}
+	for i := range stages {
+		stages[i].StageNumber = i + 1
+	}
+
+	path.Stages = *clone(&stages)
+	path.Revision++
+	path.UpdatedAt = time.Now()
+	return clone(path), nil
+}
+
+// record stores a snapshot of a path in the revision repository, if any
+func (r *LearningPathRepository) record(ctx context.Context, path *model.LearningPath, meta model.RevisionMeta) error {
+	if r.revisions == nil {
+		return nil
+	}
+	return r.revisions.Record(ctx, model.ContentKindLearningPath, path.ID, path.Revision, path, meta)
+}
+
+func (r *LearningPathRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.paths, id)
+	return nil
+}
+
+// SoftDelete moves a learning path to the trash
+func (r *LearningPathRepository) SoftDelete(ctx context.Context, id, deletedBy primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path, ok := r.paths[id]
+	if !ok || !trash(&path.DeletedAt, &path.DeletedBy, deletedBy) {
+		return repository.ErrPathNotFound
+	}
+	return nil
+}
+
+// Restore takes a learning path out of the trash
+func (r *LearningPathRepository) Restore(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path, ok := r.paths[id]
+	if !ok || !untrash(&path.DeletedAt, &path.DeletedBy) {
+		return repository.ErrPathNotFound
+	}
+	return nil
+}
+
+// GetDeleted lists the learning paths moved to the trash before the given time
+func (r *LearningPathRepository) GetDeleted(ctx context.Context, before time.Time, page pagination.Params) ([]*model.LearningPath, error) {
+	paths := r.filter(func(p *model.LearningPath) bool { return deletedBefore(p.DeletedAt, before) })
+	return paginateByID(paths, page, pathID), nil
+}
+
+// CountDeleted counts the learning paths in the trash
+func (r *LearningPathRepository) CountDeleted(ctx context.Context) (int64, error) {
+	return int64(len(r.filter(func(p *model.LearningPath) bool { return p.DeletedAt != nil }))), nil
+}
+
+// RemoveExercise pulls an exercise out of every stage that references it, in
+// trashed paths too, and returns the number of learning paths that were changed.
+// Each path changed gets a new revision.
+func (r *LearningPathRepository) RemoveExercise(ctx context.Context, exerciseID primitive.ObjectID, meta model.RevisionMeta) (int64, error) {
+	return r.rewriteEach(ctx, meta, func(path *model.LearningPath) bool {
+		if !usesExercise(path, exerciseID) {
+			return false
+		}
+		for i := range path.Stages {
+			path.Stages[i].ExerciseIDs = withoutID(path.Stages[i].ExerciseIDs, exerciseID)
+		}
+		return true
+	})
+}
+
+// RemovePrerequisite drops a path from the prerequisites of every path that
+// depends on it, in trashed paths too, and returns the number of learning paths
+// that were changed. Each path changed gets a new revision.
+func (r *LearningPathRepository) RemovePrerequisite(ctx context.Context, id primitive.ObjectID, meta model.RevisionMeta) (int64, error) {
+	return r.rewriteEach(ctx, meta, func(path *model.LearningPath) bool {
+		if !containsID(path.PrerequisitePathIDs, id) {
+			return false
+		}
+		path.PrerequisitePathIDs = withoutID(path.PrerequisitePathIDs, id)
+		return true
+	})
+}
+
+// rewriteEach applies change to every stored path, bumping and recording the
+// revision of each path it reports as changed
+func (r *LearningPathRepository) rewriteEach(ctx context.Context, meta model.RevisionMeta, change func(*model.LearningPath) bool) (int64, error) {
+	r.mu.Lock()
+	var changed []*model.LearningPath
+	for _, path := range r.paths {
+		if !change(path) {
+			continue
+		}
+		path.Revision++
+		path.UpdatedAt = time.Now()
+		changed = append(changed, clone(path))
+	}
+	r.mu.Unlock()
+
+	for _, path := range sortByID(changed, pathID) {
+		if err := r.record(ctx, path, meta); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(changed)), nil
+}
+
+// GetByStatus lists paths in a status, oldest submission first. after is the
+// submission time of page.After in cursor mode.
+func (r *LearningPathRepository) GetByStatus(ctx context.Context, status string, page pagination.Params, after *time.Time) ([]*model.LearningPath, error) {
+	paths := r.filter(func(p *model.LearningPath) bool { return p.DeletedAt == nil && p.Status == status })
+	return paginateBySubmission(paths, page, after, func(p *model.LearningPath) (*time.Time, primitive.ObjectID) {
+		return p.Review.SubmittedAt, p.ID
+	}), nil
+}
+
+// Count counts the paths matching a query
+func (r *LearningPathRepository) Count(ctx context.Context, query model.PathQuery) (int64, error) {
+	return int64(len(r.filter(matchPath(query)))), nil
+}
+
+// AnonymizeUser removes a deleted user from the review records of learning paths
+func (r *LearningPathRepository) AnonymizeUser(ctx context.Context, userID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, path := range r.paths {
+		path.Review.RemoveUser(userID)
+	}
+	return nil
+}
+
+// list returns a page, in ID order, of the paths matching a query
+func (r *LearningPathRepository) list(query model.PathQuery, page pagination.Params) []*model.LearningPath {
+	return paginateByID(r.filter(matchPath(query)), page, pathID)
+}
+
+// filter returns copies of the paths matching a condition
+func (r *LearningPathRepository) filter(match func(*model.LearningPath) bool) []*model.LearningPath {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var paths []*model.LearningPath
+	for _, path := range r.paths {
+		if match(path) {
+			paths = append(paths, clone(path))
+		}
+	}
+	return paths
+}
+
+// matchPath matches the paths a query selects: not in the trash, published
+// unless the query includes unpublished ones, and passing its filters
+func matchPath(q model.PathQuery) func(*model.LearningPath) bool {
+	return func(p *model.LearningPath) bool {
+		switch {
+		case p.DeletedAt != nil:
+			return false
+		case !q.IncludeUnpublished && !model.IsPublished(p.Status):
+			return false
+		case q.IncludeUnpublished && q.Status != "" && p.Status != q.Status:
+			return false
+		case q.Difficulty != "" && p.Difficulty != q.Difficulty:
+			return false
+		case q.Category != "" && !containsString(p.Categories, q.Category):
+			return false
+		}
+		return true
+	}
+}
+
+// usesExercise reports whether any stage of a path references an exercise
+func usesExercise(path *model.LearningPath, exerciseID primitive.ObjectID) bool {
+	for _, stage := range path.Stages {
+		if containsID(stage.ExerciseIDs, exerciseID) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutID returns ids with every occurrence of id removed
+func withoutID(ids []primitive.ObjectID, id primitive.ObjectID) []primitive.ObjectID {
+	kept := []primitive.ObjectID{}
+	for _, candidate := range ids {
+		if candidate != id {
+			kept = append(kept, candidate)
+		}
+	}
+	return kept
+}
+
+func pathID(path *model.LearningPath) primitive.ObjectID {
+	return path.ID
+}
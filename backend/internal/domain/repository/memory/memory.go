@@ -0,0 +1,129 @@
+// Package memory implements the repositories in memory. It keeps the behaviour
+// of the Mongo repositories, including uniqueness constraints, revision checks
+// and pagination order, so services can be run without a database.
+package memory
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// clone returns a deep copy of v, so stored records never share memory with
+// the values handed to or returned from a repository
+func clone[T any](v *T) *T {
+	copied := deepCopy(reflect.ValueOf(v).Elem()).Interface().(T)
+	return &copied
+}
+
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		copied := reflect.New(v.Type().Elem())
+		copied.Elem().Set(deepCopy(v.Elem()))
+		return copied
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return copied
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return copied
+	case reflect.Interface:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(deepCopy(v.Elem()))
+		return copied
+	case reflect.Struct:
+		// Unexported fields, such as those of time.Time, are copied by value
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if copied.Field(i).CanSet() {
+				copied.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+// compareIDs orders ObjectIDs the way Mongo sorts _id
+func compareIDs(a, b primitive.ObjectID) int {
+	return bytes.Compare(a[:], b[:])
+}
+
+// paginate sorts items by compare, which must break ties by ID, and cuts out
+// the page the way pagination.Params.ApplySorted does. In cursor mode
+// afterCursor reports whether an item comes after the cursor.
+func paginate[T any](items []T, page pagination.Params, compare func(a, b T) int, afterCursor func(T) bool) []T {
+	sort.SliceStable(items, func(i, j int) bool { return compare(items[i], items[j]) < 0 })
+
+	if !page.UseCursor {
+		return window(items, page.Offset, page.Limit)
+	}
+	if page.After != nil {
+		kept := items[:0]
+		for _, item := range items {
+			if afterCursor(item) {
+				kept = append(kept, item)
+			}
+		}
+		items = kept
+	}
+	return window(items, 0, page.Limit+1)
+}
+
+// paginateByID is paginate for lists ordered by ID
+func paginateByID[T any](items []T, page pagination.Params, id func(T) primitive.ObjectID) []T {
+	compare := func(a, b T) int { return compareIDs(id(a), id(b)) }
+	afterCursor := func(item T) bool { return compareIDs(id(item), page.After.ID) > 0 }
+	return paginate(items, page, compare, afterCursor)
+}
+
+// sortByID orders items the way Mongo returns them without a sort
+func sortByID[T any](items []T, id func(T) primitive.ObjectID) []T {
+	sort.Slice(items, func(i, j int) bool { return compareIDs(id(items[i]), id(items[j])) < 0 })
+	return items
+}
+
+// window returns up to limit items starting at offset; a limit of zero or less
+// does not limit
+func window[T any](items []T, offset, limit int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// matchesRevision reports whether a stored revision is the one a write expects.
+// Records written before revisions were tracked are at revision zero.
+func matchesRevision(stored, expected int) bool {
+	return expected == model.AnyRevision || stored == expected
+}
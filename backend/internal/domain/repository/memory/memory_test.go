@@ -0,0 +1,24 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository/memory"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository/repositorytest"
+)
+
+func TestRepositories(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Repositories {
+		revisions := memory.NewRevisionRepository()
+		exercises := memory.NewExerciseRepository(revisions)
+		return repositorytest.Repositories{
+			Exercises:     exercises,
+			Attempts:      memory.NewAttemptRepository(exercises),
+			Users:         memory.NewUserRepository(),
+			Sessions:      memory.NewSessionRepository(),
+			Progress:      memory.NewProgressRepository(),
+			LearningPaths: memory.NewLearningPathRepository(revisions),
+			Revisions:     revisions,
+		}
+	})
+}
@@ -0,0 +1,221 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ProgressRepository struct {
+	mu       sync.RWMutex
+	progress map[primitive.ObjectID]*model.UserProgress
+}
+
+func NewProgressRepository() *ProgressRepository {
+	return &ProgressRepository{progress: make(map[primitive.ObjectID]*model.UserProgress)}
+}
+
+var _ repository.ProgressRepository = (*ProgressRepository)(nil)
+
+func (r *ProgressRepository) Create(ctx context.Context, progress *model.UserProgress) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.find(progress.UserID, progress.ExerciseID) != nil {
+		return repository.ErrDuplicateKey
+	}
+
+	progress.ID = primitive.NewObjectID()
+	progress.LastAttempted = time.Now()
+	r.progress[progress.ID] = clone(progress)
+	return nil
+}
+
+func (r *ProgressRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*model.UserProgress, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	progress, ok := r.progress[id]
+	if !ok {
+		return nil, repository.ErrProgressNotFound
+	}
+	return clone(progress), nil
+}
+
+func (r *ProgressRepository) GetByUserID(ctx context.Context, userID primitive.ObjectID) ([]*model.UserProgress, error) {
+	return sortByID(r.filter(forUser(userID)), progressID), nil
+}
+
+// ListByUserID returns a page of a user's progress records
+func (r *ProgressRepository) ListByUserID(ctx context.Context, userID primitive.ObjectID, page pagination.Params) ([]*model.UserProgress, error) {
+	return paginateByID(r.filter(forUser(userID)), page, progressID), nil
+}
+
+func (r *ProgressRepository) CountByUserID(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return int64(len(r.filter(forUser(userID)))), nil
+}
+
+func (r *ProgressRepository) GetByUserAndExercise(ctx context.Context, userID, exerciseID primitive.ObjectID) (*model.UserProgress, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	progress := r.find(userID, exerciseID)
+	if progress == nil {
+		return nil, repository.ErrProgressNotFound
+	}
+	return clone(progress), nil
+}
+
+func (r *ProgressRepository) GetByUserAndExercises(ctx context.Context, userID primitive.ObjectID, exerciseIDs []primitive.ObjectID) ([]*model.UserProgress, error) {
+	wanted := make(map[primitive.ObjectID]bool, len(exerciseIDs))
+	for _, id := range exerciseIDs {
+		wanted[id] = true
+	}
+	progress := r.filter(func(p *model.UserProgress) bool { return p.UserID == userID && wanted[p.ExerciseID] })
+	return sortByID(progress, progressID), nil
+}
+
+// Update replaces a stored progress record. Like the Mongo repository,
+// updating a record that does not exist is not an error.
+func (r *ProgressRepository) Update(ctx context.Context, progress *model.UserProgress) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	progress.LastAttempted = time.Now()
+	if _, ok := r.progress[progress.ID]; ok {
+		r.progress[progress.ID] = clone(progress)
+	}
+	return nil
+}
+
+// RecordAttempt folds an attempt into the user's progress summary for the exercise,
+// creating the summary if needed
+func (r *ProgressRepository) RecordAttempt(ctx context.Context, attempt model.Attempt) (*model.UserProgress, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	progress := r.find(attempt.UserID, attempt.ExerciseID)
+	if progress == nil {
+		progress = &model.UserProgress{
+			ID:         primitive.NewObjectID(),
+			UserID:     attempt.UserID,
+			ExerciseID: attempt.ExerciseID,
+		}
+		r.progress[progress.ID] = progress
+	}
+
+	progress.AttemptCount++
+	if attempt.IsCorrect {
+		progress.CorrectCount++
+	}
+	progress.TotalTimeTaken += attempt.TimeTaken
+	progress.RecentAttempts = append(progress.RecentAttempts, attempt)
+	if len(progress.RecentAttempts) > model.RecentAttemptLimit {
+		progress.RecentAttempts = progress.RecentAttempts[len(progress.RecentAttempts)-model.RecentAttemptLimit:]
+	}
+	progress.LastAttempted = attempt.Timestamp
+	progress.MasteryLevel = float64(progress.CorrectCount) / float64(progress.AttemptCount)
+
+	return clone(progress), nil
+}
+
+func (r *ProgressRepository) UpdateMasteryLevel(ctx context.Context, progressID primitive.ObjectID, level float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if progress, ok := r.progress[progressID]; ok {
+		progress.MasteryLevel = level
+	}
+	return nil
+}
+
+// ReplaceTotals rewrites the counts, total time, mastery level and last attempt
+// of the user's summary for the exercise, provided it still has expectedAttempts
+// attempts. A missing summary is created when expectedAttempts is zero.
+func (r *ProgressRepository) ReplaceTotals(ctx context.Context, progress *model.UserProgress, expectedAttempts int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := r.find(progress.UserID, progress.ExerciseID)
+	if stored == nil {
+		if expectedAttempts != 0 {
+			return false, nil
+		}
+		stored = &model.UserProgress{
+			ID:             primitive.NewObjectID(),
+			UserID:         progress.UserID,
+			ExerciseID:     progress.ExerciseID,
+			RecentAttempts: []model.Attempt{},
+		}
+		r.progress[stored.ID] = stored
+	} else if stored.AttemptCount != expectedAttempts {
+		return false, nil
+	}
+
+	stored.AttemptCount = progress.AttemptCount
+	stored.CorrectCount = progress.CorrectCount
+	stored.TotalTimeTaken = progress.TotalTimeTaken
+	stored.MasteryLevel = progress.MasteryLevel
+	stored.LastAttempted = progress.LastAttempted
+	return true, nil
+}
+
+func (r *ProgressRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.progress, id)
+	return nil
+}
+
+// DeleteByUserID removes all of a user's progress summaries
+func (r *ProgressRepository) DeleteByUserID(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, progress := range r.progress {
+		if progress.UserID == userID {
+			delete(r.progress, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// find returns the stored summary for a user and exercise, or nil. The caller holds the lock.
+func (r *ProgressRepository) find(userID, exerciseID primitive.ObjectID) *model.UserProgress {
+	for _, progress := range r.progress {
+		if progress.UserID == userID && progress.ExerciseID == exerciseID {
+			return progress
+		}
+	}
+	return nil
+}
+
+// filter returns copies of the progress records matching a condition
+func (r *ProgressRepository) filter(match func(*model.UserProgress) bool) []*model.UserProgress {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var progress []*model.UserProgress
+	for _, p := range r.progress {
+		if match(p) {
+			progress = append(progress, clone(p))
+		}
+	}
+	return progress
+}
+
+func forUser(userID primitive.ObjectID) func(*model.UserProgress) bool {
+	return func(progress *model.UserProgress) bool { return progress.UserID == userID }
+}
+
+func progressID(progress *model.UserProgress) primitive.ObjectID {
+	return progress.ID
+}
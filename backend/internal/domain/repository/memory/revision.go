@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RevisionRepository struct {
+	mu        sync.RWMutex
+	revisions []*model.Revision
+}
+
+func NewRevisionRepository() *RevisionRepository {
+	return &RevisionRepository{}
+}
+
+var _ repository.RevisionRepository = (*RevisionRepository)(nil)
+
+// Record stores a snapshot of a document as the given revision
+func (r *RevisionRepository) Record(ctx context.Context, kind string, documentID primitive.ObjectID, number int, document interface{}, meta model.RevisionMeta) error {
+	snapshot, err := bson.Marshal(document)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.revisions = append(r.revisions, &model.Revision{
+		ID:         primitive.NewObjectID(),
+		Kind:       kind,
+		DocumentID: documentID,
+		Number:     number,
+		AuthorID:   meta.AuthorID,
+		Note:       meta.Note,
+		CreatedAt:  time.Now(),
+		Snapshot:   snapshot,
+	})
+	return nil
+}
+
+// GetByDocument lists a document's revisions, newest first, without their snapshots
+func (r *RevisionRepository) GetByDocument(ctx context.Context, kind string, documentID primitive.ObjectID, page pagination.Params) ([]*model.Revision, error) {
+	revisions := r.filter(kind, documentID)
+	for _, revision := range revisions {
+		revision.Snapshot = nil
+	}
+
+	compare := func(a, b *model.Revision) int { return compareIDs(b.ID, a.ID) }
+	afterCursor := func(revision *model.Revision) bool { return compareIDs(revision.ID, page.After.ID) < 0 }
+	return paginate(revisions, page, compare, afterCursor), nil
+}
+
+// CountByDocument counts a document's revisions
+func (r *RevisionRepository) CountByDocument(ctx context.Context, kind string, documentID primitive.ObjectID) (int64, error) {
+	return int64(len(r.filter(kind, documentID))), nil
+}
+
+func (r *RevisionRepository) GetByNumber(ctx context.Context, kind string, documentID primitive.ObjectID, number int) (*model.Revision, error) {
+	for _, revision := range r.filter(kind, documentID) {
+		if revision.Number == number {
+			return revision, nil
+		}
+	}
+	return nil, errors.New("revision not found")
+}
+
+// AnonymizeAuthor clears a deleted user from the revisions they authored
+func (r *RevisionRepository) AnonymizeAuthor(ctx context.Context, authorID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, revision := range r.revisions {
+		if revision.AuthorID == authorID {
+			revision.AuthorID = primitive.NilObjectID
+		}
+	}
+	return nil
+}
+
+// filter returns copies of a document's revisions in the order they were recorded
+func (r *RevisionRepository) filter(kind string, documentID primitive.ObjectID) []*model.Revision {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var revisions []*model.Revision
+	for _, revision := range r.revisions {
+		if revision.Kind == kind && revision.DocumentID == documentID {
+			revisions = append(revisions, clone(revision))
+		}
+	}
+	return revisions
+}
@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SessionRepository struct {
+	mu       sync.RWMutex
+	sessions map[primitive.ObjectID]*model.UserSession
+}
+
+func NewSessionRepository() *SessionRepository {
+	return &SessionRepository{sessions: make(map[primitive.ObjectID]*model.UserSession)}
+}
+
+var _ repository.SessionRepository = (*SessionRepository)(nil)
+
+func (r *SessionRepository) Create(ctx context.Context, session *model.UserSession) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, other := range r.sessions {
+		if other.SessionToken == session.SessionToken {
+			return repository.ErrDuplicateKey
+		}
+	}
+
+	session.ID = primitive.NewObjectID()
+	session.CreatedAt = time.Now()
+	r.sessions[session.ID] = clone(session)
+	return nil
+}
+
+func (r *SessionRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*model.UserSession, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, repository.ErrSessionNotFound
+	}
+	return clone(session), nil
+}
+
+func (r *SessionRepository) GetByToken(ctx context.Context, token string) (*model.UserSession, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, session := range r.sessions {
+		if session.SessionToken == token {
+			return clone(session), nil
+		}
+	}
+	return nil, repository.ErrSessionNotFound
+}
+
+func (r *SessionRepository) GetByUserID(ctx context.Context, userID primitive.ObjectID) ([]*model.UserSession, error) {
+	sessions := r.filter(func(session *model.UserSession) bool { return session.UserID == userID })
+	return sortByID(sessions, sessionID), nil
+}
+
+// ListActiveByUserID returns a page of a user's sessions that have not expired
+func (r *SessionRepository) ListActiveByUserID(ctx context.Context, userID primitive.ObjectID, page pagination.Params) ([]*model.UserSession, error) {
+	return paginateByID(r.filter(activeSession(userID)), page, sessionID), nil
+}
+
+func (r *SessionRepository) CountActiveByUserID(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return int64(len(r.filter(activeSession(userID)))), nil
+}
+
+func (r *SessionRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.sessions, id)
+	return nil
+}
+
+func (r *SessionRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	now := time.Now()
+	return r.deleteWhere(func(session *model.UserSession) bool { return session.ExpiresAt.Before(now) }), nil
+}
+
+func (r *SessionRepository) DeleteAllForUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return r.deleteWhere(func(session *model.UserSession) bool { return session.UserID == userID }), nil
+}
+
+// filter returns copies of the sessions matching a condition
+func (r *SessionRepository) filter(match func(*model.UserSession) bool) []*model.UserSession {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var sessions []*model.UserSession
+	for _, session := range r.sessions {
+		if match(session) {
+			sessions = append(sessions, clone(session))
+		}
+	}
+	return sessions
+}
+
+// deleteWhere removes the sessions matching a condition and returns how many were removed
+func (r *SessionRepository) deleteWhere(match func(*model.UserSession) bool) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, session := range r.sessions {
+		if match(session) {
+			delete(r.sessions, id)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// activeSession matches a user's sessions that have not expired
+func activeSession(userID primitive.ObjectID) func(*model.UserSession) bool {
+	now := time.Now()
+	return func(session *model.UserSession) bool {
+		return session.UserID == userID && session.ExpiresAt.After(now)
+	}
+}
+
+func sessionID(session *model.UserSession) primitive.ObjectID {
+	return session.ID
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SkillRepository struct {
+	mu     sync.RWMutex
+	skills map[string]*model.Skill
+}
+
+func NewSkillRepository() *SkillRepository {
+	return &SkillRepository{skills: make(map[string]*model.Skill)}
+}
+
+var _ repository.SkillRepository = (*SkillRepository)(nil)
+
+// Upsert creates or replaces the skill identified by its tag
+func (r *SkillRepository) Upsert(ctx context.Context, skill *model.Skill) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	skill.UpdatedAt = now
+	if stored, ok := r.skills[skill.Tag]; ok {
+		skill.ID = stored.ID
+		skill.CreatedAt = stored.CreatedAt
+	} else {
+		skill.ID = primitive.NewObjectID()
+		skill.CreatedAt = now
+	}
+	r.skills[skill.Tag] = clone(skill)
+	return nil
+}
+
+func (r *SkillRepository) GetByTag(ctx context.Context, tag string) (*model.Skill, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	skill, ok := r.skills[tag]
+	if !ok {
+		return nil, errors.New("skill not found")
+	}
+	return clone(skill), nil
+}
+
+func (r *SkillRepository) GetAll(ctx context.Context) ([]*model.Skill, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var skills []*model.Skill
+	for _, skill := range r.skills {
+		skills = append(skills, clone(skill))
+	}
+	sort.Slice(skills, func(i, j int) bool { return skills[i].Tag < skills[j].Tag })
+	return skills, nil
+}
+
+func (r *SkillRepository) Delete(ctx context.Context, tag string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.skills, tag)
+	return nil
+}
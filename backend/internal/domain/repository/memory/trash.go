@@ -0,0 +1,31 @@
+package memory
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// trash sets a record's deletion fields, reporting false when it is already in the trash
+func trash(deletedAt **time.Time, deletedBy **primitive.ObjectID, by primitive.ObjectID) bool {
+	if *deletedAt != nil {
+		return false
+	}
+	now := time.Now()
+	*deletedAt, *deletedBy = &now, &by
+	return true
+}
+
+// untrash clears a record's deletion fields, reporting false when it is not in the trash
+func untrash(deletedAt **time.Time, deletedBy **primitive.ObjectID) bool {
+	if *deletedAt == nil {
+		return false
+	}
+	*deletedAt, *deletedBy = nil, nil
+	return true
+}
+
+// deletedBefore reports whether a record was moved to the trash before the given time
+func deletedBefore(deletedAt *time.Time, before time.Time) bool {
+	return deletedAt != nil && !deletedAt.After(before)
+}
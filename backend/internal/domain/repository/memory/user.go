@@ -0,0 +1,273 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type UserRepository struct {
+	mu    sync.RWMutex
+	users map[primitive.ObjectID]*model.User
+}
+
+func NewUserRepository() *UserRepository {
+	return &UserRepository{users: make(map[primitive.ObjectID]*model.User)}
+}
+
+var _ repository.UserRepository = (*UserRepository)(nil)
+
+func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conflicts(user, primitive.NilObjectID) {
+		return repository.ErrDuplicateKey
+	}
+
+	user.ID = primitive.NewObjectID()
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = time.Now()
+	r.users[user.ID] = clone(user)
+	return nil
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt != nil {
+		return nil, repository.ErrUserNotFound
+	}
+	return clone(user), nil
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	return r.find(func(user *model.User) bool { return user.Email == email })
+}
+
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	return r.find(func(user *model.User) bool { return user.Username == username })
+}
+
+// Update saves a user's account details, keeping the stored statistics, last
+// login, deletion schedule and trash state. Like the Mongo repository,
+// updating a user that does not exist is not an error.
+func (r *UserRepository) Update(ctx context.Context, user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user.UpdatedAt = time.Now()
+	stored, ok := r.users[user.ID]
+	if !ok {
+		return nil
+	}
+	if r.conflicts(user, user.ID) {
+		return repository.ErrDuplicateKey
+	}
+
+	updated := clone(user)
+	updated.CreatedAt = stored.CreatedAt
+	updated.LastLogin = stored.LastLogin
+	updated.Statistics = stored.Statistics
+	updated.DeletionScheduledAt = stored.DeletionScheduledAt
+	updated.DeletedAt = stored.DeletedAt
+	updated.DeletedBy = stored.DeletedBy
+	r.users[user.ID] = updated
+	return nil
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.users, id)
+	return nil
+}
+
+// SoftDelete moves a user to the trash
+func (r *UserRepository) SoftDelete(ctx context.Context, id, deletedBy primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || !trash(&user.DeletedAt, &user.DeletedBy, deletedBy) {
+		return repository.ErrUserNotFound
+	}
+	return nil
+}
+
+// Restore takes a user out of the trash
+func (r *UserRepository) Restore(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt == nil {
+		return repository.ErrUserNotFound
+	}
+	if r.conflicts(user, id) {
+		return repository.ErrDuplicateKey
+	}
+	untrash(&user.DeletedAt, &user.DeletedBy)
+	return nil
+}
+
+// GetDeleted lists the users moved to the trash before the given time
+func (r *UserRepository) GetDeleted(ctx context.Context, before time.Time, page pagination.Params) ([]*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var users []*model.User
+	for _, user := range r.users {
+		if deletedBefore(user.DeletedAt, before) {
+			users = append(users, clone(user))
+		}
+	}
+	return paginateByID(users, page, func(u *model.User) primitive.ObjectID { return u.ID }), nil
+}
+
+// CountDeleted counts the users in the trash
+func (r *UserRepository) CountDeleted(ctx context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, user := range r.users {
+		if user.DeletedAt != nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *UserRepository) UpdateLastLogin(ctx context.Context, id primitive.ObjectID) error {
+	return r.modify(id, func(user *model.User) { user.LastLogin = time.Now() })
+}
+
+// SetDeletionSchedule schedules the user's account for deletion at the given
+// time, or cancels a scheduled deletion when at is nil
+func (r *UserRepository) SetDeletionSchedule(ctx context.Context, id primitive.ObjectID, at *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return repository.ErrUserNotFound
+	}
+	user.DeletionScheduledAt = nil
+	if at != nil {
+		scheduled := *at
+		user.DeletionScheduledAt = &scheduled
+	}
+	return nil
+}
+
+// GetDueForDeletion returns users whose scheduled deletion time is before the given time
+func (r *UserRepository) GetDueForDeletion(ctx context.Context, before time.Time, limit int) ([]*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var users []*model.User
+	for _, user := range r.users {
+		if user.DeletionScheduledAt != nil && !user.DeletionScheduledAt.After(before) {
+			users = append(users, clone(user))
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].DeletionScheduledAt.Before(*users[j].DeletionScheduledAt)
+	})
+	return window(users, 0, limit), nil
+}
+
+func (r *UserRepository) UpdateStatistics(ctx context.Context, id primitive.ObjectID, stats model.UserStatistics) error {
+	return r.modify(id, func(user *model.User) { user.Statistics = *clone(&stats) })
+}
+
+// SwapStatistics replaces the user's statistics only if they are still at the
+// expected revision, reporting whether the swap happened
+func (r *UserRepository) SwapStatistics(ctx context.Context, id primitive.ObjectID, expectedRevision int64, stats model.UserStatistics) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.Statistics.Revision != expectedRevision {
+		return false, nil
+	}
+	user.Statistics = *clone(&stats)
+	return true, nil
+}
+
+// ApplyAttemptStatistics counts an attempt towards the user's statistics
+func (r *UserRepository) ApplyAttemptStatistics(ctx context.Context, id primitive.ObjectID, attempt model.StatisticsAttempt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return repository.ErrUserNotFound
+	}
+	user.Statistics.ApplyAttempt(attempt)
+	return nil
+}
+
+// ForEach calls fn for every user not in the trash, in ID order, stopping at the first error
+func (r *UserRepository) ForEach(ctx context.Context, fn func(*model.User) error) error {
+	r.mu.RLock()
+	var users []*model.User
+	for _, user := range r.users {
+		if user.DeletedAt == nil {
+			users = append(users, clone(user))
+		}
+	}
+	r.mu.RUnlock()
+
+	for _, user := range sortByID(users, func(u *model.User) primitive.ObjectID { return u.ID }) {
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// find returns the first user not in the trash matching a condition
+func (r *UserRepository) find(match func(*model.User) bool) (*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.DeletedAt == nil && match(user) {
+			return clone(user), nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+// modify changes a stored user in place; a missing user is ignored
+func (r *UserRepository) modify(id primitive.ObjectID, change func(*model.User)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user, ok := r.users[id]; ok {
+		change(user)
+	}
+	return nil
+}
+
+// conflicts reports whether another user than self, outside the trash, has the
+// user's email or username
+func (r *UserRepository) conflicts(user *model.User, self primitive.ObjectID) bool {
+	for id, other := range r.users {
+		if id != self && other.DeletedAt == nil && (other.Email == user.Email || other.Username == user.Username) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package postgres
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const attemptColumns = `id, user_id, exercise_id, timestamp, user_answer, is_correct, time_taken, exercise_revision`
+
+// maxTagBreakdowns caps the tag breakdown of a performance report, like the
+// Mongo aggregation
+const maxTagBreakdowns = 50
+
+// scopedAttempts selects the attempts of a performance report with the
+// category, difficulty and tags of the exercises they answered, trashed ones
+// included. It binds the user, from and to as $1 to $3.
+const scopedAttempts = `WITH scoped AS (
+	SELECT a.is_correct, a.time_taken, a.timestamp, e.category, e.difficulty, e.tags
+	FROM attempts a LEFT JOIN exercises e ON e.id = a.exercise_id
+	WHERE a.user_id = $1 AND a.timestamp >= $2 AND a.timestamp < $3
+) `
+
+// bucketStats are the columns of a time period or breakdown
+const bucketStats = `count(*), count(*) FILTER (WHERE is_correct),
+	percentile_cont(0.5) WITHIN GROUP (ORDER BY time_taken)`
+
+type AttemptRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAttemptRepository(pool *pgxpool.Pool) *AttemptRepository {
+	return &AttemptRepository{pool: pool}
+}
+
+var _ repository.AttemptRepository = (*AttemptRepository)(nil)
+
+func (r *AttemptRepository) Create(ctx context.Context, attempt *model.Attempt) error {
+	attempt.ID = primitive.NewObjectID()
+	if attempt.Timestamp.IsZero() {
+		attempt.Timestamp = time.Now()
+	}
+
+	_, err := r.pool.Exec(ctx, `INSERT INTO attempts (`+attemptColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		attempt.ID.Hex(), attempt.UserID.Hex(), attempt.ExerciseID.Hex(), attempt.Timestamp, attempt.UserAnswer,
+		attempt.IsCorrect, attempt.TimeTaken, attempt.ExerciseRevision)
+	return translateError(err)
+}
+
+func (r *AttemptRepository) GetByUser(ctx context.Context, userID primitive.ObjectID, from, to time.Time) ([]*model.Attempt, error) {
+	return r.list(ctx, "SELECT "+attemptColumns+" FROM attempts WHERE user_id = $1 AND timestamp >= $2 AND timestamp < $3 ORDER BY timestamp, id",
+		userID.Hex(), from, to)
+}
+
+func (r *AttemptRepository) GetByUserAndExercise(ctx context.Context, userID, exerciseID primitive.ObjectID, limit int) ([]*model.Attempt, error) {
+	query := "SELECT " + attemptColumns + " FROM attempts WHERE user_id = $1 AND exercise_id = $2 ORDER BY timestamp DESC, id DESC"
+	if limit > 0 {
+		query += " LIMIT $3"
+		return r.list(ctx, query, userID.Hex(), exerciseID.Hex(), limit)
+	}
+	return r.list(ctx, query, userID.Hex(), exerciseID.Hex())
+}
+
+// AnonymizeUser moves a deleted user's attempts to a new random ID and clears
+// their free-text answers
+func (r *AttemptRepository) AnonymizeUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	tag, err := r.pool.Exec(ctx, "UPDATE attempts SET user_id = $2, user_answer = '' WHERE user_id = $1",
+		userID.Hex(), primitive.NewObjectID().Hex())
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// AggregatePerformance builds the report from one snapshot of the attempts.
+// Percentiles are interpolated between the nearest response times.
+func (r *AttemptRepository) AggregatePerformance(ctx context.Context, query model.AnalyticsQuery) (*model.PerformanceReport, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	report := &model.PerformanceReport{
+		From:        query.From,
+		To:          query.To,
+		Granularity: query.Granularity,
+		Timezone:    query.Timezone,
+	}
+	args := []interface{}{query.UserID.Hex(), query.From, query.To}
+
+	var percentiles []float64
+	err = tx.QueryRow(ctx, scopedAttempts+`SELECT count(*), count(*) FILTER (WHERE is_correct),
+		COALESCE(avg(time_taken), 0), COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY time_taken), 0),
+		percentile_cont(ARRAY[0.5, 0.75, 0.9, 0.95]) WITHIN GROUP (ORDER BY time_taken)
+		FROM scoped`, args...).Scan(&report.Summary.Attempts, &report.Summary.Correct, &report.Summary.AverageTime,
+		&report.Summary.MedianTime, &percentiles)
+	if err != nil {
+		return nil, err
+	}
+	report.Summary.Accuracy = accuracy(report.Summary.Correct, report.Summary.Attempts)
+	if len(percentiles) == 4 {
+		report.Percentiles = model.ResponseTimePercentiles{P50: percentiles[0], P75: percentiles[1], P90: percentiles[2], P95: percentiles[3]}
+	}
+
+	// date_trunc starts weeks on Monday, like the Mongo aggregation
+	rows, err := tx.Query(ctx, scopedAttempts+`SELECT date_trunc($4, timestamp, $5) AS period, `+bucketStats+`
+		FROM scoped GROUP BY period ORDER BY period`, append(args, query.Granularity, query.Timezone)...)
+	if err != nil {
+		return nil, err
+	}
+	report.Series, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.PerformanceBucket, error) {
+		var bucket model.PerformanceBucket
+		err := row.Scan(&bucket.Period, &bucket.Attempts, &bucket.Correct, &bucket.MedianTime)
+		bucket.Period = bucket.Period.UTC()
+		bucket.Accuracy = accuracy(bucket.Correct, bucket.Attempts)
+		return bucket, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if report.ByCategory, err = breakdowns(ctx, tx, "COALESCE(category, 'unknown')", "scoped", 0, args); err != nil {
+		return nil, err
+	}
+	if report.ByDifficulty, err = breakdowns(ctx, tx, "COALESCE(difficulty, 'unknown')", "scoped", 0, args); err != nil {
+		return nil, err
+	}
+	if report.ByTag, err = breakdowns(ctx, tx, "tag", "scoped CROSS JOIN LATERAL unnest(tags) AS tag", maxTagBreakdowns, args); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// breakdowns groups the scoped attempts by key, most attempts first and then
+// by key in byte order, as Mongo sorts strings. A limit of zero does not limit.
+func breakdowns(ctx context.Context, q querier, key, from string, limit int, args []interface{}) ([]model.PerformanceBreakdown, error) {
+	query := scopedAttempts + "SELECT " + key + " AS key, " + bucketStats + " FROM " + from +
+		" GROUP BY 1 ORDER BY count(*) DESC, " + key + ` COLLATE "C"`
+	if limit > 0 {
+		query += " LIMIT " + strconv.Itoa(limit)
+	}
+
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.PerformanceBreakdown, error) {
+		var breakdown model.PerformanceBreakdown
+		err := row.Scan(&breakdown.Key, &breakdown.Attempts, &breakdown.Correct, &breakdown.MedianTime)
+		breakdown.Accuracy = accuracy(breakdown.Correct, breakdown.Attempts)
+		return breakdown, err
+	})
+}
+
+// TotalsByExercise counts a user's attempts at each exercise they answered, in
+// exercise ID order
+func (r *AttemptRepository) TotalsByExercise(ctx context.Context, userID primitive.ObjectID) ([]model.AttemptTotals, error) {
+	rows, err := r.pool.Query(ctx, `SELECT exercise_id, count(*), count(*) FILTER (WHERE is_correct),
+		coalesce(sum(time_taken), 0), max(timestamp)
+		FROM attempts WHERE user_id = $1 GROUP BY exercise_id ORDER BY exercise_id`, userID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.AttemptTotals, error) {
+		var totals model.AttemptTotals
+		err := row.Scan(scanID(&totals.ExerciseID), &totals.Attempts, &totals.Correct, &totals.TimeTaken, &totals.LastAttempted)
+		return totals, err
+	})
+}
+
+func (r *AttemptRepository) list(ctx context.Context, query string, args ...interface{}) ([]*model.Attempt, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.Attempt, error) {
+		var attempt model.Attempt
+		err := row.Scan(scanID(&attempt.ID), scanID(&attempt.UserID), scanID(&attempt.ExerciseID), &attempt.Timestamp,
+			&attempt.UserAnswer, &attempt.IsCorrect, &attempt.TimeTaken, &attempt.ExerciseRevision)
+		return &attempt, err
+	})
+}
+
+// accuracy returns the percentage of correct attempts
+func accuracy(correct, attempts int) float64 {
+	if attempts == 0 {
+		return 0
+	}
+	return float64(correct) / float64(attempts) * 100
+}
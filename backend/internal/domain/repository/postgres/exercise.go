@@ -0,0 +1,319 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const exerciseColumns = `id, external_key, content_hash, title, description, type, category, difficulty,
+	problem, options, correct_answer, explanation, generated_by, template_id, created_at, tags,
+	status, review, revision, deleted_at, deleted_by`
+
+// exerciseSortColumns maps sort orders to the column they sort on
+var exerciseSortColumns = map[string]string{
+	model.ExerciseSortCreatedAt: "created_at",
+	model.ExerciseSortTitle:     "title",
+}
+
+type ExerciseRepository struct {
+	pool      *pgxpool.Pool
+	revisions repository.RevisionRepository
+}
+
+// NewExerciseRepository creates an exercise repository that records revisions
+// in the given revision repository
+func NewExerciseRepository(pool *pgxpool.Pool, revisions repository.RevisionRepository) *ExerciseRepository {
+	return &ExerciseRepository{pool: pool, revisions: revisions}
+}
+
+var _ repository.ExerciseRepository = (*ExerciseRepository)(nil)
+
+// Create inserts the exercise and records it as its first revision
+func (r *ExerciseRepository) Create(ctx context.Context, exercise *model.Exercise, meta model.RevisionMeta) error {
+	exercise.ID = primitive.NewObjectID()
+	exercise.Revision = 1
+	exercise.ContentHash = model.ContentHash(exercise)
+
+	_, err := r.pool.Exec(ctx, `INSERT INTO exercises (`+exerciseColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)`,
+		exerciseArgs(exercise)...)
+	if err != nil {
+		return translateError(err)
+	}
+	return r.revisions.Record(ctx, model.ContentKindExercise, exercise.ID, exercise.Revision, exercise, meta)
+}
+
+// GetByID returns an exercise that is not in the trash
+func (r *ExerciseRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*model.Exercise, error) {
+	return r.getOne(ctx, "id = $1 AND deleted_at IS NULL", id.Hex())
+}
+
+// GetByIDs returns the exercises with the given IDs, including soft-deleted ones.
+// IDs that do not exist are silently skipped.
+func (r *ExerciseRepository) GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*model.Exercise, error) {
+	return r.list(ctx, "SELECT "+exerciseColumns+" FROM exercises WHERE id = ANY($1) ORDER BY id", hexIDs(ids))
+}
+
+// GetByExternalKey returns the exercise imported under a key. Exercises in the
+// trash are skipped, since their keys can be reused.
+func (r *ExerciseRepository) GetByExternalKey(ctx context.Context, key string) (*model.Exercise, error) {
+	return r.getOne(ctx, "external_key = $1 AND deleted_at IS NULL", key)
+}
+
+// GetByContentHashes returns the exercises, excluding soft-deleted ones, whose
+// content hash is one of the given hashes, oldest first
+func (r *ExerciseRepository) GetByContentHashes(ctx context.Context, hashes []string) ([]*model.Exercise, error) {
+	return r.list(ctx, "SELECT "+exerciseColumns+" FROM exercises WHERE content_hash = ANY($1) AND deleted_at IS NULL ORDER BY id", hashes)
+}
+
+func (r *ExerciseRepository) GetByCategory(ctx context.Context, category string, includeUnpublished bool, page pagination.Params) ([]*model.Exercise, error) {
+	return r.listPage(ctx, model.ExerciseQuery{Category: category, IncludeUnpublished: includeUnpublished}, page)
+}
+
+func (r *ExerciseRepository) GetByDifficulty(ctx context.Context, difficulty string, includeUnpublished bool, page pagination.Params) ([]*model.Exercise, error) {
+	return r.listPage(ctx, model.ExerciseQuery{Difficulty: difficulty, IncludeUnpublished: includeUnpublished}, page)
+}
+
+func (r *ExerciseRepository) GetByTags(ctx context.Context, tags []string, includeUnpublished bool, page pagination.Params) ([]*model.Exercise, error) {
+	return r.listPage(ctx, model.ExerciseQuery{Tags: tags, IncludeUnpublished: includeUnpublished}, page)
+}
+
+// Update replaces the exercise if it is still at the revision it was read at and
+// records the result as a new revision. A stale write returns ErrVersionConflict.
+func (r *ExerciseRepository) Update(ctx context.Context, exercise *model.Exercise, meta model.RevisionMeta) error {
+	expected := exercise.Revision
+	exercise.Revision++
+	exercise.ContentHash = model.ContentHash(exercise)
+
+	args := append(exerciseArgs(exercise), expected)
+	tag, err := r.pool.Exec(ctx, `UPDATE exercises SET external_key = $2, content_hash = $3, title = $4,
+		description = $5, type = $6, category = $7, difficulty = $8, problem = $9, options = $10,
+		correct_answer = $11, explanation = $12, generated_by = $13, template_id = $14, created_at = $15,
+		tags = $16, status = $17, review = $18, revision = $19, deleted_at = $20,
+		deleted_by = $21
+		WHERE id = $1 AND revision = $22`, args...)
+	if err != nil || tag.RowsAffected() == 0 {
+		exercise.Revision = expected
+		if err != nil {
+			return translateError(err)
+		}
+		return repository.ErrVersionConflict
+	}
+	return r.revisions.Record(ctx, model.ContentKindExercise, exercise.ID, exercise.Revision, exercise, meta)
+}
+
+// Delete removes an exercise. It fails while learning path stages still use it.
+func (r *ExerciseRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.pool.Exec(ctx, "DELETE FROM exercises WHERE id = $1", id.Hex())
+	return err
+}
+
+// SoftDelete moves an exercise to the trash
+func (r *ExerciseRepository) SoftDelete(ctx context.Context, id, deletedBy primitive.ObjectID) error {
+	return softDelete(ctx, r.pool, "exercises", id, deletedBy, repository.ErrExerciseNotFound)
+}
+
+// Restore takes an exercise out of the trash
+func (r *ExerciseRepository) Restore(ctx context.Context, id primitive.ObjectID) error {
+	return restore(ctx, r.pool, "exercises", id, repository.ErrExerciseNotFound)
+}
+
+// GetDeleted lists the exercises moved to the trash before the given time
+func (r *ExerciseRepository) GetDeleted(ctx context.Context, before time.Time, page pagination.Params) ([]*model.Exercise, error) {
+	var c conditions
+	c.add("deleted_at <= ?", before)
+	order := c.page(page)
+
+	return r.list(ctx, "SELECT "+exerciseColumns+" FROM exercises WHERE "+c.where()+order, c.args...)
+}
+
+// CountDeleted counts the exercises in the trash
+func (r *ExerciseRepository) CountDeleted(ctx context.Context) (int64, error) {
+	return countDeleted(ctx, r.pool, "exercises")
+}
+
+// GetByStatus lists exercises in a status, oldest submission first. after is the
+// submission time of page.After in cursor mode.
+func (r *ExerciseRepository) GetByStatus(ctx context.Context, status string, page pagination.Params, after *time.Time) ([]*model.Exercise, error) {
+	var c conditions
+	c.add("deleted_at IS NULL")
+	c.add("status = ?", status)
+	order := c.pageSorted(page, submittedAt, after, false)
+
+	return r.list(ctx, "SELECT "+exerciseColumns+" FROM exercises WHERE "+c.where()+order, c.args...)
+}
+
+// Count counts the exercises matching a query's filters
+func (r *ExerciseRepository) Count(ctx context.Context, query model.ExerciseQuery) (int64, error) {
+	c := exerciseConditions(query)
+
+	var count int64
+	err := r.pool.QueryRow(ctx, "SELECT count(*) FROM exercises WHERE "+c.where(), c.args...).Scan(&count)
+	return count, err
+}
+
+// Search returns a page of the exercises matching a query in its sort order.
+// Relevance order only supports offset pages.
+func (r *ExerciseRepository) Search(ctx context.Context, query model.ExerciseQuery, page pagination.Params) ([]*model.Exercise, error) {
+	c := exerciseConditions(query)
+
+	var order string
+	if query.Sort == model.ExerciseSortRelevance {
+		rank := "ts_rank(search, to_tsquery('english', " + c.bind(tsQuery(query.Text)) + "))"
+		order = " ORDER BY " + rank + " DESC, id LIMIT " + strconv.Itoa(page.Limit) + " OFFSET " + strconv.Itoa(page.Offset)
+	} else {
+		column, ok := exerciseSortColumns[query.Sort]
+		if !ok {
+			column = exerciseSortColumns[model.ExerciseSortCreatedAt]
+		}
+		order = c.pageSorted(page, column, query.After, query.Descending)
+	}
+
+	return r.list(ctx, "SELECT "+exerciseColumns+" FROM exercises WHERE "+c.where()+order, c.args...)
+}
+
+// ForEach calls fn with every exercise matching a query's filters in id order,
+// without loading them all at once. It stops at the first error fn returns.
+func (r *ExerciseRepository) ForEach(ctx context.Context, query model.ExerciseQuery, fn func(*model.Exercise) error) error {
+	c := exerciseConditions(query)
+	rows, err := r.pool.Query(ctx, "SELECT "+exerciseColumns+" FROM exercises WHERE "+c.where()+" ORDER BY id", c.args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		exercise, err := scanExercise(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(exercise); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// AnonymizeUser removes a deleted user from the review records of exercises
+func (r *ExerciseRepository) AnonymizeUser(ctx context.Context, userID primitive.ObjectID) error {
+	return anonymizeReviews(ctx, r.pool, "exercises", userID)
+}
+
+func (r *ExerciseRepository) getOne(ctx context.Context, condition string, arg interface{}) (*model.Exercise, error) {
+	exercise, err := scanExercise(r.pool.QueryRow(ctx, "SELECT "+exerciseColumns+" FROM exercises WHERE "+condition, arg))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, repository.ErrExerciseNotFound
+	}
+	return exercise, err
+}
+
+// listPage returns a page, in id order, of the exercises matching a query
+func (r *ExerciseRepository) listPage(ctx context.Context, query model.ExerciseQuery, page pagination.Params) ([]*model.Exercise, error) {
+	c := exerciseConditions(query)
+	order := c.page(page)
+	return r.list(ctx, "SELECT "+exerciseColumns+" FROM exercises WHERE "+c.where()+order, c.args...)
+}
+
+func (r *ExerciseRepository) list(ctx context.Context, query string, args ...interface{}) ([]*model.Exercise, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.Exercise, error) { return scanExercise(row) })
+}
+
+// exerciseConditions returns the conditions selecting the exercises a query
+// matches: not soft-deleted, published unless the query includes unpublished
+// ones, and passing its filters
+func exerciseConditions(q model.ExerciseQuery) *conditions {
+	c := &conditions{}
+	c.add("deleted_at IS NULL")
+	if !q.IncludeUnpublished {
+		c.add("status IN ('', ?)", model.StatusPublished)
+	} else if q.Status != "" {
+		c.add("status = ?", q.Status)
+	}
+	if q.Category != "" {
+		c.add("category = ?", q.Category)
+	}
+	if q.Difficulty != "" {
+		c.add("difficulty = ?", q.Difficulty)
+	}
+	if q.Type != "" {
+		c.add("type = ?", q.Type)
+	}
+	if len(q.Tags) > 0 {
+		if q.MatchAllTags {
+			c.add("tags @> ?", q.Tags)
+		} else {
+			c.add("tags && ?", q.Tags)
+		}
+	}
+	if q.GeneratedBy != "" {
+		c.add("generated_by = ?", q.GeneratedBy)
+	}
+	if q.CreatedFrom != nil {
+		c.add("created_at >= ?", *q.CreatedFrom)
+	}
+	if q.CreatedTo != nil {
+		c.add("created_at <= ?", *q.CreatedTo)
+	}
+	if q.Text != "" {
+		c.add("search @@ to_tsquery('english', ?)", tsQuery(q.Text))
+	}
+	return c
+}
+
+// tsQuery turns free text into a query matching any of its words, like a Mongo
+// text search
+func tsQuery(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		// Matches nothing rather than failing to parse
+		return "''"
+	}
+	return strings.Join(words, " | ")
+}
+
+// exerciseArgs returns the column values of an exercise in exerciseColumns order
+func exerciseArgs(e *model.Exercise) []interface{} {
+	var externalKey *string
+	if e.ExternalKey != "" {
+		externalKey = &e.ExternalKey
+	}
+	return []interface{}{
+		e.ID.Hex(), externalKey, e.ContentHash, e.Title, e.Description, e.Type, e.Category, e.Difficulty,
+		e.Content.Problem, e.Content.Options, e.Content.CorrectAnswer, e.Content.Explanation,
+		e.Metadata.GeneratedBy, e.Metadata.TemplateID, e.Metadata.CreatedAt, e.Tags,
+		e.Status, e.Review, e.Revision, e.DeletedAt, optionalHex(e.DeletedBy),
+	}
+}
+
+func scanExercise(row pgx.Row) (*model.Exercise, error) {
+	var e model.Exercise
+	var externalKey *string
+	err := row.Scan(scanID(&e.ID), &externalKey, &e.ContentHash, &e.Title, &e.Description, &e.Type, &e.Category,
+		&e.Difficulty, &e.Content.Problem, &e.Content.Options, &e.Content.CorrectAnswer, &e.Content.Explanation,
+		&e.Metadata.GeneratedBy, &e.Metadata.TemplateID, &e.Metadata.CreatedAt, &e.Tags,
+		&e.Status, &e.Review, &e.Revision, &e.DeletedAt, optionalIDScanner{dst: &e.DeletedBy})
+	if err != nil {
+		return nil, err
+	}
+	if externalKey != nil {
+		e.ExternalKey = *externalKey
+	}
+	return &e, nil
+}
@@ -0,0 +1,530 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const pathColumns = `id, title, description, difficulty, categories, status, review, revision,
+	prerequisite_path_ids, required_skills, created_at, updated_at, deleted_at, deleted_by`
+
+// LearningPathRepository keeps a path's stages in path_stages and their
+// exercises in stage_exercises, and rewrites both whenever the path changes
+type LearningPathRepository struct {
+	pool      *pgxpool.Pool
+	revisions repository.RevisionRepository
+}
+
+// NewLearningPathRepository creates a learning path repository that records
+// revisions in the given revision repository
+func NewLearningPathRepository(pool *pgxpool.Pool, revisions repository.RevisionRepository) *LearningPathRepository {
+	return &LearningPathRepository{pool: pool, revisions: revisions}
+}
+
+var _ repository.LearningPathRepository = (*LearningPathRepository)(nil)
+
+// Create inserts the learning path and records it as its first revision
+func (r *LearningPathRepository) Create(ctx context.Context, path *model.LearningPath, meta model.RevisionMeta) error {
+	path.ID = primitive.NewObjectID()
+	path.CreatedAt = time.Now()
+	path.UpdatedAt = time.Now()
+	path.Revision = 1
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `INSERT INTO learning_paths (`+pathColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`, pathArgs(path)...)
+	if err != nil {
+		return translateError(err)
+	}
+	if err := writeStages(ctx, tx, path.ID, path.Stages); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	return r.revisions.Record(ctx, model.ContentKindLearningPath, path.ID, path.Revision, path, meta)
+}
+
+func (r *LearningPathRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*model.LearningPath, error) {
+	return getPath(ctx, r.pool, "id = $1 AND deleted_at IS NULL", id.Hex())
+}
+
+func (r *LearningPathRepository) GetAll(ctx context.Context, includeUnpublished bool, page pagination.Params) ([]*model.LearningPath, error) {
+	return r.listPage(ctx, model.PathQuery{IncludeUnpublished: includeUnpublished}, page)
+}
+
+func (r *LearningPathRepository) GetByDifficulty(ctx context.Context, difficulty string, includeUnpublished bool, page pagination.Params) ([]*model.LearningPath, error) {
+	return r.listPage(ctx, model.PathQuery{Difficulty: difficulty, IncludeUnpublished: includeUnpublished}, page)
+}
+
+func (r *LearningPathRepository) GetByCategory(ctx context.Context, category string, includeUnpublished bool, page pagination.Params) ([]*model.LearningPath, error) {
+	return r.listPage(ctx, model.PathQuery{Category: category, IncludeUnpublished: includeUnpublished}, page)
+}
+
+func (r *LearningPathRepository) GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*model.LearningPath, error) {
+	return listPaths(ctx, r.pool, "SELECT "+pathColumns+" FROM learning_paths WHERE id = ANY($1) AND deleted_at IS NULL ORDER BY id", hexIDs(ids))
+}
+
+func (r *LearningPathRepository) GetByExerciseID(ctx context.Context, exerciseID primitive.ObjectID) ([]*model.LearningPath, error) {
+	return listPaths(ctx, r.pool, `SELECT `+pathColumns+` FROM learning_paths
+		WHERE id IN (SELECT path_id FROM stage_exercises WHERE exercise_id = $1) AND deleted_at IS NULL ORDER BY id`, exerciseID.Hex())
+}
+
+// GetDependents returns the learning paths that list the given path as a prerequisite
+func (r *LearningPathRepository) GetDependents(ctx context.Context, pathID primitive.ObjectID) ([]*model.LearningPath, error) {
+	return listPaths(ctx, r.pool, "SELECT "+pathColumns+" FROM learning_paths WHERE $1 = ANY(prerequisite_path_ids) AND deleted_at IS NULL ORDER BY id",
+		pathID.Hex())
+}
+
+// Update replaces the learning path if it is still at the revision it was read at
+// and records the result as a new revision. A stale write returns ErrVersionConflict.
+func (r *LearningPathRepository) Update(ctx context.Context, path *model.LearningPath, meta model.RevisionMeta) error {
+	expected := path.Revision
+	path.UpdatedAt = time.Now()
+	path.Revision++
+
+	err := r.update(ctx, path, expected)
+	if err != nil {
+		path.Revision = expected
+		return err
+	}
+	return r.revisions.Record(ctx, model.ContentKindLearningPath, path.ID, path.Revision, path, meta)
+}
+
+func (r *LearningPathRepository) update(ctx context.Context, path *model.LearningPath, expected int) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE learning_paths SET title = $2, description = $3, difficulty = $4,
+		categories = $5, status = $6, review = $7, revision = $8, prerequisite_path_ids = $9,
+		required_skills = $10, created_at = $11, updated_at = $12, deleted_at = $13, deleted_by = $14
+		WHERE id = $1 AND revision = $15`, append(pathArgs(path), expected)...)
+	if err != nil {
+		return translateError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrVersionConflict
+	}
+	if err := writeStages(ctx, tx, path.ID, path.Stages); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// InsertStage adds a stage so that it becomes stage number position, moving the
+// stages from there on down. A position of zero or past the end appends the stage.
+func (r *LearningPathRepository) InsertStage(ctx context.Context, pathID primitive.ObjectID, stage model.PathStage, position int, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
+	return r.updateStages(ctx, pathID, 0, expectedRevision, meta, func(stages []model.PathStage) ([]model.PathStage, bool) {
+		if position <= 0 || position > len(stages) {
+			return append(stages, stage), true
+		}
+		inserted := append([]model.PathStage{}, stages[:position-1]...)
+		inserted = append(inserted, stage)
+		return append(inserted, stages[position-1:]...), true
+	})
+}
+
+// ReplaceStage replaces the content of a stage, keeping its identity and position
+func (r *LearningPathRepository) ReplaceStage(ctx context.Context, pathID primitive.ObjectID, stage model.PathStage, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
+	return r.updateStages(ctx, pathID, stage.StageNumber, expectedRevision, meta, func(stages []model.PathStage) ([]model.PathStage, bool) {
+		current := &stages[stage.StageNumber-1]
+		current.Title = stage.Title
+		current.Description = stage.Description
+		current.ExerciseIDs = stage.ExerciseIDs
+		current.CompletionCriteria = stage.CompletionCriteria
+		return stages, true
+	})
+}
+
+// RemoveStage removes a stage and renumbers the stages after it
+func (r *LearningPathRepository) RemoveStage(ctx context.Context, pathID primitive.ObjectID, stageNumber int, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
+	return r.updateStages(ctx, pathID, stageNumber, expectedRevision, meta, func(stages []model.PathStage) ([]model.PathStage, bool) {
+		return append(stages[:stageNumber-1], stages[stageNumber:]...), true
+	})
+}
+
+// ReorderStages puts the stages in a new order, given as the current stage numbers
+// in the order they should appear. The write is rejected as a conflict if the
+// number of stages changed meanwhile.
+func (r *LearningPathRepository) ReorderStages(ctx context.Context, pathID primitive.ObjectID, order []int, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
+	return r.updateStages(ctx, pathID, 0, expectedRevision, meta, func(stages []model.PathStage) ([]model.PathStage, bool) {
+		if len(order) != len(stages) {
+			return nil, false
+		}
+		reordered := make([]model.PathStage, 0, len(order))
+		for _, number := range order {
+			if number < 1 || number > len(stages) {
+				return nil, false
+			}
+			reordered = append(reordered, stages[number-1])
+		}
+		return reordered, true
+	})
+}
+
+// AddStageExercise appends an exercise to a stage unless the stage already has it
+func (r *LearningPathRepository) AddStageExercise(ctx context.Context, pathID primitive.ObjectID, stageNumber int, exerciseID primitive.ObjectID, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
+	return r.updateStages(ctx, pathID, stageNumber, expectedRevision, meta, func(stages []model.PathStage) ([]model.PathStage, bool) {
+		stage := &stages[stageNumber-1]
+		for _, id := range stage.ExerciseIDs {
+			if id == exerciseID {
+				return stages, true
+			}
+		}
+		stage.ExerciseIDs = append(stage.ExerciseIDs, exerciseID)
+		return stages, true
+	})
+}
+
+// RemoveStageExercise removes an exercise from a stage
+func (r *LearningPathRepository) RemoveStageExercise(ctx context.Context, pathID primitive.ObjectID, stageNumber int, exerciseID primitive.ObjectID, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
+	return r.updateStages(ctx, pathID, stageNumber, expectedRevision, meta, func(stages []model.PathStage) ([]model.PathStage, bool) {
+		stage := &stages[stageNumber-1]
+		kept := []primitive.ObjectID{}
+		for _, id := range stage.ExerciseIDs {
+			if id != exerciseID {
+				kept = append(kept, id)
+			}
+		}
+		stage.ExerciseIDs = kept
+		return stages, true
+	})
+}
+
+// updateStages locks a path, replaces its stages with the result of change,
+// renumbers them and bumps the revision. When stageNumber is set the stage must
+// exist; when expectedRevision is not model.AnyRevision the path must still be
+// at that revision. change reports false to reject the write as a conflict.
+func (r *LearningPathRepository) updateStages(ctx context.Context, pathID primitive.ObjectID, stageNumber int, expectedRevision int, meta model.RevisionMeta, change func([]model.PathStage) ([]model.PathStage, bool)) (*model.LearningPath, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	path, err := getPath(ctx, tx, "id = $1 AND deleted_at IS NULL FOR UPDATE", pathID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if stageNumber > 0 && (stageNumber > len(path.Stages) || path.Stages[stageNumber-1].StageNumber != stageNumber) {
+		return nil, repository.ErrStageNotFound
+	}
+	if expectedRevision != model.AnyRevision && path.Revision != expectedRevision {
+		return nil, repository.ErrVersionConflict
+	}
+
+	stages, ok := change(path.Stages)
+	if !ok {
+		return nil, repository.ErrVersionConflict
+	}
+	for i := range stages {
+		stages[i].StageNumber = i + 1
+	}
+	path.Stages = stages
+	path.Revision++
+	path.UpdatedAt = time.Now()
+
+	_, err = tx.Exec(ctx, "UPDATE learning_paths SET revision = $2, updated_at = $3 WHERE id = $1",
+		path.ID.Hex(), path.Revision, path.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeStages(ctx, tx, path.ID, path.Stages); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := r.revisions.Record(ctx, model.ContentKindLearningPath, path.ID, path.Revision, path, meta); err != nil {
+		return nil, err
+	}
+	return path, nil
+}
+
+// Delete removes a learning path together with its stages
+func (r *LearningPathRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.pool.Exec(ctx, "DELETE FROM learning_paths WHERE id = $1", id.Hex())
+	return err
+}
+
+// SoftDelete moves a learning path to the trash
+func (r *LearningPathRepository) SoftDelete(ctx context.Context, id, deletedBy primitive.ObjectID) error {
+	return softDelete(ctx, r.pool, "learning_paths", id, deletedBy, repository.ErrPathNotFound)
+}
+
+// Restore takes a learning path out of the trash
+func (r *LearningPathRepository) Restore(ctx context.Context, id primitive.ObjectID) error {
+	return restore(ctx, r.pool, "learning_paths", id, repository.ErrPathNotFound)
+}
+
+// GetDeleted lists the learning paths moved to the trash before the given time
+func (r *LearningPathRepository) GetDeleted(ctx context.Context, before time.Time, page pagination.Params) ([]*model.LearningPath, error) {
+	var c conditions
+	c.add("deleted_at <= ?", before)
+	order := c.page(page)
+
+	return listPaths(ctx, r.pool, "SELECT "+pathColumns+" FROM learning_paths WHERE "+c.where()+order, c.args...)
+}
+
+// CountDeleted counts the learning paths in the trash
+func (r *LearningPathRepository) CountDeleted(ctx context.Context) (int64, error) {
+	return countDeleted(ctx, r.pool, "learning_paths")
+}
+
+// RemoveExercise pulls an exercise out of every stage that references it, in
+// trashed paths too, and returns the number of learning paths that were changed.
+// Each path changed gets a new revision.
+func (r *LearningPathRepository) RemoveExercise(ctx context.Context, exerciseID primitive.ObjectID, meta model.RevisionMeta) (int64, error) {
+	condition := "id IN (SELECT path_id FROM stage_exercises WHERE exercise_id = $1)"
+	return r.rewriteEach(ctx, condition, exerciseID.Hex(), meta, func(path *model.LearningPath) {
+		for i := range path.Stages {
+			kept := []primitive.ObjectID{}
+			for _, id := range path.Stages[i].ExerciseIDs {
+				if id != exerciseID {
+					kept = append(kept, id)
+				}
+			}
+			path.Stages[i].ExerciseIDs = kept
+		}
+	})
+}
+
+// RemovePrerequisite drops a path from the prerequisites of every path that
+// depends on it, in trashed paths too, and returns the number of learning paths
+// that were changed. Each path changed gets a new revision.
+func (r *LearningPathRepository) RemovePrerequisite(ctx context.Context, pathID primitive.ObjectID, meta model.RevisionMeta) (int64, error) {
+	return r.rewriteEach(ctx, "$1 = ANY(prerequisite_path_ids)", pathID.Hex(), meta, func(path *model.LearningPath) {
+		kept := []primitive.ObjectID{}
+		for _, id := range path.PrerequisitePathIDs {
+			if id != pathID {
+				kept = append(kept, id)
+			}
+		}
+		path.PrerequisitePathIDs = kept
+	})
+}
+
+// rewriteEach locks the paths matching a condition, applies change to each,
+// bumps their revisions and records them like an Update
+func (r *LearningPathRepository) rewriteEach(ctx context.Context, condition string, arg interface{}, meta model.RevisionMeta, change func(*model.LearningPath)) (int64, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	paths, err := listPaths(ctx, tx, "SELECT "+pathColumns+" FROM learning_paths WHERE "+condition+" ORDER BY id FOR UPDATE", arg)
+	if err != nil {
+		return 0, err
+	}
+	for _, path := range paths {
+		change(path)
+		path.Revision++
+		path.UpdatedAt = time.Now()
+
+		_, err := tx.Exec(ctx, "UPDATE learning_paths SET prerequisite_path_ids = $2, revision = $3, updated_at = $4 WHERE id = $1",
+			path.ID.Hex(), hexIDs(path.PrerequisitePathIDs), path.Revision, path.UpdatedAt)
+		if err != nil {
+			return 0, err
+		}
+		if err := writeStages(ctx, tx, path.ID, path.Stages); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	for _, path := range paths {
+		if err := r.revisions.Record(ctx, model.ContentKindLearningPath, path.ID, path.Revision, path, meta); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(paths)), nil
+}
+
+// GetByStatus lists paths in a status, oldest submission first. after is the
+// submission time of page.After in cursor mode.
+func (r *LearningPathRepository) GetByStatus(ctx context.Context, status string, page pagination.Params, after *time.Time) ([]*model.LearningPath, error) {
+	var c conditions
+	c.add("deleted_at IS NULL")
+	c.add("status = ?", status)
+	order := c.pageSorted(page, submittedAt, after, false)
+
+	return listPaths(ctx, r.pool, "SELECT "+pathColumns+" FROM learning_paths WHERE "+c.where()+order, c.args...)
+}
+
+// Count counts the paths matching a query
+func (r *LearningPathRepository) Count(ctx context.Context, query model.PathQuery) (int64, error) {
+	c := pathConditions(query)
+
+	var count int64
+	err := r.pool.QueryRow(ctx, "SELECT count(*) FROM learning_paths WHERE "+c.where(), c.args...).Scan(&count)
+	return count, err
+}
+
+// AnonymizeUser removes a deleted user from the review records of learning paths
+func (r *LearningPathRepository) AnonymizeUser(ctx context.Context, userID primitive.ObjectID) error {
+	return anonymizeReviews(ctx, r.pool, "learning_paths", userID)
+}
+
+// listPage returns a page, in id order, of the paths matching a query
+func (r *LearningPathRepository) listPage(ctx context.Context, query model.PathQuery, page pagination.Params) ([]*model.LearningPath, error) {
+	c := pathConditions(query)
+	order := c.page(page)
+	return listPaths(ctx, r.pool, "SELECT "+pathColumns+" FROM learning_paths WHERE "+c.where()+order, c.args...)
+}
+
+// pathConditions returns the conditions selecting the paths a query matches:
+// not in the trash, published unless the query includes unpublished ones, and
+// passing its filters
+func pathConditions(q model.PathQuery) *conditions {
+	c := &conditions{}
+	c.add("deleted_at IS NULL")
+	if !q.IncludeUnpublished {
+		c.add("status IN ('', ?)", model.StatusPublished)
+	} else if q.Status != "" {
+		c.add("status = ?", q.Status)
+	}
+	if q.Difficulty != "" {
+		c.add("difficulty = ?", q.Difficulty)
+	}
+	if q.Category != "" {
+		c.add("? = ANY(categories)", q.Category)
+	}
+	return c
+}
+
+func getPath(ctx context.Context, q querier, condition string, arg interface{}) (*model.LearningPath, error) {
+	paths, err := listPaths(ctx, q, "SELECT "+pathColumns+" FROM learning_paths WHERE "+condition, arg)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, repository.ErrPathNotFound
+	}
+	return paths[0], nil
+}
+
+// listPaths runs a query for path rows and loads the stages of the paths found
+func listPaths(ctx context.Context, q querier, query string, args ...interface{}) ([]*model.LearningPath, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.LearningPath, error) { return scanPath(row) })
+	if err != nil || len(paths) == 0 {
+		return paths, err
+	}
+	if err := loadStages(ctx, q, paths); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// loadStages fills in the stages of paths and the exercises of each stage
+func loadStages(ctx context.Context, q querier, paths []*model.LearningPath) error {
+	byID := make(map[primitive.ObjectID]*model.LearningPath, len(paths))
+	ids := make([]primitive.ObjectID, len(paths))
+	for i, path := range paths {
+		path.Stages = []model.PathStage{}
+		byID[path.ID] = path
+		ids[i] = path.ID
+	}
+
+	rows, err := q.Query(ctx, `SELECT path_id, stage_number, id, title, description, min_accuracy, min_exercises
+		FROM path_stages WHERE path_id = ANY($1) ORDER BY path_id, stage_number`, hexIDs(ids))
+	if err != nil {
+		return err
+	}
+	var pathID primitive.ObjectID
+	var stage model.PathStage
+	_, err = pgx.ForEachRow(rows, []interface{}{scanID(&pathID), &stage.StageNumber, scanID(&stage.ID), &stage.Title,
+		&stage.Description, &stage.CompletionCriteria.MinAccuracy, &stage.CompletionCriteria.MinExercises}, func() error {
+		stage.ExerciseIDs = []primitive.ObjectID{}
+		path := byID[pathID]
+		path.Stages = append(path.Stages, stage)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	rows, err = q.Query(ctx, `SELECT path_id, stage_number, exercise_id
+		FROM stage_exercises WHERE path_id = ANY($1) ORDER BY path_id, stage_number, position`, hexIDs(ids))
+	if err != nil {
+		return err
+	}
+	var stageNumber int
+	var exerciseID primitive.ObjectID
+	_, err = pgx.ForEachRow(rows, []interface{}{scanID(&pathID), &stageNumber, scanID(&exerciseID)}, func() error {
+		path := byID[pathID]
+		if stageNumber < 1 || stageNumber > len(path.Stages) {
+			return errors.New("stage exercise without a stage")
+		}
+		stage := &path.Stages[stageNumber-1]
+		stage.ExerciseIDs = append(stage.ExerciseIDs, exerciseID)
+		return nil
+	})
+	return err
+}
+
+// writeStages replaces the stored stages of a path
+func writeStages(ctx context.Context, tx pgx.Tx, pathID primitive.ObjectID, stages []model.PathStage) error {
+	if _, err := tx.Exec(ctx, "DELETE FROM path_stages WHERE path_id = $1", pathID.Hex()); err != nil {
+		return err
+	}
+
+	batch := &pgx.Batch{}
+	for _, stage := range stages {
+		batch.Queue(`INSERT INTO path_stages (path_id, stage_number, id, title, description, min_accuracy, min_exercises)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			pathID.Hex(), stage.StageNumber, stage.ID.Hex(), stage.Title, stage.Description,
+			stage.CompletionCriteria.MinAccuracy, stage.CompletionCriteria.MinExercises)
+		for position, exerciseID := range stage.ExerciseIDs {
+			batch.Queue(`INSERT INTO stage_exercises (path_id, stage_number, position, exercise_id) VALUES ($1, $2, $3, $4)`,
+				pathID.Hex(), stage.StageNumber, position, exerciseID.Hex())
+		}
+	}
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+// pathArgs returns the column values of a path in pathColumns order
+func pathArgs(p *model.LearningPath) []interface{} {
+	return []interface{}{
+		p.ID.Hex(), p.Title, p.Description, p.Difficulty, p.Categories, p.Status, p.Review, p.Revision,
+		hexIDs(p.PrerequisitePathIDs), p.RequiredSkills, p.CreatedAt, p.UpdatedAt, p.DeletedAt, optionalHex(p.DeletedBy),
+	}
+}
+
+func scanPath(row pgx.Row) (*model.LearningPath, error) {
+	var p model.LearningPath
+	var prerequisites []string
+	err := row.Scan(scanID(&p.ID), &p.Title, &p.Description, &p.Difficulty, &p.Categories, &p.Status, &p.Review,
+		&p.Revision, &prerequisites, &p.RequiredSkills, &p.CreatedAt, &p.UpdatedAt, &p.DeletedAt,
+		optionalIDScanner{dst: &p.DeletedBy})
+	if err != nil {
+		return nil, err
+	}
+	if p.PrerequisitePathIDs, err = objectIDs(prerequisites); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
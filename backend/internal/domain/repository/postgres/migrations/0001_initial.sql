@@ -0,0 +1,129 @@
+-- IDs are Mongo ObjectIDs in hex, so records keep the IDs the API exposes and
+-- sort in creation order like _id
+
+CREATE TABLE users (
+    id                    CHAR(24) PRIMARY KEY,
+    email                 TEXT NOT NULL UNIQUE,
+    password_hash         TEXT NOT NULL,
+    username              TEXT NOT NULL UNIQUE,
+    first_name            TEXT NOT NULL DEFAULT '',
+    last_name             TEXT NOT NULL DEFAULT '',
+    timezone              TEXT NOT NULL DEFAULT '',
+    role                  TEXT NOT NULL DEFAULT '',
+    created_at            TIMESTAMPTZ NOT NULL,
+    updated_at            TIMESTAMPTZ NOT NULL,
+    last_login            TIMESTAMPTZ NOT NULL,
+    preferences           JSONB NOT NULL DEFAULT '{}',
+    statistics            JSONB NOT NULL DEFAULT '{}',
+    statistics_revision   BIGINT NOT NULL DEFAULT 0,
+    deletion_scheduled_at TIMESTAMPTZ
+);
+
+CREATE INDEX users_deletion_scheduled_at_idx ON users (deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
+
+CREATE TABLE user_sessions (
+    id            CHAR(24) PRIMARY KEY,
+    user_id       CHAR(24) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role          TEXT NOT NULL DEFAULT '',
+    session_token TEXT NOT NULL UNIQUE,
+    created_at    TIMESTAMPTZ NOT NULL,
+    expires_at    TIMESTAMPTZ NOT NULL,
+    ip_address    TEXT NOT NULL DEFAULT '',
+    device_info   TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX user_sessions_user_id_idx ON user_sessions (user_id);
+CREATE INDEX user_sessions_expires_at_idx ON user_sessions (expires_at);
+
+CREATE TABLE exercises (
+    id             CHAR(24) PRIMARY KEY,
+    external_key   TEXT UNIQUE,
+    content_hash   TEXT NOT NULL DEFAULT '',
+    title          TEXT NOT NULL,
+    description    TEXT NOT NULL DEFAULT '',
+    type           TEXT NOT NULL,
+    category       TEXT NOT NULL,
+    difficulty     TEXT NOT NULL,
+    problem        TEXT NOT NULL,
+    options        TEXT[],
+    correct_answer TEXT NOT NULL,
+    explanation    TEXT NOT NULL DEFAULT '',
+    generated_by   TEXT NOT NULL DEFAULT '',
+    template_id    TEXT NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL,
+    tags           TEXT[],
+    status         TEXT NOT NULL DEFAULT '',
+    review         JSONB NOT NULL DEFAULT '{}',
+    revision       INTEGER NOT NULL DEFAULT 0,
+    deleted_at     TIMESTAMPTZ,
+    search         TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', title), 'A') ||
+        setweight(to_tsvector('english', description), 'B') ||
+        setweight(to_tsvector('english', problem), 'C')
+    ) STORED
+);
+
+CREATE INDEX exercises_category_idx ON exercises (category);
+CREATE INDEX exercises_difficulty_idx ON exercises (difficulty);
+CREATE INDEX exercises_tags_idx ON exercises USING GIN (tags);
+CREATE INDEX exercises_status_idx ON exercises (status);
+CREATE INDEX exercises_created_at_idx ON exercises (created_at);
+CREATE INDEX exercises_content_hash_idx ON exercises (content_hash);
+CREATE INDEX exercises_search_idx ON exercises USING GIN (search);
+
+CREATE TABLE user_progress (
+    id               CHAR(24) PRIMARY KEY,
+    user_id          CHAR(24) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    exercise_id      CHAR(24) NOT NULL REFERENCES exercises (id) ON DELETE CASCADE,
+    attempt_count    INTEGER NOT NULL DEFAULT 0,
+    correct_count    INTEGER NOT NULL DEFAULT 0,
+    total_time_taken INTEGER NOT NULL DEFAULT 0,
+    recent_attempts  JSONB NOT NULL DEFAULT '[]',
+    mastery_level    DOUBLE PRECISION NOT NULL DEFAULT 0,
+    last_attempted   TIMESTAMPTZ NOT NULL,
+    UNIQUE (user_id, exercise_id)
+);
+
+CREATE TABLE learning_paths (
+    id                    CHAR(24) PRIMARY KEY,
+    title                 TEXT NOT NULL,
+    description           TEXT NOT NULL DEFAULT '',
+    difficulty            TEXT NOT NULL,
+    categories            TEXT[],
+    status                TEXT NOT NULL DEFAULT '',
+    review                JSONB NOT NULL DEFAULT '{}',
+    revision              INTEGER NOT NULL DEFAULT 0,
+    prerequisite_path_ids CHAR(24)[],
+    required_skills       TEXT[],
+    created_at            TIMESTAMPTZ NOT NULL,
+    updated_at            TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX learning_paths_difficulty_idx ON learning_paths (difficulty);
+CREATE INDEX learning_paths_categories_idx ON learning_paths USING GIN (categories);
+CREATE INDEX learning_paths_prerequisites_idx ON learning_paths USING GIN (prerequisite_path_ids);
+CREATE INDEX learning_paths_status_idx ON learning_paths (status);
+
+CREATE TABLE path_stages (
+    path_id       CHAR(24) NOT NULL REFERENCES learning_paths (id) ON DELETE CASCADE,
+    stage_number  INTEGER NOT NULL,
+    id            CHAR(24) NOT NULL,
+    title         TEXT NOT NULL,
+    description   TEXT NOT NULL DEFAULT '',
+    min_accuracy  DOUBLE PRECISION NOT NULL DEFAULT 0,
+    min_exercises INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (path_id, stage_number)
+);
+
+-- Exercises cannot be deleted while a stage uses them; remove them from the
+-- stages first
+CREATE TABLE stage_exercises (
+    path_id      CHAR(24) NOT NULL,
+    stage_number INTEGER NOT NULL,
+    position     INTEGER NOT NULL,
+    exercise_id  CHAR(24) NOT NULL REFERENCES exercises (id) ON DELETE RESTRICT,
+    PRIMARY KEY (path_id, stage_number, position),
+    FOREIGN KEY (path_id, stage_number) REFERENCES path_stages (path_id, stage_number) ON DELETE CASCADE
+);
+
+CREATE INDEX stage_exercises_exercise_id_idx ON stage_exercises (exercise_id);
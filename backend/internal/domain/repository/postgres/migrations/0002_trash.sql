@@ -0,0 +1,15 @@
+-- Soft deletion of exercises, learning paths and users
+
+ALTER TABLE exercises ADD COLUMN deleted_by CHAR(24);
+
+ALTER TABLE learning_paths
+    ADD COLUMN deleted_at TIMESTAMPTZ,
+    ADD COLUMN deleted_by CHAR(24);
+
+ALTER TABLE users
+    ADD COLUMN deleted_at TIMESTAMPTZ,
+    ADD COLUMN deleted_by CHAR(24);
+
+CREATE INDEX exercises_deleted_at_idx ON exercises (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX learning_paths_deleted_at_idx ON learning_paths (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX users_deleted_at_idx ON users (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,17 @@
+-- Attempts, so analytics can join them with the exercises they answered.
+-- There are no foreign keys: attempts outlive their users, whose attempts are
+-- anonymized, and the exercises purged from the trash.
+
+CREATE TABLE attempts (
+    id                CHAR(24) PRIMARY KEY,
+    user_id           CHAR(24) NOT NULL,
+    exercise_id       CHAR(24) NOT NULL,
+    timestamp         TIMESTAMPTZ NOT NULL,
+    user_answer       TEXT NOT NULL DEFAULT '',
+    is_correct        BOOLEAN NOT NULL,
+    time_taken        INTEGER NOT NULL DEFAULT 0,
+    exercise_revision INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX attempts_user_id_timestamp_idx ON attempts (user_id, timestamp);
+CREATE INDEX attempts_user_id_exercise_id_idx ON attempts (user_id, exercise_id, timestamp DESC);
@@ -0,0 +1,13 @@
+-- Emails, usernames and external keys only have to be unique among rows that
+-- are not in the trash, so they can be reused while a row waits to be purged
+
+ALTER TABLE users
+    DROP CONSTRAINT users_email_key,
+    DROP CONSTRAINT users_username_key;
+
+CREATE UNIQUE INDEX users_email_key ON users (email) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX users_username_key ON users (username) WHERE deleted_at IS NULL;
+
+ALTER TABLE exercises DROP CONSTRAINT exercises_external_key_key;
+
+CREATE UNIQUE INDEX exercises_external_key_key ON exercises (external_key) WHERE deleted_at IS NULL;
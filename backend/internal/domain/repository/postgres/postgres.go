@@ -0,0 +1,285 @@
+// Package postgres implements the user, session, exercise, progress, attempt
+// and learning path repositories on PostgreSQL. Records keep their Mongo ObjectIDs,
+// stored as hex, so IDs and list orders match the Mongo repositories.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrationLock is the advisory lock held while migrating, so instances
+// starting together do not apply the same migration twice
+const migrationLock = 4207
+
+// Migrate applies the SQL migrations that have not been applied yet, in file
+// name order and each in its own transaction, and returns the versions it applied
+func Migrate(ctx context.Context, pool *pgxpool.Pool) ([]string, error) {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLock); err != nil {
+		return nil, err
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLock)
+
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    TEXT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := pending(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []string
+	for _, version := range versions {
+		script, err := migrations.ReadFile("migrations/" + version + ".sql")
+		if err != nil {
+			return applied, err
+		}
+
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return applied, err
+		}
+		if _, err := tx.Exec(ctx, string(script)); err != nil {
+			tx.Rollback(ctx)
+			return applied, fmt.Errorf("migration %s failed: %w", version, err)
+		}
+		if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", version); err != nil {
+			tx.Rollback(ctx)
+			return applied, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return applied, err
+		}
+		applied = append(applied, version)
+	}
+	return applied, nil
+}
+
+// Pending returns the versions of the SQL migrations that have not been applied,
+// in the order Migrate applies them
+func Pending(ctx context.Context, pool *pgxpool.Pool) ([]string, error) {
+	return pending(ctx, pool)
+}
+
+func pending(ctx context.Context, q querier) ([]string, error) {
+	files, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	// Before the first migration there is no schema_migrations table
+	var exists bool
+	if err := q.QueryRow(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, file := range files {
+		version := strings.TrimSuffix(strings.TrimPrefix(file, "migrations/"), ".sql")
+
+		done := false
+		if exists {
+			if err := q.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", version).Scan(&done); err != nil {
+				return nil, err
+			}
+		}
+		if !done {
+			versions = append(versions, version)
+		}
+	}
+	return versions, nil
+}
+
+// submittedAt is the submission time in a review column, for ordering review queues
+const submittedAt = "(review->>'submitted_at')::timestamptz"
+
+// querier is a pool or a transaction
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// anonymizeReviews removes a deleted user from the review records of a table
+func anonymizeReviews(ctx context.Context, pool *pgxpool.Pool, table string, userID primitive.ObjectID) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, "SELECT id, review FROM "+table+" WHERE strpos(review::text, $1) > 0 FOR UPDATE", userID.Hex())
+	if err != nil {
+		return err
+	}
+	type record struct {
+		id     string
+		review model.Review
+	}
+	records, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (record, error) {
+		var r record
+		err := row.Scan(&r.id, &r.review)
+		return r, err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		r.review.RemoveUser(userID)
+		if _, err := tx.Exec(ctx, "UPDATE "+table+" SET review = $2 WHERE id = $1", r.id, r.review); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// translateError maps constraint violations to the repository errors
+func translateError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return repository.ErrDuplicateKey
+	}
+	return err
+}
+
+// idScanner scans a hex ID column into an ObjectID
+type idScanner struct {
+	dst *primitive.ObjectID
+}
+
+func (s idScanner) Scan(src interface{}) error {
+	text, ok := src.(string)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into an ID", src)
+	}
+	id, err := primitive.ObjectIDFromHex(strings.TrimSpace(text))
+	if err != nil {
+		return err
+	}
+	*s.dst = id
+	return nil
+}
+
+// scanID returns a scan destination for a hex ID column
+func scanID(dst *primitive.ObjectID) sql.Scanner {
+	return idScanner{dst: dst}
+}
+
+// hexIDs converts IDs to their column values
+func hexIDs(ids []primitive.ObjectID) []string {
+	if ids == nil {
+		return nil
+	}
+	hexes := make([]string, len(ids))
+	for i, id := range ids {
+		hexes[i] = id.Hex()
+	}
+	return hexes
+}
+
+// objectIDs converts ID column values back to IDs
+func objectIDs(hexes []string) ([]primitive.ObjectID, error) {
+	if hexes == nil {
+		return nil, nil
+	}
+	ids := make([]primitive.ObjectID, len(hexes))
+	for i, hex := range hexes {
+		id, err := primitive.ObjectIDFromHex(strings.TrimSpace(hex))
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// conditions builds the WHERE clause of a query and its arguments
+type conditions struct {
+	clauses []string
+	args    []interface{}
+}
+
+// add adds a clause; each ? in it is bound to the next argument
+func (c *conditions) add(clause string, args ...interface{}) {
+	for _, arg := range args {
+		c.args = append(c.args, arg)
+		clause = strings.Replace(clause, "?", "$"+strconv.Itoa(len(c.args)), 1)
+	}
+	c.clauses = append(c.clauses, clause)
+}
+
+// bind adds an argument without a clause and returns its placeholder
+func (c *conditions) bind(arg interface{}) string {
+	c.args = append(c.args, arg)
+	return "$" + strconv.Itoa(len(c.args))
+}
+
+func (c *conditions) where() string {
+	if len(c.clauses) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(c.clauses, " AND ")
+}
+
+// page returns the ORDER BY, LIMIT and OFFSET of a page ordered by id, the way
+// pagination.Params.Apply orders a Mongo find, adding the cursor condition
+func (c *conditions) page(page pagination.Params) string {
+	return c.pageSorted(page, "", nil, false)
+}
+
+// pageSorted is page for lists ordered by a column, with id breaking ties.
+// after is the cursor's sort value.
+func (c *conditions) pageSorted(page pagination.Params, column string, after interface{}, descending bool) string {
+	direction, compare := "ASC", ">"
+	if descending {
+		direction, compare = "DESC", "<"
+	}
+
+	order := " ORDER BY id " + direction
+	if column != "" {
+		order = " ORDER BY " + column + " " + direction + ", id " + direction
+	}
+
+	if !page.UseCursor {
+		return order + " LIMIT " + strconv.Itoa(page.Limit) + " OFFSET " + strconv.Itoa(page.Offset)
+	}
+	if page.After != nil {
+		if column == "" {
+			c.add("id "+compare+" ?", page.After.ID.Hex())
+		} else {
+			c.add("("+column+", id) "+compare+" (?, ?)", after, page.After.ID.Hex())
+		}
+	}
+	return order + " LIMIT " + strconv.Itoa(page.Limit+1)
+}
@@ -0,0 +1,72 @@
+package postgres_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository/memory"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository/postgres"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository/repositorytest"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestRepositories runs the shared suite against the database at
+// POSTGRES_TEST_URL, each test in a schema of its own that is dropped afterwards
+func TestRepositories(t *testing.T) {
+	url := os.Getenv("POSTGRES_TEST_URL")
+	if url == "" {
+		t.Skip("POSTGRES_TEST_URL is not set")
+	}
+
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Repositories {
+		pool := openSchema(t, url)
+		revisions := memory.NewRevisionRepository()
+		return repositorytest.Repositories{
+			Exercises:     postgres.NewExerciseRepository(pool, revisions),
+			Attempts:      postgres.NewAttemptRepository(pool),
+			Users:         postgres.NewUserRepository(pool),
+			Sessions:      postgres.NewSessionRepository(pool),
+			Progress:      postgres.NewProgressRepository(pool),
+			LearningPaths: postgres.NewLearningPathRepository(pool, revisions),
+			Revisions:     revisions,
+		}
+	})
+}
+
+// openSchema creates a migrated schema and returns a pool that uses it
+func openSchema(t *testing.T, url string) *pgxpool.Pool {
+	t.Helper()
+	ctx := context.Background()
+
+	admin, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := "test_" + primitive.NewObjectID().Hex()
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		admin.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE")
+		admin.Close()
+	})
+
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	if _, err := postgres.Migrate(ctx, pool); err != nil {
+		t.Fatal(err)
+	}
+	return pool
+}
@@ -0,0 +1,205 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const progressColumns = `id, user_id, exercise_id, attempt_count, correct_count, total_time_taken,
+	recent_attempts, mastery_level, last_attempted`
+
+type ProgressRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewProgressRepository(pool *pgxpool.Pool) *ProgressRepository {
+	return &ProgressRepository{pool: pool}
+}
+
+var _ repository.ProgressRepository = (*ProgressRepository)(nil)
+
+func (r *ProgressRepository) Create(ctx context.Context, progress *model.UserProgress) error {
+	progress.ID = primitive.NewObjectID()
+	progress.LastAttempted = time.Now()
+
+	_, err := r.pool.Exec(ctx, `INSERT INTO user_progress (`+progressColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		progressArgs(progress)...)
+	return translateError(err)
+}
+
+func (r *ProgressRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*model.UserProgress, error) {
+	return r.getOne(ctx, r.pool, "id = $1", id.Hex())
+}
+
+func (r *ProgressRepository) GetByUserID(ctx context.Context, userID primitive.ObjectID) ([]*model.UserProgress, error) {
+	return r.list(ctx, "SELECT "+progressColumns+" FROM user_progress WHERE user_id = $1 ORDER BY id", userID.Hex())
+}
+
+// ListByUserID returns a page of a user's progress records
+func (r *ProgressRepository) ListByUserID(ctx context.Context, userID primitive.ObjectID, page pagination.Params) ([]*model.UserProgress, error) {
+	var c conditions
+	c.add("user_id = ?", userID.Hex())
+	order := c.page(page)
+
+	return r.list(ctx, "SELECT "+progressColumns+" FROM user_progress WHERE "+c.where()+order, c.args...)
+}
+
+func (r *ProgressRepository) CountByUserID(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	var count int64
+	err := r.pool.QueryRow(ctx, "SELECT count(*) FROM user_progress WHERE user_id = $1", userID.Hex()).Scan(&count)
+	return count, err
+}
+
+func (r *ProgressRepository) GetByUserAndExercise(ctx context.Context, userID, exerciseID primitive.ObjectID) (*model.UserProgress, error) {
+	return r.getOne(ctx, r.pool, "user_id = $1 AND exercise_id = $2", userID.Hex(), exerciseID.Hex())
+}
+
+func (r *ProgressRepository) GetByUserAndExercises(ctx context.Context, userID primitive.ObjectID, exerciseIDs []primitive.ObjectID) ([]*model.UserProgress, error) {
+	return r.list(ctx, "SELECT "+progressColumns+" FROM user_progress WHERE user_id = $1 AND exercise_id = ANY($2) ORDER BY id",
+		userID.Hex(), hexIDs(exerciseIDs))
+}
+
+func (r *ProgressRepository) Update(ctx context.Context, progress *model.UserProgress) error {
+	progress.LastAttempted = time.Now()
+
+	_, err := r.pool.Exec(ctx, `UPDATE user_progress SET user_id = $2, exercise_id = $3, attempt_count = $4,
+		correct_count = $5, total_time_taken = $6, recent_attempts = $7, mastery_level = $8, last_attempted = $9
+		WHERE id = $1`, progressArgs(progress)...)
+	return translateError(err)
+}
+
+// RecordAttempt folds an attempt into the user's progress summary for the exercise,
+// creating the summary if needed. The summary is locked while it is updated so
+// concurrent attempts never lose updates.
+func (r *ProgressRepository) RecordAttempt(ctx context.Context, attempt model.Attempt) (*model.UserProgress, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `INSERT INTO user_progress (id, user_id, exercise_id, last_attempted) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, exercise_id) DO NOTHING`,
+		primitive.NewObjectID().Hex(), attempt.UserID.Hex(), attempt.ExerciseID.Hex(), attempt.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	progress, err := r.getOne(ctx, tx, "user_id = $1 AND exercise_id = $2 FOR UPDATE", attempt.UserID.Hex(), attempt.ExerciseID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	progress.AttemptCount++
+	if attempt.IsCorrect {
+		progress.CorrectCount++
+	}
+	progress.TotalTimeTaken += attempt.TimeTaken
+	progress.RecentAttempts = append(progress.RecentAttempts, attempt)
+	if len(progress.RecentAttempts) > model.RecentAttemptLimit {
+		progress.RecentAttempts = progress.RecentAttempts[len(progress.RecentAttempts)-model.RecentAttemptLimit:]
+	}
+	progress.LastAttempted = attempt.Timestamp
+	progress.MasteryLevel = float64(progress.CorrectCount) / float64(progress.AttemptCount)
+
+	_, err = tx.Exec(ctx, `UPDATE user_progress SET attempt_count = $2, correct_count = $3, total_time_taken = $4,
+		recent_attempts = $5, mastery_level = $6, last_attempted = $7 WHERE id = $1`,
+		progress.ID.Hex(), progress.AttemptCount, progress.CorrectCount, progress.TotalTimeTaken,
+		progress.RecentAttempts, progress.MasteryLevel, progress.LastAttempted)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+func (r *ProgressRepository) UpdateMasteryLevel(ctx context.Context, progressID primitive.ObjectID, level float64) error {
+	_, err := r.pool.Exec(ctx, "UPDATE user_progress SET mastery_level = $2 WHERE id = $1", progressID.Hex(), level)
+	return err
+}
+
+// ReplaceTotals rewrites the counts, total time, mastery level and last attempt
+// of the user's summary for the exercise, provided it still has expectedAttempts
+// attempts. A missing summary is created when expectedAttempts is zero.
+func (r *ProgressRepository) ReplaceTotals(ctx context.Context, progress *model.UserProgress, expectedAttempts int) (bool, error) {
+	args := []interface{}{progress.UserID.Hex(), progress.ExerciseID.Hex(), expectedAttempts, progress.AttemptCount,
+		progress.CorrectCount, progress.TotalTimeTaken, progress.MasteryLevel, progress.LastAttempted}
+
+	query := `UPDATE user_progress SET attempt_count = $4, correct_count = $5, total_time_taken = $6,
+		mastery_level = $7, last_attempted = $8 WHERE user_id = $1 AND exercise_id = $2 AND attempt_count = $3`
+	if expectedAttempts == 0 {
+		query = `INSERT INTO user_progress (id, user_id, exercise_id, attempt_count, correct_count, total_time_taken,
+			mastery_level, last_attempted) VALUES ($9, $1, $2, $4, $5, $6, $7, $8)
+			ON CONFLICT (user_id, exercise_id) DO UPDATE SET attempt_count = $4, correct_count = $5,
+			total_time_taken = $6, mastery_level = $7, last_attempted = $8 WHERE user_progress.attempt_count = $3`
+		args = append(args, primitive.NewObjectID().Hex())
+	}
+
+	tag, err := r.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return false, translateError(err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *ProgressRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.pool.Exec(ctx, "DELETE FROM user_progress WHERE id = $1", id.Hex())
+	return err
+}
+
+// DeleteByUserID removes all of a user's progress summaries
+func (r *ProgressRepository) DeleteByUserID(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	tag, err := r.pool.Exec(ctx, "DELETE FROM user_progress WHERE user_id = $1", userID.Hex())
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *ProgressRepository) getOne(ctx context.Context, q querier, condition string, args ...interface{}) (*model.UserProgress, error) {
+	progress, err := scanProgress(q.QueryRow(ctx, "SELECT "+progressColumns+" FROM user_progress WHERE "+condition, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, repository.ErrProgressNotFound
+	}
+	return progress, err
+}
+
+func (r *ProgressRepository) list(ctx context.Context, query string, args ...interface{}) ([]*model.UserProgress, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.UserProgress, error) { return scanProgress(row) })
+}
+
+// progressArgs returns the column values of a progress record in progressColumns order
+func progressArgs(progress *model.UserProgress) []interface{} {
+	recent := progress.RecentAttempts
+	if recent == nil {
+		recent = []model.Attempt{}
+	}
+	return []interface{}{
+		progress.ID.Hex(), progress.UserID.Hex(), progress.ExerciseID.Hex(), progress.AttemptCount, progress.CorrectCount,
+		progress.TotalTimeTaken, recent, progress.MasteryLevel, progress.LastAttempted,
+	}
+}
+
+func scanProgress(row pgx.Row) (*model.UserProgress, error) {
+	var progress model.UserProgress
+	err := row.Scan(scanID(&progress.ID), scanID(&progress.UserID), scanID(&progress.ExerciseID), &progress.AttemptCount,
+		&progress.CorrectCount, &progress.TotalTimeTaken, &progress.RecentAttempts, &progress.MasteryLevel, &progress.LastAttempted)
+	if err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
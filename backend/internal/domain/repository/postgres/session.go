@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const sessionColumns = "id, user_id, role, session_token, created_at, expires_at, ip_address, device_info"
+
+type SessionRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewSessionRepository(pool *pgxpool.Pool) *SessionRepository {
+	return &SessionRepository{pool: pool}
+}
+
+var _ repository.SessionRepository = (*SessionRepository)(nil)
+
+func (r *SessionRepository) Create(ctx context.Context, session *model.UserSession) error {
+	session.ID = primitive.NewObjectID()
+	session.CreatedAt = time.Now()
+
+	_, err := r.pool.Exec(ctx, `INSERT INTO user_sessions (`+sessionColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		session.ID.Hex(), session.UserID.Hex(), session.Role, session.SessionToken,
+		session.CreatedAt, session.ExpiresAt, session.IPAddress, session.DeviceInfo)
+	return translateError(err)
+}
+
+func (r *SessionRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*model.UserSession, error) {
+	return r.getOne(ctx, "id = $1", id.Hex())
+}
+
+func (r *SessionRepository) GetByToken(ctx context.Context, token string) (*model.UserSession, error) {
+	return r.getOne(ctx, "session_token = $1", token)
+}
+
+func (r *SessionRepository) GetByUserID(ctx context.Context, userID primitive.ObjectID) ([]*model.UserSession, error) {
+	return r.list(ctx, "SELECT "+sessionColumns+" FROM user_sessions WHERE user_id = $1 ORDER BY id", userID.Hex())
+}
+
+// ListActiveByUserID returns a page of a user's sessions that have not expired
+func (r *SessionRepository) ListActiveByUserID(ctx context.Context, userID primitive.ObjectID, page pagination.Params) ([]*model.UserSession, error) {
+	var c conditions
+	c.add("user_id = ?", userID.Hex())
+	c.add("expires_at > ?", time.Now())
+	order := c.page(page)
+
+	return r.list(ctx, "SELECT "+sessionColumns+" FROM user_sessions WHERE "+c.where()+order, c.args...)
+}
+
+func (r *SessionRepository) CountActiveByUserID(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	var count int64
+	err := r.pool.QueryRow(ctx, "SELECT count(*) FROM user_sessions WHERE user_id = $1 AND expires_at > $2",
+		userID.Hex(), time.Now()).Scan(&count)
+	return count, err
+}
+
+func (r *SessionRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.pool.Exec(ctx, "DELETE FROM user_sessions WHERE id = $1", id.Hex())
+	return err
+}
+
+func (r *SessionRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	tag, err := r.pool.Exec(ctx, "DELETE FROM user_sessions WHERE expires_at < $1", time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *SessionRepository) DeleteAllForUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	tag, err := r.pool.Exec(ctx, "DELETE FROM user_sessions WHERE user_id = $1", userID.Hex())
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *SessionRepository) getOne(ctx context.Context, condition string, arg interface{}) (*model.UserSession, error) {
+	session, err := scanSession(r.pool.QueryRow(ctx, "SELECT "+sessionColumns+" FROM user_sessions WHERE "+condition, arg))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, repository.ErrSessionNotFound
+	}
+	return session, err
+}
+
+func (r *SessionRepository) list(ctx context.Context, query string, args ...interface{}) ([]*model.UserSession, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.UserSession, error) { return scanSession(row) })
+}
+
+func scanSession(row pgx.Row) (*model.UserSession, error) {
+	var session model.UserSession
+	err := row.Scan(scanID(&session.ID), scanID(&session.UserID), &session.Role, &session.SessionToken,
+		&session.CreatedAt, &session.ExpiresAt, &session.IPAddress, &session.DeviceInfo)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
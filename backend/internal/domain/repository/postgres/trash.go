@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// softDelete moves a row to the trash, returning notFound when there is no such
+// row or it is already in the trash
+func softDelete(ctx context.Context, pool *pgxpool.Pool, table string, id, deletedBy primitive.ObjectID, notFound error) error {
+	tag, err := pool.Exec(ctx, "UPDATE "+table+" SET deleted_at = $2, deleted_by = $3 WHERE id = $1 AND deleted_at IS NULL",
+		id.Hex(), time.Now(), deletedBy.Hex())
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// restore takes a row out of the trash, returning notFound when it is not in
+// the trash and repository.ErrDuplicateKey when a row outside the trash has
+// taken one of its unique values
+func restore(ctx context.Context, pool *pgxpool.Pool, table string, id primitive.ObjectID, notFound error) error {
+	tag, err := pool.Exec(ctx, "UPDATE "+table+" SET deleted_at = NULL, deleted_by = NULL WHERE id = $1 AND deleted_at IS NOT NULL",
+		id.Hex())
+	if err != nil {
+		return translateError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// countDeleted counts the rows of a table in the trash
+func countDeleted(ctx context.Context, pool *pgxpool.Pool, table string) (int64, error) {
+	var count int64
+	err := pool.QueryRow(ctx, "SELECT count(*) FROM "+table+" WHERE deleted_at IS NOT NULL").Scan(&count)
+	return count, err
+}
+
+// optionalHex is the column value of an optional ID
+func optionalHex(id *primitive.ObjectID) *string {
+	if id == nil {
+		return nil
+	}
+	hex := id.Hex()
+	return &hex
+}
+
+// optionalIDScanner scans a nullable hex ID column into an optional ObjectID
+type optionalIDScanner struct {
+	dst **primitive.ObjectID
+}
+
+func (s optionalIDScanner) Scan(src interface{}) error {
+	if src == nil {
+		*s.dst = nil
+		return nil
+	}
+	var id primitive.ObjectID
+	if err := (idScanner{dst: &id}).Scan(src); err != nil {
+		return err
+	}
+	*s.dst = &id
+	return nil
+}
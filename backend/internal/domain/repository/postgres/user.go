@@ -0,0 +1,214 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const userColumns = `id, email, password_hash, username, first_name, last_name, timezone, role,
+	created_at, updated_at, last_login, preferences, statistics, statistics_revision, deletion_scheduled_at,
+	deleted_at, deleted_by`
+
+type UserRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewUserRepository(pool *pgxpool.Pool) *UserRepository {
+	return &UserRepository{pool: pool}
+}
+
+var _ repository.UserRepository = (*UserRepository)(nil)
+
+func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
+	user.ID = primitive.NewObjectID()
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = time.Now()
+
+	_, err := r.pool.Exec(ctx, `INSERT INTO users (`+userColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)`,
+		user.ID.Hex(), user.Email, user.PasswordHash, user.Username, user.FirstName, user.LastName, user.Timezone, user.Role,
+		user.CreatedAt, user.UpdatedAt, user.LastLogin, user.Preferences, user.Statistics, user.Statistics.Revision, user.DeletionScheduledAt,
+		user.DeletedAt, optionalHex(user.DeletedBy))
+	return translateError(err)
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*model.User, error) {
+	return r.getOne(ctx, "id = $1", id.Hex())
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	return r.getOne(ctx, "email = $1", email)
+}
+
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	return r.getOne(ctx, "username = $1", username)
+}
+
+// Update saves a user's account details. Statistics, the last login and the
+// deletion schedule have their own updates and are left as stored.
+func (r *UserRepository) Update(ctx context.Context, user *model.User) error {
+	user.UpdatedAt = time.Now()
+
+	_, err := r.pool.Exec(ctx, `UPDATE users SET email = $2, password_hash = $3, username = $4, first_name = $5,
+		last_name = $6, timezone = $7, role = $8, updated_at = $9, preferences = $10
+		WHERE id = $1`,
+		user.ID.Hex(), user.Email, user.PasswordHash, user.Username, user.FirstName,
+		user.LastName, user.Timezone, user.Role, user.UpdatedAt, user.Preferences)
+	return translateError(err)
+}
+
+// Delete removes a user; their sessions and progress are removed with them
+func (r *UserRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.pool.Exec(ctx, "DELETE FROM users WHERE id = $1", id.Hex())
+	return err
+}
+
+// SoftDelete moves a user to the trash
+func (r *UserRepository) SoftDelete(ctx context.Context, id, deletedBy primitive.ObjectID) error {
+	return softDelete(ctx, r.pool, "users", id, deletedBy, repository.ErrUserNotFound)
+}
+
+// Restore takes a user out of the trash
+func (r *UserRepository) Restore(ctx context.Context, id primitive.ObjectID) error {
+	return restore(ctx, r.pool, "users", id, repository.ErrUserNotFound)
+}
+
+// GetDeleted lists the users moved to the trash before the given time
+func (r *UserRepository) GetDeleted(ctx context.Context, before time.Time, page pagination.Params) ([]*model.User, error) {
+	var c conditions
+	c.add("deleted_at <= ?", before)
+	order := c.page(page)
+
+	rows, err := r.pool.Query(ctx, "SELECT "+userColumns+" FROM users WHERE "+c.where()+order, c.args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.User, error) { return scanUser(row) })
+}
+
+// CountDeleted counts the users in the trash
+func (r *UserRepository) CountDeleted(ctx context.Context) (int64, error) {
+	return countDeleted(ctx, r.pool, "users")
+}
+
+func (r *UserRepository) UpdateLastLogin(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.pool.Exec(ctx, "UPDATE users SET last_login = $2 WHERE id = $1", id.Hex(), time.Now())
+	return err
+}
+
+// SetDeletionSchedule schedules the user's account for deletion at the given
+// time, or cancels a scheduled deletion when at is nil
+func (r *UserRepository) SetDeletionSchedule(ctx context.Context, id primitive.ObjectID, at *time.Time) error {
+	tag, err := r.pool.Exec(ctx, "UPDATE users SET deletion_scheduled_at = $2 WHERE id = $1", id.Hex(), at)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrUserNotFound
+	}
+	return nil
+}
+
+// GetDueForDeletion returns users whose scheduled deletion time is before the given time
+func (r *UserRepository) GetDueForDeletion(ctx context.Context, before time.Time, limit int) ([]*model.User, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+userColumns+` FROM users
+		WHERE deletion_scheduled_at <= $1 ORDER BY deletion_scheduled_at LIMIT $2`, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.User, error) { return scanUser(row) })
+}
+
+func (r *UserRepository) UpdateStatistics(ctx context.Context, id primitive.ObjectID, stats model.UserStatistics) error {
+	_, err := r.pool.Exec(ctx, "UPDATE users SET statistics = $2, statistics_revision = $3 WHERE id = $1",
+		id.Hex(), stats, stats.Revision)
+	return err
+}
+
+// SwapStatistics replaces the user's statistics only if they are still at the
+// expected revision, reporting whether the swap happened
+func (r *UserRepository) SwapStatistics(ctx context.Context, id primitive.ObjectID, expectedRevision int64, stats model.UserStatistics) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `UPDATE users SET statistics = $3, statistics_revision = $4
+		WHERE id = $1 AND statistics_revision = $2`, id.Hex(), expectedRevision, stats, stats.Revision)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ApplyAttemptStatistics counts an attempt towards the user's statistics. The
+// user is locked while the statistics are updated so concurrent attempts never
+// lose updates.
+func (r *UserRepository) ApplyAttemptStatistics(ctx context.Context, id primitive.ObjectID, attempt model.StatisticsAttempt) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var stats model.UserStatistics
+	err = tx.QueryRow(ctx, "SELECT statistics, statistics_revision FROM users WHERE id = $1 FOR UPDATE", id.Hex()).
+		Scan(&stats, &stats.Revision)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return repository.ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	stats.ApplyAttempt(attempt)
+	_, err = tx.Exec(ctx, "UPDATE users SET statistics = $2, statistics_revision = $3 WHERE id = $1",
+		id.Hex(), stats, stats.Revision)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ForEach calls fn for every user not in the trash, in ID order, stopping at the first error
+func (r *UserRepository) ForEach(ctx context.Context, fn func(*model.User) error) error {
+	rows, err := r.pool.Query(ctx, "SELECT "+userColumns+" FROM users WHERE deleted_at IS NULL ORDER BY id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *UserRepository) getOne(ctx context.Context, condition string, arg interface{}) (*model.User, error) {
+	user, err := scanUser(r.pool.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE deleted_at IS NULL AND "+condition, arg))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, repository.ErrUserNotFound
+	}
+	return user, err
+}
+
+func scanUser(row pgx.Row) (*model.User, error) {
+	var user model.User
+	err := row.Scan(scanID(&user.ID), &user.Email, &user.PasswordHash, &user.Username, &user.FirstName,
+		&user.LastName, &user.Timezone, &user.Role, &user.CreatedAt, &user.UpdatedAt, &user.LastLogin,
+		&user.Preferences, &user.Statistics, &user.Statistics.Revision, &user.DeletionScheduledAt,
+		&user.DeletedAt, optionalIDScanner{dst: &user.DeletedBy})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -16,11 +17,16 @@ type ProgressRepository interface {
 	Create(ctx context.Context, progress *model.UserProgress) error
 	GetByID(ctx context.Context, id primitive.ObjectID) (*model.UserProgress, error)
 	GetByUserID(ctx context.Context, userID primitive.ObjectID) ([]*model.UserProgress, error)
+	ListByUserID(ctx context.Context, userID primitive.ObjectID, page pagination.Params) ([]*model.UserProgress, error)
+	CountByUserID(ctx context.Context, userID primitive.ObjectID) (int64, error)
 	GetByUserAndExercise(ctx context.Context, userID, exerciseID primitive.ObjectID) (*model.UserProgress, error)
+	GetByUserAndExercises(ctx context.Context, userID primitive.ObjectID, exerciseIDs []primitive.ObjectID) ([]*model.UserProgress, error)
 	Update(ctx context.Context, progress *model.UserProgress) error
-	AddAttempt(ctx context.Context, progressID primitive.ObjectID, attempt model.Attempt) error
+	RecordAttempt(ctx context.Context, attempt model.Attempt) (*model.UserProgress, error)
 	UpdateMasteryLevel(ctx context.Context, progressID primitive.ObjectID, level float64) error
+	ReplaceTotals(ctx context.Context, progress *model.UserProgress, expectedAttempts int) (bool, error)
 	Delete(ctx context.Context, id primitive.ObjectID) error
+	DeleteByUserID(ctx context.Context, userID primitive.ObjectID) (int64, error)
 }
 
 type MongoProgressRepository struct {
@@ -28,28 +34,7 @@ type MongoProgressRepository struct {
 }
 
 func NewProgressRepository(db *mongo.Database) ProgressRepository {
-	collection := db.Collection("user_progress")
-
-	// Create indexes
-	indexes := []mongo.IndexModel{
-		{
-			Keys: bson.D{{Key: "user_id", Value: 1}},
-		},
-		{
-			Keys: bson.D{
-				{Key: "user_id", Value: 1},
-				{Key: "exercise_id", Value: 1},
-			},
-			Options: options.Index().SetUnique(true),
-		},
-	}
-
-	_, err := collection.Indexes().CreateMany(context.Background(), indexes)
-	if err != nil {
-		panic(err)
-	}
-
-	return &MongoProgressRepository{collection: collection}
+	return &MongoProgressRepository{collection: db.Collection("user_progress")}
 }
 
 func (r *MongoProgressRepository) Create(ctx context.Context, progress *model.UserProgress) error {
@@ -57,7 +42,7 @@ func (r *MongoProgressRepository) Create(ctx context.Context, progress *model.Us
 	progress.LastAttempted = time.Now()
 
 	_, err := r.collection.InsertOne(ctx, progress)
-	return err
+	return translateWriteError(err)
 }
 
 func (r *MongoProgressRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*model.UserProgress, error) {
@@ -65,7 +50,7 @@ func (r *MongoProgressRepository) GetByID(ctx context.Context, id primitive.Obje
 	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&progress)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("progress record not found")
+			return nil, ErrProgressNotFound
 		}
 		return nil, err
 	}
@@ -87,18 +72,56 @@ func (r *MongoProgressRepository) GetByUserID(ctx context.Context, userID primit
 	return progresses, nil
 }
 
+// ListByUserID returns a page of a user's progress records
+func (r *MongoProgressRepository) ListByUserID(ctx context.Context, userID primitive.ObjectID, page pagination.Params) ([]*model.UserProgress, error) {
+	findOptions := options.Find()
+	filter := page.Apply(bson.M{"user_id": userID}, findOptions)
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var progresses []*model.UserProgress
+	if err := cursor.All(ctx, &progresses); err != nil {
+		return nil, err
+	}
+
+	return progresses, nil
+}
+
+func (r *MongoProgressRepository) CountByUserID(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"user_id": userID})
+}
+
 func (r *MongoProgressRepository) GetByUserAndExercise(ctx context.Context, userID, exerciseID primitive.ObjectID) (*model.UserProgress, error) {
 	var progress model.UserProgress
 	err := r.collection.FindOne(ctx, bson.M{"user_id": userID, "exercise_id": exerciseID}).Decode(&progress)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("progress record not found")
+			return nil, ErrProgressNotFound
 		}
 		return nil, err
 	}
 	return &progress, nil
 }
 
+func (r *MongoProgressRepository) GetByUserAndExercises(ctx context.Context, userID primitive.ObjectID, exerciseIDs []primitive.ObjectID) ([]*model.UserProgress, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID, "exercise_id": bson.M{"$in": exerciseIDs}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var progresses []*model.UserProgress
+	if err := cursor.All(ctx, &progresses); err != nil {
+		return nil, err
+	}
+
+	return progresses, nil
+}
+
 func (r *MongoProgressRepository) Update(ctx context.Context, progress *model.UserProgress) error {
 	progress.LastAttempted = time.Now()
 
@@ -109,15 +132,46 @@ func (r *MongoProgressRepository) Update(ctx context.Context, progress *model.Us
 	return err
 }
 
-func (r *MongoProgressRepository) AddAttempt(ctx context.Context, progressID primitive.ObjectID, attempt model.Attempt) error {
-	filter := bson.M{"_id": progressID}
-	update := bson.M{
-		"$push": bson.M{"attempts": attempt},
-		"$set":  bson.M{"last_attempted": time.Now()},
+// RecordAttempt folds an attempt into the user's progress summary for the exercise,
+// creating the summary if needed. Counters, recent attempts and the mastery level
+// are computed by a single upsert so concurrent attempts never lose updates.
+func (r *MongoProgressRepository) RecordAttempt(ctx context.Context, attempt model.Attempt) (*model.UserProgress, error) {
+	correct := 0
+	if attempt.IsCorrect {
+		correct = 1
 	}
 
-	_, err := r.collection.UpdateOne(ctx, filter, update)
-	return err
+	filter := bson.M{"user_id": attempt.UserID, "exercise_id": attempt.ExerciseID}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"attempt_count":    bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$attempt_count", 0}}, 1}},
+			"correct_count":    bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$correct_count", 0}}, correct}},
+			"total_time_taken": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$total_time_taken", 0}}, attempt.TimeTaken}},
+			"recent_attempts": bson.M{"$slice": bson.A{
+				bson.M{"$concatArrays": bson.A{
+					bson.M{"$ifNull": bson.A{"$recent_attempts", bson.A{}}},
+					bson.A{bson.M{"$literal": attempt}},
+				}},
+				-model.RecentAttemptLimit,
+			}},
+			"last_attempted": attempt.Timestamp,
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"mastery_level": bson.M{"$divide": bson.A{"$correct_count", "$attempt_count"}},
+		}}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var progress model.UserProgress
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&progress)
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent attempt created the summary first; it now exists, so retry once
+		err = r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&progress)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &progress, nil
 }
 
 func (r *MongoProgressRepository) UpdateMasteryLevel(ctx context.Context, progressID primitive.ObjectID, level float64) error {
@@ -128,7 +182,45 @@ func (r *MongoProgressRepository) UpdateMasteryLevel(ctx context.Context, progre
 	return err
 }
 
+// ReplaceTotals rewrites the counts, total time, mastery level and last attempt
+// of the user's summary for the exercise, provided it still has expectedAttempts
+// attempts, so attempts folded in since it was read are not lost. A missing
+// summary is created when expectedAttempts is zero. Recent attempts are kept.
+// It reports whether the summary was written.
+func (r *MongoProgressRepository) ReplaceTotals(ctx context.Context, progress *model.UserProgress, expectedAttempts int) (bool, error) {
+	filter := bson.M{"user_id": progress.UserID, "exercise_id": progress.ExerciseID, "attempt_count": expectedAttempts}
+	update := bson.M{
+		"$set": bson.M{
+			"attempt_count":    progress.AttemptCount,
+			"correct_count":    progress.CorrectCount,
+			"total_time_taken": progress.TotalTimeTaken,
+			"mastery_level":    progress.MasteryLevel,
+			"last_attempted":   progress.LastAttempted,
+		},
+		"$setOnInsert": bson.M{"recent_attempts": bson.A{}},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(expectedAttempts == 0))
+	if mongo.IsDuplicateKeyError(err) {
+		// The summary was created, or had attempts folded in, since it was read
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0 || result.UpsertedCount > 0, nil
+}
+
 func (r *MongoProgressRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
 	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
 	return err
 }
+
+// DeleteByUserID removes all of a user's progress summaries
+func (r *MongoProgressRepository) DeleteByUserID(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
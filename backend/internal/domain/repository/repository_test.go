@@ -0,0 +1,46 @@
+package repository_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository/repositorytest"
+	"github.com/flutterninja9/mental-math-app/internal/migration"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TestRepositories runs the shared suite against the server at MONGO_TEST_URI,
+// each test in a migrated database of its own that is dropped afterwards
+func TestRepositories(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI is not set")
+	}
+
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Repositories {
+		db := client.Database("test_" + primitive.NewObjectID().Hex())
+		t.Cleanup(func() { db.Drop(context.Background()) })
+		if _, err := migration.NewMigrator(db).Up(context.Background(), false); err != nil {
+			t.Fatal(err)
+		}
+		return repositorytest.Repositories{
+			Exercises:     repository.NewExerciseRepository(db),
+			Attempts:      repository.NewAttemptRepository(db),
+			Users:         repository.NewUserRepository(db),
+			Sessions:      repository.NewSessionRepository(db),
+			Progress:      repository.NewProgressRepository(db),
+			LearningPaths: repository.NewLearningPathRepository(db),
+			Revisions:     repository.NewRevisionRepository(db),
+		}
+	})
+}
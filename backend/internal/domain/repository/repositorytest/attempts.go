@@ -0,0 +1,228 @@
+package repositorytest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RunAttempts tests the attempt repository, including the performance report
+// that joins attempts with the exercises they answered
+func RunAttempts(t *testing.T, open Open) {
+	t.Run("ListsByUser", func(t *testing.T) { testAttemptLists(t, open(t)) })
+	t.Run("AggregatesPerformance", func(t *testing.T) { testAggregatePerformance(t, open(t)) })
+	t.Run("Anonymizes", func(t *testing.T) { testAnonymizeAttempts(t, open(t)) })
+	t.Run("TotalsByExercise", func(t *testing.T) { testAttemptTotals(t, open(t)) })
+}
+
+// attemptWeek is a Monday
+var attemptWeek = time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+// attemptFixture is a user's attempts at an addition exercise, a trashed
+// multiplication exercise and an exercise that no longer exists
+type attemptFixture struct {
+	user           primitive.ObjectID
+	add, mul, gone primitive.ObjectID
+	from, to       time.Time
+}
+
+func newAttemptFixture(t *testing.T, repos Repositories) attemptFixture {
+	t.Helper()
+	ctx := context.Background()
+
+	add := newExercise(t, repos, "addition", "easy", "mental", "sums")
+	mul := newExercise(t, repos, "multiplication", "medium", "mental", "tables")
+	f := attemptFixture{
+		user: newID(),
+		add:  add.ID,
+		mul:  mul.ID,
+		gone: newID(),
+		from: attemptWeek,
+		to:   attemptWeek.AddDate(0, 0, 14),
+	}
+
+	attempts := []model.Attempt{
+		{UserID: f.user, ExerciseID: f.add, IsCorrect: true, TimeTaken: 2, Timestamp: attemptWeek},
+		{UserID: f.user, ExerciseID: f.add, IsCorrect: false, TimeTaken: 4, Timestamp: attemptWeek.Add(time.Hour), UserAnswer: "3"},
+		{UserID: f.user, ExerciseID: f.mul, IsCorrect: true, TimeTaken: 6, Timestamp: attemptWeek.AddDate(0, 0, 1)},
+		// Sunday evening in UTC but Monday morning in Kolkata
+		{UserID: f.user, ExerciseID: f.gone, IsCorrect: true, TimeTaken: 8, Timestamp: time.Date(2026, 3, 8, 20, 0, 0, 0, time.UTC)},
+		{UserID: f.user, ExerciseID: f.add, IsCorrect: true, TimeTaken: 10, Timestamp: attemptWeek.AddDate(0, 0, 8)},
+		// Outside the range, and another user's attempt
+		{UserID: f.user, ExerciseID: f.add, IsCorrect: false, TimeTaken: 1, Timestamp: attemptWeek.Add(-time.Minute)},
+		{UserID: newID(), ExerciseID: f.add, IsCorrect: false, TimeTaken: 1, Timestamp: attemptWeek},
+	}
+	for i := range attempts {
+		if err := repos.Attempts.Create(ctx, &attempts[i]); err != nil {
+			t.Fatalf("creating attempt: %v", err)
+		}
+	}
+
+	// Trashed exercises still describe the attempts made at them
+	if err := repos.Exercises.SoftDelete(ctx, f.mul, newID()); err != nil {
+		t.Fatalf("trashing exercise: %v", err)
+	}
+	return f
+}
+
+func testAttemptLists(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	f := newAttemptFixture(t, repos)
+
+	attempts, err := repos.Attempts.GetByUser(ctx, f.user, f.from, f.to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != 5 {
+		t.Fatalf("attempts in range = %d, want 5", len(attempts))
+	}
+	for i := 1; i < len(attempts); i++ {
+		if attempts[i].Timestamp.Before(attempts[i-1].Timestamp) {
+			t.Errorf("attempt %d is older than the one before it", i)
+		}
+	}
+
+	recent, err := repos.Attempts.GetByUserAndExercise(ctx, f.user, f.add, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 2 || recent[0].TimeTaken != 10 || recent[1].TimeTaken != 4 {
+		t.Errorf("recent attempts = %v, want the ones taking 10s and 4s", timesTaken(recent))
+	}
+}
+
+func testAttemptTotals(t *testing.T, repos Repositories) {
+	f := newAttemptFixture(t, repos)
+
+	totals, err := repos.Attempts.TotalsByExercise(context.Background(), f.user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[primitive.ObjectID]model.AttemptTotals{
+		f.add:  {ExerciseID: f.add, Attempts: 4, Correct: 2, TimeTaken: 17, LastAttempted: attemptWeek.AddDate(0, 0, 8)},
+		f.mul:  {ExerciseID: f.mul, Attempts: 1, Correct: 1, TimeTaken: 6, LastAttempted: attemptWeek.AddDate(0, 0, 1)},
+		f.gone: {ExerciseID: f.gone, Attempts: 1, Correct: 1, TimeTaken: 8, LastAttempted: time.Date(2026, 3, 8, 20, 0, 0, 0, time.UTC)},
+	}
+	if len(totals) != len(want) {
+		t.Fatalf("totals = %+v, want one per exercise answered", totals)
+	}
+	for i, got := range totals {
+		if i > 0 && totals[i-1].ExerciseID.Hex() >= got.ExerciseID.Hex() {
+			t.Errorf("totals are not ordered by exercise")
+		}
+		w := want[got.ExerciseID]
+		if got.Attempts != w.Attempts || got.Correct != w.Correct || got.TimeTaken != w.TimeTaken || !got.LastAttempted.Equal(w.LastAttempted) {
+			t.Errorf("totals for %s = %+v, want %+v", got.ExerciseID.Hex(), got, w)
+		}
+	}
+}
+
+func testAggregatePerformance(t *testing.T, repos Repositories) {
+	f := newAttemptFixture(t, repos)
+
+	report, err := repos.Attempts.AggregatePerformance(context.Background(), model.AnalyticsQuery{
+		UserID:      f.user,
+		From:        f.from,
+		To:          f.to,
+		Granularity: model.GranularityWeek,
+		Timezone:    "Asia/Kolkata",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary := report.Summary
+	if summary.Attempts != 5 || summary.Correct != 4 || summary.Accuracy != 80 {
+		t.Errorf("summary = %d attempts, %d correct, %v%%; want 5, 4, 80%%", summary.Attempts, summary.Correct, summary.Accuracy)
+	}
+	if summary.AverageTime != 6 || summary.MedianTime != 6 {
+		t.Errorf("average and median time = %v, %v; want 6, 6", summary.AverageTime, summary.MedianTime)
+	}
+	p := report.Percentiles
+	if p.P50 != 6 || p.P75 < p.P50 || p.P90 < p.P75 || p.P95 < p.P90 || p.P95 > 10 {
+		t.Errorf("percentiles = %+v", p)
+	}
+
+	// Weeks start on Monday in the report's timezone
+	kolkata, _ := time.LoadLocation("Asia/Kolkata")
+	wantSeries := []model.PerformanceBucket{
+		{Period: time.Date(2026, 3, 2, 0, 0, 0, 0, kolkata).UTC(), Attempts: 3, Correct: 2},
+		{Period: time.Date(2026, 3, 9, 0, 0, 0, 0, kolkata).UTC(), Attempts: 2, Correct: 2},
+	}
+	if len(report.Series) != len(wantSeries) {
+		t.Fatalf("series = %+v", report.Series)
+	}
+	for i, want := range wantSeries {
+		got := report.Series[i]
+		if !got.Period.Equal(want.Period) || got.Attempts != want.Attempts || got.Correct != want.Correct {
+			t.Errorf("series[%d] = %v %d/%d, want %v %d/%d", i, got.Period, got.Correct, got.Attempts, want.Period, want.Correct, want.Attempts)
+		}
+	}
+
+	checkBreakdowns(t, "category", report.ByCategory, []model.PerformanceBreakdown{
+		{Key: "addition", Attempts: 3, Correct: 2, MedianTime: 4},
+		{Key: "multiplication", Attempts: 1, Correct: 1, MedianTime: 6},
+		{Key: "unknown", Attempts: 1, Correct: 1, MedianTime: 8},
+	})
+	checkBreakdowns(t, "difficulty", report.ByDifficulty, []model.PerformanceBreakdown{
+		{Key: "easy", Attempts: 3, Correct: 2, MedianTime: 4},
+		{Key: "medium", Attempts: 1, Correct: 1, MedianTime: 6},
+		{Key: "unknown", Attempts: 1, Correct: 1, MedianTime: 8},
+	})
+	checkBreakdowns(t, "tag", report.ByTag, []model.PerformanceBreakdown{
+		{Key: "mental", Attempts: 4, Correct: 3},
+		{Key: "sums", Attempts: 3, Correct: 2, MedianTime: 4},
+		{Key: "tables", Attempts: 1, Correct: 1, MedianTime: 6},
+	})
+}
+
+func testAnonymizeAttempts(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	f := newAttemptFixture(t, repos)
+
+	anonymized, err := repos.Attempts.AnonymizeUser(ctx, f.user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if anonymized != 6 {
+		t.Errorf("anonymized = %d, want 6", anonymized)
+	}
+
+	attempts, err := repos.Attempts.GetByUser(ctx, f.user, f.from.AddDate(0, 0, -1), f.to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != 0 {
+		t.Errorf("attempts left for the user = %d", len(attempts))
+	}
+}
+
+// checkBreakdowns compares breakdowns in order. A zero MedianTime in want is
+// not checked, as medians of an even number of attempts differ between stores.
+func checkBreakdowns(t *testing.T, name string, got, want []model.PerformanceBreakdown) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("by %s = %+v, want %+v", name, got, want)
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		accuracy := float64(w.Correct) / float64(w.Attempts) * 100
+		if g.Key != w.Key || g.Attempts != w.Attempts || g.Correct != w.Correct || g.Accuracy != accuracy {
+			t.Errorf("by %s[%d] = %+v, want %+v", name, i, g, w)
+		}
+		if w.MedianTime != 0 && g.MedianTime != w.MedianTime {
+			t.Errorf("by %s[%d] median time = %v, want %v", name, i, g.MedianTime, w.MedianTime)
+		}
+	}
+}
+
+func timesTaken(attempts []*model.Attempt) []int {
+	var times []int
+	for _, attempt := range attempts {
+		times = append(times, attempt.TimeTaken)
+	}
+	return times
+}
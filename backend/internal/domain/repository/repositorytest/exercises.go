@@ -0,0 +1,188 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RunExercises tests writing exercises and listing them a page at a time
+func RunExercises(t *testing.T, open Open) {
+	t.Run("CreatesAndUpdates", func(t *testing.T) { testExerciseWrites(t, open(t)) })
+	t.Run("Deletes", func(t *testing.T) { testExerciseDelete(t, open(t)) })
+	t.Run("ListsVisible", func(t *testing.T) { testExerciseListings(t, open(t)) })
+	t.Run("Paginates", func(t *testing.T) { testExercisePages(t, open(t)) })
+}
+
+func testExerciseWrites(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	exercise := newExercise(t, repos, "addition", "easy", "sums")
+	if exercise.ID.IsZero() || exercise.Revision != 1 || exercise.ContentHash == "" {
+		t.Fatalf("created exercise = %+v, want an ID, revision 1 and a content hash", exercise)
+	}
+
+	stale := *exercise
+	exercise.Title = "Sums"
+	exercise.Content.Problem = "2 + 2 = ?"
+	if err := repos.Exercises.Update(ctx, exercise, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+	if exercise.Revision != 2 {
+		t.Errorf("revision after update = %d, want 2", exercise.Revision)
+	}
+
+	stored, err := repos.Exercises.GetByID(ctx, exercise.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Title != "Sums" || stored.Revision != 2 || stored.ContentHash == stale.ContentHash {
+		t.Errorf("stored exercise = %+v, want the update with a new content hash", stored)
+	}
+
+	stale.Title = "Stale"
+	if err := repos.Exercises.Update(ctx, &stale, model.RevisionMeta{}); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("stale update: %v, want ErrVersionConflict", err)
+	}
+	if stale.Revision != 1 {
+		t.Errorf("revision after a rejected update = %d, want it left at 1", stale.Revision)
+	}
+
+	if _, err := repos.Exercises.GetByID(ctx, newID()); !errors.Is(err, repository.ErrExerciseNotFound) {
+		t.Errorf("unknown id: %v, want ErrExerciseNotFound", err)
+	}
+}
+
+func testExerciseDelete(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	deleted := newExercise(t, repos, "addition", "easy")
+	trashed := newExercise(t, repos, "addition", "easy")
+
+	if err := repos.Exercises.Delete(ctx, deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.Exercises.GetByID(ctx, deleted.ID); !errors.Is(err, repository.ErrExerciseNotFound) {
+		t.Errorf("deleted exercise: %v, want ErrExerciseNotFound", err)
+	}
+
+	// Trashed exercises are hidden from GetByID but still describe old references
+	if err := repos.Exercises.SoftDelete(ctx, trashed.ID, newID()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.Exercises.GetByID(ctx, trashed.ID); !errors.Is(err, repository.ErrExerciseNotFound) {
+		t.Errorf("trashed exercise: %v, want ErrExerciseNotFound", err)
+	}
+	found, err := repos.Exercises.GetByIDs(ctx, []primitive.ObjectID{deleted.ID, trashed.ID})
+	if err != nil || len(found) != 1 || found[0].ID != trashed.ID {
+		t.Errorf("GetByIDs = %d exercises, %v, want only the trashed one", len(found), err)
+	}
+}
+
+func testExerciseListings(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	published := newExercise(t, repos, "addition", "easy", "sums")
+	draft := newExercise(t, repos, "addition", "easy", "sums")
+	draft.Status = model.StatusDraft
+	if err := repos.Exercises.Update(ctx, draft, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+	trashed := newExercise(t, repos, "addition", "easy", "sums")
+	if err := repos.Exercises.SoftDelete(ctx, trashed.ID, newID()); err != nil {
+		t.Fatal(err)
+	}
+	newExercise(t, repos, "multiplication", "hard", "tables")
+
+	page := pagination.Params{Limit: 10}
+	listings := []struct {
+		name string
+		list func(includeUnpublished bool) ([]*model.Exercise, error)
+	}{
+		{"category", func(all bool) ([]*model.Exercise, error) {
+			return repos.Exercises.GetByCategory(ctx, "addition", all, page)
+		}},
+		{"difficulty", func(all bool) ([]*model.Exercise, error) {
+			return repos.Exercises.GetByDifficulty(ctx, "easy", all, page)
+		}},
+		{"tags", func(all bool) ([]*model.Exercise, error) {
+			return repos.Exercises.GetByTags(ctx, []string{"sums"}, all, page)
+		}},
+	}
+	for _, listing := range listings {
+		public, err := listing.list(false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := exerciseIDs(public); len(ids) != 1 || ids[0] != published.ID {
+			t.Errorf("published by %s = %v, want only the published exercise", listing.name, ids)
+		}
+		all, err := listing.list(true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := exerciseIDs(all); len(ids) != 2 || ids[0] != published.ID || ids[1] != draft.ID {
+			t.Errorf("all by %s = %v, want the published exercise and the draft", listing.name, ids)
+		}
+	}
+
+	count, err := repos.Exercises.Count(ctx, model.ExerciseQuery{Category: "addition"})
+	if err != nil || count != 1 {
+		t.Errorf("count = %d, %v, want 1", count, err)
+	}
+}
+
+func testExercisePages(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	var created []primitive.ObjectID
+	for i := 0; i < 5; i++ {
+		created = append(created, newExercise(t, repos, "addition", "easy").ID)
+	}
+
+	offset, err := repos.Exercises.GetByCategory(ctx, "addition", false, pagination.Params{Limit: 2, Offset: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := exerciseIDs(offset); len(ids) != 2 || ids[0] != created[2] || ids[1] != created[3] {
+		t.Errorf("offset page = %v, want exercises 2 and 3", ids)
+	}
+
+	// Cursor pages fetch one extra exercise to tell whether there is another page
+	var walked []primitive.ObjectID
+	params := pagination.Params{Limit: 2, UseCursor: true}
+	for pages := 0; pages < 5; pages++ {
+		exercises, err := repos.Exercises.GetByCategory(ctx, "addition", false, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page := pagination.NewPage(exercises, params, 5, func(e *model.Exercise) pagination.Cursor { return pagination.IDCursor(e.ID) })
+		walked = append(walked, exerciseIDs(page.Items)...)
+		if !page.Meta.HasMore {
+			break
+		}
+		cursor, err := pagination.DecodeCursor(page.Meta.NextCursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		params.After = cursor
+	}
+	if len(walked) != len(created) {
+		t.Fatalf("cursor pages = %d exercises, want %d", len(walked), len(created))
+	}
+	for i := range created {
+		if walked[i] != created[i] {
+			t.Errorf("cursor pages = %v, want %v", walked, created)
+			break
+		}
+	}
+}
+
+func exerciseIDs(exercises []*model.Exercise) []primitive.ObjectID {
+	ids := []primitive.ObjectID{}
+	for _, exercise := range exercises {
+		ids = append(ids, exercise.ID)
+	}
+	return ids
+}
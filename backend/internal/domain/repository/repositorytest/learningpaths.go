@@ -0,0 +1,314 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RunLearningPaths tests writing learning paths, editing their stages in
+// place and looking paths up by the exercises and paths they reference
+func RunLearningPaths(t *testing.T, open Open) {
+	t.Run("CreatesAndUpdates", func(t *testing.T) { testPathWrites(t, open(t)) })
+	t.Run("EditsStages", func(t *testing.T) { testStageEdits(t, open(t)) })
+	t.Run("RejectsStageConflicts", func(t *testing.T) { testStageConflicts(t, open(t)) })
+	t.Run("LooksUpReferences", func(t *testing.T) { testPathReferences(t, open(t)) })
+	t.Run("RemovesReferences", func(t *testing.T) { testRemoveReferences(t, open(t)) })
+}
+
+func testPathWrites(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	exercise := newExercise(t, repos, "addition", "easy")
+	path := newPath(t, repos, "Addition", []primitive.ObjectID{exercise.ID})
+	if path.ID.IsZero() || path.Revision != 1 {
+		t.Fatalf("created path = %+v, want an ID and revision 1", path)
+	}
+
+	stale := *path
+	path.Title = "Sums"
+	path.Status = model.StatusPublished
+	if err := repos.LearningPaths.Update(ctx, path, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := repos.LearningPaths.GetByID(ctx, path.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Title != "Sums" || stored.Revision != 2 || len(stored.Stages) != 1 || stageExercises(stored, 1)[0] != exercise.ID {
+		t.Errorf("stored path = %+v, want the update with its stage", stored)
+	}
+
+	stale.Title = "Stale"
+	if err := repos.LearningPaths.Update(ctx, &stale, model.RevisionMeta{}); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("stale update: %v, want ErrVersionConflict", err)
+	}
+
+	draft := newPath(t, repos, "Draft")
+	all, err := repos.LearningPaths.GetAll(ctx, true, pagination.Params{Limit: 10})
+	if err != nil || len(all) != 2 {
+		t.Errorf("all paths = %d, %v, want 2", len(all), err)
+	}
+	published, err := repos.LearningPaths.GetByCategory(ctx, "addition", false, pagination.Params{Limit: 10})
+	if err != nil || len(published) != 1 || published[0].ID != path.ID {
+		t.Errorf("published paths = %d, %v, want only %s", len(published), err, path.ID.Hex())
+	}
+
+	if err := repos.LearningPaths.SoftDelete(ctx, draft.ID, newID()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.LearningPaths.GetByID(ctx, draft.ID); !errors.Is(err, repository.ErrPathNotFound) {
+		t.Errorf("trashed path: %v, want ErrPathNotFound", err)
+	}
+	if count, err := repos.LearningPaths.Count(ctx, model.PathQuery{IncludeUnpublished: true}); err != nil || count != 1 {
+		t.Errorf("count = %d, %v, want the trashed path left out", count, err)
+	}
+}
+
+func testStageEdits(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	a := newExercise(t, repos, "addition", "easy").ID
+	b := newExercise(t, repos, "addition", "easy").ID
+	c := newExercise(t, repos, "addition", "easy").ID
+	path := newPath(t, repos, "Addition", []primitive.ObjectID{a}, []primitive.ObjectID{b})
+
+	revision := path.Revision
+	edit := func(name string, change func(revision int) (*model.LearningPath, error)) *model.LearningPath {
+		t.Helper()
+		updated, err := change(revision)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if updated.Revision != revision+1 {
+			t.Errorf("%s: revision = %d, want %d", name, updated.Revision, revision+1)
+		}
+		revision = updated.Revision
+
+		stored, err := repos.LearningPaths.GetByID(ctx, path.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Revision != updated.Revision || len(stored.Stages) != len(updated.Stages) {
+			t.Errorf("%s: stored path does not match the returned one", name)
+		}
+		for i, stage := range stored.Stages {
+			if stage.StageNumber != i+1 {
+				t.Errorf("%s: stage %d is numbered %d", name, i+1, stage.StageNumber)
+			}
+		}
+		return stored
+	}
+
+	inserted := model.PathStage{ID: newID(), Title: "Warm up", ExerciseIDs: []primitive.ObjectID{c}}
+	stored := edit("insert", func(revision int) (*model.LearningPath, error) {
+		return repos.LearningPaths.InsertStage(ctx, path.ID, inserted, 1, revision, model.RevisionMeta{})
+	})
+	if len(stored.Stages) != 3 || stored.Stages[0].Title != "Warm up" || stageExercises(stored, 2)[0] != a {
+		t.Errorf("stages after insert = %+v, want the new stage first", stored.Stages)
+	}
+
+	stored = edit("append", func(revision int) (*model.LearningPath, error) {
+		return repos.LearningPaths.InsertStage(ctx, path.ID, model.PathStage{ID: newID(), Title: "Review", ExerciseIDs: []primitive.ObjectID{}}, 0, revision, model.RevisionMeta{})
+	})
+	if len(stored.Stages) != 4 || stored.Stages[3].Title != "Review" {
+		t.Errorf("stages after append = %+v, want the new stage last", stored.Stages)
+	}
+
+	stored = edit("replace", func(revision int) (*model.LearningPath, error) {
+		replaced := model.PathStage{StageNumber: 2, Title: "Ones", ExerciseIDs: []primitive.ObjectID{a, b}}
+		return repos.LearningPaths.ReplaceStage(ctx, path.ID, replaced, revision, model.RevisionMeta{})
+	})
+	if stored.Stages[1].Title != "Ones" || len(stageExercises(stored, 2)) != 2 || stored.Stages[1].ID != path.Stages[0].ID {
+		t.Errorf("stage after replace = %+v, want new content under the same ID", stored.Stages[1])
+	}
+
+	stored = edit("reorder", func(revision int) (*model.LearningPath, error) {
+		return repos.LearningPaths.ReorderStages(ctx, path.ID, []int{4, 3, 2, 1}, revision, model.RevisionMeta{})
+	})
+	if titles := stageTitles(stored); titles != "Review,Addition stage,Ones,Warm up" {
+		t.Errorf("stages after reorder = %s", titles)
+	}
+
+	stored = edit("add exercise", func(revision int) (*model.LearningPath, error) {
+		return repos.LearningPaths.AddStageExercise(ctx, path.ID, 1, c, revision, model.RevisionMeta{})
+	})
+	stored = edit("add exercise again", func(revision int) (*model.LearningPath, error) {
+		return repos.LearningPaths.AddStageExercise(ctx, path.ID, 1, c, revision, model.RevisionMeta{})
+	})
+	if ids := stageExercises(stored, 1); len(ids) != 1 || ids[0] != c {
+		t.Errorf("stage 1 exercises = %v, want c once", ids)
+	}
+
+	stored = edit("remove exercise", func(revision int) (*model.LearningPath, error) {
+		return repos.LearningPaths.RemoveStageExercise(ctx, path.ID, 3, a, revision, model.RevisionMeta{})
+	})
+	if ids := stageExercises(stored, 3); len(ids) != 1 || ids[0] != b {
+		t.Errorf("stage 3 exercises = %v, want only b", ids)
+	}
+
+	stored = edit("remove", func(revision int) (*model.LearningPath, error) {
+		return repos.LearningPaths.RemoveStage(ctx, path.ID, 1, revision, model.RevisionMeta{})
+	})
+	if titles := stageTitles(stored); titles != "Addition stage,Ones,Warm up" {
+		t.Errorf("stages after remove = %s", titles)
+	}
+}
+
+func testStageConflicts(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	exercise := newExercise(t, repos, "addition", "easy").ID
+	path := newPath(t, repos, "Addition", []primitive.ObjectID{exercise}, []primitive.ObjectID{})
+
+	if _, err := repos.LearningPaths.RemoveStage(ctx, path.ID, 1, path.Revision+1, model.RevisionMeta{}); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("stale stage edit: %v, want ErrVersionConflict", err)
+	}
+	if _, err := repos.LearningPaths.AddStageExercise(ctx, path.ID, 3, exercise, path.Revision, model.RevisionMeta{}); !errors.Is(err, repository.ErrStageNotFound) {
+		t.Errorf("editing a missing stage: %v, want ErrStageNotFound", err)
+	}
+	if _, err := repos.LearningPaths.ReorderStages(ctx, path.ID, []int{1}, path.Revision, model.RevisionMeta{}); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("reorder missing a stage: %v, want ErrVersionConflict", err)
+	}
+	if _, err := repos.LearningPaths.RemoveStage(ctx, newID(), 1, model.AnyRevision, model.RevisionMeta{}); !errors.Is(err, repository.ErrPathNotFound) {
+		t.Errorf("editing a missing path: %v, want ErrPathNotFound", err)
+	}
+
+	// Rejected edits leave the path as it was
+	stored, err := repos.LearningPaths.GetByID(ctx, path.ID)
+	if err != nil || stored.Revision != path.Revision || len(stored.Stages) != 2 {
+		t.Errorf("path after rejected edits = %+v, %v, want it unchanged", stored, err)
+	}
+
+	// Any revision skips the check
+	if _, err := repos.LearningPaths.RemoveStage(ctx, path.ID, 2, model.AnyRevision, model.RevisionMeta{}); err != nil {
+		t.Errorf("edit at any revision: %v", err)
+	}
+
+	if err := repos.LearningPaths.SoftDelete(ctx, path.ID, newID()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.LearningPaths.RemoveStage(ctx, path.ID, 1, model.AnyRevision, model.RevisionMeta{}); !errors.Is(err, repository.ErrPathNotFound) {
+		t.Errorf("editing a trashed path: %v, want ErrPathNotFound", err)
+	}
+}
+
+func testPathReferences(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	exercise := newExercise(t, repos, "addition", "easy").ID
+	basics := newPath(t, repos, "Basics", []primitive.ObjectID{exercise})
+	advanced := newPath(t, repos, "Advanced", []primitive.ObjectID{exercise})
+	advanced.PrerequisitePathIDs = []primitive.ObjectID{basics.ID}
+	if err := repos.LearningPaths.Update(ctx, advanced, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+	newPath(t, repos, "Unrelated")
+
+	using, err := repos.LearningPaths.GetByExerciseID(ctx, exercise)
+	if err != nil || len(using) != 2 || using[0].ID != basics.ID || using[1].ID != advanced.ID {
+		t.Errorf("paths using the exercise = %d, %v, want basics and advanced", len(using), err)
+	}
+	dependents, err := repos.LearningPaths.GetDependents(ctx, basics.ID)
+	if err != nil || len(dependents) != 1 || dependents[0].ID != advanced.ID {
+		t.Errorf("dependents = %d, %v, want advanced", len(dependents), err)
+	}
+	byIDs, err := repos.LearningPaths.GetByIDs(ctx, []primitive.ObjectID{advanced.ID, newID()})
+	if err != nil || len(byIDs) != 1 || byIDs[0].ID != advanced.ID {
+		t.Errorf("paths by ID = %d, %v, want advanced", len(byIDs), err)
+	}
+
+	// Trashed paths are left out of every lookup
+	if err := repos.LearningPaths.SoftDelete(ctx, advanced.ID, newID()); err != nil {
+		t.Fatal(err)
+	}
+	if using, err := repos.LearningPaths.GetByExerciseID(ctx, exercise); err != nil || len(using) != 1 {
+		t.Errorf("paths using the exercise after trashing one = %d, %v, want 1", len(using), err)
+	}
+	if dependents, err := repos.LearningPaths.GetDependents(ctx, basics.ID); err != nil || len(dependents) != 0 {
+		t.Errorf("dependents after trashing = %d, %v, want none", len(dependents), err)
+	}
+}
+
+// testRemoveReferences checks that removing an exercise or prerequisite from
+// the paths using it is a revision of each path, so writes based on the old
+// revision are rejected and the history shows the change
+func testRemoveReferences(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	exercise := newExercise(t, repos, "addition", "easy").ID
+	kept := newExercise(t, repos, "addition", "easy").ID
+	basics := newPath(t, repos, "Basics", []primitive.ObjectID{exercise, kept})
+	trashed := newPath(t, repos, "Trashed", []primitive.ObjectID{exercise})
+	if err := repos.LearningPaths.SoftDelete(ctx, trashed.ID, newID()); err != nil {
+		t.Fatal(err)
+	}
+	advanced := newPath(t, repos, "Advanced")
+	advanced.PrerequisitePathIDs = []primitive.ObjectID{basics.ID, trashed.ID}
+	if err := repos.LearningPaths.Update(ctx, advanced, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+	newPath(t, repos, "Unrelated", []primitive.ObjectID{kept})
+
+	author := newID()
+	changed, err := repos.LearningPaths.RemoveExercise(ctx, exercise, model.RevisionMeta{AuthorID: author, Note: "Removed exercise"})
+	if err != nil || changed != 2 {
+		t.Fatalf("paths changed by removing the exercise = %d, %v, want 2", changed, err)
+	}
+	stored, err := repos.LearningPaths.GetByID(ctx, basics.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := stageExercises(stored, 1); len(ids) != 1 || ids[0] != kept {
+		t.Errorf("stage exercises = %v, want only the kept exercise", ids)
+	}
+	checkRemovalRevision(t, repos, basics, stored.Revision, "Removed exercise")
+
+	changed, err = repos.LearningPaths.RemovePrerequisite(ctx, basics.ID, model.RevisionMeta{AuthorID: author, Note: "Removed prerequisite"})
+	if err != nil || changed != 1 {
+		t.Fatalf("paths changed by removing the prerequisite = %d, %v, want 1", changed, err)
+	}
+	stored, err = repos.LearningPaths.GetByID(ctx, advanced.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.PrerequisitePathIDs) != 1 || stored.PrerequisitePathIDs[0] != trashed.ID {
+		t.Errorf("prerequisites = %v, want only the other one", stored.PrerequisitePathIDs)
+	}
+	checkRemovalRevision(t, repos, advanced, stored.Revision, "Removed prerequisite")
+}
+
+// checkRemovalRevision checks that a path read before a removal is one revision
+// behind, cannot be written back and that the removal was recorded
+func checkRemovalRevision(t *testing.T, repos Repositories, before *model.LearningPath, revision int, note string) {
+	t.Helper()
+	ctx := context.Background()
+	if revision != before.Revision+1 {
+		t.Errorf("%s: revision = %d, want %d", note, revision, before.Revision+1)
+	}
+	stale := *before
+	if err := repos.LearningPaths.Update(ctx, &stale, model.RevisionMeta{}); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("%s: writing back the old revision: %v, want ErrVersionConflict", note, err)
+	}
+	recorded, err := repos.Revisions.GetByNumber(ctx, model.ContentKindLearningPath, before.ID, revision)
+	if err != nil || recorded.Note != note {
+		t.Errorf("%s: recorded revision = %+v, %v", note, recorded, err)
+	}
+}
+
+// stageExercises returns the exercises of a stage, numbered from one
+func stageExercises(path *model.LearningPath, stageNumber int) []primitive.ObjectID {
+	return path.Stages[stageNumber-1].ExerciseIDs
+}
+
+// stageTitles returns the titles of a path's stages joined by commas
+func stageTitles(path *model.LearningPath) string {
+	titles := ""
+	for i, stage := range path.Stages {
+		if i > 0 {
+			titles += ","
+		}
+		titles += stage.Title
+	}
+	return titles
+}
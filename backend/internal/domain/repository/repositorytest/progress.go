@@ -0,0 +1,223 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RunProgress tests the per-exercise progress summaries kept for each user
+func RunProgress(t *testing.T, open Open) {
+	t.Run("RecordsAttempts", func(t *testing.T) { testProgressAttempts(t, open(t)) })
+	t.Run("UpdatesMastery", func(t *testing.T) { testProgressMastery(t, open(t)) })
+	t.Run("Lists", func(t *testing.T) { testProgressLists(t, open(t)) })
+	t.Run("RejectsDuplicates", func(t *testing.T) { testProgressDuplicates(t, open(t)) })
+	t.Run("ReplacesTotals", func(t *testing.T) { testProgressTotals(t, open(t)) })
+}
+
+func testProgressAttempts(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	user := newUser(t, repos, "ada")
+	exercise := newExercise(t, repos, "addition", "easy")
+
+	if _, err := repos.Progress.GetByUserAndExercise(ctx, user.ID, exercise.ID); !errors.Is(err, repository.ErrProgressNotFound) {
+		t.Fatalf("summary before any attempt: %v, want ErrProgressNotFound", err)
+	}
+
+	// The first attempt creates the summary and later ones fold into it
+	var progress *model.UserProgress
+	var last time.Time
+	for i := 0; i < model.RecentAttemptLimit+2; i++ {
+		last = attemptWeek.Add(time.Duration(i) * time.Minute)
+		attempt := model.Attempt{UserID: user.ID, ExerciseID: exercise.ID, IsCorrect: i%3 != 0, TimeTaken: 2, Timestamp: last}
+		recorded, err := repos.Progress.RecordAttempt(ctx, attempt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if progress != nil && recorded.ID != progress.ID {
+			t.Fatalf("attempt %d created a second summary", i)
+		}
+		progress = recorded
+	}
+
+	attempts := model.RecentAttemptLimit + 2
+	correct := attempts - 4
+	if progress.AttemptCount != attempts || progress.CorrectCount != correct || progress.TotalTimeTaken != 2*attempts {
+		t.Errorf("summary = %d attempts, %d correct, %ds; want %d, %d, %ds",
+			progress.AttemptCount, progress.CorrectCount, progress.TotalTimeTaken, attempts, correct, 2*attempts)
+	}
+	if want := float64(correct) / float64(attempts); progress.MasteryLevel != want {
+		t.Errorf("mastery = %v, want %v", progress.MasteryLevel, want)
+	}
+	if len(progress.RecentAttempts) != model.RecentAttemptLimit || !progress.RecentAttempts[len(progress.RecentAttempts)-1].Timestamp.Equal(last) {
+		t.Errorf("recent attempts = %d ending at %v, want the last %d", len(progress.RecentAttempts),
+			progress.RecentAttempts[len(progress.RecentAttempts)-1].Timestamp, model.RecentAttemptLimit)
+	}
+	if !progress.LastAttempted.Equal(last) {
+		t.Errorf("last attempted = %v, want %v", progress.LastAttempted, last)
+	}
+
+	stored, err := repos.Progress.GetByUserAndExercise(ctx, user.ID, exercise.ID)
+	if err != nil || stored.ID != progress.ID || stored.AttemptCount != attempts {
+		t.Errorf("stored summary = %+v, %v, want the recorded one", stored, err)
+	}
+}
+
+func testProgressMastery(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	user := newUser(t, repos, "ada")
+	exercise := newExercise(t, repos, "addition", "easy")
+
+	progress, err := repos.Progress.RecordAttempt(ctx, model.Attempt{UserID: user.ID, ExerciseID: exercise.ID, IsCorrect: true, Timestamp: attemptWeek})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Progress.UpdateMasteryLevel(ctx, progress.ID, 0.25); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := repos.Progress.GetByID(ctx, progress.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.MasteryLevel != 0.25 || stored.AttemptCount != 1 || stored.CorrectCount != 1 {
+		t.Errorf("summary = %+v, want only the mastery changed", stored)
+	}
+
+	// Like an update, setting the mastery of a missing summary is not an error
+	if err := repos.Progress.UpdateMasteryLevel(ctx, newID(), 0.5); err != nil {
+		t.Errorf("mastery of a missing summary: %v", err)
+	}
+	if _, err := repos.Progress.GetByID(ctx, newID()); !errors.Is(err, repository.ErrProgressNotFound) {
+		t.Errorf("unknown id: %v, want ErrProgressNotFound", err)
+	}
+}
+
+func testProgressLists(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	user := newUser(t, repos, "ada")
+	other := newUser(t, repos, "grace")
+
+	var summaries []*model.UserProgress
+	var exercises []primitive.ObjectID
+	for i := 0; i < 3; i++ {
+		exercise := newExercise(t, repos, "addition", "easy")
+		exercises = append(exercises, exercise.ID)
+		progress, err := repos.Progress.RecordAttempt(ctx, model.Attempt{UserID: user.ID, ExerciseID: exercise.ID, Timestamp: attemptWeek})
+		if err != nil {
+			t.Fatal(err)
+		}
+		summaries = append(summaries, progress)
+	}
+	if _, err := repos.Progress.RecordAttempt(ctx, model.Attempt{UserID: other.ID, ExerciseID: exercises[0], Timestamp: attemptWeek}); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := repos.Progress.GetByUserID(ctx, user.ID)
+	if err != nil || len(all) != 3 {
+		t.Fatalf("user's summaries = %d, %v, want 3", len(all), err)
+	}
+	if count, err := repos.Progress.CountByUserID(ctx, user.ID); err != nil || count != 3 {
+		t.Errorf("count = %d, %v, want 3", count, err)
+	}
+
+	page, err := repos.Progress.ListByUserID(ctx, user.ID, pagination.Params{Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].ID != summaries[1].ID || page[1].ID != summaries[2].ID {
+		t.Errorf("page = %d summaries, want the second and third", len(page))
+	}
+
+	chosen, err := repos.Progress.GetByUserAndExercises(ctx, user.ID, []primitive.ObjectID{exercises[0], exercises[2], newID()})
+	if err != nil || len(chosen) != 2 {
+		t.Errorf("summaries for chosen exercises = %d, %v, want 2", len(chosen), err)
+	}
+
+	deleted, err := repos.Progress.DeleteByUserID(ctx, user.ID)
+	if err != nil || deleted != 3 {
+		t.Errorf("deleted summaries = %d, %v, want 3", deleted, err)
+	}
+	if count, err := repos.Progress.CountByUserID(ctx, other.ID); err != nil || count != 1 {
+		t.Errorf("other user's summaries = %d, %v, want 1", count, err)
+	}
+}
+
+func testProgressDuplicates(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	user := newUser(t, repos, "ada")
+	exercise := newExercise(t, repos, "addition", "easy")
+
+	first := &model.UserProgress{UserID: user.ID, ExerciseID: exercise.ID, RecentAttempts: []model.Attempt{}}
+	if err := repos.Progress.Create(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	second := &model.UserProgress{UserID: user.ID, ExerciseID: exercise.ID, RecentAttempts: []model.Attempt{}}
+	if err := repos.Progress.Create(ctx, second); !errors.Is(err, repository.ErrDuplicateKey) {
+		t.Errorf("second summary for an exercise: %v, want ErrDuplicateKey", err)
+	}
+
+	// Attempts fold into the summary created directly
+	progress, err := repos.Progress.RecordAttempt(ctx, model.Attempt{UserID: user.ID, ExerciseID: exercise.ID, Timestamp: attemptWeek})
+	if err != nil || progress.ID != first.ID || progress.AttemptCount != 1 {
+		t.Errorf("recorded attempt = %+v, %v, want it folded into %s", progress, err, first.ID.Hex())
+	}
+}
+
+func testProgressTotals(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	user := newUser(t, repos, "ada")
+	exercise := newExercise(t, repos, "addition", "easy")
+	missing := newExercise(t, repos, "addition", "easy")
+
+	progress, err := repos.Progress.RecordAttempt(ctx, model.Attempt{UserID: user.ID, ExerciseID: exercise.ID, IsCorrect: true, TimeTaken: 3, Timestamp: attemptWeek})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	last := attemptWeek.Add(time.Hour)
+	totals := &model.UserProgress{UserID: user.ID, ExerciseID: exercise.ID, AttemptCount: 4, CorrectCount: 3, TotalTimeTaken: 12, MasteryLevel: 0.75, LastAttempted: last}
+
+	// A summary that had attempts folded in since it was read is left alone
+	if written, err := repos.Progress.ReplaceTotals(ctx, totals, 2); err != nil || written {
+		t.Errorf("replacing from a stale read = %v, %v, want nothing written", written, err)
+	}
+	if written, err := repos.Progress.ReplaceTotals(ctx, totals, 0); err != nil || written {
+		t.Errorf("creating a summary that exists = %v, %v, want nothing written", written, err)
+	}
+
+	if written, err := repos.Progress.ReplaceTotals(ctx, totals, 1); err != nil || !written {
+		t.Fatalf("replacing totals = %v, %v", written, err)
+	}
+	stored, err := repos.Progress.GetByID(ctx, progress.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.AttemptCount != 4 || stored.CorrectCount != 3 || stored.TotalTimeTaken != 12 || stored.MasteryLevel != 0.75 || !stored.LastAttempted.Equal(last) {
+		t.Errorf("replaced summary = %+v, want the new totals", stored)
+	}
+	if len(stored.RecentAttempts) != 1 {
+		t.Errorf("recent attempts = %d, want the one recorded kept", len(stored.RecentAttempts))
+	}
+
+	// A missing summary is created only when none was expected
+	created := &model.UserProgress{UserID: user.ID, ExerciseID: missing.ID, AttemptCount: 2, CorrectCount: 1, TotalTimeTaken: 4, MasteryLevel: 0.5, LastAttempted: last}
+	if written, err := repos.Progress.ReplaceTotals(ctx, created, 3); err != nil || written {
+		t.Errorf("replacing a missing summary = %v, %v, want nothing written", written, err)
+	}
+	if written, err := repos.Progress.ReplaceTotals(ctx, created, 0); err != nil || !written {
+		t.Fatalf("creating a summary = %v, %v", written, err)
+	}
+	stored, err = repos.Progress.GetByUserAndExercise(ctx, user.ID, missing.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.ID.IsZero() || stored.AttemptCount != 2 || stored.MasteryLevel != 0.5 || !stored.LastAttempted.Equal(last) || stored.RecentAttempts == nil {
+		t.Errorf("created summary = %+v, want the totals and no recent attempts", stored)
+	}
+}
@@ -0,0 +1,104 @@
+// Package repositorytest is a test suite shared by the repository
+// implementations, so the Mongo, Postgres and in-memory repositories are held
+// to the same behaviour
+package repositorytest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Repositories are the repositories under test, backed by one store
+type Repositories struct {
+	Exercises     repository.ExerciseRepository
+	Attempts      repository.AttemptRepository
+	Users         repository.UserRepository
+	Sessions      repository.SessionRepository
+	Progress      repository.ProgressRepository
+	LearningPaths repository.LearningPathRepository
+	Revisions     repository.RevisionRepository
+}
+
+// Open returns repositories on an empty store for a test
+type Open func(t *testing.T) Repositories
+
+// Run runs the whole suite
+func Run(t *testing.T, open Open) {
+	t.Run("Users", func(t *testing.T) { RunUsers(t, open) })
+	t.Run("Sessions", func(t *testing.T) { RunSessions(t, open) })
+	t.Run("Exercises", func(t *testing.T) { RunExercises(t, open) })
+	t.Run("Progress", func(t *testing.T) { RunProgress(t, open) })
+	t.Run("LearningPaths", func(t *testing.T) { RunLearningPaths(t, open) })
+	t.Run("Attempts", func(t *testing.T) { RunAttempts(t, open) })
+	t.Run("Trash", func(t *testing.T) { RunTrash(t, open) })
+}
+
+// newExercise creates an exercise with every field the stores require
+func newExercise(t *testing.T, repos Repositories, category, difficulty string, tags ...string) *model.Exercise {
+	t.Helper()
+	exercise := &model.Exercise{
+		Title:      category + " exercise",
+		Type:       "fill_in",
+		Category:   category,
+		Difficulty: difficulty,
+		Content:    model.ExerciseContent{Problem: "1 + 1 = ?", Options: []string{}, CorrectAnswer: "2"},
+		Metadata:   model.ExerciseMetadata{CreatedAt: time.Now()},
+		Tags:       tags,
+		Status:     model.StatusPublished,
+	}
+	if err := repos.Exercises.Create(context.Background(), exercise, model.RevisionMeta{}); err != nil {
+		t.Fatalf("creating exercise: %v", err)
+	}
+	return exercise
+}
+
+// newUser creates a user with every field the stores require
+func newUser(t *testing.T, repos Repositories, name string) *model.User {
+	t.Helper()
+	user := &model.User{
+		Email:        name + "@example.com",
+		Username:     name,
+		PasswordHash: "hash",
+		LastLogin:    time.Now(),
+	}
+	if err := repos.Users.Create(context.Background(), user); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	return user
+}
+
+// newPath creates a draft learning path with a stage per group of exercises
+func newPath(t *testing.T, repos Repositories, title string, stages ...[]primitive.ObjectID) *model.LearningPath {
+	t.Helper()
+	path := &model.LearningPath{
+		Title:               title,
+		Difficulty:          "easy",
+		Categories:          []string{"addition"},
+		Status:              model.StatusDraft,
+		Stages:              []model.PathStage{},
+		PrerequisitePathIDs: []primitive.ObjectID{},
+		RequiredSkills:      []string{},
+	}
+	for i, exerciseIDs := range stages {
+		path.Stages = append(path.Stages, model.PathStage{
+			ID:          newID(),
+			StageNumber: i + 1,
+			Title:       title + " stage",
+			ExerciseIDs: exerciseIDs,
+		})
+	}
+	if err := repos.LearningPaths.Create(context.Background(), path, model.RevisionMeta{}); err != nil {
+		t.Fatalf("creating learning path: %v", err)
+	}
+	return path
+}
+
+// newID returns a fresh ID, so tests sharing a store do not see each other's records
+func newID() primitive.ObjectID {
+	return primitive.NewObjectID()
+}
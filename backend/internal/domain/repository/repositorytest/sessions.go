@@ -0,0 +1,148 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RunSessions tests creating and looking up sessions and expiring them
+func RunSessions(t *testing.T, open Open) {
+	t.Run("LooksUp", func(t *testing.T) { testSessionLookups(t, open(t)) })
+	t.Run("Expires", func(t *testing.T) { testSessionExpiry(t, open(t)) })
+	t.Run("DeletesForUser", func(t *testing.T) { testSessionDeletes(t, open(t)) })
+}
+
+// newSession creates a session for a user that expires after ttl, which may be negative
+func newSession(t *testing.T, repos Repositories, userID primitive.ObjectID, ttl time.Duration) *model.UserSession {
+	t.Helper()
+	session := &model.UserSession{
+		UserID:       userID,
+		Role:         "user",
+		SessionToken: newID().Hex(),
+		ExpiresAt:    time.Now().Add(ttl).Truncate(time.Millisecond),
+		IPAddress:    "127.0.0.1",
+		DeviceInfo:   "test",
+	}
+	if err := repos.Sessions.Create(context.Background(), session); err != nil {
+		t.Fatalf("creating session: %v", err)
+	}
+	return session
+}
+
+func testSessionLookups(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	user := newUser(t, repos, "ada")
+	session := newSession(t, repos, user.ID, time.Hour)
+	if session.ID.IsZero() || session.CreatedAt.IsZero() {
+		t.Fatalf("created session = %+v, want an ID and creation time", session)
+	}
+
+	for name, lookup := range map[string]func() (*model.UserSession, error){
+		"id":    func() (*model.UserSession, error) { return repos.Sessions.GetByID(ctx, session.ID) },
+		"token": func() (*model.UserSession, error) { return repos.Sessions.GetByToken(ctx, session.SessionToken) },
+	} {
+		found, err := lookup()
+		if err != nil {
+			t.Errorf("looking up by %s: %v", name, err)
+			continue
+		}
+		if found.ID != session.ID || found.UserID != user.ID || found.Role != "user" || !found.ExpiresAt.Equal(session.ExpiresAt) {
+			t.Errorf("looking up by %s = %+v, want %+v", name, found, session)
+		}
+	}
+
+	if _, err := repos.Sessions.GetByID(ctx, newID()); !errors.Is(err, repository.ErrSessionNotFound) {
+		t.Errorf("unknown id: %v, want ErrSessionNotFound", err)
+	}
+	if _, err := repos.Sessions.GetByToken(ctx, "unknown"); !errors.Is(err, repository.ErrSessionNotFound) {
+		t.Errorf("unknown token: %v, want ErrSessionNotFound", err)
+	}
+
+	reused := &model.UserSession{UserID: user.ID, SessionToken: session.SessionToken, ExpiresAt: session.ExpiresAt}
+	if err := repos.Sessions.Create(ctx, reused); !errors.Is(err, repository.ErrDuplicateKey) {
+		t.Errorf("reusing a token: %v, want ErrDuplicateKey", err)
+	}
+
+	if err := repos.Sessions.Delete(ctx, session.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.Sessions.GetByToken(ctx, session.SessionToken); !errors.Is(err, repository.ErrSessionNotFound) {
+		t.Errorf("deleted session: %v, want ErrSessionNotFound", err)
+	}
+}
+
+func testSessionExpiry(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	user := newUser(t, repos, "ada")
+	other := newUser(t, repos, "grace")
+
+	var active []*model.UserSession
+	for i := 0; i < 3; i++ {
+		active = append(active, newSession(t, repos, user.ID, time.Hour))
+	}
+	expired := newSession(t, repos, user.ID, -time.Hour)
+	newSession(t, repos, other.ID, -time.Hour)
+	newSession(t, repos, other.ID, time.Hour)
+
+	if count, err := repos.Sessions.CountActiveByUserID(ctx, user.ID); err != nil || count != 3 {
+		t.Errorf("active sessions = %d, %v, want 3", count, err)
+	}
+
+	// Pages of active sessions are in ID order
+	first, err := repos.Sessions.ListActiveByUserID(ctx, user.ID, pagination.Params{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := repos.Sessions.ListActiveByUserID(ctx, user.ID, pagination.Params{Limit: 2, Offset: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed := append(first, second...)
+	if len(first) != 2 || len(listed) != 3 {
+		t.Fatalf("active session pages = %d and %d sessions, want 2 and 1", len(first), len(second))
+	}
+	for i, session := range listed {
+		if session.ID != active[i].ID {
+			t.Errorf("active session %d = %s, want %s", i, session.ID.Hex(), active[i].ID.Hex())
+		}
+	}
+
+	// Mongo's TTL index may remove expired sessions before DeleteExpired does
+	deleted, err := repos.Sessions.DeleteExpired(ctx)
+	if err != nil || deleted > 2 {
+		t.Errorf("deleted expired sessions = %d, %v, want at most 2", deleted, err)
+	}
+	if _, err := repos.Sessions.GetByID(ctx, expired.ID); !errors.Is(err, repository.ErrSessionNotFound) {
+		t.Errorf("expired session after clean up: %v, want ErrSessionNotFound", err)
+	}
+	if sessions, err := repos.Sessions.GetByUserID(ctx, user.ID); err != nil || len(sessions) != 3 {
+		t.Errorf("sessions left = %d, %v, want the 3 active ones", len(sessions), err)
+	}
+}
+
+func testSessionDeletes(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	user := newUser(t, repos, "ada")
+	other := newUser(t, repos, "grace")
+	newSession(t, repos, user.ID, time.Hour)
+	newSession(t, repos, user.ID, 2*time.Hour)
+	kept := newSession(t, repos, other.ID, time.Hour)
+
+	deleted, err := repos.Sessions.DeleteAllForUser(ctx, user.ID)
+	if err != nil || deleted != 2 {
+		t.Errorf("deleted sessions = %d, %v, want 2", deleted, err)
+	}
+	if sessions, err := repos.Sessions.GetByUserID(ctx, user.ID); err != nil || len(sessions) != 0 {
+		t.Errorf("sessions left = %d, %v, want none", len(sessions), err)
+	}
+	if _, err := repos.Sessions.GetByID(ctx, kept.ID); err != nil {
+		t.Errorf("other user's session: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+)
+
+// RunTrash tests that unique values are only unique outside the trash, so
+// they can be reused while a record waits to be purged
+func RunTrash(t *testing.T, open Open) {
+	t.Run("ReusesExternalKeys", func(t *testing.T) { testTrashedExternalKeys(t, open(t)) })
+	t.Run("ReusesEmails", func(t *testing.T) { testTrashedEmails(t, open(t)) })
+}
+
+func testTrashedExternalKeys(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+
+	trashed := newExercise(t, repos, "addition", "easy")
+	trashed.ExternalKey = "addition-1"
+	if err := repos.Exercises.Update(ctx, trashed, model.RevisionMeta{}); err != nil {
+		t.Fatalf("setting the external key: %v", err)
+	}
+	if err := repos.Exercises.SoftDelete(ctx, trashed.ID, newID()); err != nil {
+		t.Fatalf("trashing exercise: %v", err)
+	}
+	if _, err := repos.Exercises.GetByExternalKey(ctx, "addition-1"); !errors.Is(err, repository.ErrExerciseNotFound) {
+		t.Fatalf("looking up a trashed key: %v, want ErrExerciseNotFound", err)
+	}
+
+	replacement := newExercise(t, repos, "addition", "easy")
+	replacement.ExternalKey = "addition-1"
+	if err := repos.Exercises.Update(ctx, replacement, model.RevisionMeta{}); err != nil {
+		t.Fatalf("reusing a trashed key: %v", err)
+	}
+	found, err := repos.Exercises.GetByExternalKey(ctx, "addition-1")
+	if err != nil || found.ID != replacement.ID {
+		t.Fatalf("looking up the reused key = %v, %v, want %s", found, err, replacement.ID.Hex())
+	}
+
+	if err := repos.Exercises.Restore(ctx, trashed.ID); !errors.Is(err, repository.ErrDuplicateKey) {
+		t.Errorf("restoring with a taken key: %v, want ErrDuplicateKey", err)
+	}
+}
+
+func testTrashedEmails(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	newUser := func() *model.User {
+		t.Helper()
+		user := &model.User{Email: "ada@example.com", Username: "ada", PasswordHash: "hash", LastLogin: time.Now()}
+		if err := repos.Users.Create(ctx, user); err != nil {
+			t.Fatalf("creating user: %v", err)
+		}
+		return user
+	}
+
+	trashed := newUser()
+	if err := repos.Users.SoftDelete(ctx, trashed.ID, newID()); err != nil {
+		t.Fatalf("trashing user: %v", err)
+	}
+	replacement := newUser()
+	if found, err := repos.Users.GetByEmail(ctx, "ada@example.com"); err != nil || found.ID != replacement.ID {
+		t.Fatalf("looking up the reused email = %v, %v, want %s", found, err, replacement.ID.Hex())
+	}
+
+	if err := repos.Users.Create(ctx, &model.User{Email: "ada@example.com", Username: "ada2", PasswordHash: "hash"}); !errors.Is(err, repository.ErrDuplicateKey) {
+		t.Errorf("creating a second user outside the trash: %v, want ErrDuplicateKey", err)
+	}
+	if err := repos.Users.Restore(ctx, trashed.ID); !errors.Is(err, repository.ErrDuplicateKey) {
+		t.Errorf("restoring with a taken email: %v, want ErrDuplicateKey", err)
+	}
+}
@@ -0,0 +1,122 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+)
+
+// RunUsers tests creating, looking up, updating and deleting users
+func RunUsers(t *testing.T, open Open) {
+	t.Run("LooksUp", func(t *testing.T) { testUserLookups(t, open(t)) })
+	t.Run("RejectsDuplicates", func(t *testing.T) { testUserDuplicates(t, open(t)) })
+	t.Run("UpdatesProfile", func(t *testing.T) { testUserUpdate(t, open(t)) })
+	t.Run("Deletes", func(t *testing.T) { testUserDelete(t, open(t)) })
+}
+
+func testUserLookups(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	user := newUser(t, repos, "ada")
+	if user.ID.IsZero() || user.CreatedAt.IsZero() {
+		t.Fatalf("created user = %+v, want an ID and creation time", user)
+	}
+
+	lookups := map[string]func() (*model.User, error){
+		"id":       func() (*model.User, error) { return repos.Users.GetByID(ctx, user.ID) },
+		"email":    func() (*model.User, error) { return repos.Users.GetByEmail(ctx, "ada@example.com") },
+		"username": func() (*model.User, error) { return repos.Users.GetByUsername(ctx, "ada") },
+	}
+	for name, lookup := range lookups {
+		found, err := lookup()
+		if err != nil || found.ID != user.ID || found.Email != user.Email || found.Username != user.Username {
+			t.Errorf("looking up by %s = %+v, %v, want %s", name, found, err, user.ID.Hex())
+		}
+	}
+
+	if _, err := repos.Users.GetByID(ctx, newID()); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("unknown id: %v, want ErrUserNotFound", err)
+	}
+	if _, err := repos.Users.GetByEmail(ctx, "nobody@example.com"); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("unknown email: %v, want ErrUserNotFound", err)
+	}
+	if _, err := repos.Users.GetByUsername(ctx, "nobody"); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("unknown username: %v, want ErrUserNotFound", err)
+	}
+}
+
+func testUserDuplicates(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	ada := newUser(t, repos, "ada")
+	grace := newUser(t, repos, "grace")
+
+	duplicates := []*model.User{
+		{Email: "ada@example.com", Username: "ada2", PasswordHash: "hash", LastLogin: ada.LastLogin},
+		{Email: "ada2@example.com", Username: "ada", PasswordHash: "hash", LastLogin: ada.LastLogin},
+	}
+	for _, user := range duplicates {
+		if err := repos.Users.Create(ctx, user); !errors.Is(err, repository.ErrDuplicateKey) {
+			t.Errorf("creating %s/%s: %v, want ErrDuplicateKey", user.Email, user.Username, err)
+		}
+	}
+
+	grace.Email = "ada@example.com"
+	if err := repos.Users.Update(ctx, grace); !errors.Is(err, repository.ErrDuplicateKey) {
+		t.Errorf("taking another user's email: %v, want ErrDuplicateKey", err)
+	}
+}
+
+func testUserUpdate(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	user := newUser(t, repos, "ada")
+
+	stats := user.Statistics
+	stats.TotalExercisesCompleted = 3
+	stats.Revision = 1
+	if swapped, err := repos.Users.SwapStatistics(ctx, user.ID, 0, stats); err != nil || !swapped {
+		t.Fatalf("setting statistics: %v, %v", swapped, err)
+	}
+
+	// A profile update written from a stale read keeps the statistics
+	user.FirstName = "Ada"
+	user.Username = "lovelace"
+	user.Preferences.DailyGoal = 7
+	if err := repos.Users.Update(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := repos.Users.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.FirstName != "Ada" || stored.Username != "lovelace" || stored.Preferences.DailyGoal != 7 {
+		t.Errorf("updated user = %+v, want the new profile", stored)
+	}
+	if stored.Statistics.TotalExercisesCompleted != 3 {
+		t.Errorf("statistics after a profile update = %+v, want them kept", stored.Statistics)
+	}
+	if _, err := repos.Users.GetByUsername(ctx, "ada"); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("old username: %v, want ErrUserNotFound", err)
+	}
+}
+
+func testUserDelete(t *testing.T, repos Repositories) {
+	ctx := context.Background()
+	deleted := newUser(t, repos, "ada")
+	kept := newUser(t, repos, "grace")
+
+	if err := repos.Users.Delete(ctx, deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.Users.GetByID(ctx, deleted.ID); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("deleted user: %v, want ErrUserNotFound", err)
+	}
+	if _, err := repos.Users.GetByID(ctx, kept.ID); err != nil {
+		t.Errorf("other user: %v", err)
+	}
+
+	// The email is free again
+	newUser(t, repos, "ada")
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// revisionsCollection is shared by the repositories that record revisions
+const revisionsCollection = "revisions"
+
+// RevisionRepository reads the revision history written by the exercise and
+// learning path repositories. Revisions are immutable, so there is no update.
+// Repositories that store content outside Mongo write their revisions with Record.
+type RevisionRepository interface {
+	Record(ctx context.Context, kind string, documentID primitive.ObjectID, number int, document interface{}, meta model.RevisionMeta) error
+	GetByDocument(ctx context.Context, kind string, documentID primitive.ObjectID, page pagination.Params) ([]*model.Revision, error)
+	CountByDocument(ctx context.Context, kind string, documentID primitive.ObjectID) (int64, error)
+	GetByNumber(ctx context.Context, kind string, documentID primitive.ObjectID, number int) (*model.Revision, error)
+	AnonymizeAuthor(ctx context.Context, authorID primitive.ObjectID) error
+}
+
+type MongoRevisionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRevisionRepository(db *mongo.Database) RevisionRepository {
+	return &MongoRevisionRepository{collection: db.Collection(revisionsCollection)}
+}
+
+// GetByDocument lists a document's revisions, newest first, without their snapshots.
+// Revisions are written in order, so _id order is revision order.
+func (r *MongoRevisionRepository) GetByDocument(ctx context.Context, kind string, documentID primitive.ObjectID, page pagination.Params) ([]*model.Revision, error) {
+	findOptions := options.Find()
+	findOptions.SetProjection(bson.M{"snapshot": 0})
+	filter := page.ApplySorted(bson.M{"kind": kind, "document_id": documentID}, findOptions, "", nil, true)
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var revisions []*model.Revision
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// CountByDocument counts a document's revisions
+func (r *MongoRevisionRepository) CountByDocument(ctx context.Context, kind string, documentID primitive.ObjectID) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"kind": kind, "document_id": documentID})
+}
+
+func (r *MongoRevisionRepository) GetByNumber(ctx context.Context, kind string, documentID primitive.ObjectID, number int) (*model.Revision, error) {
+	var revision model.Revision
+	filter := bson.M{"kind": kind, "document_id": documentID, "number": number}
+	err := r.collection.FindOne(ctx, filter).Decode(&revision)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("revision not found")
+		}
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// Record stores a snapshot of a document as the given revision
+func (r *MongoRevisionRepository) Record(ctx context.Context, kind string, documentID primitive.ObjectID, number int, document interface{}, meta model.RevisionMeta) error {
+	return saveRevision(ctx, r.collection, kind, documentID, number, document, meta)
+}
+
+// AnonymizeAuthor clears a deleted user from the revisions they authored
+func (r *MongoRevisionRepository) AnonymizeAuthor(ctx context.Context, authorID primitive.ObjectID) error {
+	_, err := r.collection.UpdateMany(ctx, bson.M{"author_id": authorID}, bson.M{"$set": bson.M{"author_id": primitive.NilObjectID}})
+	return err
+}
+
+// saveRevision stores a snapshot of a document as the given revision
+func saveRevision(ctx context.Context, collection *mongo.Collection, kind string, documentID primitive.ObjectID, number int, document interface{}, meta model.RevisionMeta) error {
+	snapshot, err := bson.Marshal(document)
+	if err != nil {
+		return err
+	}
+
+	revision := model.Revision{
+		ID:         primitive.NewObjectID(),
+		Kind:       kind,
+		DocumentID: documentID,
+		Number:     number,
+		AuthorID:   meta.AuthorID,
+		Note:       meta.Note,
+		CreatedAt:  time.Now(),
+		Snapshot:   snapshot,
+	}
+
+	_, err = collection.InsertOne(ctx, revision)
+	return err
+}
+
+// matchRevision matches the revision a caller expects a document to be at
+func matchRevision(expected int) interface{} {
+	if expected == 0 {
+		// Documents written before revisions were tracked have no revision field
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return expected
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -17,6 +18,8 @@ type SessionRepository interface {
 	GetByID(ctx context.Context, id primitive.ObjectID) (*model.UserSession, error)
 	GetByToken(ctx context.Context, token string) (*model.UserSession, error)
 	GetByUserID(ctx context.Context, userID primitive.ObjectID) ([]*model.UserSession, error)
+	ListActiveByUserID(ctx context.Context, userID primitive.ObjectID, page pagination.Params) ([]*model.UserSession, error)
+	CountActiveByUserID(ctx context.Context, userID primitive.ObjectID) (int64, error)
 	Delete(ctx context.Context, id primitive.ObjectID) error
 	DeleteExpired(ctx context.Context) (int64, error)
 	DeleteAllForUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
@@ -27,29 +30,7 @@ type MongoSessionRepository struct {
 }
 
 func NewSessionRepository(db *mongo.Database) SessionRepository {
-	collection := db.Collection("user_sessions")
-
-	// Create indexes
-	indexes := []mongo.IndexModel{
-		{
-			Keys:    bson.D{{Key: "session_token", Value: 1}},
-			Options: options.Index().SetUnique(true),
-		},
-		{
-			Keys: bson.D{{Key: "user_id", Value: 1}},
-		},
-		{
-			Keys:    bson.D{{Key: "expires_at", Value: 1}},
-			Options: options.Index().SetExpireAfterSeconds(0),
-		},
-	}
-
-	_, err := collection.Indexes().CreateMany(context.Background(), indexes)
-	if err != nil {
-		panic(err)
-	}
-
-	return &MongoSessionRepository{collection: collection}
+	return &MongoSessionRepository{collection: db.Collection("user_sessions")}
 }
 
 func (r *MongoSessionRepository) Create(ctx context.Context, session *model.UserSession) error {
@@ -57,7 +38,7 @@ func (r *MongoSessionRepository) Create(ctx context.Context, session *model.User
 	session.CreatedAt = time.Now()
 
 	_, err := r.collection.InsertOne(ctx, session)
-	return err
+	return translateWriteError(err)
 }
 
 func (r *MongoSessionRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*model.UserSession, error) {
@@ -65,7 +46,7 @@ func (r *MongoSessionRepository) GetByID(ctx context.Context, id primitive.Objec
 	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&session)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("session not found")
+			return nil, ErrSessionNotFound
 		}
 		return nil, err
 	}
@@ -77,7 +58,7 @@ func (r *MongoSessionRepository) GetByToken(ctx context.Context, token string) (
 	err := r.collection.FindOne(ctx, bson.M{"session_token": token}).Decode(&session)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("session not found")
+			return nil, ErrSessionNotFound
 		}
 		return nil, err
 	}
@@ -99,6 +80,29 @@ func (r *MongoSessionRepository) GetByUserID(ctx context.Context, userID primiti
 	return sessions, nil
 }
 
+// ListActiveByUserID returns a page of a user's sessions that have not expired
+func (r *MongoSessionRepository) ListActiveByUserID(ctx context.Context, userID primitive.ObjectID, page pagination.Params) ([]*model.UserSession, error) {
+	findOptions := options.Find()
+	filter := page.Apply(activeSessions(userID), findOptions)
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []*model.UserSession
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (r *MongoSessionRepository) CountActiveByUserID(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return r.collection.CountDocuments(ctx, activeSessions(userID))
+}
+
 func (r *MongoSessionRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
 	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
 	return err
@@ -119,3 +123,8 @@ func (r *MongoSessionRepository) DeleteAllForUser(ctx context.Context, userID pr
 	}
 	return result.DeletedCount, nil
 }
+
+// activeSessions matches a user's sessions that have not expired
+func activeSessions(userID primitive.ObjectID) bson.M {
+	return bson.M{"user_id": userID, "expires_at": bson.M{"$gt": time.Now()}}
+}
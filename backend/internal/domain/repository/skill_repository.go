@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type SkillRepository interface {
+	Upsert(ctx context.Context, skill *model.Skill) error
+	GetByTag(ctx context.Context, tag string) (*model.Skill, error)
+	GetAll(ctx context.Context) ([]*model.Skill, error)
+	Delete(ctx context.Context, tag string) error
+}
+
+type MongoSkillRepository struct {
+	collection *mongo.Collection
+}
+
+func NewSkillRepository(db *mongo.Database) SkillRepository {
+	return &MongoSkillRepository{collection: db.Collection("skills")}
+}
+
+// Upsert creates or replaces the skill identified by its tag
+func (r *MongoSkillRepository) Upsert(ctx context.Context, skill *model.Skill) error {
+	now := time.Now()
+	skill.UpdatedAt = now
+
+	filter := bson.M{"tag": skill.Tag}
+	update := bson.M{
+		"$set": bson.M{
+			"name":              skill.Name,
+			"description":       skill.Description,
+			"prerequisites":     skill.Prerequisites,
+			"mastery_threshold": skill.MasteryThreshold,
+			"updated_at":        skill.UpdatedAt,
+		},
+		"$setOnInsert": bson.M{
+			"_id":        primitive.NewObjectID(),
+			"created_at": now,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	return r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(skill)
+}
+
+func (r *MongoSkillRepository) GetByTag(ctx context.Context, tag string) (*model.Skill, error) {
+	var skill model.Skill
+	err := r.collection.FindOne(ctx, bson.M{"tag": tag}).Decode(&skill)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errors.New("skill not found")
+		}
+		return nil, err
+	}
+	return &skill, nil
+}
+
+func (r *MongoSkillRepository) GetAll(ctx context.Context) ([]*model.Skill, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "tag", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var skills []*model.Skill
+	if err := cursor.All(ctx, &skills); err != nil {
+		return nil, err
+	}
+
+	return skills, nil
+}
+
+func (r *MongoSkillRepository) Delete(ctx context.Context, tag string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"tag": tag})
+	return err
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Soft-deleted exercises, learning paths and users stay in their collections with
+// deleted_at and deleted_by set until they are restored or purged. Queries leave
+// them out unless they document otherwise.
+
+// notDeleted restricts a filter to documents that have not been soft-deleted
+func notDeleted(filter bson.M) bson.M {
+	scoped := bson.M{"deleted_at": bson.M{"$exists": false}}
+	for key, value := range filter {
+		scoped[key] = value
+	}
+	return scoped
+}
+
+// softDelete moves a document to the trash, returning notFound when there is no
+// such document or it is already in the trash
+func softDelete(ctx context.Context, collection *mongo.Collection, id, deletedBy primitive.ObjectID, notFound error) error {
+	update := bson.M{"$set": bson.M{"deleted_at": time.Now(), "deleted_by": deletedBy}}
+
+	result, err := collection.UpdateOne(ctx, notDeleted(bson.M{"_id": id}), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// restore takes a document out of the trash, returning notFound when it is not in the trash
+func restore(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID, notFound error) error {
+	filter := bson.M{"_id": id, "deleted_at": bson.M{"$exists": true}}
+	update := bson.M{"$unset": bson.M{"deleted_at": "", "deleted_by": ""}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return translateWriteError(err)
+	}
+	if result.MatchedCount == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// findDeleted returns a page, in ID order, of the documents that were moved to
+// the trash before the given time
+func findDeleted[T any](ctx context.Context, collection *mongo.Collection, before time.Time, page pagination.Params) ([]*T, error) {
+	findOptions := options.Find()
+	filter := page.Apply(bson.M{"deleted_at": bson.M{"$lte": before}}, findOptions)
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var documents []*T
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+
+	return documents, nil
+}
+
+// countDeleted counts the documents in the trash
+func countDeleted(ctx context.Context, collection *mongo.Collection) (int64, error) {
+	return collection.CountDocuments(ctx, bson.M{"deleted_at": bson.M{"$exists": true}})
+}
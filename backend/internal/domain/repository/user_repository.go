@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -19,8 +20,17 @@ type UserRepository interface {
 	GetByUsername(ctx context.Context, username string) (*model.User, error)
 	Update(ctx context.Context, user *model.User) error
 	Delete(ctx context.Context, id primitive.ObjectID) error
+	SoftDelete(ctx context.Context, id, deletedBy primitive.ObjectID) error
+	Restore(ctx context.Context, id primitive.ObjectID) error
+	GetDeleted(ctx context.Context, before time.Time, page pagination.Params) ([]*model.User, error)
+	CountDeleted(ctx context.Context) (int64, error)
 	UpdateLastLogin(ctx context.Context, id primitive.ObjectID) error
+	SetDeletionSchedule(ctx context.Context, id primitive.ObjectID, at *time.Time) error
+	GetDueForDeletion(ctx context.Context, before time.Time, limit int) ([]*model.User, error)
 	UpdateStatistics(ctx context.Context, id primitive.ObjectID, stats model.UserStatistics) error
+	SwapStatistics(ctx context.Context, id primitive.ObjectID, expectedRevision int64, stats model.UserStatistics) (bool, error)
+	ApplyAttemptStatistics(ctx context.Context, id primitive.ObjectID, attempt model.StatisticsAttempt) error
+	ForEach(ctx context.Context, fn func(*model.User) error) error
 }
 
 type MongoUserRepository struct {
@@ -28,26 +38,7 @@ type MongoUserRepository struct {
 }
 
 func NewUserRepository(db *mongo.Database) UserRepository {
-	collection := db.Collection("users")
-
-	// Create indexes
-	indexes := []mongo.IndexModel{
-		{
-			Keys:    bson.D{{Key: "email", Value: 1}},
-			Options: options.Index().SetUnique(true),
-		},
-		{
-			Keys:    bson.D{{Key: "username", Value: 1}},
-			Options: options.Index().SetUnique(true),
-		},
-	}
-
-	_, err := collection.Indexes().CreateMany(context.Background(), indexes)
-	if err != nil {
-		panic(err)
-	}
-
-	return &MongoUserRepository{collection: collection}
+	return &MongoUserRepository{collection: db.Collection("users")}
 }
 
 func (r *MongoUserRepository) Create(ctx context.Context, user *model.User) error {
@@ -56,15 +47,15 @@ func (r *MongoUserRepository) Create(ctx context.Context, user *model.User) erro
 	user.UpdatedAt = time.Now()
 
 	_, err := r.collection.InsertOne(ctx, user)
-	return err
+	return translateWriteError(err)
 }
 
 func (r *MongoUserRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*model.User, error) {
 	var user model.User
-	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
+	err := r.collection.FindOne(ctx, notDeleted(bson.M{"_id": id})).Decode(&user)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -73,10 +64,10 @@ func (r *MongoUserRepository) GetByID(ctx context.Context, id primitive.ObjectID
 
 func (r *MongoUserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
 	var user model.User
-	err := r.collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	err := r.collection.FindOne(ctx, notDeleted(bson.M{"email": email})).Decode(&user)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -85,24 +76,37 @@ func (r *MongoUserRepository) GetByEmail(ctx context.Context, email string) (*mo
 
 func (r *MongoUserRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
 	var user model.User
-	err := r.collection.FindOne(ctx, bson.M{"username": username}).Decode(&user)
+	err := r.collection.FindOne(ctx, notDeleted(bson.M{"username": username})).Decode(&user)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
 	return &user, nil
 }
 
+// Update saves a user's account details. Statistics, the last login and the
+// deletion schedule have their own updates and are left as stored, so saving
+// a user never undoes a concurrent change to them.
 func (r *MongoUserRepository) Update(ctx context.Context, user *model.User) error {
 	user.UpdatedAt = time.Now()
 
 	filter := bson.M{"_id": user.ID}
-	update := bson.M{"$set": user}
+	update := bson.M{"$set": bson.M{
+		"email":         user.Email,
+		"password_hash": user.PasswordHash,
+		"username":      user.Username,
+		"first_name":    user.FirstName,
+		"last_name":     user.LastName,
+		"timezone":      user.Timezone,
+		"role":          user.Role,
+		"preferences":   user.Preferences,
+		"updated_at":    user.UpdatedAt,
+	}}
 
 	_, err := r.collection.UpdateOne(ctx, filter, update)
-	return err
+	return translateWriteError(err)
 }
 
 func (r *MongoUserRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
@@ -110,6 +114,26 @@ func (r *MongoUserRepository) Delete(ctx context.Context, id primitive.ObjectID)
 	return err
 }
 
+// SoftDelete moves a user to the trash
+func (r *MongoUserRepository) SoftDelete(ctx context.Context, id, deletedBy primitive.ObjectID) error {
+	return softDelete(ctx, r.collection, id, deletedBy, ErrUserNotFound)
+}
+
+// Restore takes a user out of the trash
+func (r *MongoUserRepository) Restore(ctx context.Context, id primitive.ObjectID) error {
+	return restore(ctx, r.collection, id, ErrUserNotFound)
+}
+
+// GetDeleted lists the users moved to the trash before the given time
+func (r *MongoUserRepository) GetDeleted(ctx context.Context, before time.Time, page pagination.Params) ([]*model.User, error) {
+	return findDeleted[model.User](ctx, r.collection, before, page)
+}
+
+// CountDeleted counts the users in the trash
+func (r *MongoUserRepository) CountDeleted(ctx context.Context) (int64, error) {
+	return countDeleted(ctx, r.collection)
+}
+
 func (r *MongoUserRepository) UpdateLastLogin(ctx context.Context, id primitive.ObjectID) error {
 	filter := bson.M{"_id": id}
 	update := bson.M{"$set": bson.M{"last_login": time.Now()}}
@@ -118,6 +142,41 @@ func (r *MongoUserRepository) UpdateLastLogin(ctx context.Context, id primitive.
 	return err
 }
 
+// SetDeletionSchedule schedules the user's account for deletion at the given
+// time, or cancels a scheduled deletion when at is nil
+func (r *MongoUserRepository) SetDeletionSchedule(ctx context.Context, id primitive.ObjectID, at *time.Time) error {
+	update := bson.M{"$unset": bson.M{"deletion_scheduled_at": ""}}
+	if at != nil {
+		update = bson.M{"$set": bson.M{"deletion_scheduled_at": *at}}
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// GetDueForDeletion returns users whose scheduled deletion time is before the given time
+func (r *MongoUserRepository) GetDueForDeletion(ctx context.Context, before time.Time, limit int) ([]*model.User, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "deletion_scheduled_at", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, bson.M{"deletion_scheduled_at": bson.M{"$lte": before}}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []*model.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
 func (r *MongoUserRepository) UpdateStatistics(ctx context.Context, id primitive.ObjectID, stats model.UserStatistics) error {
 	filter := bson.M{"_id": id}
 	update := bson.M{"$set": bson.M{"statistics": stats}}
@@ -125,3 +184,164 @@ func (r *MongoUserRepository) UpdateStatistics(ctx context.Context, id primitive
 	_, err := r.collection.UpdateOne(ctx, filter, update)
 	return err
 }
+
+// SwapStatistics replaces the user's statistics only if they are still at the
+// expected revision, reporting whether the swap happened
+func (r *MongoUserRepository) SwapStatistics(ctx context.Context, id primitive.ObjectID, expectedRevision int64, stats model.UserStatistics) (bool, error) {
+	filter := bson.M{"_id": id, "statistics.revision": expectedRevision}
+	if expectedRevision == 0 {
+		// Statistics written before revisions were tracked have no revision field
+		filter["statistics.revision"] = bson.M{"$in": bson.A{0, nil}}
+	}
+	update := bson.M{"$set": bson.M{"statistics": stats}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// ApplyAttemptStatistics counts an attempt towards the user's statistics in a
+// single pipeline update, so concurrent attempts and account changes never
+// overwrite each other. The stages follow model.UserStatistics.ApplyAttempt;
+// _goal_step and _goal_gap are scratch fields removed by the last stage.
+func (r *MongoUserRepository) ApplyAttemptStatistics(ctx context.Context, id primitive.ObjectID, attempt model.StatisticsAttempt) error {
+	field := func(name string) bson.M { return bson.M{"$ifNull": bson.A{"$statistics." + name, 0}} }
+	correct := 0
+	if attempt.IsCorrect {
+		correct = 1
+	}
+
+	// Answer totals, with correct answers estimated for statistics from before they were counted
+	legacyCorrect := bson.M{"$toLong": bson.M{"$floor": bson.M{"$add": bson.A{
+		bson.M{"$divide": bson.A{bson.M{"$multiply": bson.A{field("total_exercises_completed"), field("average_accuracy")}}, 100}},
+		0.5,
+	}}}}
+	countTotals := bson.M{
+		"statistics.correct_exercises": bson.M{"$add": bson.A{
+			bson.M{"$cond": bson.A{
+				bson.M{"$and": bson.A{
+					bson.M{"$eq": bson.A{field("correct_exercises"), 0}},
+					bson.M{"$gt": bson.A{field("total_exercises_completed"), 0}},
+				}},
+				legacyCorrect,
+				field("correct_exercises"),
+			}},
+			correct,
+		}},
+		"statistics.total_exercises_completed": bson.M{"$add": bson.A{field("total_exercises_completed"), 1}},
+		"statistics.last_active":               bson.M{"$max": bson.A{"$statistics.last_active", attempt.Timestamp}},
+		"statistics.revision":                  bson.M{"$add": bson.A{field("revision"), 1}},
+		"statistics.daily_progress_count": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{"$statistics.daily_progress_date", attempt.Day}},
+			bson.M{"$add": bson.A{field("daily_progress_count"), 1}},
+			1,
+		}},
+		"statistics.daily_progress_date": attempt.Day,
+	}
+
+	// How the streak moves if this attempt meets the daily goal for the first time today
+	parseDay := func(day interface{}) bson.M {
+		return bson.M{"$dateFromString": bson.M{"dateString": day, "format": "%Y-%m-%d", "onError": nil, "onNull": nil}}
+	}
+	gap := bson.M{"$toLong": bson.M{"$divide": bson.A{
+		bson.M{"$subtract": bson.A{parseDay(attempt.Day), parseDay("$statistics.last_goal_date")}},
+		24 * 60 * 60 * 1000,
+	}}}
+	planStreak := bson.M{
+		"statistics.average_accuracy": bson.M{"$multiply": bson.A{
+			bson.M{"$divide": bson.A{"$statistics.correct_exercises", "$statistics.total_exercises_completed"}},
+			100,
+		}},
+		"_goal_gap": gap,
+		"_goal_step": bson.M{"$let": bson.M{
+			"vars": bson.M{"gap": gap},
+			"in": bson.M{"$switch": bson.M{
+				"branches": bson.A{
+					bson.M{"case": bson.M{"$or": bson.A{
+						bson.M{"$lt": bson.A{"$statistics.daily_progress_count", attempt.DailyGoal}},
+						bson.M{"$eq": bson.A{"$statistics.last_goal_date", attempt.Day}},
+					}}, "then": "none"},
+					bson.M{"case": bson.M{"$eq": bson.A{"$$gap", nil}}, "then": "reset"},
+					// The user moved to an earlier timezone; this day was already counted
+					bson.M{"case": bson.M{"$lt": bson.A{"$$gap", 1}}, "then": "none"},
+					bson.M{"case": bson.M{"$eq": bson.A{"$$gap", 1}}, "then": "continue"},
+					bson.M{"case": bson.M{"$lte": bson.A{bson.M{"$subtract": bson.A{"$$gap", 1}}, field("streak_freezes")}}, "then": "freeze"},
+				},
+				"default": "reset",
+			}},
+		}},
+	}
+
+	step := func(steps ...string) bson.M {
+		return bson.M{"$in": bson.A{"$_goal_step", steps}}
+	}
+	moveStreak := bson.M{
+		"statistics.streak_days": bson.M{"$switch": bson.M{
+			"branches": bson.A{
+				bson.M{"case": step("continue", "freeze"), "then": bson.M{"$add": bson.A{field("streak_days"), 1}}},
+				bson.M{"case": step("reset"), "then": 1},
+			},
+			"default": field("streak_days"),
+		}},
+		"statistics.streak_freezes": bson.M{"$cond": bson.A{
+			step("freeze"),
+			bson.M{"$subtract": bson.A{field("streak_freezes"), bson.M{"$subtract": bson.A{"$_goal_gap", 1}}}},
+			field("streak_freezes"),
+		}},
+		"statistics.last_goal_date": bson.M{"$cond": bson.A{step("none"), "$statistics.last_goal_date", attempt.Day}},
+	}
+
+	rewardStreak := bson.M{
+		"statistics.longest_streak": bson.M{"$max": bson.A{field("longest_streak"), "$statistics.streak_days"}},
+		"statistics.streak_freezes": bson.M{"$cond": bson.A{
+			bson.M{"$and": bson.A{
+				bson.M{"$not": bson.A{step("none")}},
+				bson.M{"$eq": bson.A{bson.M{"$mod": bson.A{"$statistics.streak_days", model.StreakFreezeInterval}}, 0}},
+				bson.M{"$lt": bson.A{"$statistics.streak_freezes", model.MaxStreakFreezes}},
+			}},
+			bson.M{"$add": bson.A{"$statistics.streak_freezes", 1}},
+			"$statistics.streak_freezes",
+		}},
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: countTotals}},
+		{{Key: "$set", Value: planStreak}},
+		{{Key: "$set", Value: moveStreak}},
+		{{Key: "$set", Value: rewardStreak}},
+		{{Key: "$unset", Value: bson.A{"_goal_step", "_goal_gap"}}},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, pipeline)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// ForEach calls fn for every user not in the trash, in ID order, stopping at the first error
+func (r *MongoUserRepository) ForEach(ctx context.Context, fn func(*model.User) error) error {
+	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, notDeleted(bson.M{}), findOptions)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var user model.User
+		if err := cursor.Decode(&user); err != nil {
+			return err
+		}
+		if err := fn(&user); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
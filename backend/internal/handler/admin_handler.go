@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/flutterninja9/mental-math-app/internal/auth"
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/service"
+	"github.com/flutterninja9/mental-math-app/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AdminHandler defines the handler for administrative endpoints
+type AdminHandler struct {
+	pathService  service.LearningPathService
+	trashService service.TrashService
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(pathService service.LearningPathService, trashService service.TrashService) *AdminHandler {
+	return &AdminHandler{
+		pathService:  pathService,
+		trashService: trashService,
+	}
+}
+
+// RegisterRoutes registers the admin routes
+func (h *AdminHandler) RegisterRoutes(router fiber.Router, authMiddleware fiber.Handler) {
+	admin := router.Group("/admin", authMiddleware, auth.RequireRole(model.RoleAdmin))
+
+	admin.Get("/integrity", h.CheckIntegrity)
+	admin.Delete("/users/:id", h.DeleteUser)
+
+	trash := admin.Group("/trash")
+	trash.Get("/exercises", h.ListDeletedExercises)
+	trash.Get("/paths", h.ListDeletedPaths)
+	trash.Get("/users", h.ListDeletedUsers)
+	trash.Post("/exercises/:id/restore", h.restore(h.trashService.RestoreExercise, "Exercise"))
+	trash.Post("/paths/:id/restore", h.restore(h.trashService.RestorePath, "Learning path"))
+	trash.Post("/users/:id/restore", h.restore(h.trashService.RestoreUser, "User"))
+}
+
+// CheckIntegrity reports learning path stages that reference missing or deleted exercises
+func (h *AdminHandler) CheckIntegrity(c *fiber.Ctx) error {
+	report, err := h.pathService.CheckIntegrity(c.Context())
+	if err != nil {
+		return utils.ServerErrorResponse(c, err)
+	}
+
+	return utils.SuccessResponse(c, report, "Integrity check completed", fiber.StatusOK)
+}
+
+// DeleteUser moves a user to the trash and signs them out
+func (h *AdminHandler) DeleteUser(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid user ID", fiber.StatusBadRequest)
+	}
+
+	adminID, _ := auth.GetUserID(c)
+	if id == adminID {
+		return utils.ErrorResponse(c, nil, "Administrators cannot delete their own account", fiber.StatusBadRequest)
+	}
+
+	if err := h.trashService.DeleteUser(c.Context(), id, adminID); err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			return utils.NotFoundResponse(c, "User not found")
+		}
+		return utils.ServerErrorResponse(c, err)
+	}
+
+	return utils.SuccessResponse(c, nil, "User deleted successfully", fiber.StatusOK)
+}
+
+// ListDeletedExercises returns the exercises in the trash with pagination
+func (h *AdminHandler) ListDeletedExercises(c *fiber.Ctx) error {
+	params, ok := pageParams(c)
+	if !ok {
+		return nil
+	}
+
+	page, err := h.trashService.ListExercises(c.Context(), params)
+	if err != nil {
+		return listError(c, err)
+	}
+
+	return pageResponse(c, page, "Deleted exercises retrieved successfully")
+}
+
+// ListDeletedPaths returns the learning paths in the trash with pagination
+func (h *AdminHandler) ListDeletedPaths(c *fiber.Ctx) error {
+	params, ok := pageParams(c)
+	if !ok {
+		return nil
+	}
+
+	page, err := h.trashService.ListPaths(c.Context(), params)
+	if err != nil {
+		return listError(c, err)
+	}
+
+	return pageResponse(c, page, "Deleted learning paths retrieved successfully")
+}
+
+// ListDeletedUsers returns the users in the trash with pagination
+func (h *AdminHandler) ListDeletedUsers(c *fiber.Ctx) error {
+	params, ok := pageParams(c)
+	if !ok {
+		return nil
+	}
+
+	page, err := h.trashService.ListUsers(c.Context(), params)
+	if err != nil {
+		return listError(c, err)
+	}
+
+	return pageResponse(c, page, "Deleted users retrieved successfully")
+}
+
+// restore returns a handler taking a record of the named kind out of the trash
+func (h *AdminHandler) restore(restore func(ctx context.Context, id primitive.ObjectID) error, kind string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return utils.ErrorResponse(c, nil, "Invalid "+strings.ToLower(kind)+" ID", fiber.StatusBadRequest)
+		}
+
+		if err := restore(c.Context(), id); err != nil {
+			var cycle *service.PrerequisiteCycleError
+			switch {
+			case errors.As(err, &cycle):
+				return utils.ErrorResponse(c, fiber.Map{"cycle": cycle.Cycle}, kind+" cannot be restored: its prerequisites would form a cycle", fiber.StatusConflict)
+			case errors.Is(err, service.ErrNotInTrash):
+				return utils.NotFoundResponse(c, kind+" not found in the trash")
+			case errors.Is(err, service.ErrRestoreConflict):
+				return utils.ErrorResponse(c, nil, kind+" cannot be restored: one outside the trash now has its email, username or external key", fiber.StatusConflict)
+			}
+			return utils.ServerErrorResponse(c, err)
+		}
+
+		return utils.SuccessResponse(c, nil, kind+" restored successfully", fiber.StatusOK)
+	}
+}
@@ -1,13 +1,18 @@
 package handler
 
 import (
-	"strconv"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"strings"
 	"time"
 
+	"github.com/flutterninja9/mental-math-app/internal/auth"
 	"github.com/flutterninja9/mental-math-app/internal/domain/model"
 	"github.com/flutterninja9/mental-math-app/internal/llm"
 	"github.com/flutterninja9/mental-math-app/internal/service"
+	"github.com/flutterninja9/mental-math-app/pkg/logger"
 	"github.com/flutterninja9/mental-math-app/pkg/utils"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -16,34 +21,42 @@ import (
 // ExerciseHandler defines the handler for exercise-related endpoints
 type ExerciseHandler struct {
 	exerciseService service.ExerciseService
+	transferService service.ExerciseTransferService
 	llmService      llm.Service
 	validator       *utils.CustomValidator
 }
 
 // NewExerciseHandler creates a new exercise handler
-func NewExerciseHandler(exerciseService service.ExerciseService, llmService llm.Service) *ExerciseHandler {
+func NewExerciseHandler(exerciseService service.ExerciseService, transferService service.ExerciseTransferService, llmService llm.Service) *ExerciseHandler {
 	return &ExerciseHandler{
 		exerciseService: exerciseService,
+		transferService: transferService,
 		llmService:      llmService,
 		validator:       utils.NewValidator(),
 	}
 }
 
 // RegisterRoutes registers the exercise routes
-func (h *ExerciseHandler) RegisterRoutes(router fiber.Router, authMiddleware fiber.Handler) {
+func (h *ExerciseHandler) RegisterRoutes(router fiber.Router, authMiddleware, optionalAuthMiddleware fiber.Handler) {
 	exercises := router.Group("/exercises")
 
-	// Public routes
-	exercises.Get("/:id", h.GetExercise)
-	exercises.Get("/category/:category", h.GetByCategory)
-	exercises.Get("/difficulty/:difficulty", h.GetByDifficulty)
-	exercises.Get("/tags", h.GetByTags)
-
-	// Protected routes
+	// Public routes; editors also see unpublished exercises
+	exercises.Get("/", optionalAuthMiddleware, h.SearchExercises)
+	exercises.Get("/export", optionalAuthMiddleware, h.ExportExercises)
+	exercises.Get("/:id", optionalAuthMiddleware, h.GetExercise)
+	exercises.Get("/:id/paths", optionalAuthMiddleware, h.GetReferencingPaths)
+	exercises.Get("/category/:category", optionalAuthMiddleware, h.GetByCategory)
+	exercises.Get("/difficulty/:difficulty", optionalAuthMiddleware, h.GetByDifficulty)
+	exercises.Get("/tags", optionalAuthMiddleware, h.GetByTags)
+
+	// Protected routes; anyone signed in can write a draft, but only editors
+	// change or delete existing exercises, which may already be published
 	protected := exercises.Use(authMiddleware)
+	editor := auth.RequireRole(model.RoleEditor, model.RoleAdmin)
 	protected.Post("/", h.CreateExercise)
-	protected.Put("/:id", h.UpdateExercise)
-	protected.Delete("/:id", h.DeleteExercise)
+	protected.Post("/import", editor, h.ImportExercises)
+	protected.Put("/:id", editor, h.UpdateExercise)
+	protected.Delete("/:id", editor, h.DeleteExercise)
 
 	// LLM-powered routes
 	protected.Post("/generate", h.GenerateExercise)
@@ -60,6 +73,7 @@ type CreateExerciseRequest struct {
 	Difficulty  string                `json:"difficulty" validate:"required,oneof=easy medium hard"`
 	Content     model.ExerciseContent `json:"content" validate:"required"`
 	Tags        []string              `json:"tags" validate:"omitempty"`
+	ChangeNote  string                `json:"change_note"`
 }
 
 // GetExercise returns a single exercise by ID
@@ -70,43 +84,250 @@ func (h *ExerciseHandler) GetExercise(c *fiber.Ctx) error {
 	}
 
 	exercise, err := h.exerciseService.GetByID(c.Context(), id)
-	if err != nil {
+	if err != nil || (!model.IsPublished(exercise.Status) && !canSeeUnpublished(c)) {
 		return utils.NotFoundResponse(c, "Exercise not found")
 	}
 
+	setETag(c, exercise.Revision)
 	return utils.SuccessResponse(c, exercise, "Exercise retrieved successfully", 0)
 }
 
+// SearchExercisesQuery defines the query parameters for searching exercises
+type SearchExercisesQuery struct {
+	Q           string `query:"q"`
+	Category    string `query:"category"`
+	Difficulty  string `query:"difficulty" validate:"omitempty,oneof=easy medium hard"`
+	Type        string `query:"type" validate:"omitempty,oneof=multiple_choice fill_in"`
+	Tags        string `query:"tags"`                                          // comma-separated
+	TagsMatch   string `query:"tags_match" validate:"omitempty,oneof=any all"` // any by default
+	GeneratedBy string `query:"generated_by"`
+	CreatedFrom string `query:"created_from"` // RFC 3339 timestamp or YYYY-MM-DD
+	CreatedTo   string `query:"created_to"`
+	Sort        string `query:"sort" validate:"omitempty,oneof=created_at -created_at title -title relevance"`
+}
+
+// SearchExercises returns exercises matching combined filters and an optional
+// text query, paginated by page or by cursor
+func (h *ExerciseHandler) SearchExercises(c *fiber.Ctx) error {
+	page, ok := pageParams(c)
+	if !ok {
+		return nil
+	}
+
+	query, ok := h.searchQuery(c)
+	if !ok {
+		return nil
+	}
+
+	result, err := h.exerciseService.Search(c.Context(), service.ExerciseSearch{ExerciseQuery: query, Page: page})
+	if err != nil {
+		if errors.Is(err, service.ErrRelevanceSort) {
+			return utils.ErrorResponse(c, nil, "Sorting by relevance requires q and page pagination", fiber.StatusBadRequest)
+		}
+		return listError(c, err)
+	}
+
+	return pageResponse(c, result, "Exercises retrieved successfully")
+}
+
+// searchQuery reads the search filters shared by listing and exporting. Invalid
+// parameters are answered with a bad request and ok is false.
+func (h *ExerciseHandler) searchQuery(c *fiber.Ctx) (search model.ExerciseQuery, ok bool) {
+	var query SearchExercisesQuery
+	if err := c.QueryParser(&query); err != nil {
+		utils.ErrorResponse(c, nil, "Invalid query parameters", fiber.StatusBadRequest)
+		return search, false
+	}
+
+	valErrors := h.validator.Validate(query)
+	if valErrors.HasErrors() {
+		utils.ValidationErrorResponse(c, valErrors)
+		return search, false
+	}
+
+	search.Text = strings.TrimSpace(query.Q)
+	search.Category = query.Category
+	search.Difficulty = query.Difficulty
+	search.Type = query.Type
+	search.MatchAllTags = query.TagsMatch == "all"
+	search.GeneratedBy = query.GeneratedBy
+	search.Sort = strings.TrimPrefix(query.Sort, "-")
+	search.Descending = strings.HasPrefix(query.Sort, "-")
+	search.IncludeUnpublished = canSeeUnpublished(c)
+
+	for _, tag := range strings.Split(query.Tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			search.Tags = append(search.Tags, tag)
+		}
+	}
+
+	var err error
+	if search.CreatedFrom, err = parseDateParam(query.CreatedFrom, false); err != nil {
+		utils.ErrorResponse(c, nil, "Invalid created_from date", fiber.StatusBadRequest)
+		return search, false
+	}
+	if search.CreatedTo, err = parseDateParam(query.CreatedTo, true); err != nil {
+		utils.ErrorResponse(c, nil, "Invalid created_to date", fiber.StatusBadRequest)
+		return search, false
+	}
+
+	return search, true
+}
+
+// ImportExercises creates or updates exercises from a JSON Lines, CSV, QTI or
+// GIFT upload. The format comes from the format query parameter or the
+// Content-Type header. With dry_run=true rows are only validated; with
+// upsert=true rows whose external_key already exists update that exercise
+// instead of failing. category and difficulty fill in rows without them.
+func (h *ExerciseHandler) ImportExercises(c *fiber.Ctx) error {
+	format := transferFormat(c.Query("format"), c.Get(fiber.HeaderContentType))
+	if format == "" {
+		return utils.ErrorResponse(c, nil, "Format must be jsonl, csv, qti or gift", fiber.StatusUnsupportedMediaType)
+	}
+
+	userID, _ := auth.GetUserID(c)
+	opts := service.ImportOptions{
+		AuthorID:   userID,
+		DryRun:     c.QueryBool("dry_run"),
+		Upsert:     c.QueryBool("upsert"),
+		Category:   c.Query("category"),
+		Difficulty: c.Query("difficulty"),
+	}
+
+	report, err := h.transferService.Import(c.Context(), format, bytes.NewReader(c.Body()), opts)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrTooManyRows):
+			return utils.ErrorResponse(c, nil, "Imports are limited to 5000 rows", fiber.StatusRequestEntityTooLarge)
+		case errors.Is(err, service.ErrMalformedImport):
+			return utils.ErrorResponse(c, nil, "Import file could not be read", fiber.StatusBadRequest)
+		}
+		return utils.ServerErrorResponse(c, err)
+	}
+
+	status := fiber.StatusOK
+	if report.Failed > 0 && report.Created+report.Updated == 0 && !report.DryRun {
+		status = fiber.StatusUnprocessableEntity
+	}
+	return utils.SuccessResponse(c, report, "Import processed", status)
+}
+
+// ExportExercises streams every exercise matching the search filters as JSON
+// Lines (the default), CSV, a QTI content package or GIFT
+func (h *ExerciseHandler) ExportExercises(c *fiber.Ctx) error {
+	format := strings.Clone(c.Query("format", service.TransferFormatJSONL))
+	file, ok := exportFiles[format]
+	if !ok {
+		return utils.ErrorResponse(c, nil, "Format must be jsonl, csv, qti or gift", fiber.StatusBadRequest)
+	}
+
+	query, ok := h.searchQuery(c)
+	if !ok {
+		return nil
+	}
+	query.Sort = ""
+
+	c.Set(fiber.HeaderContentType, file.contentType)
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+file.name+`"`)
+
+	// The stream is written after the handler returns, so it cannot use the request
+	// context, and the request details are copied because fiber reuses them
+	method, path, ip := c.Method(), strings.Clone(c.Path()), c.IP()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := h.transferService.Export(context.Background(), format, query, w); err != nil {
+			logger.Logger.Error().
+				Err(err).
+				Str("method", method).
+				Str("path", path).
+				Str("ip", ip).
+				Str("format", format).
+				Msg("Exercise export failed")
+		}
+		w.Flush()
+	})
+	return nil
+}
+
+// exportFiles gives the content type and file name of each export format
+var exportFiles = map[string]struct{ contentType, name string }{
+	service.TransferFormatJSONL: {"application/x-ndjson", "exercises.jsonl"},
+	service.TransferFormatCSV:   {"text/csv", "exercises.csv"},
+	service.TransferFormatQTI:   {"application/zip", "exercises-qti.zip"},
+	service.TransferFormatGIFT:  {"text/plain; charset=utf-8", "exercises.gift.txt"},
+}
+
+// transferFormat picks an import format from an explicit parameter or a content type
+func transferFormat(param, contentType string) string {
+	switch strings.ToLower(param) {
+	case service.TransferFormatJSONL, service.TransferFormatCSV, service.TransferFormatQTI, service.TransferFormatGIFT:
+		return strings.ToLower(param)
+	case "":
+	default:
+		return ""
+	}
+
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return service.TransferFormatCSV
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines", "application/json-lines":
+		return service.TransferFormatJSONL
+	case "application/zip", "application/xml", "text/xml":
+		return service.TransferFormatQTI
+	}
+	return ""
+}
+
+// parseDateParam parses an RFC 3339 timestamp or a YYYY-MM-DD date. A bare date
+// is taken as the start of the day, or its end when endOfDay is set.
+func parseDateParam(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t, nil
+}
+
 // GetByCategory returns exercises by category with pagination
 func (h *ExerciseHandler) GetByCategory(c *fiber.Ctx) error {
 	category := c.Params("category")
-	page, _ := strconv.Atoi(c.Query("page", "1"))
-	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+	page, ok := pageParams(c)
+	if !ok {
+		return nil
+	}
 
-	exercises, total, err := h.exerciseService.GetByCategory(c.Context(), category, page, limit)
+	exercises, err := h.exerciseService.GetByCategory(c.Context(), category, canSeeUnpublished(c), page)
 	if err != nil {
-		return utils.ServerErrorResponse(c, err)
+		return listError(c, err)
 	}
 
-	pagination := utils.NewPagination(total, limit, page)
-
-	return utils.PaginatedResponse(c, exercises, pagination, "Exercises retrieved successfully")
+	return pageResponse(c, exercises, "Exercises retrieved successfully")
 }
 
 // GetByDifficulty returns exercises by difficulty level with pagination
 func (h *ExerciseHandler) GetByDifficulty(c *fiber.Ctx) error {
 	difficulty := c.Params("difficulty")
-	page, _ := strconv.Atoi(c.Query("page", "1"))
-	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+	page, ok := pageParams(c)
+	if !ok {
+		return nil
+	}
 
-	exercises, total, err := h.exerciseService.GetByDifficulty(c.Context(), difficulty, page, limit)
+	exercises, err := h.exerciseService.GetByDifficulty(c.Context(), difficulty, canSeeUnpublished(c), page)
 	if err != nil {
-		return utils.ServerErrorResponse(c, err)
+		return listError(c, err)
 	}
 
-	pagination := utils.NewPagination(total, limit, page)
-
-	return utils.PaginatedResponse(c, exercises, pagination, "Exercises retrieved successfully")
+	return pageResponse(c, exercises, "Exercises retrieved successfully")
 }
 
 // GetByTags returns exercises that match the provided tags with pagination
@@ -117,17 +338,17 @@ func (h *ExerciseHandler) GetByTags(c *fiber.Ctx) error {
 	}
 
 	tags := strings.Split(tagsStr, ",")
-	page, _ := strconv.Atoi(c.Query("page", "1"))
-	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+	page, ok := pageParams(c)
+	if !ok {
+		return nil
+	}
 
-	exercises, total, err := h.exerciseService.GetByTags(c.Context(), tags, page, limit)
+	exercises, err := h.exerciseService.GetByTags(c.Context(), tags, canSeeUnpublished(c), page)
 	if err != nil {
-		return utils.ServerErrorResponse(c, err)
+		return listError(c, err)
 	}
 
-	pagination := utils.NewPagination(total, limit, page)
-
-	return utils.PaginatedResponse(c, exercises, pagination, "Exercises retrieved successfully")
+	return pageResponse(c, exercises, "Exercises retrieved successfully")
 }
 
 // CreateExercise creates a new exercise
@@ -156,7 +377,7 @@ func (h *ExerciseHandler) CreateExercise(c *fiber.Ctx) error {
 		},
 	}
 
-	if err := h.exerciseService.Create(c.Context(), exercise); err != nil {
+	if err := h.exerciseService.Create(c.Context(), exercise, revisionMeta(c, req.ChangeNote, "Created exercise")); err != nil {
 		return utils.ServerErrorResponse(c, err)
 	}
 
@@ -180,11 +401,19 @@ func (h *ExerciseHandler) UpdateExercise(c *fiber.Ctx) error {
 		return utils.ValidationErrorResponse(c, valErrors)
 	}
 
+	expectedRevision, ok := ifMatch(c)
+	if !ok {
+		return nil
+	}
+
 	// Get existing exercise
 	exercise, err := h.exerciseService.GetByID(c.Context(), id)
 	if err != nil {
 		return utils.NotFoundResponse(c, "Exercise not found")
 	}
+	if expectedRevision != model.AnyRevision && expectedRevision != exercise.Revision {
+		return conflictResponse(c)
+	}
 
 	// Update fields
 	exercise.Title = req.Title
@@ -195,14 +424,18 @@ func (h *ExerciseHandler) UpdateExercise(c *fiber.Ctx) error {
 	exercise.Content = req.Content
 	exercise.Tags = req.Tags
 
-	if err := h.exerciseService.Update(c.Context(), exercise); err != nil {
+	if err := h.exerciseService.Update(c.Context(), exercise, revisionMeta(c, req.ChangeNote, "Updated exercise")); err != nil {
+		if errors.Is(err, service.ErrVersionConflict) {
+			return conflictResponse(c)
+		}
 		return utils.ServerErrorResponse(c, err)
 	}
 
+	setETag(c, exercise.Revision)
 	return utils.SuccessResponse(c, exercise, "Exercise updated successfully", 0)
 }
 
-// DeleteExercise deletes an exercise
+// DeleteExercise moves an exercise to the trash
 func (h *ExerciseHandler) DeleteExercise(c *fiber.Ctx) error {
 	id, err := primitive.ObjectIDFromHex(c.Params("id"))
 	if err != nil {
@@ -214,13 +447,50 @@ func (h *ExerciseHandler) DeleteExercise(c *fiber.Ctx) error {
 		return utils.NotFoundResponse(c, "Exercise not found")
 	}
 
-	if err := h.exerciseService.Delete(c.Context(), id); err != nil {
+	userID, _ := auth.GetUserID(c)
+	if err := h.exerciseService.Delete(c.Context(), id, userID); err != nil {
+		if errors.Is(err, service.ErrExerciseInUse) {
+			paths, _ := h.exerciseService.GetReferencingPaths(c.Context(), id)
+			pathIDs := make([]string, 0, len(paths))
+			for _, path := range paths {
+				pathIDs = append(pathIDs, path.ID.Hex())
+			}
+			return utils.ErrorResponse(c, fiber.Map{"learning_paths": pathIDs}, "Exercise is used by learning paths", fiber.StatusConflict)
+		}
 		return utils.ServerErrorResponse(c, err)
 	}
 
 	return utils.SuccessResponse(c, nil, "Exercise deleted successfully", 0)
 }
 
+// GetReferencingPaths returns the learning paths that use an exercise. Like
+// GetExercise, only editors see unpublished exercises and paths.
+func (h *ExerciseHandler) GetReferencingPaths(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid exercise ID", fiber.StatusBadRequest)
+	}
+
+	unpublished := canSeeUnpublished(c)
+	exercise, err := h.exerciseService.GetByID(c.Context(), id)
+	if err != nil || (!model.IsPublished(exercise.Status) && !unpublished) {
+		return utils.NotFoundResponse(c, "Exercise not found")
+	}
+
+	paths, err := h.exerciseService.GetReferencingPaths(c.Context(), id)
+	if err != nil {
+		return utils.ServerErrorResponse(c, err)
+	}
+
+	visible := make([]*model.LearningPath, 0, len(paths))
+	for _, path := range paths {
+		if unpublished || model.IsPublished(path.Status) {
+			visible = append(visible, path)
+		}
+	}
+	return utils.SuccessResponse(c, visible, "Learning paths retrieved successfully", fiber.StatusOK)
+}
+
 // GenerateExerciseRequest defines the request structure for generating an exercise
 type GenerateExerciseRequest struct {
 	Category   string `json:"category" validate:"required"`
@@ -245,8 +515,9 @@ func (h *ExerciseHandler) GenerateExercise(c *fiber.Ctx) error {
 		return utils.ServerErrorResponse(c, err)
 	}
 
+	// Saved exercises start as drafts and go live once a reviewer approves them
 	if req.SaveToDb {
-		if err := h.exerciseService.Create(c.Context(), exercise); err != nil {
+		if err := h.exerciseService.Create(c.Context(), exercise, revisionMeta(c, "", "Generated exercise")); err != nil {
 			return utils.ServerErrorResponse(c, err)
 		}
 	}
@@ -281,7 +552,7 @@ func (h *ExerciseHandler) GenerateBatch(c *fiber.Ctx) error {
 
 	if req.SaveToDb {
 		for _, exercise := range exercises {
-			if err := h.exerciseService.Create(c.Context(), exercise); err != nil {
+			if err := h.exerciseService.Create(c.Context(), exercise, revisionMeta(c, "", "Generated exercise")); err != nil {
 				return utils.ServerErrorResponse(c, err)
 			}
 		}
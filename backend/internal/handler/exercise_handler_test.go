@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository/memory"
+	"github.com/flutterninja9/mental-math-app/internal/service"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// exerciseFixture is an API over memory repositories with a published and a
+// draft exercise, each used by a published and a draft path
+type exerciseFixture struct {
+	app              *fiber.App
+	published, draft *model.Exercise
+}
+
+func newExerciseFixture(t *testing.T) exerciseFixture {
+	t.Helper()
+	ctx := context.Background()
+	exercises := memory.NewExerciseRepository(nil)
+	paths := memory.NewLearningPathRepository(nil)
+	exerciseService := service.NewExerciseService(exercises, paths, "", nil)
+
+	f := exerciseFixture{
+		app:       fiber.New(),
+		published: &model.Exercise{Title: "2 + 2", Category: "addition", Difficulty: "easy", Status: model.StatusPublished},
+		draft:     &model.Exercise{Title: "3 + 3", Category: "addition", Difficulty: "easy", Status: model.StatusDraft},
+	}
+	for _, exercise := range []*model.Exercise{f.published, f.draft} {
+		if err := exercises.Create(ctx, exercise, model.RevisionMeta{}); err != nil {
+			t.Fatal(err)
+		}
+		for _, status := range []string{model.StatusPublished, model.StatusDraft} {
+			path := &model.LearningPath{
+				Title:  status + " path",
+				Status: status,
+				Stages: []model.PathStage{{ID: primitive.NewObjectID(), StageNumber: 1, ExerciseIDs: []primitive.ObjectID{exercise.ID}}},
+			}
+			if err := paths.Create(ctx, path, model.RevisionMeta{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	NewExerciseHandler(exerciseService, nil, nil).RegisterRoutes(f.app, testAuth(true), testAuth(false))
+	return f
+}
+
+func TestGetReferencingPathsHidesUnpublished(t *testing.T) {
+	f := newExerciseFixture(t)
+
+	tests := []struct {
+		name     string
+		exercise *model.Exercise
+		role     string
+		status   int
+		paths    int
+	}{
+		{"anonymous, published exercise", f.published, "", fiber.StatusOK, 1},
+		{"learner, published exercise", f.published, model.RoleUser, fiber.StatusOK, 1},
+		{"editor, published exercise", f.published, model.RoleEditor, fiber.StatusOK, 2},
+		{"anonymous, draft exercise", f.draft, "", fiber.StatusNotFound, 0},
+		{"learner, draft exercise", f.draft, model.RoleUser, fiber.StatusNotFound, 0},
+		{"admin, draft exercise", f.draft, model.RoleAdmin, fiber.StatusOK, 2},
+	}
+	for _, tt := range tests {
+		status, resp := request(t, f.app, fiber.MethodGet, "/exercises/"+tt.exercise.ID.Hex()+"/paths", tt.role, "")
+		if status != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, status, tt.status)
+			continue
+		}
+		paths, _ := resp.Data.([]interface{})
+		if len(paths) != tt.paths {
+			t.Errorf("%s: paths = %d, want %d", tt.name, len(paths), tt.paths)
+		}
+	}
+}
+
+func TestExerciseWritesRequireEditor(t *testing.T) {
+	f := newExerciseFixture(t)
+	target := "/exercises/" + f.published.ID.Hex()
+	update := `{"title": "Two plus two", "description": "Add", "type": "fill_in", "category": "addition",
+		"difficulty": "easy", "content": {"problem": "2 + 2 = ?", "correct_answer": "4"}}`
+
+	for _, method := range []string{fiber.MethodPut, fiber.MethodDelete} {
+		if status, _ := request(t, f.app, method, target, "", update); status != fiber.StatusUnauthorized {
+			t.Errorf("anonymous %s: status = %d, want 401", method, status)
+		}
+		if status, _ := request(t, f.app, method, target, model.RoleUser, update); status != fiber.StatusForbidden {
+			t.Errorf("learner %s: status = %d, want 403", method, status)
+		}
+	}
+
+	if status, resp := request(t, f.app, fiber.MethodPut, target, model.RoleEditor, update); status != fiber.StatusOK {
+		t.Errorf("editor PUT: status = %d, %s", status, resp.Message)
+	}
+}
@@ -1,10 +1,15 @@
 package handler
 
 import (
+	"bytes"
+	"errors"
 	"strconv"
+	"strings"
 
+	"github.com/flutterninja9/mental-math-app/internal/auth"
 	"github.com/flutterninja9/mental-math-app/internal/domain/model"
 	"github.com/flutterninja9/mental-math-app/internal/service"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
 	"github.com/flutterninja9/mental-math-app/pkg/utils"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -12,36 +17,73 @@ import (
 
 // LearningPathHandler defines the handler for learning path-related endpoints
 type LearningPathHandler struct {
-	pathService service.LearningPathService
-	validator   *utils.CustomValidator
+	pathService           service.LearningPathService
+	pathProgressService   service.PathProgressService
+	recommendationService service.RecommendationService
+	authoringService      service.PathAuthoringService
+	packageService        service.PathPackageService
+	validator             *utils.CustomValidator
+}
+
+// PathListing is a learning path together with whether the current user has unlocked it
+type PathListing struct {
+	*model.LearningPath
+	service.PathAccess
 }
 
 // NewLearningPathHandler creates a new learning path handler
-func NewLearningPathHandler(pathService service.LearningPathService) *LearningPathHandler {
+func NewLearningPathHandler(
+	pathService service.LearningPathService,
+	pathProgressService service.PathProgressService,
+	recommendationService service.RecommendationService,
+	authoringService service.PathAuthoringService,
+	packageService service.PathPackageService,
+) *LearningPathHandler {
 	return &LearningPathHandler{
-		pathService: pathService,
-		validator:   utils.NewValidator(),
+		pathService:           pathService,
+		pathProgressService:   pathProgressService,
+		recommendationService: recommendationService,
+		authoringService:      authoringService,
+		packageService:        packageService,
+		validator:             utils.NewValidator(),
 	}
 }
 
 // RegisterRoutes registers the learning path routes
-func (h *LearningPathHandler) RegisterRoutes(router fiber.Router, authMiddleware fiber.Handler) {
+func (h *LearningPathHandler) RegisterRoutes(router fiber.Router, authMiddleware, optionalAuthMiddleware fiber.Handler) {
 	paths := router.Group("/learning-paths")
 
-	// Public routes
-	paths.Get("/", h.GetAllPaths)
-	paths.Get("/:id", h.GetPath)
-	paths.Get("/difficulty/:difficulty", h.GetPathsByDifficulty)
-	paths.Get("/category/:category", h.GetPathsByCategory)
+	// Registered before /:id so it is not taken for a path ID
+	paths.Get("/recommended", authMiddleware, h.GetRecommendedPaths)
 
-	// Protected routes (Admin only in a real system)
+	// Public routes; authenticated users also see which paths are locked
+	paths.Get("/", optionalAuthMiddleware, h.GetAllPaths)
+	paths.Get("/:id", optionalAuthMiddleware, h.GetPath)
+	paths.Get("/difficulty/:difficulty", optionalAuthMiddleware, h.GetPathsByDifficulty)
+	paths.Get("/category/:category", optionalAuthMiddleware, h.GetPathsByCategory)
+
+	// Protected routes; anyone signed in can write a draft, but only editors
+	// change or delete existing paths, which may already be published
 	protected := paths.Use(authMiddleware)
+	editor := auth.RequireRole(model.RoleEditor, model.RoleAdmin)
 	protected.Post("/", h.CreatePath)
-	protected.Put("/:id", h.UpdatePath)
-	protected.Delete("/:id", h.DeletePath)
-	protected.Post("/:id/stages", h.AddStage)
-	protected.Put("/:id/stages/:stageNumber", h.UpdateStage)
-	protected.Delete("/:id/stages/:stageNumber", h.RemoveStage)
+	protected.Put("/:id", editor, h.UpdatePath)
+	protected.Delete("/:id", editor, h.DeletePath)
+	protected.Post("/:id/stages", editor, h.AddStage)
+	protected.Patch("/:id/stages/reorder", editor, h.ReorderStages)
+	protected.Put("/:id/stages/:stageNumber", editor, h.UpdateStage)
+	protected.Delete("/:id/stages/:stageNumber", editor, h.RemoveStage)
+	protected.Post("/:id/stages/:stageNumber/exercises", editor, h.AddStageExercise)
+	protected.Delete("/:id/stages/:stageNumber/exercises/:exerciseId", editor, h.RemoveStageExercise)
+
+	// Editor routes
+	protected.Post("/generate", editor, h.GeneratePath)
+	protected.Post("/import", editor, h.ImportPackage)
+	protected.Get("/:id/package", editor, h.ExportPackage)
+
+	// Learner routes
+	protected.Post("/:id/enroll", h.Enroll)
+	protected.Get("/:id/progress", h.GetProgress)
 }
 
 // CreatePathRequest defines the request structure for creating a learning path
@@ -51,6 +93,23 @@ type CreatePathRequest struct {
 	Difficulty  string            `json:"difficulty" validate:"required,oneof=easy medium hard"`
 	Categories  []string          `json:"categories" validate:"required,min=1"`
 	Stages      []model.PathStage `json:"stages" validate:"required,min=1"`
+
+	PrerequisitePathIDs []string `json:"prerequisite_path_ids"`
+	RequiredSkills      []string `json:"required_skills"`
+	ChangeNote          string   `json:"change_note"`
+}
+
+// prerequisiteIDs converts the prerequisite path IDs from strings to ObjectIDs
+func (r CreatePathRequest) prerequisiteIDs() ([]primitive.ObjectID, error) {
+	ids := make([]primitive.ObjectID, len(r.PrerequisitePathIDs))
+	for i, idStr := range r.PrerequisitePathIDs {
+		id, err := primitive.ObjectIDFromHex(idStr)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
 }
 
 // CreatePath creates a new learning path
@@ -65,17 +124,24 @@ func (h *LearningPathHandler) CreatePath(c *fiber.Ctx) error {
 		return utils.ValidationErrorResponse(c, valErrors)
 	}
 
+	prerequisiteIDs, err := req.prerequisiteIDs()
+	if err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid prerequisite path ID", fiber.StatusBadRequest)
+	}
+
 	// Create path object
 	path := &model.LearningPath{
-		Title:       req.Title,
-		Description: req.Description,
-		Difficulty:  req.Difficulty,
-		Categories:  req.Categories,
-		Stages:      req.Stages,
+		Title:               req.Title,
+		Description:         req.Description,
+		Difficulty:          req.Difficulty,
+		Categories:          req.Categories,
+		Stages:              req.Stages,
+		PrerequisitePathIDs: prerequisiteIDs,
+		RequiredSkills:      req.RequiredSkills,
 	}
 
-	if err := h.pathService.Create(c.Context(), path); err != nil {
-		return utils.ServerErrorResponse(c, err)
+	if err := h.pathService.Create(c.Context(), path, revisionMeta(c, req.ChangeNote, "Created learning path")); err != nil {
+		return pathWriteError(c, err)
 	}
 
 	return utils.SuccessResponse(c, path, "Learning path created successfully", fiber.StatusCreated)
@@ -83,15 +149,17 @@ func (h *LearningPathHandler) CreatePath(c *fiber.Ctx) error {
 
 // GetAllPaths returns all learning paths with pagination
 func (h *LearningPathHandler) GetAllPaths(c *fiber.Ctx) error {
-	limit, _ := strconv.Atoi(c.Query("limit", "10"))
-	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+	page, ok := pageParams(c)
+	if !ok {
+		return nil
+	}
 
-	paths, err := h.pathService.GetAll(c.Context(), limit, offset)
+	paths, err := h.pathService.GetAll(c.Context(), canSeeUnpublished(c), page)
 	if err != nil {
-		return utils.ServerErrorResponse(c, err)
+		return listError(c, err)
 	}
 
-	return utils.SuccessResponse(c, paths, "Learning paths retrieved successfully", fiber.StatusOK)
+	return h.pathListResponse(c, paths)
 }
 
 // GetPath returns a single learning path by ID
@@ -106,35 +174,45 @@ func (h *LearningPathHandler) GetPath(c *fiber.Ctx) error {
 		return utils.NotFoundResponse(c, "Learning path not found")
 	}
 
+	// Unpublished paths are only visible to editors
+	if !model.IsPublished(path.Status) && !canSeeUnpublished(c) {
+		return utils.NotFoundResponse(c, "Learning path not found")
+	}
+
+	setETag(c, path.Revision)
 	return utils.SuccessResponse(c, path, "Learning path retrieved successfully", fiber.StatusOK)
 }
 
 // GetPathsByDifficulty returns learning paths by difficulty level with pagination
 func (h *LearningPathHandler) GetPathsByDifficulty(c *fiber.Ctx) error {
 	difficulty := c.Params("difficulty")
-	limit, _ := strconv.Atoi(c.Query("limit", "10"))
-	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+	page, ok := pageParams(c)
+	if !ok {
+		return nil
+	}
 
-	paths, err := h.pathService.GetByDifficulty(c.Context(), difficulty, limit, offset)
+	paths, err := h.pathService.GetByDifficulty(c.Context(), difficulty, canSeeUnpublished(c), page)
 	if err != nil {
-		return utils.ServerErrorResponse(c, err)
+		return listError(c, err)
 	}
 
-	return utils.SuccessResponse(c, paths, "Learning paths retrieved successfully", fiber.StatusOK)
+	return h.pathListResponse(c, paths)
 }
 
 // GetPathsByCategory returns learning paths by category with pagination
 func (h *LearningPathHandler) GetPathsByCategory(c *fiber.Ctx) error {
 	category := c.Params("category")
-	limit, _ := strconv.Atoi(c.Query("limit", "10"))
-	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+	page, ok := pageParams(c)
+	if !ok {
+		return nil
+	}
 
-	paths, err := h.pathService.GetByCategory(c.Context(), category, limit, offset)
+	paths, err := h.pathService.GetByCategory(c.Context(), category, canSeeUnpublished(c), page)
 	if err != nil {
-		return utils.ServerErrorResponse(c, err)
+		return listError(c, err)
 	}
 
-	return utils.SuccessResponse(c, paths, "Learning paths retrieved successfully", fiber.StatusOK)
+	return h.pathListResponse(c, paths)
 }
 
 // UpdatePath updates an existing learning path
@@ -154,11 +232,24 @@ func (h *LearningPathHandler) UpdatePath(c *fiber.Ctx) error {
 		return utils.ValidationErrorResponse(c, valErrors)
 	}
 
+	prerequisiteIDs, err := req.prerequisiteIDs()
+	if err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid prerequisite path ID", fiber.StatusBadRequest)
+	}
+
+	expectedRevision, ok := ifMatch(c)
+	if !ok {
+		return nil
+	}
+
 	// Get existing path
 	path, err := h.pathService.GetByID(c.Context(), id)
 	if err != nil {
 		return utils.NotFoundResponse(c, "Learning path not found")
 	}
+	if expectedRevision != model.AnyRevision && expectedRevision != path.Revision {
+		return conflictResponse(c)
+	}
 
 	// Update fields
 	path.Title = req.Title
@@ -166,23 +257,27 @@ func (h *LearningPathHandler) UpdatePath(c *fiber.Ctx) error {
 	path.Difficulty = req.Difficulty
 	path.Categories = req.Categories
 	path.Stages = req.Stages
+	path.PrerequisitePathIDs = prerequisiteIDs
+	path.RequiredSkills = req.RequiredSkills
 
-	if err := h.pathService.Update(c.Context(), path); err != nil {
-		return utils.ServerErrorResponse(c, err)
+	if err := h.pathService.Update(c.Context(), path, revisionMeta(c, req.ChangeNote, "Updated learning path")); err != nil {
+		return pathWriteError(c, err)
 	}
 
+	setETag(c, path.Revision)
 	return utils.SuccessResponse(c, path, "Learning path updated successfully", fiber.StatusOK)
 }
 
-// DeletePath deletes a learning path
+// DeletePath moves a learning path to the trash
 func (h *LearningPathHandler) DeletePath(c *fiber.Ctx) error {
 	id, err := primitive.ObjectIDFromHex(c.Params("id"))
 	if err != nil {
 		return utils.ErrorResponse(c, nil, "Invalid learning path ID", fiber.StatusBadRequest)
 	}
 
-	if err := h.pathService.Delete(c.Context(), id); err != nil {
-		return utils.ServerErrorResponse(c, err)
+	userID, _ := auth.GetUserID(c)
+	if err := h.pathService.Delete(c.Context(), id, userID); err != nil {
+		return pathWriteError(c, err)
 	}
 
 	return utils.SuccessResponse(c, nil, "Learning path deleted successfully", fiber.StatusOK)
@@ -194,9 +289,11 @@ type AddStageRequest struct {
 	Description        string                   `json:"description" validate:"required"`
 	ExerciseIDs        []string                 `json:"exercise_ids" validate:"required,min=1"`
 	CompletionCriteria model.CompletionCriteria `json:"completion_criteria" validate:"required"`
+	Position           int                      `json:"position" validate:"omitempty,min=1"` // stage number to insert at; appended when omitted
+	ChangeNote         string                   `json:"change_note"`
 }
 
-// AddStage adds a new stage to a learning path
+// AddStage adds a new stage to a learning path, at the requested position or at the end
 func (h *LearningPathHandler) AddStage(c *fiber.Ctx) error {
 	id, err := primitive.ObjectIDFromHex(c.Params("id"))
 	if err != nil {
@@ -213,6 +310,11 @@ func (h *LearningPathHandler) AddStage(c *fiber.Ctx) error {
 		return utils.ValidationErrorResponse(c, valErrors)
 	}
 
+	expectedRevision, ok := ifMatch(c)
+	if !ok {
+		return nil
+	}
+
 	// Convert exercise IDs from strings to ObjectIDs
 	exerciseIDs := make([]primitive.ObjectID, len(req.ExerciseIDs))
 	for i, idStr := range req.ExerciseIDs {
@@ -230,16 +332,12 @@ func (h *LearningPathHandler) AddStage(c *fiber.Ctx) error {
 		CompletionCriteria: req.CompletionCriteria,
 	}
 
-	if err := h.pathService.AddStage(c.Context(), id, stage); err != nil {
-		return utils.ServerErrorResponse(c, err)
-	}
-
-	// Fetch updated path
-	path, err := h.pathService.GetByID(c.Context(), id)
+	path, err := h.pathService.AddStage(c.Context(), id, stage, req.Position, expectedRevision, revisionMeta(c, req.ChangeNote, "Added stage"))
 	if err != nil {
-		return utils.ServerErrorResponse(c, err)
+		return pathWriteError(c, err)
 	}
 
+	setETag(c, path.Revision)
 	return utils.SuccessResponse(c, path, "Stage added successfully", fiber.StatusOK)
 }
 
@@ -265,6 +363,11 @@ func (h *LearningPathHandler) UpdateStage(c *fiber.Ctx) error {
 		return utils.ValidationErrorResponse(c, valErrors)
 	}
 
+	expectedRevision, ok := ifMatch(c)
+	if !ok {
+		return nil
+	}
+
 	// Convert exercise IDs from strings to ObjectIDs
 	exerciseIDs := make([]primitive.ObjectID, len(req.ExerciseIDs))
 	for i, idStr := range req.ExerciseIDs {
@@ -283,16 +386,12 @@ func (h *LearningPathHandler) UpdateStage(c *fiber.Ctx) error {
 		CompletionCriteria: req.CompletionCriteria,
 	}
 
-	if err := h.pathService.UpdateStage(c.Context(), id, stage); err != nil {
-		return utils.ServerErrorResponse(c, err)
-	}
-
-	// Fetch updated path
-	path, err := h.pathService.GetByID(c.Context(), id)
+	path, err := h.pathService.UpdateStage(c.Context(), id, stage, expectedRevision, revisionMeta(c, req.ChangeNote, "Updated stage"))
 	if err != nil {
-		return utils.ServerErrorResponse(c, err)
+		return pathWriteError(c, err)
 	}
 
+	setETag(c, path.Revision)
 	return utils.SuccessResponse(c, path, "Stage updated successfully", fiber.StatusOK)
 }
 
@@ -308,15 +407,343 @@ func (h *LearningPathHandler) RemoveStage(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, nil, "Invalid stage number", fiber.StatusBadRequest)
 	}
 
-	if err := h.pathService.RemoveStage(c.Context(), id, stageNumber); err != nil {
-		return utils.ServerErrorResponse(c, err)
+	expectedRevision, ok := ifMatch(c)
+	if !ok {
+		return nil
+	}
+
+	path, err := h.pathService.RemoveStage(c.Context(), id, stageNumber, expectedRevision, revisionMeta(c, c.Query("change_note"), "Removed stage"))
+	if err != nil {
+		return pathWriteError(c, err)
+	}
+
+	setETag(c, path.Revision)
+	return utils.SuccessResponse(c, path, "Stage removed successfully", fiber.StatusOK)
+}
+
+// ReorderStagesRequest defines the request structure for reordering the stages of a learning path
+type ReorderStagesRequest struct {
+	Order      []int  `json:"order" validate:"required,min=1"` // current stage numbers in their new order
+	ChangeNote string `json:"change_note"`
+}
+
+// ReorderStages moves the stages of a learning path into a new order
+func (h *LearningPathHandler) ReorderStages(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid learning path ID", fiber.StatusBadRequest)
+	}
+
+	var req ReorderStagesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid request body", fiber.StatusBadRequest)
+	}
+
+	valErrors := h.validator.Validate(req)
+	if valErrors.HasErrors() {
+		return utils.ValidationErrorResponse(c, valErrors)
+	}
+
+	expectedRevision, ok := ifMatch(c)
+	if !ok {
+		return nil
+	}
+
+	path, err := h.pathService.ReorderStages(c.Context(), id, req.Order, expectedRevision, revisionMeta(c, req.ChangeNote, "Reordered stages"))
+	if err != nil {
+		return pathWriteError(c, err)
+	}
+
+	setETag(c, path.Revision)
+	return utils.SuccessResponse(c, path, "Stages reordered successfully", fiber.StatusOK)
+}
+
+// StageExerciseRequest defines the request structure for adding an exercise to a stage
+type StageExerciseRequest struct {
+	ExerciseID string `json:"exercise_id" validate:"required"`
+	ChangeNote string `json:"change_note"`
+}
+
+// AddStageExercise adds a single exercise to a stage
+func (h *LearningPathHandler) AddStageExercise(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid learning path ID", fiber.StatusBadRequest)
+	}
+
+	stageNumber, err := strconv.Atoi(c.Params("stageNumber"))
+	if err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid stage number", fiber.StatusBadRequest)
+	}
+
+	var req StageExerciseRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid request body", fiber.StatusBadRequest)
+	}
+
+	valErrors := h.validator.Validate(req)
+	if valErrors.HasErrors() {
+		return utils.ValidationErrorResponse(c, valErrors)
+	}
+
+	exerciseID, err := primitive.ObjectIDFromHex(req.ExerciseID)
+	if err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid exercise ID", fiber.StatusBadRequest)
+	}
+
+	expectedRevision, ok := ifMatch(c)
+	if !ok {
+		return nil
+	}
+
+	path, err := h.pathService.AddStageExercise(c.Context(), id, stageNumber, exerciseID, expectedRevision, revisionMeta(c, req.ChangeNote, "Added exercise to stage"))
+	if err != nil {
+		return pathWriteError(c, err)
+	}
+
+	setETag(c, path.Revision)
+	return utils.SuccessResponse(c, path, "Exercise added to stage successfully", fiber.StatusOK)
+}
+
+// RemoveStageExercise removes a single exercise from a stage
+func (h *LearningPathHandler) RemoveStageExercise(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid learning path ID", fiber.StatusBadRequest)
+	}
+
+	stageNumber, err := strconv.Atoi(c.Params("stageNumber"))
+	if err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid stage number", fiber.StatusBadRequest)
+	}
+
+	exerciseID, err := primitive.ObjectIDFromHex(c.Params("exerciseId"))
+	if err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid exercise ID", fiber.StatusBadRequest)
+	}
+
+	expectedRevision, ok := ifMatch(c)
+	if !ok {
+		return nil
+	}
+
+	path, err := h.pathService.RemoveStageExercise(c.Context(), id, stageNumber, exerciseID, expectedRevision, revisionMeta(c, c.Query("change_note"), "Removed exercise from stage"))
+	if err != nil {
+		return pathWriteError(c, err)
+	}
+
+	setETag(c, path.Revision)
+	return utils.SuccessResponse(c, path, "Exercise removed from stage successfully", fiber.StatusOK)
+}
+
+// Enroll enrolls the current user in a learning path
+func (h *LearningPathHandler) Enroll(c *fiber.Ctx) error {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		return utils.UnauthorizedResponse(c)
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid learning path ID", fiber.StatusBadRequest)
 	}
 
-	// Fetch updated path
 	path, err := h.pathService.GetByID(c.Context(), id)
+	if err != nil || !model.IsPublished(path.Status) {
+		return utils.NotFoundResponse(c, "Learning path not found")
+	}
+
+	progress, err := h.pathProgressService.Enroll(c.Context(), userID, id)
 	if err != nil {
 		return utils.ServerErrorResponse(c, err)
 	}
 
-	return utils.SuccessResponse(c, path, "Stage removed successfully", fiber.StatusOK)
+	return utils.SuccessResponse(c, progress, "Enrolled in learning path successfully", fiber.StatusCreated)
+}
+
+// GetProgress returns the current user's per-stage progress in a learning path
+func (h *LearningPathHandler) GetProgress(c *fiber.Ctx) error {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		return utils.UnauthorizedResponse(c)
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid learning path ID", fiber.StatusBadRequest)
+	}
+
+	if _, err := h.pathService.GetByID(c.Context(), id); err != nil {
+		return utils.NotFoundResponse(c, "Learning path not found")
+	}
+
+	progress, err := h.pathProgressService.GetProgress(c.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, service.ErrNotEnrolled) {
+			return utils.NotFoundResponse(c, "Not enrolled in this learning path")
+		}
+		return utils.ServerErrorResponse(c, err)
+	}
+
+	return utils.SuccessResponse(c, progress, "Learning path progress retrieved successfully", fiber.StatusOK)
+}
+
+// GeneratePathRequest defines the request structure for drafting a learning path with the LLM
+type GeneratePathRequest struct {
+	Goal       string `json:"goal" validate:"required"`
+	Difficulty string `json:"difficulty" validate:"required,oneof=easy medium hard"`
+	StageCount int    `json:"stage_count" validate:"required,min=1,max=10"`
+}
+
+// GeneratePath drafts a learning path for a goal using the LLM service
+func (h *LearningPathHandler) GeneratePath(c *fiber.Ctx) error {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		return utils.UnauthorizedResponse(c)
+	}
+
+	var req GeneratePathRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid request body", fiber.StatusBadRequest)
+	}
+
+	valErrors := h.validator.Validate(req)
+	if valErrors.HasErrors() {
+		return utils.ValidationErrorResponse(c, valErrors)
+	}
+
+	generated, err := h.authoringService.GenerateDraft(c.Context(), service.GeneratePathRequest{
+		AuthorID:   userID,
+		Goal:       req.Goal,
+		Difficulty: req.Difficulty,
+		StageCount: req.StageCount,
+	})
+	if errors.Is(err, service.ErrIncompleteDraft) {
+		return utils.ErrorResponse(c, nil, err.Error(), fiber.StatusBadGateway)
+	}
+	if err != nil {
+		return utils.ServerErrorResponse(c, err)
+	}
+
+	return utils.SuccessResponse(c, generated, "Draft learning path generated successfully", fiber.StatusCreated)
+}
+
+// ExportPackage downloads a learning path and its exercises as a package that
+// can be imported into another environment
+func (h *LearningPathHandler) ExportPackage(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid learning path ID", fiber.StatusBadRequest)
+	}
+
+	var buf bytes.Buffer
+	if err := h.packageService.Export(c.Context(), id, &buf); err != nil {
+		return pathWriteError(c, err)
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="learning-path-`+id.Hex()+`.zip"`)
+	return c.Send(buf.Bytes())
+}
+
+// ImportPackage creates a draft learning path from an uploaded package. With
+// dry_run=true it only reports which exercises would be created or reused.
+func (h *LearningPathHandler) ImportPackage(c *fiber.Ctx) error {
+	userID, _ := auth.GetUserID(c)
+	opts := service.PackageImportOptions{AuthorID: userID, DryRun: c.QueryBool("dry_run")}
+
+	result, err := h.packageService.Import(c.Context(), bytes.NewReader(c.Body()), opts)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPackage) {
+			reason := strings.TrimPrefix(err.Error(), service.ErrInvalidPackage.Error()+": ")
+			return utils.ErrorResponse(c, fiber.Map{"reason": reason}, "Invalid learning path package", fiber.StatusBadRequest)
+		}
+		return pathWriteError(c, err)
+	}
+
+	if result.DryRun {
+		return utils.SuccessResponse(c, result, "Learning path package checked", 0)
+	}
+	return utils.SuccessResponse(c, result, "Learning path package imported successfully", fiber.StatusCreated)
+}
+
+// GetRecommendedPaths suggests the unlocked learning paths the current user should take next
+func (h *LearningPathHandler) GetRecommendedPaths(c *fiber.Ctx) error {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		return utils.UnauthorizedResponse(c)
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit", "3"))
+
+	recommendations, err := h.recommendationService.RecommendPaths(c.Context(), userID, limit)
+	if err != nil {
+		return utils.ServerErrorResponse(c, err)
+	}
+
+	return utils.SuccessResponse(c, recommendations, "Recommended learning paths retrieved successfully", fiber.StatusOK)
+}
+
+// pathListResponse returns a page of paths, marking locked paths when the user is authenticated
+func (h *LearningPathHandler) pathListResponse(c *fiber.Ctx, page pagination.Page[*model.LearningPath]) error {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		return pageResponse(c, page, "Learning paths retrieved successfully")
+	}
+
+	access, err := h.recommendationService.GetPathAccess(c.Context(), userID, page.Items)
+	if err != nil {
+		return utils.ServerErrorResponse(c, err)
+	}
+
+	listings := make([]PathListing, len(page.Items))
+	for i, path := range page.Items {
+		listings[i] = PathListing{LearningPath: path, PathAccess: access[path.ID]}
+	}
+
+	return utils.PaginatedResponse(c, listings, page.Meta, "Learning paths retrieved successfully")
+}
+
+// pathWriteError converts errors from learning path writes into responses
+func pathWriteError(c *fiber.Ctx, err error) error {
+	var missing *service.MissingExercisesError
+	if errors.As(err, &missing) {
+		ids := make([]string, len(missing.IDs))
+		for i, id := range missing.IDs {
+			ids[i] = id.Hex()
+		}
+		return utils.ErrorResponse(c, fiber.Map{"exercise_ids": ids}, "Learning path references unknown exercises", fiber.StatusUnprocessableEntity)
+	}
+	var cycle *service.PrerequisiteCycleError
+	if errors.As(err, &cycle) {
+		return utils.ErrorResponse(c, fiber.Map{"cycle": cycle.Cycle}, "Prerequisites would form a cycle", fiber.StatusUnprocessableEntity)
+	}
+	if errors.Is(err, service.ErrVersionConflict) {
+		return conflictResponse(c)
+	}
+	if errors.Is(err, service.ErrUnknownPrerequisite) {
+		return utils.ErrorResponse(c, nil, "Learning path references an unknown prerequisite path", fiber.StatusUnprocessableEntity)
+	}
+	if errors.Is(err, service.ErrUnknownStage) {
+		return utils.ErrorResponse(c, nil, "Stage IDs must name stages of the learning path, each at most once", fiber.StatusUnprocessableEntity)
+	}
+	if errors.Is(err, service.ErrInvalidStageOrder) {
+		return utils.ErrorResponse(c, nil, "Stage order must list every stage exactly once", fiber.StatusUnprocessableEntity)
+	}
+	if errors.Is(err, service.ErrLastStageExercise) {
+		return utils.ErrorResponse(c, nil, "A stage must keep at least one exercise", fiber.StatusUnprocessableEntity)
+	}
+	if errors.Is(err, service.ErrExerciseInStage) {
+		return utils.ErrorResponse(c, nil, "Exercise is already in the stage", fiber.StatusConflict)
+	}
+	if errors.Is(err, service.ErrExerciseNotInStage) {
+		return utils.NotFoundResponse(c, "Exercise is not in the stage")
+	}
+	switch err.Error() {
+	case "stage not found":
+		return utils.NotFoundResponse(c, "Stage not found")
+	case "learning path not found":
+		return utils.NotFoundResponse(c, "Learning path not found")
+	}
+	return utils.ServerErrorResponse(c, err)
 }
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository/memory"
+	"github.com/flutterninja9/mental-math-app/internal/service"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPathWritesRequireEditor(t *testing.T) {
+	ctx := context.Background()
+	exercises := memory.NewExerciseRepository(nil)
+	pathService := service.NewLearningPathService(memory.NewLearningPathRepository(nil), exercises, nil)
+
+	exercise := &model.Exercise{Title: "2 + 2", Category: "addition", Difficulty: "easy", Status: model.StatusPublished}
+	if err := exercises.Create(ctx, exercise, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+	path := &model.LearningPath{
+		Title:  "Addition",
+		Status: model.StatusPublished,
+		Stages: []model.PathStage{{Title: "Sums", ExerciseIDs: []primitive.ObjectID{exercise.ID}}},
+	}
+	if err := pathService.Create(ctx, path, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	NewLearningPathHandler(pathService, nil, nil, nil, nil).RegisterRoutes(app, testAuth(true), testAuth(false))
+
+	base := "/learning-paths/" + path.ID.Hex()
+	stage := `{"title": "More sums", "description": "Longer sums", "completion_criteria": {"min_accuracy": 80},
+		"exercise_ids": ["` + exercise.ID.Hex() + `"]}`
+	routes := []struct{ method, target, body string }{
+		{fiber.MethodPut, base, ""},
+		{fiber.MethodDelete, base, ""},
+		{fiber.MethodPost, base + "/stages", stage},
+		{fiber.MethodPatch, base + "/stages/reorder", `{"order": [1]}`},
+		{fiber.MethodPut, base + "/stages/1", stage},
+		{fiber.MethodDelete, base + "/stages/1", ""},
+		{fiber.MethodPost, base + "/stages/1/exercises", `{"exercise_id": "` + exercise.ID.Hex() + `"}`},
+		{fiber.MethodDelete, base + "/stages/1/exercises/" + exercise.ID.Hex(), ""},
+	}
+	for _, route := range routes {
+		if status, _ := request(t, app, route.method, route.target, model.RoleUser, route.body); status != fiber.StatusForbidden {
+			t.Errorf("learner %s %s: status = %d, want 403", route.method, route.target, status)
+		}
+	}
+
+	// Editors get through to the handler
+	status, resp := request(t, app, fiber.MethodPost, base+"/stages", model.RoleEditor, stage)
+	if status != fiber.StatusOK {
+		t.Errorf("editor adding a stage: status = %d, %s", status, resp.Message)
+	}
+}
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"github.com/flutterninja9/mental-math-app/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// pageParams reads the pagination parameters of a list request. An invalid
+// cursor is answered with a bad request and ok is false.
+func pageParams(c *fiber.Ctx) (params pagination.Params, ok bool) {
+	params, err := pagination.FromQuery(c)
+	if err != nil {
+		utils.ErrorResponse(c, nil, "Invalid cursor", fiber.StatusBadRequest)
+		return params, false
+	}
+	return params, true
+}
+
+// pageResponse returns one page of a list with its pagination metadata
+func pageResponse[T any](c *fiber.Ctx, page pagination.Page[T], message string) error {
+	return utils.PaginatedResponse(c, page.Items, page.Meta, message)
+}
+
+// listError converts errors from list requests into responses
+func listError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		return utils.ErrorResponse(c, nil, "Invalid cursor", fiber.StatusBadRequest)
+	}
+	return utils.ServerErrorResponse(c, err)
+}
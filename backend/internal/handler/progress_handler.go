@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"errors"
 	"strconv"
+	"time"
 
 	"github.com/flutterninja9/mental-math-app/internal/auth"
 	"github.com/flutterninja9/mental-math-app/internal/service"
@@ -33,6 +35,8 @@ func (h *ProgressHandler) RegisterRoutes(router fiber.Router, authMiddleware fib
 	progress.Get("/exercise/:exerciseID", h.GetProgressForExercise)
 	progress.Post("/record", h.RecordAttempt)
 	progress.Get("/performance", h.GetRecentPerformance)
+	progress.Get("/streak", h.GetStreak)
+	progress.Get("/analytics", h.GetAnalytics)
 }
 
 // RecordAttemptRequest defines the request structure for recording an attempt
@@ -41,6 +45,9 @@ type RecordAttemptRequest struct {
 	UserAnswer string `json:"user_answer" validate:"required"`
 	IsCorrect  bool   `json:"is_correct"`
 	TimeTaken  int    `json:"time_taken" validate:"required,min=1"` // in seconds
+
+	// ExerciseRevision is the revision the learner was shown; the current one when omitted
+	ExerciseRevision int `json:"exercise_revision" validate:"omitempty,min=1"`
 }
 
 // RecordAttempt records a user's attempt at an exercise
@@ -69,6 +76,7 @@ func (h *ProgressHandler) RecordAttempt(c *fiber.Ctx) error {
 		c.Context(),
 		userID,
 		exerciseID,
+		req.ExerciseRevision,
 		req.UserAnswer,
 		req.IsCorrect,
 		req.TimeTaken,
@@ -80,19 +88,24 @@ func (h *ProgressHandler) RecordAttempt(c *fiber.Ctx) error {
 	return utils.SuccessResponse(c, nil, "Attempt recorded successfully", fiber.StatusCreated)
 }
 
-// GetUserProgress returns all progress records for the current user
+// GetUserProgress returns a page of the current user's progress records
 func (h *ProgressHandler) GetUserProgress(c *fiber.Ctx) error {
 	userID, ok := auth.GetUserID(c)
 	if !ok {
 		return utils.UnauthorizedResponse(c)
 	}
 
-	progress, err := h.progressService.GetUserProgress(c.Context(), userID)
+	page, ok := pageParams(c)
+	if !ok {
+		return nil
+	}
+
+	progress, err := h.progressService.GetUserProgress(c.Context(), userID, page)
 	if err != nil {
-		return utils.ServerErrorResponse(c, err)
+		return listError(c, err)
 	}
 
-	return utils.SuccessResponse(c, progress, "Progress retrieved successfully", fiber.StatusOK)
+	return pageResponse(c, progress, "Progress retrieved successfully")
 }
 
 // GetProgressForExercise returns a user's progress for a specific exercise
@@ -136,3 +149,67 @@ func (h *ProgressHandler) GetRecentPerformance(c *fiber.Ctx) error {
 
 	return utils.SuccessResponse(c, performance, "Recent performance retrieved successfully", fiber.StatusOK)
 }
+
+// GetStreak returns the user's current and longest streak along with today's goal progress
+func (h *ProgressHandler) GetStreak(c *fiber.Ctx) error {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		return utils.UnauthorizedResponse(c)
+	}
+
+	streak, err := h.progressService.GetStreak(c.Context(), userID)
+	if err != nil {
+		return utils.NotFoundResponse(c, "User not found")
+	}
+
+	return utils.SuccessResponse(c, streak, "Streak retrieved successfully", fiber.StatusOK)
+}
+
+// GetAnalytics returns a performance report with time series and breakdowns.
+// Supports from/to (RFC3339 or YYYY-MM-DD) and granularity (day, week, month) query parameters.
+// A to date includes the whole day.
+func (h *ProgressHandler) GetAnalytics(c *fiber.Ctx) error {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		return utils.UnauthorizedResponse(c)
+	}
+
+	from, err := parseTimeQuery(c.Query("from"), false)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.Map{"from": "Must be RFC3339 or YYYY-MM-DD"}, "Invalid query parameters", fiber.StatusBadRequest)
+	}
+	to, err := parseTimeQuery(c.Query("to"), true)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.Map{"to": "Must be RFC3339 or YYYY-MM-DD"}, "Invalid query parameters", fiber.StatusBadRequest)
+	}
+
+	report, err := h.progressService.GetPerformanceReport(c.Context(), userID, from, to, c.Query("granularity"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidGranularity) || errors.Is(err, service.ErrInvalidReportRange) {
+			return utils.ErrorResponse(c, nil, err.Error(), fiber.StatusBadRequest)
+		}
+		return utils.ServerErrorResponse(c, err)
+	}
+
+	return utils.SuccessResponse(c, report, "Analytics retrieved successfully", fiber.StatusOK)
+}
+
+// parseTimeQuery parses an optional RFC3339 timestamp or YYYY-MM-DD date. Range
+// ends are exclusive, so with endOfDay set a date is moved to the start of the
+// next day to include all of it.
+func parseTimeQuery(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		date = date.AddDate(0, 0, 1)
+	}
+	return date, nil
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeQuery(t *testing.T) {
+	tests := []struct {
+		value    string
+		endOfDay bool
+		want     time.Time
+	}{
+		{"", true, time.Time{}},
+		{"2026-03-01", false, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"2026-03-01", true, time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{"2026-03-01T12:30:00Z", true, time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := parseTimeQuery(tt.value, tt.endOfDay)
+		if err != nil {
+			t.Fatalf("parseTimeQuery(%q, %v): %v", tt.value, tt.endOfDay, err)
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseTimeQuery(%q, %v) = %v, want %v", tt.value, tt.endOfDay, got, tt.want)
+		}
+	}
+
+	if _, err := parseTimeQuery("yesterday", false); err == nil {
+		t.Error("parseTimeQuery accepted an invalid date")
+	}
+}
@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/flutterninja9/mental-math-app/internal/auth"
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/service"
+	"github.com/flutterninja9/mental-math-app/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// contentKinds maps the kind segment of review URLs to content kinds
+var contentKinds = map[string]string{
+	"exercises":      model.ContentKindExercise,
+	"learning-paths": model.ContentKindLearningPath,
+}
+
+// ReviewHandler defines the handler for the content review workflow
+type ReviewHandler struct {
+	reviewService service.ReviewService
+	validator     *utils.CustomValidator
+}
+
+// NewReviewHandler creates a new review handler
+func NewReviewHandler(reviewService service.ReviewService) *ReviewHandler {
+	return &ReviewHandler{
+		reviewService: reviewService,
+		validator:     utils.NewValidator(),
+	}
+}
+
+// RegisterRoutes registers the review routes
+func (h *ReviewHandler) RegisterRoutes(router fiber.Router, authMiddleware fiber.Handler) {
+	review := router.Group("/review", authMiddleware)
+	editor := auth.RequireRole(model.RoleEditor, model.RoleAdmin)
+
+	// Authors submit their drafts and discuss them with reviewers
+	review.Post("/:kind/:id/submit", h.Submit)
+	review.Post("/:kind/:id/comments", h.Comment)
+
+	// Editor routes
+	review.Get("/:kind", editor, h.GetQueue)
+	review.Post("/:kind/:id/assign", editor, h.AssignReviewer)
+	review.Post("/:kind/:id/approve", editor, h.Approve)
+	review.Post("/:kind/:id/request-changes", editor, h.RequestChanges)
+	review.Post("/:kind/:id/archive", editor, h.Archive)
+	review.Post("/:kind/:id/reopen", editor, h.Reopen)
+}
+
+// AssignReviewerRequest defines the request structure for assigning a reviewer
+type AssignReviewerRequest struct {
+	ReviewerID string `json:"reviewer_id" validate:"required"`
+}
+
+// ReviewCommentRequest defines the request structure for review comments
+type ReviewCommentRequest struct {
+	Body string `json:"body" validate:"required"`
+}
+
+// GetQueue returns the content of a kind waiting for review
+func (h *ReviewHandler) GetQueue(c *fiber.Ctx) error {
+	kind, ok := contentKinds[c.Params("kind")]
+	if !ok {
+		return utils.NotFoundResponse(c, "Unknown content kind")
+	}
+
+	page, ok := pageParams(c)
+	if !ok {
+		return nil
+	}
+
+	items, err := h.reviewService.GetQueue(c.Context(), kind, page)
+	if err != nil {
+		return listError(c, err)
+	}
+
+	return pageResponse(c, items, "Review queue retrieved successfully")
+}
+
+// Submit sends a draft for review
+func (h *ReviewHandler) Submit(c *fiber.Ctx) error {
+	userID, kind, id, ok := h.reviewParams(c)
+	if !ok {
+		return nil
+	}
+
+	item, err := h.reviewService.Submit(c.Context(), kind, id, userID)
+	return reviewResponse(c, item, err, "Submitted for review successfully")
+}
+
+// AssignReviewer assigns an editor to review content
+func (h *ReviewHandler) AssignReviewer(c *fiber.Ctx) error {
+	userID, kind, id, ok := h.reviewParams(c)
+	if !ok {
+		return nil
+	}
+
+	var req AssignReviewerRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid request body", fiber.StatusBadRequest)
+	}
+
+	valErrors := h.validator.Validate(req)
+	if valErrors.HasErrors() {
+		return utils.ValidationErrorResponse(c, valErrors)
+	}
+
+	reviewerID, err := primitive.ObjectIDFromHex(req.ReviewerID)
+	if err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid reviewer ID", fiber.StatusBadRequest)
+	}
+
+	item, err := h.reviewService.AssignReviewer(c.Context(), kind, id, userID, reviewerID)
+	if err != nil && err.Error() == "user not found" {
+		return utils.NotFoundResponse(c, "Reviewer not found")
+	}
+	return reviewResponse(c, item, err, "Reviewer assigned successfully")
+}
+
+// Comment adds a review comment
+func (h *ReviewHandler) Comment(c *fiber.Ctx) error {
+	userID, kind, id, ok := h.reviewParams(c)
+	if !ok {
+		return nil
+	}
+
+	var req ReviewCommentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid request body", fiber.StatusBadRequest)
+	}
+
+	valErrors := h.validator.Validate(req)
+	if valErrors.HasErrors() {
+		return utils.ValidationErrorResponse(c, valErrors)
+	}
+
+	item, err := h.reviewService.Comment(c.Context(), kind, id, userID, req.Body)
+	return reviewResponse(c, item, err, "Comment added successfully")
+}
+
+// Approve publishes content that is in review
+func (h *ReviewHandler) Approve(c *fiber.Ctx) error {
+	userID, kind, id, ok := h.reviewParams(c)
+	if !ok {
+		return nil
+	}
+
+	item, err := h.reviewService.Approve(c.Context(), kind, id, userID, auth.HasRole(c, model.RoleAdmin))
+	return reviewResponse(c, item, err, "Content approved successfully")
+}
+
+// RequestChanges sends content back to its author
+func (h *ReviewHandler) RequestChanges(c *fiber.Ctx) error {
+	userID, kind, id, ok := h.reviewParams(c)
+	if !ok {
+		return nil
+	}
+
+	var req ReviewCommentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid request body", fiber.StatusBadRequest)
+	}
+
+	valErrors := h.validator.Validate(req)
+	if valErrors.HasErrors() {
+		return utils.ValidationErrorResponse(c, valErrors)
+	}
+
+	item, err := h.reviewService.RequestChanges(c.Context(), kind, id, userID, req.Body)
+	return reviewResponse(c, item, err, "Changes requested successfully")
+}
+
+// Archive retires content
+func (h *ReviewHandler) Archive(c *fiber.Ctx) error {
+	userID, kind, id, ok := h.reviewParams(c)
+	if !ok {
+		return nil
+	}
+
+	item, err := h.reviewService.Archive(c.Context(), kind, id, userID)
+	return reviewResponse(c, item, err, "Content archived successfully")
+}
+
+// Reopen moves archived content back to draft
+func (h *ReviewHandler) Reopen(c *fiber.Ctx) error {
+	userID, kind, id, ok := h.reviewParams(c)
+	if !ok {
+		return nil
+	}
+
+	item, err := h.reviewService.Reopen(c.Context(), kind, id, userID)
+	return reviewResponse(c, item, err, "Content reopened successfully")
+}
+
+// reviewParams extracts the current user, content kind and content ID from a review
+// request. When it returns false the error response has already been written.
+func (h *ReviewHandler) reviewParams(c *fiber.Ctx) (primitive.ObjectID, string, primitive.ObjectID, bool) {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		utils.UnauthorizedResponse(c)
+		return primitive.NilObjectID, "", primitive.NilObjectID, false
+	}
+
+	kind, ok := contentKinds[c.Params("kind")]
+	if !ok {
+		utils.NotFoundResponse(c, "Unknown content kind")
+		return primitive.NilObjectID, "", primitive.NilObjectID, false
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		utils.ErrorResponse(c, nil, "Invalid content ID", fiber.StatusBadRequest)
+		return primitive.NilObjectID, "", primitive.NilObjectID, false
+	}
+
+	return userID, kind, id, true
+}
+
+// reviewResponse converts the result of a review action into a response
+func reviewResponse(c *fiber.Ctx, item *service.ReviewItem, err error, message string) error {
+	if err == nil {
+		return utils.SuccessResponse(c, item, message, fiber.StatusOK)
+	}
+
+	var missing *service.MissingExercisesError
+	switch {
+	case errors.Is(err, service.ErrInvalidTransition):
+		return utils.ErrorResponse(c, nil, "Action not allowed in the current status", fiber.StatusConflict)
+	case errors.Is(err, service.ErrNotAssignedReviewer):
+		return utils.ErrorResponse(c, nil, "Content is assigned to another reviewer", fiber.StatusForbidden)
+	case errors.Is(err, service.ErrInvalidReviewer):
+		return utils.ErrorResponse(c, nil, "Reviewer must be an editor or admin", fiber.StatusUnprocessableEntity)
+	case errors.As(err, &missing):
+		return pathWriteError(c, err)
+	case errors.Is(err, service.ErrVersionConflict):
+		return conflictResponse(c)
+	case err.Error() == "exercise not found" || err.Error() == "learning path not found":
+		return utils.NotFoundResponse(c, "Content not found")
+	}
+	return utils.ServerErrorResponse(c, err)
+}
+
+// canSeeUnpublished reports whether the current user may see content that is not published
+func canSeeUnpublished(c *fiber.Ctx) bool {
+	return auth.HasRole(c, model.RoleEditor, model.RoleAdmin)
+}
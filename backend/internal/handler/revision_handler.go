@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/flutterninja9/mental-math-app/internal/auth"
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/service"
+	"github.com/flutterninja9/mental-math-app/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RevisionHandler defines the handler for the revision history of exercises and learning paths
+type RevisionHandler struct {
+	revisionService service.RevisionService
+}
+
+// NewRevisionHandler creates a new revision handler
+func NewRevisionHandler(revisionService service.RevisionService) *RevisionHandler {
+	return &RevisionHandler{
+		revisionService: revisionService,
+	}
+}
+
+// RegisterRoutes registers the revision routes under each kind of content
+func (h *RevisionHandler) RegisterRoutes(router fiber.Router, authMiddleware fiber.Handler) {
+	for segment, kind := range contentKinds {
+		revisions := router.Group("/"+segment+"/:id/revisions", authMiddleware, auth.RequireRole(model.RoleEditor, model.RoleAdmin), withContentKind(kind))
+
+		revisions.Get("/", h.ListRevisions)
+		revisions.Get("/diff", h.DiffRevisions)
+		revisions.Get("/:number", h.GetRevision)
+		revisions.Post("/:number/restore", h.RestoreRevision)
+	}
+}
+
+// RestoreRevisionRequest defines the request structure for restoring a revision
+type RestoreRevisionRequest struct {
+	ChangeNote string `json:"change_note"`
+}
+
+// ListRevisions returns a document's revisions, newest first
+func (h *RevisionHandler) ListRevisions(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid content ID", fiber.StatusBadRequest)
+	}
+
+	page, ok := pageParams(c)
+	if !ok {
+		return nil
+	}
+
+	revisions, err := h.revisionService.List(c.Context(), contentKind(c), id, page)
+	if err != nil {
+		return listError(c, err)
+	}
+
+	return pageResponse(c, revisions, "Revisions retrieved successfully")
+}
+
+// GetRevision returns a single revision with its snapshot
+func (h *RevisionHandler) GetRevision(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid content ID", fiber.StatusBadRequest)
+	}
+
+	number, err := strconv.Atoi(c.Params("number"))
+	if err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid revision number", fiber.StatusBadRequest)
+	}
+
+	revision, err := h.revisionService.Get(c.Context(), contentKind(c), id, number)
+	if err != nil {
+		return revisionError(c, err)
+	}
+
+	return utils.SuccessResponse(c, revision, "Revision retrieved successfully", fiber.StatusOK)
+}
+
+// DiffRevisions compares two revisions field by field
+func (h *RevisionHandler) DiffRevisions(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid content ID", fiber.StatusBadRequest)
+	}
+
+	from, errFrom := strconv.Atoi(c.Query("from"))
+	to, errTo := strconv.Atoi(c.Query("to"))
+	if errFrom != nil || errTo != nil {
+		return utils.ErrorResponse(c, nil, "from and to revision numbers are required", fiber.StatusBadRequest)
+	}
+
+	changes, err := h.revisionService.Diff(c.Context(), contentKind(c), id, from, to)
+	if err != nil {
+		return revisionError(c, err)
+	}
+
+	return utils.SuccessResponse(c, changes, "Revisions compared successfully", fiber.StatusOK)
+}
+
+// RestoreRevision makes an older revision's content current again
+func (h *RevisionHandler) RestoreRevision(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid content ID", fiber.StatusBadRequest)
+	}
+
+	number, err := strconv.Atoi(c.Params("number"))
+	if err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid revision number", fiber.StatusBadRequest)
+	}
+
+	var req RestoreRevisionRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.ErrorResponse(c, nil, "Invalid request body", fiber.StatusBadRequest)
+		}
+	}
+
+	document, err := h.revisionService.Restore(c.Context(), contentKind(c), id, number, revisionMeta(c, req.ChangeNote, ""))
+	if err != nil {
+		return revisionError(c, err)
+	}
+
+	return utils.SuccessResponse(c, document, "Revision restored successfully", fiber.StatusOK)
+}
+
+// revisionError converts errors from revision requests into responses
+func revisionError(c *fiber.Ctx, err error) error {
+	var missing *service.MissingExercisesError
+	if errors.As(err, &missing) {
+		return pathWriteError(c, err)
+	}
+	if errors.Is(err, service.ErrVersionConflict) {
+		return conflictResponse(c)
+	}
+
+	switch err.Error() {
+	case "revision not found":
+		return utils.NotFoundResponse(c, "Revision not found")
+	case "exercise not found", "learning path not found":
+		return utils.NotFoundResponse(c, "Content not found")
+	}
+	return utils.ServerErrorResponse(c, err)
+}
+
+// withContentKind records which kind of content a route group serves
+func withContentKind(kind string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals("contentKind", kind)
+		return c.Next()
+	}
+}
+
+// contentKind returns the kind of content recorded by withContentKind
+func contentKind(c *fiber.Ctx) string {
+	kind, _ := c.Locals("contentKind").(string)
+	return kind
+}
+
+// revisionMeta describes a change made by the current user, falling back to a
+// default note when the request did not include one
+func revisionMeta(c *fiber.Ctx, note, defaultNote string) model.RevisionMeta {
+	userID, _ := auth.GetUserID(c)
+	if note == "" {
+		note = defaultNote
+	}
+	return model.RevisionMeta{AuthorID: userID, Note: note}
+}
+
+// setETag tags a response with the revision of the content it carries
+func setETag(c *fiber.Ctx, revision int) {
+	c.Set(fiber.HeaderETag, strconv.Quote(strconv.Itoa(revision)))
+}
+
+// ifMatch returns the revision named by the If-Match header, or model.AnyRevision
+// when the header is absent or "*". An invalid header is answered with a bad
+// request and ok is false.
+func ifMatch(c *fiber.Ctx) (revision int, ok bool) {
+	value := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if value == "" || value == "*" {
+		return model.AnyRevision, true
+	}
+
+	value = strings.Trim(strings.TrimPrefix(value, "W/"), `"`)
+	revision, err := strconv.Atoi(value)
+	if err != nil || revision < 0 {
+		utils.ErrorResponse(c, nil, "Invalid If-Match header", fiber.StatusBadRequest)
+		return 0, false
+	}
+	return revision, true
+}
+
+// conflictResponse reports a write that was based on an outdated revision
+func conflictResponse(c *fiber.Ctx) error {
+	return utils.ErrorResponse(c, nil, "Content was modified by another update; reload and try again", fiber.StatusConflict)
+}
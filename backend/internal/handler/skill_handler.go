@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/flutterninja9/mental-math-app/internal/auth"
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/service"
+	"github.com/flutterninja9/mental-math-app/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SkillHandler defines the handler for skill-related endpoints
+type SkillHandler struct {
+	skillService service.SkillService
+	validator    *utils.CustomValidator
+}
+
+// NewSkillHandler creates a new skill handler
+func NewSkillHandler(skillService service.SkillService) *SkillHandler {
+	return &SkillHandler{
+		skillService: skillService,
+		validator:    utils.NewValidator(),
+	}
+}
+
+// RegisterRoutes registers the skill routes
+func (h *SkillHandler) RegisterRoutes(router fiber.Router, authMiddleware fiber.Handler) {
+	skills := router.Group("/skills")
+
+	// Public routes
+	skills.Get("/", h.GetAllSkills)
+	skills.Get("/:tag", h.GetSkill)
+
+	// Content editors maintain the skill graph
+	protected := skills.Use(authMiddleware, auth.RequireRole(model.RoleEditor, model.RoleAdmin))
+	protected.Put("/:tag", h.SaveSkill)
+	protected.Delete("/:tag", h.DeleteSkill)
+}
+
+// SaveSkillRequest defines the request structure for creating or replacing a skill
+type SaveSkillRequest struct {
+	Name             string   `json:"name" validate:"required"`
+	Description      string   `json:"description"`
+	Prerequisites    []string `json:"prerequisites"`
+	MasteryThreshold float64  `json:"mastery_threshold" validate:"omitempty,gt=0,lte=1"`
+}
+
+// GetAllSkills returns every declared skill
+func (h *SkillHandler) GetAllSkills(c *fiber.Ctx) error {
+	skills, err := h.skillService.GetAll(c.Context())
+	if err != nil {
+		return utils.ServerErrorResponse(c, err)
+	}
+
+	return utils.SuccessResponse(c, skills, "Skills retrieved successfully", fiber.StatusOK)
+}
+
+// GetSkill returns a single skill by tag
+func (h *SkillHandler) GetSkill(c *fiber.Ctx) error {
+	skill, err := h.skillService.GetByTag(c.Context(), c.Params("tag"))
+	if err != nil {
+		return utils.NotFoundResponse(c, "Skill not found")
+	}
+
+	return utils.SuccessResponse(c, skill, "Skill retrieved successfully", fiber.StatusOK)
+}
+
+// SaveSkill creates or replaces the skill for a tag
+func (h *SkillHandler) SaveSkill(c *fiber.Ctx) error {
+	var req SaveSkillRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid request body", fiber.StatusBadRequest)
+	}
+
+	valErrors := h.validator.Validate(req)
+	if valErrors.HasErrors() {
+		return utils.ValidationErrorResponse(c, valErrors)
+	}
+
+	skill := &model.Skill{
+		Tag:              c.Params("tag"),
+		Name:             req.Name,
+		Description:      req.Description,
+		Prerequisites:    req.Prerequisites,
+		MasteryThreshold: req.MasteryThreshold,
+	}
+
+	if err := h.skillService.Save(c.Context(), skill); err != nil {
+		var cycle *service.PrerequisiteCycleError
+		if errors.As(err, &cycle) {
+			return utils.ErrorResponse(c, fiber.Map{"cycle": cycle.Cycle}, "Prerequisites would form a cycle", fiber.StatusUnprocessableEntity)
+		}
+		if errors.Is(err, service.ErrUnknownSkill) {
+			return utils.ErrorResponse(c, nil, "Skill references an unknown prerequisite skill", fiber.StatusUnprocessableEntity)
+		}
+		return utils.ServerErrorResponse(c, err)
+	}
+
+	return utils.SuccessResponse(c, skill, "Skill saved successfully", fiber.StatusOK)
+}
+
+// DeleteSkill deletes a skill that no other skill depends on
+func (h *SkillHandler) DeleteSkill(c *fiber.Ctx) error {
+	if err := h.skillService.Delete(c.Context(), c.Params("tag")); err != nil {
+		if errors.Is(err, service.ErrSkillInUse) {
+			return utils.ErrorResponse(c, nil, "Skill is a prerequisite of other skills", fiber.StatusConflict)
+		}
+		if err.Error() == "skill not found" {
+			return utils.NotFoundResponse(c, "Skill not found")
+		}
+		return utils.ServerErrorResponse(c, err)
+	}
+
+	return utils.SuccessResponse(c, nil, "Skill deleted successfully", fiber.StatusOK)
+}
@@ -1,26 +1,33 @@
 package handler
 
 import (
+	"bytes"
+	"errors"
+	"time"
+
 	"github.com/flutterninja9/mental-math-app/internal/auth"
 	"github.com/flutterninja9/mental-math-app/internal/domain/model"
 	"github.com/flutterninja9/mental-math-app/internal/service"
 	"github.com/flutterninja9/mental-math-app/pkg/utils"
 	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // UserHandler defines the handler for user-related endpoints
 type UserHandler struct {
-	userService service.UserService
-	authService auth.Service
-	validator   *utils.CustomValidator
+	userService    service.UserService
+	accountService service.AccountService
+	authService    auth.Service
+	validator      *utils.CustomValidator
 }
 
 // NewUserHandler creates a new user handler
-func NewUserHandler(userService service.UserService, authService auth.Service) *UserHandler {
+func NewUserHandler(userService service.UserService, accountService service.AccountService, authService auth.Service) *UserHandler {
 	return &UserHandler{
-		userService: userService,
-		authService: authService,
-		validator:   utils.NewValidator(),
+		userService:    userService,
+		accountService: accountService,
+		authService:    authService,
+		validator:      utils.NewValidator(),
 	}
 }
 
@@ -38,8 +45,12 @@ func (h *UserHandler) RegisterRoutes(router fiber.Router, authMiddleware fiber.H
 	protected.Put("/profile", h.UpdateProfile)
 	protected.Put("/password", h.UpdatePassword)
 	protected.Put("/preferences", h.UpdatePreferences)
+	protected.Get("/sessions", h.GetSessions)
 	protected.Delete("/logout", h.Logout)
 	protected.Delete("/logout-all", h.LogoutAll)
+	protected.Get("/me/export", h.ExportData)
+	protected.Delete("/me", h.DeleteAccount)
+	protected.Delete("/me/deletion", h.CancelDeletion)
 }
 
 // RegisterRequest defines the request structure for user registration
@@ -49,6 +60,7 @@ type RegisterRequest struct {
 	Password  string `json:"password" validate:"required,min=8"`
 	FirstName string `json:"first_name" validate:"required"`
 	LastName  string `json:"last_name" validate:"required"`
+	Timezone  string `json:"timezone" validate:"omitempty,timezone"`
 }
 
 // Register handles user registration
@@ -76,6 +88,7 @@ func (h *UserHandler) Register(c *fiber.Ctx) error {
 		req.Password,
 		req.FirstName,
 		req.LastName,
+		req.Timezone,
 	)
 	if err != nil {
 		return utils.ErrorResponse(c, nil, err.Error(), fiber.StatusBadRequest)
@@ -140,6 +153,7 @@ func (h *UserHandler) GetProfile(c *fiber.Ctx) error {
 type UpdateProfileRequest struct {
 	FirstName string `json:"first_name" validate:"required"`
 	LastName  string `json:"last_name" validate:"required"`
+	Timezone  string `json:"timezone" validate:"omitempty,timezone"`
 }
 
 // UpdateProfile updates the current user's profile
@@ -164,6 +178,7 @@ func (h *UserHandler) UpdateProfile(c *fiber.Ctx) error {
 		userID,
 		req.FirstName,
 		req.LastName,
+		req.Timezone,
 	)
 	if err != nil {
 		return utils.ServerErrorResponse(c, err)
@@ -275,6 +290,49 @@ func (h *UserHandler) Logout(c *fiber.Ctx) error {
 	return utils.SuccessResponse(c, nil, "Logged out successfully", fiber.StatusOK)
 }
 
+// SessionResponse describes an active session without its token
+type SessionResponse struct {
+	ID         primitive.ObjectID `json:"id"`
+	CreatedAt  time.Time          `json:"created_at"`
+	ExpiresAt  time.Time          `json:"expires_at"`
+	IPAddress  string             `json:"ip_address"`
+	DeviceInfo string             `json:"device_info"`
+	Current    bool               `json:"current"`
+}
+
+// GetSessions returns a page of the current user's active sessions
+func (h *UserHandler) GetSessions(c *fiber.Ctx) error {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		return utils.UnauthorizedResponse(c)
+	}
+	currentID, _ := auth.GetSessionID(c)
+
+	page, ok := pageParams(c)
+	if !ok {
+		return nil
+	}
+
+	sessions, err := h.authService.ListSessions(c.Context(), userID, page)
+	if err != nil {
+		return listError(c, err)
+	}
+
+	items := make([]SessionResponse, len(sessions.Items))
+	for i, session := range sessions.Items {
+		items[i] = SessionResponse{
+			ID:         session.ID,
+			CreatedAt:  session.CreatedAt,
+			ExpiresAt:  session.ExpiresAt,
+			IPAddress:  session.IPAddress,
+			DeviceInfo: session.DeviceInfo,
+			Current:    session.ID == currentID,
+		}
+	}
+
+	return utils.PaginatedResponse(c, items, sessions.Meta, "Sessions retrieved successfully")
+}
+
 // LogoutAll invalidates all sessions for the current user
 func (h *UserHandler) LogoutAll(c *fiber.Ctx) error {
 	userID, ok := auth.GetUserID(c)
@@ -288,3 +346,83 @@ func (h *UserHandler) LogoutAll(c *fiber.Ctx) error {
 
 	return utils.SuccessResponse(c, nil, "Logged out of all devices successfully", fiber.StatusOK)
 }
+
+// ExportData downloads everything stored about the current user as a zip archive
+func (h *UserHandler) ExportData(c *fiber.Ctx) error {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		return utils.UnauthorizedResponse(c)
+	}
+
+	var buf bytes.Buffer
+	if err := h.accountService.ExportData(c.Context(), userID, &buf); err != nil {
+		if err.Error() == "user not found" {
+			return utils.NotFoundResponse(c, "User not found")
+		}
+		return utils.ServerErrorResponse(c, err)
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="account-data-`+time.Now().UTC().Format("2006-01-02")+`.zip"`)
+	return c.Send(buf.Bytes())
+}
+
+// DeleteAccountRequest defines the request structure for deleting the current user's account
+type DeleteAccountRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// DeleteAccount deletes the current user's account and data. When a grace
+// period is configured the deletion is scheduled instead and can be cancelled
+// until it takes effect.
+func (h *UserHandler) DeleteAccount(c *fiber.Ctx) error {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		return utils.UnauthorizedResponse(c)
+	}
+
+	var req DeleteAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, nil, "Invalid request body", fiber.StatusBadRequest)
+	}
+
+	valErrors := h.validator.Validate(req)
+	if valErrors.HasErrors() {
+		return utils.ValidationErrorResponse(c, valErrors)
+	}
+
+	scheduledAt, err := h.accountService.RequestDeletion(c.Context(), userID, req.Password)
+	if err != nil {
+		switch {
+		case err.Error() == "incorrect current password":
+			return utils.ErrorResponse(c, fiber.Map{"password": "Incorrect password"}, "Account deletion failed", fiber.StatusBadRequest)
+		case errors.Is(err, service.ErrDeletionScheduled):
+			return utils.ErrorResponse(c, nil, "Account deletion is already scheduled", fiber.StatusConflict)
+		case err.Error() == "user not found":
+			return utils.NotFoundResponse(c, "User not found")
+		}
+		return utils.ServerErrorResponse(c, err)
+	}
+
+	if scheduledAt != nil {
+		return utils.SuccessResponse(c, fiber.Map{"deletion_scheduled_at": scheduledAt}, "Account deletion scheduled", fiber.StatusAccepted)
+	}
+	return utils.SuccessResponse(c, nil, "Account deleted successfully", fiber.StatusOK)
+}
+
+// CancelDeletion keeps the current user's account when its deletion is still pending
+func (h *UserHandler) CancelDeletion(c *fiber.Ctx) error {
+	userID, ok := auth.GetUserID(c)
+	if !ok {
+		return utils.UnauthorizedResponse(c)
+	}
+
+	if err := h.accountService.CancelDeletion(c.Context(), userID); err != nil {
+		if errors.Is(err, service.ErrDeletionNotScheduled) {
+			return utils.ErrorResponse(c, nil, "Account deletion is not scheduled", fiber.StatusConflict)
+		}
+		return utils.ServerErrorResponse(c, err)
+	}
+
+	return utils.SuccessResponse(c, nil, "Account deletion cancelled", fiber.StatusOK)
+}
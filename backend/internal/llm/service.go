@@ -15,6 +15,24 @@ type Service interface {
 	GenerateExercise(ctx context.Context, category, difficulty string) (*model.Exercise, error)
 	GenerateExerciseBatch(ctx context.Context, category, difficulty string, count int) ([]*model.Exercise, error)
 	EnhanceExplanation(ctx context.Context, problem, answer string) (string, error)
+	OutlineLearningPath(ctx context.Context, goal, difficulty string, stageCount int) (*PathOutline, error)
+}
+
+// PathOutline is the LLM's proposed structure for a learning path
+type PathOutline struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Categories  []string       `json:"categories"`
+	Stages      []StageOutline `json:"stages"`
+}
+
+// StageOutline describes one proposed stage and the exercises it should contain
+type StageOutline struct {
+	Title         string   `json:"title"`
+	Description   string   `json:"description"`
+	Category      string   `json:"category"`
+	Tags          []string `json:"tags"`
+	ExerciseCount int      `json:"exercise_count"`
 }
 
 // service implements the LLM service
@@ -129,6 +147,45 @@ func (s *service) EnhanceExplanation(ctx context.Context, problem, answer string
 	return explanation, nil
 }
 
+// OutlineLearningPath proposes the title, categories and stages of a learning path for a goal
+func (s *service) OutlineLearningPath(ctx context.Context, goal, difficulty string, stageCount int) (*PathOutline, error) {
+	if stageCount <= 0 {
+		stageCount = 1
+	}
+	if stageCount > 10 {
+		stageCount = 10 // Limit path length
+	}
+
+	prompt := buildPathOutlinePrompt(goal, difficulty, stageCount)
+
+	// Generate outline using LLM
+	jsonResponse, err := s.client.GenerateWithJSON(prompt)
+	if err != nil {
+		logger.Error("Failed to generate learning path outline", err)
+		return nil, fmt.Errorf("failed to generate learning path outline: %w", err)
+	}
+
+	// Convert to JSON string and parse into PathOutline struct
+	jsonData, err := json.Marshal(jsonResponse)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal learning path outline: %w", err)
+	}
+
+	var outline PathOutline
+	if err := json.Unmarshal(jsonData, &outline); err != nil {
+		return nil, fmt.Errorf("failed to parse learning path outline: %w", err)
+	}
+
+	if len(outline.Stages) == 0 {
+		return nil, fmt.Errorf("invalid response format: missing stages")
+	}
+	if len(outline.Stages) > stageCount {
+		outline.Stages = outline.Stages[:stageCount]
+	}
+
+	return &outline, nil
+}
+
 // Helper functions for building prompts
 
 func buildExercisePrompt(category, difficulty string) string {
@@ -185,3 +242,31 @@ For category %s, focus specifically on related concepts.
 Make sure all exercises are different from each other.
 `, count, category, difficulty, difficulty, category)
 }
+
+func buildPathOutlinePrompt(goal, difficulty string, stageCount int) string {
+	return fmt.Sprintf(`Design a mental math learning path for the goal: %s with difficulty: %s.
+The path must have exactly %d stages that build on each other, from foundations to mastery of the goal.
+Format the response as a JSON object with the following structure:
+{
+  "title": "Short title for the learning path",
+  "description": "What the learner will be able to do after the path",
+  "categories": ["mental math categories covered by the path"],
+  "stages": [
+    {
+      "title": "Stage title",
+      "description": "What this stage practises",
+      "category": "The single category the stage's exercises belong to",
+      "tags": ["specific", "techniques", "practised"],
+      "exercise_count": 5
+    }
+  ]
+}
+
+For %s difficulty, ensure the complexity is appropriate:
+- "easy": Basic operations, single-step mental calculations
+- "medium": Multi-step calculations, requires some mental math shortcuts
+- "hard": Complex calculations requiring multiple mental math techniques
+
+Use between 3 and 8 exercises per stage.
+`, goal, difficulty, stageCount, difficulty)
+}
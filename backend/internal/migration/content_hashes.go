@@ -0,0 +1,39 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// BackfillContentHashes sets the content hash of exercises written before
+// hashes were recorded. It only touches exercises without one, so it is safe
+// to run repeatedly.
+func BackfillContentHashes(ctx context.Context, db *mongo.Database) (int, error) {
+	exercises := db.Collection("exercises")
+
+	cursor, err := exercises.Find(ctx, bson.M{"content_hash": bson.M{"$exists": false}})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	updated := 0
+	for cursor.Next(ctx) {
+		var exercise model.Exercise
+		if err := cursor.Decode(&exercise); err != nil {
+			return updated, err
+		}
+
+		update := bson.M{"$set": bson.M{"content_hash": model.ContentHash(&exercise)}}
+		if _, err := exercises.UpdateOne(ctx, bson.M{"_id": exercise.ID}, update); err != nil {
+			return updated, fmt.Errorf("failed to hash exercise %s: %w", exercise.ID.Hex(), err)
+		}
+		updated++
+	}
+
+	return updated, cursor.Err()
+}
@@ -0,0 +1,248 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// initialIndexes are the indexes the repositories used to create at startup,
+// by collection
+var initialIndexes = map[string][]mongo.IndexModel{
+	"users": {
+		{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "username", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "deletion_scheduled_at", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	},
+	"user_sessions": {
+		{
+			Keys:    bson.D{{Key: "session_token", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	},
+	"exercises": {
+		{
+			Keys: bson.D{{Key: "category", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "difficulty", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "tags", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "metadata.created_at", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "external_key", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+		{
+			Keys: bson.D{{Key: "content_hash", Value: 1}},
+		},
+		{
+			Keys: bson.D{
+				{Key: "title", Value: "text"},
+				{Key: "description", Value: "text"},
+				{Key: "content.problem", Value: "text"},
+			},
+			Options: options.Index().
+				SetName("exercise_text").
+				SetWeights(bson.D{{Key: "title", Value: 5}, {Key: "description", Value: 2}, {Key: "content.problem", Value: 1}}),
+		},
+	},
+	"user_progress": {
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "exercise_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+	},
+	"attempts": {
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "timestamp", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "exercise_id", Value: 1},
+				{Key: "timestamp", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "exercise_id", Value: 1},
+				{Key: "timestamp", Value: -1},
+			},
+		},
+	},
+	"learning_paths": {
+		{
+			Keys: bson.D{{Key: "difficulty", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "categories", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "stages.exercise_ids", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "prerequisite_path_ids", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}},
+		},
+	},
+	"path_enrollments": {
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "path_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "path_id", Value: 1}},
+		},
+	},
+	"skills": {
+		{
+			Keys:    bson.D{{Key: "tag", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "prerequisites", Value: 1}},
+		},
+	},
+	"revisions": {
+		{
+			Keys: bson.D{
+				{Key: "kind", Value: 1},
+				{Key: "document_id", Value: 1},
+				{Key: "number", Value: -1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+	},
+}
+
+// trashIndexes find soft-deleted documents for the trash listing and the purge
+var trashIndexes = map[string][]mongo.IndexModel{
+	"exercises": {
+		{
+			Keys:    bson.D{{Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	},
+	"learning_paths": {
+		{
+			Keys:    bson.D{{Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	},
+	"users": {
+		{
+			Keys:    bson.D{{Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	},
+}
+
+// trashUniqueIndexes replace the unique indexes on emails, usernames and
+// external keys so that only documents outside the trash have to be unique.
+// Mongo cannot filter a partial index on a field not existing, so deleted_at
+// is part of the key instead: it is missing, and indexed as null, outside the
+// trash and sets each trashed document apart.
+var trashUniqueIndexes = map[string][]mongo.IndexModel{
+	"users": {
+		{
+			Keys:    bson.D{{Key: "email", Value: 1}, {Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetName("email_outside_trash").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "username", Value: 1}, {Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetName("username_outside_trash").SetUnique(true),
+		},
+	},
+	"exercises": {
+		{
+			Keys: bson.D{{Key: "external_key", Value: 1}, {Key: "deleted_at", Value: 1}},
+			Options: options.Index().
+				SetName("external_key_outside_trash").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"external_key": bson.M{"$exists": true}}),
+		},
+	},
+}
+
+// replacedUniqueIndexes are the initial indexes trashUniqueIndexes replace
+var replacedUniqueIndexes = map[string][]string{
+	"users":     {"email_1", "username_1"},
+	"exercises": {"external_key_1"},
+}
+
+// createIndexes creates indexes on collections. Creating an index that already
+// exists with the same options does nothing, so it is safe to run repeatedly.
+func createIndexes(ctx context.Context, db *mongo.Database, indexes map[string][]mongo.IndexModel) error {
+	for collection, models := range indexes {
+		if _, err := db.Collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+			return fmt.Errorf("failed to create %s indexes: %w", collection, err)
+		}
+	}
+	return nil
+}
+
+// dropIndexes drops indexes by name, by collection. Indexes that do not exist
+// are skipped, so it is safe to run repeatedly.
+func dropIndexes(ctx context.Context, db *mongo.Database, indexes map[string][]string) error {
+	for collection, names := range indexes {
+		for _, name := range names {
+			_, err := db.Collection(collection).Indexes().DropOne(ctx, name)
+			var commandErr mongo.CommandError
+			if errors.As(err, &commandErr) && (commandErr.Code == indexNotFoundCode || commandErr.Code == namespaceNotFoundCode) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to drop the %s index %s: %w", collection, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Server error codes dropIndexes treats as nothing to drop
+const (
+	namespaceNotFoundCode = 26
+	indexNotFoundCode     = 27
+)
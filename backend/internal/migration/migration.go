@@ -0,0 +1,104 @@
+// Package migration evolves the Mongo schema: indexes and the shape of stored
+// documents. Migrations are numbered, applied in order and recorded in the
+// schema_migrations collection so each one runs once. Every step is written to
+// be idempotent, so a run interrupted part way can simply be repeated.
+package migration
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migrationsCollection records the migrations that have been applied
+const migrationsCollection = "schema_migrations"
+
+// Migration is one versioned step of the schema
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+// Record is an applied migration as stored in the schema_migrations collection
+type Record struct {
+	Version     int       `json:"version" bson:"_id"`
+	Description string    `json:"description" bson:"description"`
+	AppliedAt   time.Time `json:"applied_at" bson:"applied_at"`
+}
+
+// Migrator applies migrations to a database
+type Migrator struct {
+	db         *mongo.Database
+	migrations []Migration
+}
+
+// NewMigrator creates a migrator for the registered migrations
+func NewMigrator(db *mongo.Database) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// Applied returns the migrations recorded as applied, oldest version first
+func (m *Migrator) Applied(ctx context.Context) ([]Record, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := m.db.Collection(migrationsCollection).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []Record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Pending returns the migrations that have not been applied, in the order they will run
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	records, err := m.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int]bool, len(records))
+	for _, record := range records {
+		applied[record.Version] = true
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if !applied[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies the pending migrations in order, recording each once it succeeds,
+// and returns the ones it applied. It stops at the first failure. With dryRun
+// set it only returns the migrations it would apply.
+func (m *Migrator) Up(ctx context.Context, dryRun bool) ([]Migration, error) {
+	pending, err := m.Pending(ctx)
+	if err != nil || dryRun {
+		return pending, err
+	}
+
+	collection := m.db.Collection(migrationsCollection)
+	for i, migration := range pending {
+		if err := migration.Up(ctx, m.db); err != nil {
+			return pending[:i], fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Description, err)
+		}
+
+		record := Record{Version: migration.Version, Description: migration.Description, AppliedAt: time.Now()}
+		// Another instance may have applied the same migration meanwhile; its
+		// steps are idempotent, so the earlier record stands
+		if _, err := collection.InsertOne(ctx, record); err != nil && !mongo.IsDuplicateKeyError(err) {
+			return pending[:i], fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,97 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// migrations is the schema history, oldest first. Append new migrations with
+// the next version; never change or reorder ones that have been released.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "create collection indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, initialIndexes)
+		},
+	},
+	{
+		Version:     2,
+		Description: "move attempts embedded in progress records into the attempts collection",
+		Up:          counted(SplitEmbeddedAttempts, "Migrated embedded attempts from %d progress records"),
+	},
+	{
+		Version:     3,
+		Description: "backfill exercise content hashes",
+		Up:          counted(BackfillContentHashes, "Backfilled content hashes for %d exercises"),
+	},
+	{
+		Version:     4,
+		Description: "backfill user roles and timezones",
+		Up:          backfillUserDefaults,
+	},
+	{
+		Version:     5,
+		Description: "validate documents against the collection schemas",
+		Up:          applyValidators,
+	},
+	{
+		Version:     6,
+		Description: "index and validate the soft deletion fields of exercises, learning paths and users",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			if err := createIndexes(ctx, db, trashIndexes); err != nil {
+				return err
+			}
+			return applyValidators(ctx, db)
+		},
+	},
+	{
+		Version:     7,
+		Description: "only require unique emails, usernames and external keys outside the trash",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// The new indexes are created first so uniqueness is never unenforced
+			if err := createIndexes(ctx, db, trashUniqueIndexes); err != nil {
+				return err
+			}
+			return dropIndexes(ctx, db, replacedUniqueIndexes)
+		},
+	},
+}
+
+// counted adapts a backfill that reports how many documents it changed,
+// logging the count when there were any
+func counted(backfill func(context.Context, *mongo.Database) (int, error), message string) func(context.Context, *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		count, err := backfill(ctx, db)
+		if count > 0 {
+			logger.Info(fmt.Sprintf(message, count))
+		}
+		return err
+	}
+}
+
+// backfillUserDefaults gives users created before roles and timezones were
+// stored the defaults new accounts get
+func backfillUserDefaults(ctx context.Context, db *mongo.Database) error {
+	users := db.Collection("users")
+	missing := func(field string) bson.M {
+		return bson.M{"$or": bson.A{
+			bson.M{field: bson.M{"$exists": false}},
+			bson.M{field: ""},
+			bson.M{field: nil},
+		}}
+	}
+
+	if _, err := users.UpdateMany(ctx, missing("role"), bson.M{"$set": bson.M{"role": model.RoleUser}}); err != nil {
+		return fmt.Errorf("failed to backfill user roles: %w", err)
+	}
+	if _, err := users.UpdateMany(ctx, missing("timezone"), bson.M{"$set": bson.M{"timezone": "UTC"}}); err != nil {
+		return fmt.Errorf("failed to backfill user timezones: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// legacyAttempt is an attempt as it was embedded in user_progress documents
+type legacyAttempt struct {
+	Timestamp  time.Time `bson:"timestamp"`
+	UserAnswer string    `bson:"user_answer"`
+	IsCorrect  bool      `bson:"is_correct"`
+	TimeTaken  int       `bson:"time_taken"`
+}
+
+// legacyProgress is a user_progress document that still embeds its attempts
+type legacyProgress struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	UserID     primitive.ObjectID `bson:"user_id"`
+	ExerciseID primitive.ObjectID `bson:"exercise_id"`
+	Attempts   []legacyAttempt    `bson:"attempts"`
+}
+
+// SplitEmbeddedAttempts moves attempts embedded in user_progress documents into the
+// attempts collection and turns each progress document into a rolling summary.
+// It is safe to run repeatedly: each copy's ID is derived from the progress
+// document and the attempt's position in the embedded array, so a rerun finds
+// the copies it already made, and the array is only removed once every attempt
+// has been copied. Attempts sharing a timestamp are copied separately.
+func SplitEmbeddedAttempts(ctx context.Context, db *mongo.Database) (int, error) {
+	progressColl := db.Collection("user_progress")
+	attemptColl := db.Collection("attempts")
+
+	cursor, err := progressColl.Find(ctx, bson.M{"attempts": bson.M{"$exists": true}})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		var progress legacyProgress
+		if err := cursor.Decode(&progress); err != nil {
+			return migrated, err
+		}
+
+		summary := bson.M{
+			"attempt_count":    0,
+			"correct_count":    0,
+			"total_time_taken": 0,
+			"mastery_level":    0.0,
+		}
+		recent := []model.Attempt{}
+		correct := 0
+		totalTime := 0
+
+		for i, legacy := range progress.Attempts {
+			attempt := model.Attempt{
+				UserID:     progress.UserID,
+				ExerciseID: progress.ExerciseID,
+				Timestamp:  legacy.Timestamp,
+				UserAnswer: legacy.UserAnswer,
+				IsCorrect:  legacy.IsCorrect,
+				TimeTaken:  legacy.TimeTaken,
+			}
+
+			id := legacyAttemptID(progress.ID, i, legacy.Timestamp)
+			_, err := attemptColl.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$setOnInsert": attempt}, options.Update().SetUpsert(true))
+			if err != nil {
+				return migrated, fmt.Errorf("failed to copy attempt for progress %s: %w", progress.ID.Hex(), err)
+			}
+			attempt.ID = id
+
+			if attempt.IsCorrect {
+				correct++
+			}
+			totalTime += attempt.TimeTaken
+			recent = append(recent, attempt)
+		}
+
+		if len(recent) > model.RecentAttemptLimit {
+			recent = recent[len(recent)-model.RecentAttemptLimit:]
+		}
+		if count := len(progress.Attempts); count > 0 {
+			summary["attempt_count"] = count
+			summary["correct_count"] = correct
+			summary["total_time_taken"] = totalTime
+			summary["mastery_level"] = float64(correct) / float64(count)
+		}
+		summary["recent_attempts"] = recent
+
+		update := bson.M{
+			"$set":   summary,
+			"$unset": bson.M{"attempts": ""},
+		}
+		if _, err := progressColl.UpdateOne(ctx, bson.M{"_id": progress.ID}, update); err != nil {
+			return migrated, fmt.Errorf("failed to summarise progress %s: %w", progress.ID.Hex(), err)
+		}
+		migrated++
+	}
+
+	return migrated, cursor.Err()
+}
+
+// legacyAttemptID derives the ID of the copy of the attempt at position index
+// in a progress document's embedded array. Like any ObjectID it starts with a
+// timestamp, the attempt's own, so copies sort among the attempts made later.
+func legacyAttemptID(progressID primitive.ObjectID, index int, timestamp time.Time) primitive.ObjectID {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%d", progressID.Hex(), index)))
+
+	var id primitive.ObjectID
+	binary.BigEndian.PutUint32(id[:4], uint32(timestamp.Unix()))
+	copy(id[4:], sum[:8])
+	return id
+}
@@ -0,0 +1,96 @@
+package migration
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestLegacyAttemptIDs(t *testing.T) {
+	progressID := primitive.NewObjectID()
+	at := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+
+	first := legacyAttemptID(progressID, 0, at)
+	if again := legacyAttemptID(progressID, 0, at); again != first {
+		t.Errorf("ID changed between runs: %s, then %s", first.Hex(), again.Hex())
+	}
+	if second := legacyAttemptID(progressID, 1, at); second == first {
+		t.Errorf("attempts at the same time share the ID %s", first.Hex())
+	}
+	if other := legacyAttemptID(primitive.NewObjectID(), 0, at); other == first {
+		t.Errorf("attempts from different progress records share the ID %s", first.Hex())
+	}
+	if !first.Timestamp().Equal(at) {
+		t.Errorf("ID time = %v, want the attempt's %v", first.Timestamp(), at)
+	}
+}
+
+// TestSplitEmbeddedAttempts runs against the server at MONGO_TEST_URI, in a
+// database of its own that is dropped afterwards
+func TestSplitEmbeddedAttempts(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI is not set")
+	}
+	ctx := context.Background()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(ctx)
+	db := client.Database("test_" + primitive.NewObjectID().Hex())
+	defer db.Drop(ctx)
+
+	// Answers submitted twice within the same instant, as double-clicks did
+	at := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	progress := bson.M{
+		"_id":         primitive.NewObjectID(),
+		"user_id":     primitive.NewObjectID(),
+		"exercise_id": primitive.NewObjectID(),
+		"attempts": bson.A{
+			bson.M{"timestamp": at, "user_answer": "4", "is_correct": true, "time_taken": 2},
+			bson.M{"timestamp": at, "user_answer": "4", "is_correct": true, "time_taken": 2},
+			bson.M{"timestamp": at.Add(time.Minute), "user_answer": "5", "is_correct": false, "time_taken": 3},
+		},
+	}
+	if _, err := db.Collection("user_progress").InsertOne(ctx, progress); err != nil {
+		t.Fatal(err)
+	}
+
+	migrated, err := SplitEmbeddedAttempts(ctx, db)
+	if err != nil || migrated != 1 {
+		t.Fatalf("migrated = %d, %v, want 1", migrated, err)
+	}
+	if count, err := db.Collection("attempts").CountDocuments(ctx, bson.M{}); err != nil || count != 3 {
+		t.Errorf("attempts copied = %d, %v, want all 3", count, err)
+	}
+
+	var summary model.UserProgress
+	if err := db.Collection("user_progress").FindOne(ctx, bson.M{"_id": progress["_id"]}).Decode(&summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.AttemptCount != 3 || summary.CorrectCount != 2 || len(summary.RecentAttempts) != 3 {
+		t.Errorf("summary = %d attempts, %d correct, %d recent; want 3, 2, 3",
+			summary.AttemptCount, summary.CorrectCount, len(summary.RecentAttempts))
+	}
+
+	// A run interrupted before the array was removed copies nothing twice
+	if _, err := db.Collection("user_progress").UpdateOne(ctx, bson.M{"_id": progress["_id"]},
+		bson.M{"$set": bson.M{"attempts": progress["attempts"]}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SplitEmbeddedAttempts(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := db.Collection("attempts").CountDocuments(ctx, bson.M{}); err != nil || count != 3 {
+		t.Errorf("attempts after a rerun = %d, %v, want still 3", count, err)
+	}
+}
@@ -0,0 +1,84 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/schemas"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// validatedModels are the structs stored in each collection with a schema
+var validatedModels = map[string]interface{}{
+	"attempts":         model.Attempt{},
+	"exercises":        model.Exercise{},
+	"learning_paths":   model.LearningPath{},
+	"path_enrollments": model.PathEnrollment{},
+	"revisions":        model.Revision{},
+	"skills":           model.Skill{},
+	"user_progress":    model.UserProgress{},
+	"users":            model.User{},
+	"user_sessions":    model.UserSession{},
+}
+
+// CheckSchemas reports every difference between the collection schemas and
+// the models stored in those collections
+func CheckSchemas() error {
+	var problems []error
+	for collection, doc := range validatedModels {
+		schema, err := schemas.Load(collection)
+		if err == nil {
+			err = schemas.Check(schema, doc)
+		}
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", collection, err))
+		}
+	}
+	return errors.Join(problems...)
+}
+
+// applyValidators sets the schema of every collection as its validator. It
+// refuses to when a schema has drifted from its model, since writes of that
+// model would then be rejected. Documents already in a collection are only
+// checked when they are next updated, and only if they were valid before.
+// Changing a schema file takes a new migration that runs this again.
+func applyValidators(ctx context.Context, db *mongo.Database) error {
+	if err := CheckSchemas(); err != nil {
+		return err
+	}
+
+	existing, err := db.ListCollectionNames(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	exists := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		exists[name] = true
+	}
+
+	for collection := range validatedModels {
+		validator, err := schemas.Validator(collection)
+		if err != nil {
+			return err
+		}
+
+		if !exists[collection] {
+			if err := db.CreateCollection(ctx, collection); err != nil {
+				return fmt.Errorf("failed to create %s: %w", collection, err)
+			}
+		}
+		command := bson.D{
+			{Key: "collMod", Value: collection},
+			{Key: "validator", Value: validator},
+			{Key: "validationLevel", Value: "moderate"},
+			{Key: "validationAction", Value: "error"},
+		}
+		if err := db.RunCommand(ctx, command).Err(); err != nil {
+			return fmt.Errorf("failed to set the %s validator: %w", collection, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package migration
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/flutterninja9/mental-math-app/schemas"
+)
+
+func TestSchemasMatchModels(t *testing.T) {
+	if err := CheckSchemas(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A schema without a model would never be checked or applied
+	for collection := range schemas.Files {
+		if _, ok := validatedModels[collection]; !ok {
+			t.Errorf("%s has a schema but no model", collection)
+		}
+	}
+}
+
+func TestSchemaCheckReportsDrift(t *testing.T) {
+	schema, err := schemas.Load("skills")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type driftedSkill struct {
+		Tag     string `bson:"tag"`
+		Renamed string `bson:"renamed"`
+	}
+	err = schemas.Check(schema, driftedSkill{})
+	if err == nil || !strings.Contains(err.Error(), "renamed is not in the schema") || !strings.Contains(err.Error(), "is not in the model") {
+		t.Errorf("error = %v, want the renamed field and the missing model fields reported", err)
+	}
+}
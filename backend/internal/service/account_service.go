@@ -0,0 +1,257 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// purgeBatchSize is the number of scheduled deletions carried out per purge run
+const purgeBatchSize = 100
+
+var (
+	// ErrDeletionScheduled is returned when requesting deletion of an account that is already scheduled for deletion
+	ErrDeletionScheduled = errors.New("account deletion is already scheduled")
+	// ErrDeletionNotScheduled is returned when cancelling a deletion that was never requested
+	ErrDeletionNotScheduled = errors.New("account deletion is not scheduled")
+)
+
+type AccountService interface {
+	ExportData(ctx context.Context, userID primitive.ObjectID, w io.Writer) error
+	RequestDeletion(ctx context.Context, userID primitive.ObjectID, password string) (*time.Time, error)
+	CancelDeletion(ctx context.Context, userID primitive.ObjectID) error
+	Delete(ctx context.Context, userID primitive.ObjectID) error
+	PurgeScheduledDeletions(ctx context.Context) (int, error)
+}
+
+// exportedSession is a session as included in a data export, without its token
+type exportedSession struct {
+	ID         primitive.ObjectID `json:"id"`
+	CreatedAt  time.Time          `json:"created_at"`
+	ExpiresAt  time.Time          `json:"expires_at"`
+	IPAddress  string             `json:"ip_address"`
+	DeviceInfo string             `json:"device_info"`
+}
+
+// SessionInvalidator drops the cached sessions of a user whose sessions were deleted
+type SessionInvalidator interface {
+	InvalidateUser(ctx context.Context, userID primitive.ObjectID) error
+}
+
+type accountService struct {
+	userRepo       repository.UserRepository
+	progressRepo   repository.ProgressRepository
+	attemptRepo    repository.AttemptRepository
+	enrollmentRepo repository.EnrollmentRepository
+	sessionRepo    repository.SessionRepository
+	exerciseRepo   repository.ExerciseRepository
+	pathRepo       repository.LearningPathRepository
+	revisionRepo   repository.RevisionRepository
+	sessions       SessionInvalidator
+	content        *ContentCache
+	gracePeriod    time.Duration
+}
+
+// NewAccountService creates a new instance of the account service. Deletions
+// take effect after gracePeriod, or immediately when it is zero.
+func NewAccountService(
+	userRepo repository.UserRepository,
+	progressRepo repository.ProgressRepository,
+	attemptRepo repository.AttemptRepository,
+	enrollmentRepo repository.EnrollmentRepository,
+	sessionRepo repository.SessionRepository,
+	exerciseRepo repository.ExerciseRepository,
+	pathRepo repository.LearningPathRepository,
+	revisionRepo repository.RevisionRepository,
+	sessions SessionInvalidator,
+	content *ContentCache,
+	gracePeriod time.Duration,
+) AccountService {
+	return &accountService{
+		userRepo:       userRepo,
+		progressRepo:   progressRepo,
+		attemptRepo:    attemptRepo,
+		enrollmentRepo: enrollmentRepo,
+		sessionRepo:    sessionRepo,
+		exerciseRepo:   exerciseRepo,
+		pathRepo:       pathRepo,
+		revisionRepo:   revisionRepo,
+		sessions:       sessions,
+		content:        content,
+		gracePeriod:    gracePeriod,
+	}
+}
+
+// ExportData writes a zip archive with one JSON file for each kind of data
+// held about the user: the profile with preferences and statistics, progress
+// summaries, attempts, path enrollments and sessions
+func (s *accountService) ExportData(ctx context.Context, userID primitive.ObjectID, w io.Writer) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	progress, err := s.progressRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	attempts, err := s.attemptRepo.GetByUser(ctx, userID, time.Time{}, time.Now().Add(time.Minute))
+	if err != nil {
+		return err
+	}
+	enrollments, err := s.enrollmentRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	sessions, err := s.sessionRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	exportedSessions := make([]exportedSession, len(sessions))
+	for i, session := range sessions {
+		exportedSessions[i] = exportedSession{
+			ID:         session.ID,
+			CreatedAt:  session.CreatedAt,
+			ExpiresAt:  session.ExpiresAt,
+			IPAddress:  session.IPAddress,
+			DeviceInfo: session.DeviceInfo,
+		}
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", user},
+		{"progress.json", nonNil(progress)},
+		{"attempts.json", nonNil(attempts)},
+		{"enrollments.json", nonNil(enrollments)},
+		{"sessions.json", exportedSessions},
+	}
+
+	archive := zip.NewWriter(w)
+	for _, file := range files {
+		data, err := json.MarshalIndent(file.data, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeZipFile(archive, file.name, data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// RequestDeletion deletes the account once the password is confirmed. With a
+// grace period the deletion is only scheduled and the returned time says when
+// it takes effect; without one the account is deleted straight away and nil
+// is returned.
+func (s *accountService) RequestDeletion(ctx context.Context, userID primitive.ObjectID, password string) (*time.Time, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !utils.CheckPasswordHash(password, user.PasswordHash) {
+		return nil, errors.New("incorrect current password")
+	}
+	if user.DeletionScheduledAt != nil {
+		return nil, ErrDeletionScheduled
+	}
+
+	if s.gracePeriod <= 0 {
+		return nil, s.Delete(ctx, userID)
+	}
+
+	at := time.Now().Add(s.gracePeriod)
+	if err := s.userRepo.SetDeletionSchedule(ctx, userID, &at); err != nil {
+		return nil, err
+	}
+	return &at, nil
+}
+
+// CancelDeletion keeps an account whose deletion was scheduled
+func (s *accountService) CancelDeletion(ctx context.Context, userID primitive.ObjectID) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.DeletionScheduledAt == nil {
+		return ErrDeletionNotScheduled
+	}
+	return s.userRepo.SetDeletionSchedule(ctx, userID, nil)
+}
+
+// Delete removes a user and everything tied to them. Personal records are
+// deleted; records other users' data depends on are anonymised instead:
+// attempts keep counting towards exercise statistics, and the user's content
+// revisions and review activity lose their author. The user document goes
+// last so a failed deletion can be retried.
+func (s *accountService) Delete(ctx context.Context, userID primitive.ObjectID) error {
+	if _, err := s.sessionRepo.DeleteAllForUser(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	if err := s.sessions.InvalidateUser(ctx, userID); err != nil {
+		return fmt.Errorf("failed to invalidate sessions: %w", err)
+	}
+	if _, err := s.progressRepo.DeleteByUserID(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete progress: %w", err)
+	}
+	if _, err := s.enrollmentRepo.DeleteByUserID(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete enrollments: %w", err)
+	}
+	if _, err := s.attemptRepo.AnonymizeUser(ctx, userID); err != nil {
+		return fmt.Errorf("failed to anonymise attempts: %w", err)
+	}
+	if err := s.revisionRepo.AnonymizeAuthor(ctx, userID); err != nil {
+		return fmt.Errorf("failed to anonymise revisions: %w", err)
+	}
+	err := s.exerciseRepo.AnonymizeUser(ctx, userID)
+	s.content.InvalidateExercises(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to anonymise exercise reviews: %w", err)
+	}
+	err = s.pathRepo.AnonymizeUser(ctx, userID)
+	s.content.InvalidatePaths(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to anonymise learning path reviews: %w", err)
+	}
+
+	return s.userRepo.Delete(ctx, userID)
+}
+
+// PurgeScheduledDeletions deletes the accounts whose grace period has ended and
+// returns how many were deleted
+func (s *accountService) PurgeScheduledDeletions(ctx context.Context) (int, error) {
+	deleted := 0
+	for {
+		users, err := s.userRepo.GetDueForDeletion(ctx, time.Now(), purgeBatchSize)
+		if err != nil {
+			return deleted, err
+		}
+		for _, user := range users {
+			if err := s.Delete(ctx, user.ID); err != nil {
+				return deleted, fmt.Errorf("failed to delete user %s: %w", user.ID.Hex(), err)
+			}
+			deleted++
+		}
+		if len(users) < purgeBatchSize {
+			return deleted, nil
+		}
+	}
+}
+
+// nonNil returns an empty slice in place of nil so exports contain [] rather than null
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/pkg/cache"
+	"github.com/flutterninja9/mental-math-app/pkg/logger"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+)
+
+// Content kinds cached by ContentCache, used as key prefixes
+const (
+	exerciseContent = "exercises"
+	pathContent     = "paths"
+)
+
+// ContentCache caches exercises and learning paths read through the services.
+// Every cached value is keyed by the current generation of its content kind, so
+// a write bumps the generation and invalidates all exercises or all paths at
+// once, listings included. Writes that bypass the services are only seen once
+// the cached values expire. A nil *ContentCache caches nothing.
+type ContentCache struct {
+	cache cache.Cache
+	ttl   time.Duration
+}
+
+// NewContentCache creates a content cache keeping values for ttl
+func NewContentCache(c cache.Cache, ttl time.Duration) *ContentCache {
+	return &ContentCache{cache: c, ttl: ttl}
+}
+
+// InvalidateExercises drops every cached exercise and exercise listing
+func (c *ContentCache) InvalidateExercises(ctx context.Context) {
+	c.invalidate(ctx, exerciseContent)
+}
+
+// InvalidatePaths drops every cached learning path and learning path listing
+func (c *ContentCache) InvalidatePaths(ctx context.Context) {
+	c.invalidate(ctx, pathContent)
+}
+
+func (c *ContentCache) invalidate(ctx context.Context, kind string) {
+	if c == nil {
+		return
+	}
+	if _, err := cache.NextGeneration(ctx, c.cache, generationKey(kind), 0); err != nil {
+		logger.Error(fmt.Sprintf("Failed to invalidate cached %s", kind), err)
+	}
+}
+
+func generationKey(kind string) string {
+	return kind + ":generation"
+}
+
+// cachedContent returns the value cached under a key of a content kind, loading
+// and caching it on a miss. Errors are not cached, and cache failures are
+// logged before falling back to load.
+func cachedContent[T any](ctx context.Context, c *ContentCache, kind, key string, load func() (T, error)) (T, error) {
+	if c == nil {
+		return load()
+	}
+
+	// The generation is read before loading, so a value loaded while a write
+	// bumps it is cached under the old generation and never read
+	generation, err := cache.Generation(ctx, c.cache, generationKey(kind), 0)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to read the %s cache generation", kind), err)
+		return load()
+	}
+	key = kind + ":" + generation + ":" + key
+
+	var value T
+	err = cache.GetValue(ctx, c.cache, key, &value)
+	if err == nil {
+		return value, nil
+	}
+	if !errors.Is(err, cache.ErrMiss) {
+		logger.Error(fmt.Sprintf("Failed to read cached %s", kind), err)
+	}
+
+	value, err = load()
+	if err != nil {
+		return value, err
+	}
+	if err := cache.SetValue(ctx, c.cache, key, value, c.ttl); err != nil {
+		logger.Error(fmt.Sprintf("Failed to cache %s", kind), err)
+	}
+	return value, nil
+}
+
+// contentKey joins the parts of a cache key, escaping them so that different
+// parts never produce the same key
+func contentKey(parts ...string) string {
+	escaped := make([]string, len(parts))
+	for i, part := range parts {
+		escaped[i] = url.QueryEscape(part)
+	}
+	return strings.Join(escaped, ":")
+}
+
+// listingKey is the cache key of a page of a listing
+func listingKey(listing, filter string, includeUnpublished bool, page pagination.Params) string {
+	pageKey := fmt.Sprintf("offset=%d,limit=%d", page.Offset, page.Limit)
+	if page.UseCursor {
+		pageKey = fmt.Sprintf("cursor=,limit=%d", page.Limit)
+		if page.After != nil {
+			pageKey = fmt.Sprintf("cursor=%s,limit=%d", page.After.Encode(), page.Limit)
+		}
+	}
+	return contentKey(listing, filter, fmt.Sprint(includeUnpublished), pageKey)
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/flutterninja9/mental-math-app/config"
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository/memory"
+	"github.com/flutterninja9/mental-math-app/pkg/cache"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// cachedServices are services sharing a content cache on an in-process Redis
+// server, and the repositories behind them for writes that bypass the cache
+type cachedServices struct {
+	exercises    ExerciseService
+	paths        LearningPathService
+	trash        TrashService
+	exerciseRepo *memory.ExerciseRepository
+	pathRepo     *memory.LearningPathRepository
+}
+
+func newCachedServices(t *testing.T) cachedServices {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+	content := NewContentCache(cache.NewRedis(client), time.Hour)
+
+	s := cachedServices{
+		exerciseRepo: memory.NewExerciseRepository(nil),
+		pathRepo:     memory.NewLearningPathRepository(nil),
+	}
+	s.exercises = NewExerciseService(s.exerciseRepo, s.pathRepo, config.DeletePolicySoft, content)
+	s.paths = NewLearningPathService(s.pathRepo, s.exerciseRepo, content)
+	s.trash = NewTrashService(s.exerciseRepo, s.pathRepo, memory.NewEnrollmentRepository(), memory.NewUserRepository(), nil, nil, nil, content, 0)
+	return s
+}
+
+// listedTitles lists the titles of the addition exercises through the cache
+func listedTitles(t *testing.T, s cachedServices) []string {
+	t.Helper()
+	page, err := s.exercises.GetByCategory(context.Background(), "addition", true, pagination.Params{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	titles := []string{}
+	for _, exercise := range page.Items {
+		titles = append(titles, exercise.Title)
+	}
+	return titles
+}
+
+func assertTitles(t *testing.T, step string, got []string, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: titles = %q, want %q", step, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("%s: titles = %q, want %q", step, got, want)
+		}
+	}
+}
+
+func TestContentCacheInvalidatesExercises(t *testing.T) {
+	ctx := context.Background()
+	s := newCachedServices(t)
+	editor := primitive.NewObjectID()
+
+	first := &model.Exercise{Title: "1 + 1", Category: "addition", Difficulty: "easy"}
+	if err := s.exercises.Create(ctx, first, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+	assertTitles(t, "after create", listedTitles(t, s), "1 + 1")
+
+	// Writes that bypass the services are not seen until the cache is invalidated
+	if err := s.exerciseRepo.Create(ctx, &model.Exercise{Title: "2 + 2", Category: "addition", Difficulty: "easy"}, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+	assertTitles(t, "after a bypassing write", listedTitles(t, s), "1 + 1")
+
+	first.Title = "1 + 2"
+	if err := s.exercises.Update(ctx, first, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+	assertTitles(t, "after update", listedTitles(t, s), "1 + 2", "2 + 2")
+	if cached, err := s.exercises.GetByID(ctx, first.ID); err != nil || cached.Title != "1 + 2" {
+		t.Fatalf("cached exercise = %v, %v", cached, err)
+	}
+
+	if err := s.exercises.Delete(ctx, first.ID, editor); err != nil {
+		t.Fatal(err)
+	}
+	assertTitles(t, "after delete", listedTitles(t, s), "2 + 2")
+	if _, err := s.exercises.GetByID(ctx, first.ID); err == nil {
+		t.Error("deleted exercise is still cached")
+	}
+
+	if err := s.trash.RestoreExercise(ctx, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	assertTitles(t, "after restore", listedTitles(t, s), "1 + 2", "2 + 2")
+}
+
+func TestContentCacheInvalidatesPaths(t *testing.T) {
+	ctx := context.Background()
+	s := newCachedServices(t)
+	editor := primitive.NewObjectID()
+
+	exercise := &model.Exercise{Title: "1 + 1", Category: "addition", Difficulty: "easy"}
+	if err := s.exercises.Create(ctx, exercise, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+	path := &model.LearningPath{
+		Title:  "Addition",
+		Stages: []model.PathStage{{Title: "Sums", ExerciseIDs: []primitive.ObjectID{exercise.ID}}},
+	}
+	if err := s.paths.Create(ctx, path, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+	cachedTitle := func() string {
+		t.Helper()
+		cached, err := s.paths.GetByID(ctx, path.ID)
+		if err != nil {
+			return ""
+		}
+		return cached.Title
+	}
+	if got := cachedTitle(); got != "Addition" {
+		t.Fatalf("title after create = %q", got)
+	}
+
+	path.Title = "Sums"
+	if err := s.paths.Update(ctx, path, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := cachedTitle(); got != "Sums" {
+		t.Errorf("title after update = %q, want Sums", got)
+	}
+
+	if err := s.paths.Delete(ctx, path.ID, editor); err != nil {
+		t.Fatal(err)
+	}
+	if got := cachedTitle(); got != "" {
+		t.Errorf("deleted path is still cached as %q", got)
+	}
+
+	if err := s.trash.RestorePath(ctx, path.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := cachedTitle(); got != "Sums" {
+		t.Errorf("title after restore = %q, want Sums", got)
+	}
+
+	// Purging a trashed exercise takes it out of the stages of cached paths
+	if err := s.exercises.Delete(ctx, exercise.ID, editor); err != nil {
+		t.Fatal(err)
+	}
+	if cached, err := s.paths.GetByID(ctx, path.ID); err != nil || len(cached.Stages[0].ExerciseIDs) != 1 {
+		t.Fatalf("cached path = %v, %v", cached, err)
+	}
+	if _, err := s.trash.Purge(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if cached, err := s.paths.GetByID(ctx, path.ID); err != nil || len(cached.Stages[0].ExerciseIDs) != 0 {
+		t.Errorf("path after purge = %v, %v, want the exercise removed from its stage", cached, err)
+	}
+}
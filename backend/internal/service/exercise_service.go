@@ -2,112 +2,188 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"time"
 
+	"github.com/flutterninja9/mental-math-app/config"
 	"github.com/flutterninja9/mental-math-app/internal/domain/model"
 	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type ExerciseService interface {
-	Create(ctx context.Context, exercise *model.Exercise) error
+	Create(ctx context.Context, exercise *model.Exercise, meta model.RevisionMeta) error
 	GetByID(ctx context.Context, id primitive.ObjectID) (*model.Exercise, error)
-	GetByCategory(ctx context.Context, category string, page, limit int) ([]*model.Exercise, int64, error)
-	GetByDifficulty(ctx context.Context, difficulty string, page, limit int) ([]*model.Exercise, int64, error)
-	GetByTags(ctx context.Context, tags []string, page, limit int) ([]*model.Exercise, int64, error)
-	Update(ctx context.Context, exercise *model.Exercise) error
-	Delete(ctx context.Context, id primitive.ObjectID) error
+	GetByCategory(ctx context.Context, category string, includeUnpublished bool, page pagination.Params) (pagination.Page[*model.Exercise], error)
+	GetByDifficulty(ctx context.Context, difficulty string, includeUnpublished bool, page pagination.Params) (pagination.Page[*model.Exercise], error)
+	GetByTags(ctx context.Context, tags []string, includeUnpublished bool, page pagination.Params) (pagination.Page[*model.Exercise], error)
+	Search(ctx context.Context, search ExerciseSearch) (pagination.Page[*model.Exercise], error)
+	Update(ctx context.Context, exercise *model.Exercise, meta model.RevisionMeta) error
+	Delete(ctx context.Context, id, deletedBy primitive.ObjectID) error
+	GetReferencingPaths(ctx context.Context, id primitive.ObjectID) ([]*model.LearningPath, error)
+}
+
+var (
+	// ErrExerciseInUse is returned when deleting an exercise that learning paths still reference
+	ErrExerciseInUse = errors.New("exercise is used by learning paths")
+	// ErrRelevanceSort is returned when sorting by relevance without a text query or with cursor pagination
+	ErrRelevanceSort = errors.New("relevance sort requires a text query and page pagination")
+)
+
+// ExerciseSearch is a search over exercises and the page of results to return
+type ExerciseSearch struct {
+	model.ExerciseQuery
+	Page pagination.Params
 }
 
 type exerciseService struct {
 	exerciseRepo repository.ExerciseRepository
+	pathRepo     repository.LearningPathRepository
+	deletePolicy string
+	cache        *ContentCache
 }
 
-func NewExerciseService(exerciseRepo repository.ExerciseRepository) ExerciseService {
+func NewExerciseService(
+	exerciseRepo repository.ExerciseRepository,
+	pathRepo repository.LearningPathRepository,
+	deletePolicy string,
+	cache *ContentCache,
+) ExerciseService {
 	return &exerciseService{
 		exerciseRepo: exerciseRepo,
+		pathRepo:     pathRepo,
+		deletePolicy: deletePolicy,
+		cache:        cache,
 	}
 }
 
-func (s *exerciseService) Create(ctx context.Context, exercise *model.Exercise) error {
-	return s.exerciseRepo.Create(ctx, exercise)
+func (s *exerciseService) Create(ctx context.Context, exercise *model.Exercise, meta model.RevisionMeta) error {
+	if exercise.Status == "" {
+		exercise.Status = model.StatusDraft
+	}
+	if exercise.Review.Comments == nil {
+		exercise.Review.Comments = []model.ReviewComment{}
+	}
+
+	err := s.exerciseRepo.Create(ctx, exercise, meta)
+	s.cache.InvalidateExercises(ctx)
+	return err
 }
 
 func (s *exerciseService) GetByID(ctx context.Context, id primitive.ObjectID) (*model.Exercise, error) {
-	return s.exerciseRepo.GetByID(ctx, id)
+	return cachedContent(ctx, s.cache, exerciseContent, contentKey("id", id.Hex()), func() (*model.Exercise, error) {
+		return s.exerciseRepo.GetByID(ctx, id)
+	})
 }
 
-func (s *exerciseService) GetByCategory(ctx context.Context, category string, page, limit int) ([]*model.Exercise, int64, error) {
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 {
-		limit = 10
-	}
+func (s *exerciseService) GetByCategory(ctx context.Context, category string, includeUnpublished bool, page pagination.Params) (pagination.Page[*model.Exercise], error) {
+	key := listingKey("category", category, includeUnpublished, page)
+	return cachedContent(ctx, s.cache, exerciseContent, key, func() (pagination.Page[*model.Exercise], error) {
+		exercises, err := s.exerciseRepo.GetByCategory(ctx, category, includeUnpublished, page)
+		if err != nil {
+			return pagination.Page[*model.Exercise]{}, err
+		}
+		return s.exercisePage(ctx, exercises, model.ExerciseQuery{Category: category, IncludeUnpublished: includeUnpublished}, page)
+	})
+}
 
-	offset := (page - 1) * limit
+func (s *exerciseService) GetByDifficulty(ctx context.Context, difficulty string, includeUnpublished bool, page pagination.Params) (pagination.Page[*model.Exercise], error) {
+	key := listingKey("difficulty", difficulty, includeUnpublished, page)
+	return cachedContent(ctx, s.cache, exerciseContent, key, func() (pagination.Page[*model.Exercise], error) {
+		exercises, err := s.exerciseRepo.GetByDifficulty(ctx, difficulty, includeUnpublished, page)
+		if err != nil {
+			return pagination.Page[*model.Exercise]{}, err
+		}
+		return s.exercisePage(ctx, exercises, model.ExerciseQuery{Difficulty: difficulty, IncludeUnpublished: includeUnpublished}, page)
+	})
+}
 
-	exercises, err := s.exerciseRepo.GetByCategory(ctx, category, limit, offset)
-	if err != nil {
-		return nil, 0, err
-	}
+func (s *exerciseService) GetByTags(ctx context.Context, tags []string, includeUnpublished bool, page pagination.Params) (pagination.Page[*model.Exercise], error) {
+	key := listingKey("tags", contentKey(tags...), includeUnpublished, page)
+	return cachedContent(ctx, s.cache, exerciseContent, key, func() (pagination.Page[*model.Exercise], error) {
+		exercises, err := s.exerciseRepo.GetByTags(ctx, tags, includeUnpublished, page)
+		if err != nil {
+			return pagination.Page[*model.Exercise]{}, err
+		}
+		return s.exercisePage(ctx, exercises, model.ExerciseQuery{Tags: tags, IncludeUnpublished: includeUnpublished}, page)
+	})
+}
 
-	total, err := s.exerciseRepo.Count(ctx, bson.M{"category": category})
+// exercisePage counts the exercises matching a listing's query and builds its page
+func (s *exerciseService) exercisePage(ctx context.Context, exercises []*model.Exercise, query model.ExerciseQuery, page pagination.Params) (pagination.Page[*model.Exercise], error) {
+	total, err := s.exerciseRepo.Count(ctx, query)
 	if err != nil {
-		return nil, 0, err
+		return pagination.Page[*model.Exercise]{}, err
 	}
-
-	return exercises, total, nil
+	return pagination.NewPage(exercises, page, total, func(e *model.Exercise) pagination.Cursor {
+		return pagination.IDCursor(e.ID)
+	}), nil
 }
 
-func (s *exerciseService) GetByDifficulty(ctx context.Context, difficulty string, page, limit int) ([]*model.Exercise, int64, error) {
-	if page < 1 {
-		page = 1
+// Search returns exercises matching combined filters and an optional text query.
+// Without an explicit order, text searches are sorted by relevance and other
+// searches by newest first.
+func (s *exerciseService) Search(ctx context.Context, search ExerciseSearch) (pagination.Page[*model.Exercise], error) {
+	query, page := search.ExerciseQuery, search.Page
+	if query.Sort == "" {
+		if query.Text != "" {
+			query.Sort = model.ExerciseSortRelevance
+		} else {
+			query.Sort = model.ExerciseSortCreatedAt
+			query.Descending = true
+		}
 	}
-	if limit < 1 {
-		limit = 10
+	if query.Sort == model.ExerciseSortRelevance && (query.Text == "" || page.UseCursor) {
+		return pagination.Page[*model.Exercise]{}, ErrRelevanceSort
 	}
 
-	offset := (page - 1) * limit
-
-	exercises, err := s.exerciseRepo.GetByDifficulty(ctx, difficulty, limit, offset)
-	if err != nil {
-		return nil, 0, err
+	// Cursors carry the sort they were issued for, so one from another order is rejected
+	sortKey := query.Sort
+	if query.Descending {
+		sortKey = "-" + sortKey
 	}
-
-	total, err := s.exerciseRepo.Count(ctx, bson.M{"difficulty": difficulty})
-	if err != nil {
-		return nil, 0, err
+	if page.After != nil {
+		if page.After.Sort != sortKey {
+			return pagination.Page[*model.Exercise]{}, pagination.ErrInvalidCursor
+		}
+
+		var err error
+		if query.Sort == model.ExerciseSortTitle {
+			var title string
+			err = json.Unmarshal(page.After.Value, &title)
+			query.After = title
+		} else {
+			var createdAt time.Time
+			err = json.Unmarshal(page.After.Value, &createdAt)
+			query.After = createdAt
+		}
+		if err != nil {
+			return pagination.Page[*model.Exercise]{}, pagination.ErrInvalidCursor
+		}
 	}
 
-	return exercises, total, nil
-}
-
-func (s *exerciseService) GetByTags(ctx context.Context, tags []string, page, limit int) ([]*model.Exercise, int64, error) {
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 {
-		limit = 10
-	}
-
-	offset := (page - 1) * limit
-
-	exercises, err := s.exerciseRepo.GetByTags(ctx, tags, limit, offset)
+	exercises, err := s.exerciseRepo.Search(ctx, query, page)
 	if err != nil {
-		return nil, 0, err
+		return pagination.Page[*model.Exercise]{}, err
 	}
-
-	total, err := s.exerciseRepo.Count(ctx, bson.M{"tags": bson.M{"$in": tags}})
+	total, err := s.exerciseRepo.Count(ctx, query)
 	if err != nil {
-		return nil, 0, err
+		return pagination.Page[*model.Exercise]{}, err
 	}
 
-	return exercises, total, nil
+	return pagination.NewPage(exercises, page, total, func(e *model.Exercise) pagination.Cursor {
+		var value interface{} = e.Metadata.CreatedAt
+		if query.Sort == model.ExerciseSortTitle {
+			value = e.Title
+		}
+		raw, _ := json.Marshal(value)
+		return pagination.Cursor{Sort: sortKey, Value: raw, ID: e.ID}
+	}), nil
 }
 
-func (s *exerciseService) Update(ctx context.Context, exercise *model.Exercise) error {
+func (s *exerciseService) Update(ctx context.Context, exercise *model.Exercise, meta model.RevisionMeta) error {
 	if exercise.ID.IsZero() {
 		return errors.New("exercise ID is required")
 	}
@@ -117,9 +193,38 @@ func (s *exerciseService) Update(ctx context.Context, exercise *model.Exercise)
 		return err
 	}
 
-	return s.exerciseRepo.Update(ctx, exercise)
+	err = s.exerciseRepo.Update(ctx, exercise, meta)
+	s.cache.InvalidateExercises(ctx)
+	return err
+}
+
+// Delete moves an exercise to the trash according to the configured delete
+// policy. It is removed for good when the trash is purged.
+func (s *exerciseService) Delete(ctx context.Context, id, deletedBy primitive.ObjectID) error {
+	switch s.deletePolicy {
+	case config.DeletePolicySoft:
+		// References stay valid; the exercise is only hidden from listings
+	case config.DeletePolicyCascade:
+		_, err := s.pathRepo.RemoveExercise(ctx, id, model.RevisionMeta{AuthorID: deletedBy, Note: "Removed deleted exercise"})
+		s.cache.InvalidatePaths(ctx)
+		if err != nil {
+			return err
+		}
+	default:
+		paths, err := s.pathRepo.GetByExerciseID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if len(paths) > 0 {
+			return ErrExerciseInUse
+		}
+	}
+
+	err := s.exerciseRepo.SoftDelete(ctx, id, deletedBy)
+	s.cache.InvalidateExercises(ctx)
+	return err
 }
 
-func (s *exerciseService) Delete(ctx context.Context, id primitive.ObjectID) error {
-	return s.exerciseRepo.Delete(ctx, id)
+func (s *exerciseService) GetReferencingPaths(ctx context.Context, id primitive.ObjectID) ([]*model.LearningPath, error) {
+	return s.pathRepo.GetByExerciseID(ctx, id)
 }
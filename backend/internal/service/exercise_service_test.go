@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository/memory"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+)
+
+func TestSearchRejectsCursorFromAnotherSort(t *testing.T) {
+	ctx := context.Background()
+	exercises := memory.NewExerciseRepository(nil)
+	service := NewExerciseService(exercises, memory.NewLearningPathRepository(nil), "", nil)
+	for _, title := range []string{"Sums", "Differences", "Products"} {
+		exercise := &model.Exercise{Title: title, Category: "arithmetic", Difficulty: "easy", Status: model.StatusPublished}
+		if err := exercises.Create(ctx, exercise, model.RevisionMeta{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	byTitle := model.ExerciseQuery{Sort: model.ExerciseSortTitle}
+	first, err := service.Search(ctx, ExerciseSearch{ExerciseQuery: byTitle, Page: pagination.Params{Limit: 2, UseCursor: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor, err := pagination.DecodeCursor(first.Meta.NextCursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next, err := service.Search(ctx, ExerciseSearch{ExerciseQuery: byTitle, Page: pagination.Params{Limit: 2, UseCursor: true, After: cursor}})
+	if err != nil || len(next.Items) != 1 || next.Items[0].Title != "Sums" {
+		t.Fatalf("next page by title = %d exercises, %v, want Sums", len(next.Items), err)
+	}
+
+	for _, query := range []model.ExerciseQuery{
+		{Sort: model.ExerciseSortTitle, Descending: true},
+		{Sort: model.ExerciseSortCreatedAt},
+		{},
+	} {
+		_, err := service.Search(ctx, ExerciseSearch{ExerciseQuery: query, Page: pagination.Params{Limit: 2, UseCursor: true, After: cursor}})
+		if !errors.Is(err, pagination.ErrInvalidCursor) {
+			t.Errorf("title cursor reused for sort %q (descending %v): %v, want ErrInvalidCursor", query.Sort, query.Descending, err)
+		}
+	}
+}
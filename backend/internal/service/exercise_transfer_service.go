@@ -0,0 +1,425 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Formats exercises can be imported from and exported to
+const (
+	TransferFormatJSONL = "jsonl"
+	TransferFormatCSV   = "csv"
+	TransferFormatQTI   = "qti"  // IMS QTI 2.1 content package
+	TransferFormatGIFT  = "gift" // Moodle GIFT
+)
+
+const (
+	// maxImportRows caps the number of rows in a single import
+	maxImportRows = 5000
+	// maxJSONLineSize is the longest JSON Lines row accepted
+	maxJSONLineSize = 1 << 20
+	// listSeparator separates the items of list columns in CSV files
+	listSeparator = "|"
+)
+
+var (
+	// ErrUnsupportedFormat is returned for formats other than the TransferFormat constants
+	ErrUnsupportedFormat = errors.New("unsupported format")
+	// ErrTooManyRows is returned when an import has more rows than maxImportRows
+	ErrTooManyRows = errors.New("too many rows in import")
+	// ErrMalformedImport is returned when a file cannot be read at all, such as a CSV without a header
+	ErrMalformedImport = errors.New("malformed import file")
+)
+
+// csvColumns are the columns of exported CSV files, in order. Imports match
+// columns by header name, ignore unknown ones and treat missing ones as empty.
+var csvColumns = []string{
+	"id", "external_key", "title", "description", "type", "category", "difficulty",
+	"problem", "options", "correct_answer", "explanation", "tags", "status",
+}
+
+// ExerciseRecord is an exercise as a flat row in an import or export file. ID and
+// Status are only written by exports and ignored on import.
+type ExerciseRecord struct {
+	ID            string   `json:"id,omitempty"`
+	ExternalKey   string   `json:"external_key,omitempty"`
+	Title         string   `json:"title" validate:"required"`
+	Description   string   `json:"description" validate:"required"`
+	Type          string   `json:"type" validate:"required,oneof=multiple_choice fill_in"`
+	Category      string   `json:"category" validate:"required"`
+	Difficulty    string   `json:"difficulty" validate:"required,oneof=easy medium hard"`
+	Problem       string   `json:"problem" validate:"required"`
+	Options       []string `json:"options"`
+	CorrectAnswer string   `json:"correct_answer" validate:"required"`
+	Explanation   string   `json:"explanation"`
+	Tags          []string `json:"tags"`
+	Status        string   `json:"status,omitempty"`
+}
+
+// ImportOptions controls how an import is applied
+type ImportOptions struct {
+	AuthorID primitive.ObjectID
+	DryRun   bool // validate and report without writing
+	Upsert   bool // update exercises whose external key already exists instead of rejecting the row
+
+	// Category and Difficulty fill in rows that leave them empty, as QTI and
+	// GIFT files usually do
+	Category   string
+	Difficulty string
+}
+
+// ImportReport summarises an import. Rows are numbered by their line in the
+// file, or by item for QTI packages.
+type ImportReport struct {
+	DryRun  bool       `json:"dry_run"`
+	Total   int        `json:"total"`
+	Created int        `json:"created"`
+	Updated int        `json:"updated"`
+	Failed  int        `json:"failed"`
+	Errors  []RowError `json:"errors"`
+}
+
+// RowError lists what was wrong with a single row
+type RowError struct {
+	Row         int               `json:"row"`
+	ExternalKey string            `json:"external_key,omitempty"`
+	Errors      map[string]string `json:"errors"`
+}
+
+type ExerciseTransferService interface {
+	Import(ctx context.Context, format string, r io.Reader, opts ImportOptions) (*ImportReport, error)
+	Export(ctx context.Context, format string, query model.ExerciseQuery, w io.Writer) error
+}
+
+type exerciseTransferService struct {
+	exerciseService ExerciseService
+	exerciseRepo    repository.ExerciseRepository
+	validator       *utils.CustomValidator
+}
+
+// NewExerciseTransferService creates a new instance of the exercise import and export service
+func NewExerciseTransferService(exerciseService ExerciseService, exerciseRepo repository.ExerciseRepository) ExerciseTransferService {
+	return &exerciseTransferService{
+		exerciseService: exerciseService,
+		exerciseRepo:    exerciseRepo,
+		validator:       utils.NewValidator(),
+	}
+}
+
+// numberedRecord is a decoded row and the line it started on
+type numberedRecord struct {
+	row    int
+	record ExerciseRecord
+}
+
+// Import creates, or with opts.Upsert updates, an exercise for every valid row.
+// Rows are applied independently, so one bad row does not stop the others;
+// every failure is listed in the report.
+func (s *exerciseTransferService) Import(ctx context.Context, format string, r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	var records []numberedRecord
+	var decodeErrors []RowError
+	var err error
+	switch format {
+	case TransferFormatJSONL:
+		records, decodeErrors, err = decodeJSONLines(r)
+	case TransferFormatCSV:
+		records, decodeErrors, err = decodeCSV(r)
+	case TransferFormatQTI:
+		records, decodeErrors, err = decodeQTI(r)
+	case TransferFormatGIFT:
+		records, decodeErrors, err = decodeGIFT(r)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{
+		DryRun: opts.DryRun,
+		Total:  len(records) + len(decodeErrors),
+		Errors: decodeErrors,
+	}
+
+	seenKeys := make(map[string]int)
+	for _, numbered := range records {
+		record := numbered.record
+		if record.Category == "" {
+			record.Category = opts.Category
+		}
+		if record.Difficulty == "" {
+			record.Difficulty = opts.Difficulty
+		}
+		rowError := RowError{Row: numbered.row, ExternalKey: record.ExternalKey}
+
+		if valErrors := s.validator.Validate(record); valErrors.HasErrors() {
+			rowError.Errors = valErrors.Errors
+			report.Errors = append(report.Errors, rowError)
+			continue
+		}
+
+		if record.ExternalKey != "" {
+			if first, ok := seenKeys[record.ExternalKey]; ok {
+				rowError.Errors = map[string]string{"external_key": "Duplicate of row " + strconv.Itoa(first)}
+				report.Errors = append(report.Errors, rowError)
+				continue
+			}
+			seenKeys[record.ExternalKey] = numbered.row
+		}
+
+		created, err := s.importRecord(ctx, record, opts)
+		if err != nil {
+			rowError.Errors = map[string]string{"row": err.Error()}
+			report.Errors = append(report.Errors, rowError)
+			continue
+		}
+		if created {
+			report.Created++
+		} else {
+			report.Updated++
+		}
+	}
+
+	report.Failed = len(report.Errors)
+	return report, nil
+}
+
+// importRecord writes a single valid row and reports whether it created an exercise
+func (s *exerciseTransferService) importRecord(ctx context.Context, record ExerciseRecord, opts ImportOptions) (bool, error) {
+	meta := model.RevisionMeta{AuthorID: opts.AuthorID, Note: "Imported"}
+
+	var existing *model.Exercise
+	if record.ExternalKey != "" {
+		found, err := s.exerciseRepo.GetByExternalKey(ctx, record.ExternalKey)
+		switch {
+		case err == nil:
+			if !opts.Upsert {
+				return false, errors.New("an exercise with this external key already exists")
+			}
+			existing = found
+		case err.Error() != "exercise not found":
+			return false, err
+		}
+	}
+
+	if existing != nil {
+		applyRecord(existing, record)
+		if opts.DryRun {
+			return false, nil
+		}
+		return false, s.exerciseService.Update(ctx, existing, meta)
+	}
+
+	exercise := &model.Exercise{
+		ExternalKey: record.ExternalKey,
+		Metadata: model.ExerciseMetadata{
+			GeneratedBy: "import",
+			CreatedAt:   time.Now(),
+		},
+	}
+	applyRecord(exercise, record)
+	if opts.DryRun {
+		return true, nil
+	}
+	return true, s.exerciseService.Create(ctx, exercise, meta)
+}
+
+// Export writes every exercise matching a query's filters in the given format
+func (s *exerciseTransferService) Export(ctx context.Context, format string, query model.ExerciseQuery, w io.Writer) error {
+	switch format {
+	case TransferFormatJSONL:
+		encoder := json.NewEncoder(w)
+		return s.exerciseRepo.ForEach(ctx, query, func(exercise *model.Exercise) error {
+			return encoder.Encode(exerciseToRecord(exercise))
+		})
+	case TransferFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(csvColumns); err != nil {
+			return err
+		}
+		err := s.exerciseRepo.ForEach(ctx, query, func(exercise *model.Exercise) error {
+			return writer.Write(recordToCSV(exerciseToRecord(exercise)))
+		})
+		if err != nil {
+			return err
+		}
+		writer.Flush()
+		return writer.Error()
+	case TransferFormatQTI:
+		writer := newQTIPackageWriter(w)
+		err := s.exerciseRepo.ForEach(ctx, query, func(exercise *model.Exercise) error {
+			return writer.Write(exerciseToRecord(exercise))
+		})
+		if err != nil {
+			return err
+		}
+		return writer.Close()
+	case TransferFormatGIFT:
+		writer := newGIFTWriter(w)
+		err := s.exerciseRepo.ForEach(ctx, query, func(exercise *model.Exercise) error {
+			return writer.Write(exerciseToRecord(exercise))
+		})
+		if err != nil {
+			return err
+		}
+		return writer.Flush()
+	default:
+		return ErrUnsupportedFormat
+	}
+}
+
+// decodeJSONLines reads one exercise per line, skipping blank lines
+func decodeJSONLines(r io.Reader) ([]numberedRecord, []RowError, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJSONLineSize)
+
+	var records []numberedRecord
+	var rowErrors []RowError
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if len(records)+len(rowErrors) >= maxImportRows {
+			return nil, nil, ErrTooManyRows
+		}
+
+		var record ExerciseRecord
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			rowErrors = append(rowErrors, RowError{Row: line, Errors: map[string]string{"row": "Invalid JSON"}})
+			continue
+		}
+		records = append(records, numberedRecord{row: line, record: record})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, ErrMalformedImport
+	}
+
+	return records, rowErrors, nil
+}
+
+// decodeCSV reads one exercise per row after a header row naming the columns
+func decodeCSV(r io.Reader) ([]numberedRecord, []RowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, ErrMalformedImport
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	var records []numberedRecord
+	var rowErrors []RowError
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if len(records)+len(rowErrors) >= maxImportRows {
+			return nil, nil, ErrTooManyRows
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rowErrors = append(rowErrors, RowError{Row: parseErr.StartLine, Errors: map[string]string{"row": "Invalid CSV row"}})
+			continue
+		}
+		if err != nil {
+			return nil, nil, ErrMalformedImport
+		}
+
+		column := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(fields) {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+		records = append(records, numberedRecord{row: line, record: ExerciseRecord{
+			ExternalKey:   column("external_key"),
+			Title:         column("title"),
+			Description:   column("description"),
+			Type:          column("type"),
+			Category:      column("category"),
+			Difficulty:    column("difficulty"),
+			Problem:       column("problem"),
+			Options:       splitList(column("options")),
+			CorrectAnswer: column("correct_answer"),
+			Explanation:   column("explanation"),
+			Tags:          splitList(column("tags")),
+		}})
+	}
+
+	return records, rowErrors, nil
+}
+
+// applyRecord copies the content of a row onto an exercise
+func applyRecord(exercise *model.Exercise, record ExerciseRecord) {
+	exercise.Title = record.Title
+	exercise.Description = record.Description
+	exercise.Type = record.Type
+	exercise.Category = record.Category
+	exercise.Difficulty = record.Difficulty
+	exercise.Content = model.ExerciseContent{
+		Problem:       record.Problem,
+		Options:       record.Options,
+		CorrectAnswer: record.CorrectAnswer,
+		Explanation:   record.Explanation,
+	}
+	exercise.Tags = record.Tags
+}
+
+func exerciseToRecord(exercise *model.Exercise) ExerciseRecord {
+	return ExerciseRecord{
+		ID:            exercise.ID.Hex(),
+		ExternalKey:   exercise.ExternalKey,
+		Title:         exercise.Title,
+		Description:   exercise.Description,
+		Type:          exercise.Type,
+		Category:      exercise.Category,
+		Difficulty:    exercise.Difficulty,
+		Problem:       exercise.Content.Problem,
+		Options:       exercise.Content.Options,
+		CorrectAnswer: exercise.Content.CorrectAnswer,
+		Explanation:   exercise.Content.Explanation,
+		Tags:          exercise.Tags,
+		Status:        exercise.Status,
+	}
+}
+
+// recordToCSV lays out a record in csvColumns order
+func recordToCSV(record ExerciseRecord) []string {
+	return []string{
+		record.ID, record.ExternalKey, record.Title, record.Description, record.Type,
+		record.Category, record.Difficulty, record.Problem, strings.Join(record.Options, listSeparator),
+		record.CorrectAnswer, record.Explanation, strings.Join(record.Tags, listSeparator), record.Status,
+	}
+}
+
+// splitList splits a CSV list column, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, listSeparator) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
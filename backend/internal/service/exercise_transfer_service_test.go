@@ -0,0 +1,165 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository/memory"
+)
+
+// newTransferService returns a transfer service over an empty in-memory store
+func newTransferService() (ExerciseTransferService, *memory.ExerciseRepository) {
+	exerciseRepo := memory.NewExerciseRepository(nil)
+	exerciseService := NewExerciseService(exerciseRepo, memory.NewLearningPathRepository(nil), "", nil)
+	return NewExerciseTransferService(exerciseService, exerciseRepo), exerciseRepo
+}
+
+// importFixture imports a file from testdata and fails the test on any row error
+func importFixture(t *testing.T, transfer ExerciseTransferService, format, name string, opts ImportOptions) *ImportReport {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := transfer.Import(context.Background(), format, bytes.NewReader(data), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+// storedRecords lists every stored exercise as a record, without the fields
+// imports ignore
+func storedRecords(t *testing.T, exerciseRepo *memory.ExerciseRepository) []ExerciseRecord {
+	t.Helper()
+	var records []ExerciseRecord
+	err := exerciseRepo.ForEach(context.Background(), model.ExerciseQuery{IncludeUnpublished: true}, func(exercise *model.Exercise) error {
+		record := exerciseToRecord(exercise)
+		record.ID, record.Status = "", ""
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestExerciseTransferRoundTrip(t *testing.T) {
+	for _, format := range []string{TransferFormatJSONL, TransferFormatCSV, TransferFormatQTI, TransferFormatGIFT} {
+		t.Run(format, func(t *testing.T) {
+			ctx := context.Background()
+			source, sourceRepo := newTransferService()
+			if report := importFixture(t, source, TransferFormatJSONL, "exercises.jsonl", ImportOptions{}); report.Failed > 0 || report.Created != 4 {
+				t.Fatalf("fixture import = %+v", report)
+			}
+			want := storedRecords(t, sourceRepo)
+
+			var exported bytes.Buffer
+			if err := source.Export(ctx, format, model.ExerciseQuery{IncludeUnpublished: true}, &exported); err != nil {
+				t.Fatal(err)
+			}
+
+			target, targetRepo := newTransferService()
+			report, err := target.Import(ctx, format, &exported, ImportOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Failed > 0 || report.Created != len(want) {
+				t.Fatalf("report = %+v", report)
+			}
+			if got := storedRecords(t, targetRepo); !reflect.DeepEqual(got, want) {
+				t.Errorf("imported\n%+v\nwant\n%+v", got, want)
+			}
+
+			// Importing the export again matches every exercise by its external key
+			exported.Reset()
+			if err := source.Export(ctx, format, model.ExerciseQuery{IncludeUnpublished: true}, &exported); err != nil {
+				t.Fatal(err)
+			}
+			report, err = target.Import(ctx, format, &exported, ImportOptions{Upsert: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Failed > 0 || report.Updated != 3 || report.Created != 1 {
+				t.Errorf("second import = %+v, want 3 updated and the exercise without a key created", report)
+			}
+		})
+	}
+}
+
+func TestImportQTIItem(t *testing.T) {
+	transfer, exerciseRepo := newTransferService()
+	report := importFixture(t, transfer, TransferFormatQTI, "item.xml", ImportOptions{Category: "arithmetic", Difficulty: "medium"})
+	if report.Failed > 0 || report.Created != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	want := []ExerciseRecord{{
+		ExternalKey:   "times-table-7",
+		Title:         "Seven times table",
+		Description:   "What is 7 × 8?",
+		Type:          "multiple_choice",
+		Category:      "arithmetic",
+		Difficulty:    "medium",
+		Problem:       "What is 7 × 8?",
+		Options:       []string{"54", "56", "63"},
+		CorrectAnswer: "56",
+	}}
+	if got := storedRecords(t, exerciseRepo); !reflect.DeepEqual(got, want) {
+		t.Errorf("imported\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestImportGIFTQuestions(t *testing.T) {
+	transfer, exerciseRepo := newTransferService()
+	report := importFixture(t, transfer, TransferFormatGIFT, "questions.gift", ImportOptions{Difficulty: "medium"})
+	if report.Total != 4 || report.Created != 3 || report.Failed != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+	if row := report.Errors[0]; row.Row != 21 || row.Errors["row"] != "essay questions are not supported" {
+		t.Errorf("row error = %+v", row)
+	}
+
+	want := []ExerciseRecord{
+		{
+			ExternalKey:   "times-9",
+			Title:         "Nines",
+			Description:   "What is 9 × 6?",
+			Type:          "multiple_choice",
+			Category:      "Multiplication",
+			Difficulty:    "easy",
+			Problem:       "What is 9 × 6?",
+			Options:       []string{"45", "54", "56"},
+			CorrectAnswer: "54",
+			Explanation:   "Nine sixes are ten sixes less one six",
+			Tags:          []string{"multiplication", "nines"},
+		},
+		{
+			Title:         "Is 17 a prime number?",
+			Description:   "Is 17 a prime number?",
+			Type:          "multiple_choice",
+			Category:      "Multiplication",
+			Difficulty:    "medium",
+			Problem:       "Is 17 a prime number?",
+			Options:       []string{"True", "False"},
+			CorrectAnswer: "True",
+		},
+		{
+			Title:         "Rounding",
+			Description:   "Round 4.6 to the nearest whole number",
+			Type:          "fill_in",
+			Category:      "Estimation",
+			Difficulty:    "medium",
+			Problem:       "Round 4.6 to the nearest whole number",
+			CorrectAnswer: "5",
+		},
+	}
+	if got := storedRecords(t, exerciseRepo); !reflect.DeepEqual(got, want) {
+		t.Errorf("imported\n%+v\nwant\n%+v", got, want)
+	}
+}
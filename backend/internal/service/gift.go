@@ -0,0 +1,419 @@
+package service
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Moodle GIFT files hold one question per blank-line separated block:
+//
+//	$CATEGORY: arithmetic
+//
+//	// difficulty: easy
+//	::Addition::What is 2 + 2? {
+//	=4
+//	####Count on from 2
+//	}
+//
+// GIFT has no place for descriptions, difficulties, tags or external keys, so
+// exports write them as comments above each question and imports read those
+// comments back when present.
+
+const (
+	// giftSpecialChars must be escaped with a backslash in GIFT text
+	giftSpecialChars = `~=#{}:\`
+	// maxGIFTTitleLength is the longest title derived from a question's text
+	maxGIFTTitleLength = 80
+)
+
+// Comment keys used to carry fields GIFT has no syntax for
+const (
+	giftKeyExternalKey = "external_key"
+	giftKeyDescription = "description"
+	giftKeyDifficulty  = "difficulty"
+	giftKeyTags        = "tags"
+)
+
+// giftQuestion is the raw text of one question and the line it started on
+type giftQuestion struct {
+	line     int
+	text     string
+	category string
+	comments map[string]string
+}
+
+// decodeGIFT reads one exercise per GIFT question. Essay, matching and other
+// question types without a single correct answer are reported as row errors.
+func decodeGIFT(r io.Reader) ([]numberedRecord, []RowError, error) {
+	questions, err := splitGIFT(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(questions) > maxImportRows {
+		return nil, nil, ErrTooManyRows
+	}
+
+	var records []numberedRecord
+	var rowErrors []RowError
+	for _, question := range questions {
+		record, err := parseGIFTQuestion(question)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{
+				Row:         question.line,
+				ExternalKey: question.comments[giftKeyExternalKey],
+				Errors:      map[string]string{"row": err.Error()},
+			})
+			continue
+		}
+		records = append(records, numberedRecord{row: question.line, record: record})
+	}
+	return records, rowErrors, nil
+}
+
+// splitGIFT breaks a GIFT file into questions, tracking the current category
+// and the comments directly above each question
+func splitGIFT(r io.Reader) ([]giftQuestion, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJSONLineSize)
+
+	var questions []giftQuestion
+	var current *giftQuestion
+	var lines []string
+	category := ""
+	comments := map[string]string{}
+
+	flush := func() {
+		if current != nil {
+			current.text = strings.Join(lines, "\n")
+			questions = append(questions, *current)
+		}
+		current, lines = nil, nil
+		comments = map[string]string{}
+	}
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case text == "":
+			flush()
+		case strings.HasPrefix(text, "//"):
+			if current == nil {
+				if key, value, ok := strings.Cut(strings.TrimSpace(text[2:]), ":"); ok {
+					comments[strings.TrimSpace(key)] = strings.TrimSpace(value)
+				}
+			}
+		case current == nil && strings.HasPrefix(text, "$CATEGORY:"):
+			// Moodle categories are paths such as $course$/Arithmetic/Fractions
+			path := strings.TrimSpace(strings.TrimPrefix(text, "$CATEGORY:"))
+			category = strings.TrimSpace(path[strings.LastIndex(path, "/")+1:])
+			comments = map[string]string{}
+		default:
+			if current == nil {
+				current = &giftQuestion{line: line, category: category, comments: comments}
+			}
+			lines = append(lines, text)
+		}
+	}
+	flush()
+
+	if err := scanner.Err(); err != nil {
+		return nil, ErrMalformedImport
+	}
+	return questions, nil
+}
+
+// parseGIFTQuestion converts a single GIFT question into a record
+func parseGIFTQuestion(question giftQuestion) (ExerciseRecord, error) {
+	text := question.text
+	record := ExerciseRecord{
+		ExternalKey: question.comments[giftKeyExternalKey],
+		Description: question.comments[giftKeyDescription],
+		Category:    question.category,
+		Difficulty:  question.comments[giftKeyDifficulty],
+	}
+	for _, tag := range strings.Split(question.comments[giftKeyTags], ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			record.Tags = append(record.Tags, tag)
+		}
+	}
+
+	if strings.HasPrefix(text, "::") {
+		end := indexUnescaped(text[2:], "::")
+		if end < 0 {
+			return record, errors.New("unterminated question title")
+		}
+		record.Title = unescapeGIFT(strings.TrimSpace(text[2 : 2+end]))
+		text = text[2+end+2:]
+	}
+
+	// Drop a text format marker such as [html] or [markdown]
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "[") {
+		if end := strings.Index(text, "]"); end > 0 {
+			text = text[end+1:]
+		}
+	}
+
+	open := indexUnescaped(text, "{")
+	if open < 0 {
+		return record, errors.New("question has no answer block")
+	}
+	closing := indexUnescaped(text[open:], "}")
+	if closing < 0 {
+		return record, errors.New("unterminated answer block")
+	}
+	closing += open
+
+	problem := strings.TrimSpace(text[:open])
+	if after := strings.TrimSpace(text[closing+1:]); after != "" {
+		// Missing word questions put the blank in the middle of the text
+		problem += " _____ " + after
+	}
+	record.Problem = unescapeGIFT(problem)
+
+	if err := parseGIFTAnswers(text[open+1:closing], &record); err != nil {
+		return record, err
+	}
+
+	if record.Title == "" {
+		record.Title = truncateRunes(record.Problem, maxGIFTTitleLength)
+	}
+	if record.Description == "" {
+		record.Description = record.Problem
+	}
+	return record, nil
+}
+
+// parseGIFTAnswers fills in the type, options, answer and explanation from the
+// text between a question's braces
+func parseGIFTAnswers(block string, record *ExerciseRecord) error {
+	block = strings.TrimSpace(block)
+	if i := indexUnescaped(block, "####"); i >= 0 {
+		record.Explanation = unescapeGIFT(strings.TrimSpace(block[i+4:]))
+		block = strings.TrimSpace(block[:i])
+	}
+
+	if block == "" {
+		return errors.New("essay questions are not supported")
+	}
+
+	// True/false questions
+	answer := block
+	if i := indexUnescaped(answer, "#"); i >= 0 {
+		answer = strings.TrimSpace(answer[:i])
+	}
+	switch strings.ToUpper(answer) {
+	case "T", "TRUE":
+		record.Type, record.Options, record.CorrectAnswer = "multiple_choice", []string{"True", "False"}, "True"
+		return nil
+	case "F", "FALSE":
+		record.Type, record.Options, record.CorrectAnswer = "multiple_choice", []string{"True", "False"}, "False"
+		return nil
+	}
+
+	// Numerical questions; only the exact value is kept, not the tolerance
+	if strings.HasPrefix(block, "#") {
+		value := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(block[1:]), "="))
+		value, _, _ = strings.Cut(value, "#")
+		value, _, _ = strings.Cut(value, ":")
+		value, _, _ = strings.Cut(value, "..")
+		value, _, _ = strings.Cut(value, "=")
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return errors.New("numerical question has no answer")
+		}
+		record.Type, record.CorrectAnswer = "fill_in", value
+		return nil
+	}
+
+	var correct []string
+	var options []string
+	wrong := 0
+	for _, choice := range splitGIFTChoices(block) {
+		text := choice.text
+		if indexUnescaped(text, "->") >= 0 {
+			return errors.New("matching questions are not supported")
+		}
+		if i := indexUnescaped(text, "#"); i >= 0 {
+			text = text[:i]
+		}
+
+		isCorrect := choice.marker == '='
+		if strings.HasPrefix(text, "%") {
+			if end := strings.Index(text[1:], "%"); end >= 0 {
+				weight, err := strconv.ParseFloat(text[1:1+end], 64)
+				if err != nil {
+					return fmt.Errorf("invalid answer weight %q", text[1:1+end])
+				}
+				isCorrect = weight >= 100
+				text = text[end+2:]
+			}
+		}
+
+		text = unescapeGIFT(strings.TrimSpace(text))
+		options = append(options, text)
+		if isCorrect {
+			correct = append(correct, text)
+		} else {
+			wrong++
+		}
+	}
+
+	switch {
+	case len(correct) == 0:
+		return errors.New("question has no correct answer")
+	case wrong == 0:
+		// Short answer: every listed answer is accepted, keep the first
+		record.Type, record.CorrectAnswer = "fill_in", correct[0]
+	case len(correct) > 1:
+		return errors.New("questions with several correct answers are not supported")
+	default:
+		record.Type, record.Options, record.CorrectAnswer = "multiple_choice", options, correct[0]
+	}
+	return nil
+}
+
+// giftChoice is one answer of a question and whether it was marked = or ~
+type giftChoice struct {
+	marker byte
+	text   string
+}
+
+// splitGIFTChoices splits an answer block at unescaped = and ~ markers
+func splitGIFTChoices(block string) []giftChoice {
+	var choices []giftChoice
+	for i := 0; i < len(block); i++ {
+		c := block[i]
+		if c == '=' || c == '~' {
+			choices = append(choices, giftChoice{marker: c})
+			continue
+		}
+		if len(choices) == 0 {
+			continue
+		}
+		// Escapes are kept so answer feedback and weights can still be told apart
+		if c == '\\' && i+1 < len(block) {
+			choices[len(choices)-1].text += block[i : i+2]
+			i++
+			continue
+		}
+		choices[len(choices)-1].text += string(c)
+	}
+	return choices
+}
+
+// giftWriter writes exercises as GIFT questions, starting a new category
+// whenever it changes
+type giftWriter struct {
+	w        *bufio.Writer
+	category string
+}
+
+func newGIFTWriter(w io.Writer) *giftWriter {
+	return &giftWriter{w: bufio.NewWriter(w)}
+}
+
+// Write adds a question for a record
+func (g *giftWriter) Write(record ExerciseRecord) error {
+	if record.Category != g.category {
+		g.category = record.Category
+		fmt.Fprintf(g.w, "$CATEGORY: %s\n\n", record.Category)
+	}
+
+	writeComment := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(g.w, "// %s: %s\n", key, strings.Join(strings.Fields(value), " "))
+		}
+	}
+	writeComment(giftKeyExternalKey, record.ExternalKey)
+	writeComment(giftKeyDescription, record.Description)
+	writeComment(giftKeyDifficulty, record.Difficulty)
+	writeComment(giftKeyTags, strings.Join(record.Tags, ", "))
+
+	fmt.Fprintf(g.w, "::%s::%s {\n", escapeGIFT(record.Title), escapeGIFT(record.Problem))
+	if record.Type == "multiple_choice" && len(record.Options) > 0 {
+		for _, option := range record.Options {
+			marker := "~"
+			if option == record.CorrectAnswer {
+				marker = "="
+			}
+			fmt.Fprintf(g.w, "%s%s\n", marker, escapeGIFT(option))
+		}
+	} else {
+		fmt.Fprintf(g.w, "=%s\n", escapeGIFT(record.CorrectAnswer))
+	}
+	if record.Explanation != "" {
+		fmt.Fprintf(g.w, "####%s\n", escapeGIFT(record.Explanation))
+	}
+	_, err := g.w.WriteString("}\n\n")
+	return err
+}
+
+// Flush writes any buffered questions
+func (g *giftWriter) Flush() error {
+	return g.w.Flush()
+}
+
+// escapeGIFT escapes special characters and newlines in GIFT text
+func escapeGIFT(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+		case strings.ContainsRune(giftSpecialChars, r):
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// unescapeGIFT reverses escapeGIFT
+func unescapeGIFT(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) {
+			i++
+			if text[i] == 'n' {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(text[i])
+			}
+			continue
+		}
+		b.WriteByte(text[i])
+	}
+	return b.String()
+}
+
+// indexUnescaped returns the index of the first occurrence of sub in text that
+// is not preceded by a backslash, or -1
+func indexUnescaped(text, sub string) int {
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(text[i:], sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+// truncateRunes shortens text to at most n runes
+func truncateRunes(text string, n int) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) <= n {
+		return string(runes)
+	}
+	return strings.TrimSpace(string(runes[:n]))
+}
@@ -3,162 +3,477 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/flutterninja9/mental-math-app/internal/domain/model"
 	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type LearningPathService interface {
-	Create(ctx context.Context, path *model.LearningPath) error
+	Create(ctx context.Context, path *model.LearningPath, meta model.RevisionMeta) error
 	GetByID(ctx context.Context, id primitive.ObjectID) (*model.LearningPath, error)
-	GetAll(ctx context.Context, limit, offset int) ([]*model.LearningPath, error)
-	GetByDifficulty(ctx context.Context, difficulty string, limit, offset int) ([]*model.LearningPath, error)
-	GetByCategory(ctx context.Context, category string, limit, offset int) ([]*model.LearningPath, error)
-	Update(ctx context.Context, path *model.LearningPath) error
-	Delete(ctx context.Context, id primitive.ObjectID) error
-	AddStage(ctx context.Context, pathID primitive.ObjectID, stage model.PathStage) error
-	UpdateStage(ctx context.Context, pathID primitive.ObjectID, stage model.PathStage) error
-	RemoveStage(ctx context.Context, pathID primitive.ObjectID, stageNumber int) error
+	GetAll(ctx context.Context, includeUnpublished bool, page pagination.Params) (pagination.Page[*model.LearningPath], error)
+	GetByDifficulty(ctx context.Context, difficulty string, includeUnpublished bool, page pagination.Params) (pagination.Page[*model.LearningPath], error)
+	GetByCategory(ctx context.Context, category string, includeUnpublished bool, page pagination.Params) (pagination.Page[*model.LearningPath], error)
+	Update(ctx context.Context, path *model.LearningPath, meta model.RevisionMeta) error
+	Delete(ctx context.Context, id, deletedBy primitive.ObjectID) error
+	AddStage(ctx context.Context, pathID primitive.ObjectID, stage model.PathStage, position int, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error)
+	UpdateStage(ctx context.Context, pathID primitive.ObjectID, stage model.PathStage, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error)
+	RemoveStage(ctx context.Context, pathID primitive.ObjectID, stageNumber int, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error)
+	ReorderStages(ctx context.Context, pathID primitive.ObjectID, order []int, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error)
+	AddStageExercise(ctx context.Context, pathID primitive.ObjectID, stageNumber int, exerciseID primitive.ObjectID, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error)
+	RemoveStageExercise(ctx context.Context, pathID primitive.ObjectID, stageNumber int, exerciseID primitive.ObjectID, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error)
+	CheckIntegrity(ctx context.Context) (*IntegrityReport, error)
 }
 
+var (
+	// ErrUnknownPrerequisite is returned when a learning path lists a prerequisite path that does not exist
+	ErrUnknownPrerequisite = errors.New("unknown prerequisite learning path")
+	// ErrInvalidStageOrder is returned when a new stage order does not name every stage exactly once
+	ErrInvalidStageOrder = errors.New("stage order must list every stage exactly once")
+	// ErrExerciseInStage is returned when adding an exercise a stage already has
+	ErrExerciseInStage = errors.New("exercise is already in the stage")
+	// ErrExerciseNotInStage is returned when removing an exercise a stage does not have
+	ErrExerciseNotInStage = errors.New("exercise is not in the stage")
+	// ErrLastStageExercise is returned when removing the only exercise of a stage
+	ErrLastStageExercise = errors.New("a stage must keep at least one exercise")
+	// ErrUnknownStage is returned when an update names a stage ID the path does not have, or names one twice
+	ErrUnknownStage = errors.New("stage does not belong to the learning path")
+)
+
+// MissingExercisesError is returned when stages reference exercises that do not exist
+type MissingExercisesError struct {
+	IDs []primitive.ObjectID
+}
+
+func (e *MissingExercisesError) Error() string {
+	ids := make([]string, len(e.IDs))
+	for i, id := range e.IDs {
+		ids[i] = id.Hex()
+	}
+	return "unknown exercises: " + strings.Join(ids, ", ")
+}
+
+// IntegrityReport lists learning path stages that reference missing or deleted exercises
+type IntegrityReport struct {
+	CheckedPaths     int               `json:"checked_paths"`
+	BrokenReferences []BrokenReference `json:"broken_references"`
+}
+
+// BrokenReference is a single stage reference to an exercise that is not available
+type BrokenReference struct {
+	PathID      primitive.ObjectID `json:"path_id"`
+	PathTitle   string             `json:"path_title"`
+	StageNumber int                `json:"stage_number"`
+	ExerciseID  primitive.ObjectID `json:"exercise_id"`
+	Reason      string             `json:"reason"` // missing or deleted
+}
+
+// integrityBatchSize is the number of paths loaded at a time during an integrity check
+const integrityBatchSize = 100
+
 type learningPathService struct {
-	pathRepo repository.LearningPathRepository
+	pathRepo     repository.LearningPathRepository
+	exerciseRepo repository.ExerciseRepository
+	cache        *ContentCache
 }
 
 // NewLearningPathService creates a new instance of the learning path service
-func NewLearningPathService(pathRepo repository.LearningPathRepository) LearningPathService {
+func NewLearningPathService(pathRepo repository.LearningPathRepository, exerciseRepo repository.ExerciseRepository, cache *ContentCache) LearningPathService {
 	return &learningPathService{
-		pathRepo: pathRepo,
+		pathRepo:     pathRepo,
+		exerciseRepo: exerciseRepo,
+		cache:        cache,
 	}
 }
 
-func (s *learningPathService) Create(ctx context.Context, path *model.LearningPath) error {
+func (s *learningPathService) Create(ctx context.Context, path *model.LearningPath, meta model.RevisionMeta) error {
+	if err := s.validateExercises(ctx, path.Stages...); err != nil {
+		return err
+	}
+	if err := s.validatePrerequisites(ctx, path, nil); err != nil {
+		return err
+	}
+
+	if path.Status == "" {
+		path.Status = model.StatusDraft
+	}
+	if path.Review.Comments == nil {
+		path.Review.Comments = []model.ReviewComment{}
+	}
+
 	// Set timestamps
 	now := time.Now()
 	path.CreatedAt = now
 	path.UpdatedAt = now
 
-	// Ensure stage numbers are properly set
+	// Ensure stage numbers and identifiers are properly set
 	for i := range path.Stages {
 		path.Stages[i].StageNumber = i + 1
+		path.Stages[i].ID = primitive.NewObjectID()
 	}
 
-	return s.pathRepo.Create(ctx, path)
+	err := s.pathRepo.Create(ctx, path, meta)
+	s.cache.InvalidatePaths(ctx)
+	return err
 }
 
 func (s *learningPathService) GetByID(ctx context.Context, id primitive.ObjectID) (*model.LearningPath, error) {
-	return s.pathRepo.GetByID(ctx, id)
+	return cachedContent(ctx, s.cache, pathContent, contentKey("id", id.Hex()), func() (*model.LearningPath, error) {
+		return s.pathRepo.GetByID(ctx, id)
+	})
 }
 
-func (s *learningPathService) GetAll(ctx context.Context, limit, offset int) ([]*model.LearningPath, error) {
-	if limit <= 0 {
-		limit = 10 // Default limit
-	}
+func (s *learningPathService) GetAll(ctx context.Context, includeUnpublished bool, page pagination.Params) (pagination.Page[*model.LearningPath], error) {
+	key := listingKey("all", "", includeUnpublished, page)
+	return cachedContent(ctx, s.cache, pathContent, key, func() (pagination.Page[*model.LearningPath], error) {
+		paths, err := s.pathRepo.GetAll(ctx, includeUnpublished, page)
+		if err != nil {
+			return pagination.Page[*model.LearningPath]{}, err
+		}
+		return s.pathPage(ctx, paths, model.PathQuery{IncludeUnpublished: includeUnpublished}, page)
+	})
+}
 
-	return s.pathRepo.GetAll(ctx, limit, offset)
+func (s *learningPathService) GetByDifficulty(ctx context.Context, difficulty string, includeUnpublished bool, page pagination.Params) (pagination.Page[*model.LearningPath], error) {
+	key := listingKey("difficulty", difficulty, includeUnpublished, page)
+	return cachedContent(ctx, s.cache, pathContent, key, func() (pagination.Page[*model.LearningPath], error) {
+		paths, err := s.pathRepo.GetByDifficulty(ctx, difficulty, includeUnpublished, page)
+		if err != nil {
+			return pagination.Page[*model.LearningPath]{}, err
+		}
+		return s.pathPage(ctx, paths, model.PathQuery{Difficulty: difficulty, IncludeUnpublished: includeUnpublished}, page)
+	})
 }
 
-func (s *learningPathService) GetByDifficulty(ctx context.Context, difficulty string, limit, offset int) ([]*model.LearningPath, error) {
-	if limit <= 0 {
-		limit = 10 // Default limit
-	}
+func (s *learningPathService) GetByCategory(ctx context.Context, category string, includeUnpublished bool, page pagination.Params) (pagination.Page[*model.LearningPath], error) {
+	key := listingKey("category", category, includeUnpublished, page)
+	return cachedContent(ctx, s.cache, pathContent, key, func() (pagination.Page[*model.LearningPath], error) {
+		paths, err := s.pathRepo.GetByCategory(ctx, category, includeUnpublished, page)
+		if err != nil {
+			return pagination.Page[*model.LearningPath]{}, err
+		}
+		return s.pathPage(ctx, paths, model.PathQuery{Category: category, IncludeUnpublished: includeUnpublished}, page)
+	})
+}
 
-	return s.pathRepo.GetByDifficulty(ctx, difficulty, limit, offset)
+// pathPage counts the paths matching a listing's query and builds its page
+func (s *learningPathService) pathPage(ctx context.Context, paths []*model.LearningPath, query model.PathQuery, page pagination.Params) (pagination.Page[*model.LearningPath], error) {
+	total, err := s.pathRepo.Count(ctx, query)
+	if err != nil {
+		return pagination.Page[*model.LearningPath]{}, err
+	}
+	return pagination.NewPage(paths, page, total, func(p *model.LearningPath) pagination.Cursor {
+		return pagination.IDCursor(p.ID)
+	}), nil
 }
 
-func (s *learningPathService) GetByCategory(ctx context.Context, category string, limit, offset int) ([]*model.LearningPath, error) {
-	if limit <= 0 {
-		limit = 10 // Default limit
+func (s *learningPathService) Update(ctx context.Context, path *model.LearningPath, meta model.RevisionMeta) error {
+	existing, err := s.pathRepo.GetByID(ctx, path.ID)
+	if err != nil {
+		return err
 	}
 
-	return s.pathRepo.GetByCategory(ctx, category, limit, offset)
-}
+	if err := s.validateExercises(ctx, path.Stages...); err != nil {
+		return err
+	}
+	if err := s.validatePrerequisites(ctx, path, existing.PrerequisitePathIDs); err != nil {
+		return err
+	}
+
+	// Stages keep their identity, which enrollments track them by, only when
+	// they are submitted with their ID. Stages without one are new.
+	known := make(map[primitive.ObjectID]bool, len(existing.Stages))
+	for _, stage := range existing.Stages {
+		known[stage.ID] = true
+	}
+	for i := range path.Stages {
+		path.Stages[i].StageNumber = i + 1
+		if path.Stages[i].ID.IsZero() {
+			path.Stages[i].ID = primitive.NewObjectID()
+			continue
+		}
+		if !known[path.Stages[i].ID] {
+			return ErrUnknownStage
+		}
+		delete(known, path.Stages[i].ID)
+	}
 
-func (s *learningPathService) Update(ctx context.Context, path *model.LearningPath) error {
 	// Update timestamp
 	path.UpdatedAt = time.Now()
 
-	// Ensure stage numbers are properly set
-	for i := range path.Stages {
-		path.Stages[i].StageNumber = i + 1
+	err = s.pathRepo.Update(ctx, path, meta)
+	s.cache.InvalidatePaths(ctx)
+	return err
+}
+
+// Delete moves a learning path to the trash. It is removed for good when the
+// trash is purged; until then paths that require it keep the link, which is
+// ignored while it is in the trash.
+func (s *learningPathService) Delete(ctx context.Context, id, deletedBy primitive.ObjectID) error {
+	defer s.cache.InvalidatePaths(ctx)
+	return s.pathRepo.SoftDelete(ctx, id, deletedBy)
+}
+
+// AddStage inserts a stage at a position, or appends it when position is zero.
+// The stage list is updated atomically, so concurrent stage edits do not overwrite
+// each other; expectedRevision additionally rejects the write when the path
+// changed since the caller read it.
+func (s *learningPathService) AddStage(ctx context.Context, pathID primitive.ObjectID, stage model.PathStage, position int, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
+	if err := s.validateExercises(ctx, stage); err != nil {
+		return nil, err
+	}
+
+	stage.ID = primitive.NewObjectID()
+
+	defer s.cache.InvalidatePaths(ctx)
+	return s.pathRepo.InsertStage(ctx, pathID, stage, position, expectedRevision, meta)
+}
+
+func (s *learningPathService) UpdateStage(ctx context.Context, pathID primitive.ObjectID, stage model.PathStage, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
+	if err := s.validateExercises(ctx, stage); err != nil {
+		return nil, err
 	}
 
-	return s.pathRepo.Update(ctx, path)
+	defer s.cache.InvalidatePaths(ctx)
+	return s.pathRepo.ReplaceStage(ctx, pathID, stage, expectedRevision, meta)
 }
 
-func (s *learningPathService) Delete(ctx context.Context, id primitive.ObjectID) error {
-	return s.pathRepo.Delete(ctx, id)
+func (s *learningPathService) RemoveStage(ctx context.Context, pathID primitive.ObjectID, stageNumber int, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
+	defer s.cache.InvalidatePaths(ctx)
+	return s.pathRepo.RemoveStage(ctx, pathID, stageNumber, expectedRevision, meta)
 }
 
-func (s *learningPathService) AddStage(ctx context.Context, pathID primitive.ObjectID, stage model.PathStage) error {
+// ReorderStages moves stages into a new order, given as current stage numbers
+func (s *learningPathService) ReorderStages(ctx context.Context, pathID primitive.ObjectID, order []int, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
 	path, err := s.pathRepo.GetByID(ctx, pathID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Set the stage number to be the next available number
-	stage.StageNumber = len(path.Stages) + 1
+	if len(order) != len(path.Stages) {
+		return nil, ErrInvalidStageOrder
+	}
+	seen := make(map[int]bool, len(order))
+	for _, number := range order {
+		if number < 1 || number > len(path.Stages) || seen[number] {
+			return nil, ErrInvalidStageOrder
+		}
+		seen[number] = true
+	}
 
-	// Add the stage
-	path.Stages = append(path.Stages, stage)
-	path.UpdatedAt = time.Now()
+	defer s.cache.InvalidatePaths(ctx)
+	return s.pathRepo.ReorderStages(ctx, pathID, order, expectedRevision, meta)
+}
 
-	return s.pathRepo.Update(ctx, path)
+// AddStageExercise adds a single exercise to the end of a stage
+func (s *learningPathService) AddStageExercise(ctx context.Context, pathID primitive.ObjectID, stageNumber int, exerciseID primitive.ObjectID, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
+	stage, err := s.findStage(ctx, pathID, stageNumber)
+	if err != nil {
+		return nil, err
+	}
+	if containsObjectID(stage.ExerciseIDs, exerciseID) {
+		return nil, ErrExerciseInStage
+	}
+
+	if err := s.validateExercises(ctx, model.PathStage{ExerciseIDs: []primitive.ObjectID{exerciseID}}); err != nil {
+		return nil, err
+	}
+
+	defer s.cache.InvalidatePaths(ctx)
+	return s.pathRepo.AddStageExercise(ctx, pathID, stageNumber, exerciseID, expectedRevision, meta)
 }
 
-func (s *learningPathService) UpdateStage(ctx context.Context, pathID primitive.ObjectID, stage model.PathStage) error {
+// RemoveStageExercise removes a single exercise from a stage
+func (s *learningPathService) RemoveStageExercise(ctx context.Context, pathID primitive.ObjectID, stageNumber int, exerciseID primitive.ObjectID, expectedRevision int, meta model.RevisionMeta) (*model.LearningPath, error) {
+	stage, err := s.findStage(ctx, pathID, stageNumber)
+	if err != nil {
+		return nil, err
+	}
+	if !containsObjectID(stage.ExerciseIDs, exerciseID) {
+		return nil, ErrExerciseNotInStage
+	}
+	if len(stage.ExerciseIDs) == 1 {
+		return nil, ErrLastStageExercise
+	}
+
+	defer s.cache.InvalidatePaths(ctx)
+	return s.pathRepo.RemoveStageExercise(ctx, pathID, stageNumber, exerciseID, expectedRevision, meta)
+}
+
+// findStage returns a stage of a path by number
+func (s *learningPathService) findStage(ctx context.Context, pathID primitive.ObjectID, stageNumber int) (*model.PathStage, error) {
 	path, err := s.pathRepo.GetByID(ctx, pathID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	for i := range path.Stages {
+		if path.Stages[i].StageNumber == stageNumber {
+			return &path.Stages[i], nil
+		}
 	}
+	return nil, errors.New("stage not found")
+}
+
+// CheckIntegrity scans every learning path for stages that reference missing or deleted exercises
+func (s *learningPathService) CheckIntegrity(ctx context.Context) (*IntegrityReport, error) {
+	report := &IntegrityReport{BrokenReferences: []BrokenReference{}}
+
+	for offset := 0; ; offset += integrityBatchSize {
+		paths, err := s.pathRepo.GetAll(ctx, true, pagination.Params{Limit: integrityBatchSize, Offset: offset})
+		if err != nil {
+			return nil, err
+		}
+
+		var exerciseIDs []primitive.ObjectID
+		for _, path := range paths {
+			for _, stage := range path.Stages {
+				exerciseIDs = append(exerciseIDs, stage.ExerciseIDs...)
+			}
+		}
 
-	// Find and update the stage
-	stageFound := false
-	for i, s := range path.Stages {
-		if s.StageNumber == stage.StageNumber {
-			path.Stages[i] = stage
-			stageFound = true
+		exercises, err := s.lookupExercises(ctx, exerciseIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, path := range paths {
+			for _, stage := range path.Stages {
+				for _, exerciseID := range stage.ExerciseIDs {
+					reason := ""
+					exercise, ok := exercises[exerciseID]
+					switch {
+					case !ok:
+						reason = "missing"
+					case exercise.DeletedAt != nil:
+						reason = "deleted"
+					default:
+						continue
+					}
+
+					report.BrokenReferences = append(report.BrokenReferences, BrokenReference{
+						PathID:      path.ID,
+						PathTitle:   path.Title,
+						StageNumber: stage.StageNumber,
+						ExerciseID:  exerciseID,
+						Reason:      reason,
+					})
+				}
+			}
+		}
+
+		report.CheckedPaths += len(paths)
+		if len(paths) < integrityBatchSize {
 			break
 		}
 	}
 
-	if !stageFound {
-		return errors.New("stage not found")
+	return report, nil
+}
+
+// validateExercises makes sure every exercise referenced by the stages exists and is not deleted
+func (s *learningPathService) validateExercises(ctx context.Context, stages ...model.PathStage) error {
+	var exerciseIDs []primitive.ObjectID
+	for _, stage := range stages {
+		exerciseIDs = append(exerciseIDs, stage.ExerciseIDs...)
+	}
+	if len(exerciseIDs) == 0 {
+		return nil
+	}
+
+	exercises, err := s.lookupExercises(ctx, exerciseIDs)
+	if err != nil {
+		return err
 	}
 
-	path.UpdatedAt = time.Now()
+	var missing []primitive.ObjectID
+	seen := make(map[primitive.ObjectID]bool)
+	for _, id := range exerciseIDs {
+		if exercise, ok := exercises[id]; (!ok || exercise.DeletedAt != nil) && !seen[id] {
+			missing = append(missing, id)
+			seen[id] = true
+		}
+	}
 
-	return s.pathRepo.Update(ctx, path)
+	if len(missing) > 0 {
+		return &MissingExercisesError{IDs: missing}
+	}
+	return nil
 }
 
-func (s *learningPathService) RemoveStage(ctx context.Context, pathID primitive.ObjectID, stageNumber int) error {
-	path, err := s.pathRepo.GetByID(ctx, pathID)
+// validatePrerequisites makes sure every prerequisite path exists and that the
+// path does not end up among its own prerequisites. Prerequisites in kept may be
+// in the trash: the path already had them, and the link is kept until they are
+// purged.
+func (s *learningPathService) validatePrerequisites(ctx context.Context, path *model.LearningPath, kept []primitive.ObjectID) error {
+	if path.PrerequisitePathIDs == nil {
+		path.PrerequisitePathIDs = []primitive.ObjectID{}
+	}
+	if path.RequiredSkills == nil {
+		path.RequiredSkills = []string{}
+	}
+	if len(path.PrerequisitePathIDs) == 0 {
+		return nil
+	}
+
+	// A new path has no ID yet; use a placeholder so self references are still caught
+	selfID := path.ID
+	if selfID.IsZero() {
+		selfID = primitive.NewObjectID()
+	}
+
+	graph, missing, err := pathPrerequisiteGraph(ctx, s.pathRepo, selfID, path.PrerequisitePathIDs)
 	if err != nil {
 		return err
 	}
-
-	// Find and remove the stage
-	stageIndex := -1
-	for i, s := range path.Stages {
-		if s.StageNumber == stageNumber {
-			stageIndex = i
-			break
+	for _, id := range missing {
+		if id != selfID && !containsObjectID(kept, id) {
+			return fmt.Errorf("%w: %s", ErrUnknownPrerequisite, id.Hex())
 		}
 	}
 
-	if stageIndex == -1 {
-		return errors.New("stage not found")
+	if cycle := findCycle(graph); cycle != nil {
+		return &PrerequisiteCycleError{Cycle: cycle}
 	}
+	return nil
+}
 
-	// Remove the stage and renumber remaining stages
-	path.Stages = append(path.Stages[:stageIndex], path.Stages[stageIndex+1:]...)
-	for i := range path.Stages {
-		path.Stages[i].StageNumber = i + 1
+// hexIDs converts object IDs to their hex representation
+func hexIDs(ids []primitive.ObjectID) []string {
+	result := make([]string, len(ids))
+	for i, id := range ids {
+		result[i] = id.Hex()
 	}
+	return result
+}
 
-	path.UpdatedAt = time.Now()
+func containsObjectID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupExercises loads the given exercises keyed by ID
+func (s *learningPathService) lookupExercises(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]*model.Exercise, error) {
+	result := make(map[primitive.ObjectID]*model.Exercise, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
 
-	return s.pathRepo.Update(ctx, path)
+	exercises, err := s.exerciseRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, exercise := range exercises {
+		result[exercise.ID] = exercise
+	}
+	return result, nil
 }
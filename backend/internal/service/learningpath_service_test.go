@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository/memory"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newTestPath creates a path with two stages on memory repositories
+func newTestPath(t *testing.T) (LearningPathService, *memory.RevisionRepository, *model.LearningPath) {
+	t.Helper()
+	ctx := context.Background()
+	revisions := memory.NewRevisionRepository()
+	exercises := memory.NewExerciseRepository(revisions)
+	service := NewLearningPathService(memory.NewLearningPathRepository(revisions), exercises, nil)
+
+	exercise := &model.Exercise{Title: "2 + 2", Category: "addition", Difficulty: "easy"}
+	if err := exercises.Create(ctx, exercise, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+	path := &model.LearningPath{
+		Title: "Addition",
+		Stages: []model.PathStage{
+			{Title: "First", ExerciseIDs: []primitive.ObjectID{exercise.ID}},
+			{Title: "Second", ExerciseIDs: []primitive.ObjectID{exercise.ID}},
+		},
+	}
+	if err := service.Create(ctx, path, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+	return service, revisions, path
+}
+
+func TestUpdatePathMatchesStagesByID(t *testing.T) {
+	ctx := context.Background()
+	service, _, path := newTestPath(t)
+	first, second := path.Stages[0], path.Stages[1]
+
+	// A stage inserted in front without an ID is new, and the others keep their IDs
+	path.Stages = []model.PathStage{{Title: "Warm up", ExerciseIDs: first.ExerciseIDs}, second, first}
+	if err := service.Update(ctx, path, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := service.GetByID(ctx, path.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stages := stored.Stages
+	if stages[0].ID == first.ID || stages[0].ID == second.ID || stages[0].ID.IsZero() {
+		t.Errorf("new stage took ID %s", stages[0].ID.Hex())
+	}
+	if stages[1].ID != second.ID || stages[2].ID != first.ID {
+		t.Errorf("moved stages lost their IDs")
+	}
+	for i, stage := range stages {
+		if stage.StageNumber != i+1 {
+			t.Errorf("stage %d is numbered %d", i+1, stage.StageNumber)
+		}
+	}
+}
+
+func TestUpdatePathRejectsUnknownStageIDs(t *testing.T) {
+	ctx := context.Background()
+	service, _, path := newTestPath(t)
+	first := path.Stages[0]
+
+	foreign := first
+	foreign.ID = primitive.NewObjectID()
+	for name, stages := range map[string][]model.PathStage{
+		"unknown":   {first, foreign},
+		"duplicate": {first, first},
+	} {
+		update := *path
+		update.Stages = stages
+		if err := service.Update(ctx, &update, model.RevisionMeta{}); !errors.Is(err, ErrUnknownStage) {
+			t.Errorf("%s stage ID: error = %v, want ErrUnknownStage", name, err)
+		}
+	}
+}
+
+func TestRestorePathRevisionRenewsRemovedStages(t *testing.T) {
+	ctx := context.Background()
+	paths, revisions, path := newTestPath(t)
+	removed := path.Stages[1]
+
+	path.Stages = path.Stages[:1]
+	if err := paths.Update(ctx, path, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+
+	restorer := NewRevisionService(revisions, nil, paths)
+	if _, err := restorer.Restore(ctx, model.ContentKindLearningPath, path.ID, 1, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := paths.GetByID(ctx, path.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Stages) != 2 || stored.Stages[0].ID != path.Stages[0].ID {
+		t.Fatalf("restored stages = %+v", stored.Stages)
+	}
+	if stored.Stages[1].ID == removed.ID || stored.Stages[1].Title != removed.Title {
+		t.Errorf("restored stage = %+v, want %q as a new stage", stored.Stages[1], removed.Title)
+	}
+}
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/internal/llm"
+	"github.com/flutterninja9/mental-math-app/pkg/logger"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// Bounds on the number of exercises placed in a generated stage
+	minStageExercises     = 3
+	maxStageExercises     = 8
+	defaultStageExercises = 5
+
+	// candidateExerciseLimit is the number of existing exercises considered per stage
+	candidateExerciseLimit = 50
+)
+
+// ErrIncompleteDraft is returned when the LLM's plan for a learning path cannot
+// be turned into a complete draft
+var ErrIncompleteDraft = errors.New("generated learning path is incomplete")
+
+type PathAuthoringService interface {
+	GenerateDraft(ctx context.Context, req GeneratePathRequest) (*GeneratedPath, error)
+}
+
+// GeneratePathRequest describes the learning path an editor wants drafted
+type GeneratePathRequest struct {
+	AuthorID   primitive.ObjectID
+	Goal       string
+	Difficulty string
+	StageCount int
+}
+
+// GeneratedPath is a draft learning path along with how its exercises were sourced
+type GeneratedPath struct {
+	Path             *model.LearningPath `json:"path"`
+	CreatedExercises []*model.Exercise   `json:"created_exercises"`
+	ReusedExercises  int                 `json:"reused_exercises"`
+}
+
+type pathAuthoringService struct {
+	llmService   llm.Service
+	pathService  LearningPathService
+	exerciseRepo repository.ExerciseRepository
+	content      *ContentCache
+}
+
+// NewPathAuthoringService creates a new instance of the path authoring service
+func NewPathAuthoringService(llmService llm.Service, pathService LearningPathService, exerciseRepo repository.ExerciseRepository, content *ContentCache) PathAuthoringService {
+	return &pathAuthoringService{
+		llmService:   llmService,
+		pathService:  pathService,
+		exerciseRepo: exerciseRepo,
+		content:      content,
+	}
+}
+
+// GenerateDraft asks the LLM to outline a path for the goal, fills each stage with
+// existing exercises where possible and newly generated ones otherwise, and saves
+// the result as a draft for an editor to review. The whole plan is checked before
+// anything is stored, and the exercises it stored are deleted again when the path
+// cannot be saved, so a failed draft leaves nothing behind.
+func (s *pathAuthoringService) GenerateDraft(ctx context.Context, req GeneratePathRequest) (*GeneratedPath, error) {
+	outline, err := s.llmService.OutlineLearningPath(ctx, req.Goal, req.Difficulty, req.StageCount)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, reused, err := s.planStages(ctx, outline, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePlan(outline, plan); err != nil {
+		return nil, err
+	}
+
+	result := &GeneratedPath{CreatedExercises: []*model.Exercise{}, ReusedExercises: reused}
+	stages := make([]model.PathStage, 0, len(plan))
+	for i, stage := range plan {
+		meta := model.RevisionMeta{AuthorID: req.AuthorID, Note: "Generated for learning path stage: " + stage.outline.Title}
+		for _, exercise := range stage.generated {
+			err := s.exerciseRepo.Create(ctx, exercise, meta)
+			s.content.InvalidateExercises(ctx)
+			if err != nil {
+				s.discardExercises(ctx, result.CreatedExercises)
+				return nil, err
+			}
+			stage.exerciseIDs = append(stage.exerciseIDs, exercise.ID)
+			result.CreatedExercises = append(result.CreatedExercises, exercise)
+		}
+
+		stages = append(stages, model.PathStage{
+			Title:              stage.outline.Title,
+			Description:        stage.outline.Description,
+			ExerciseIDs:        stage.exerciseIDs,
+			CompletionCriteria: stageCriteria(req.Difficulty, len(stage.exerciseIDs), i, len(plan)),
+		})
+	}
+
+	categories := outline.Categories
+	if len(categories) == 0 {
+		for _, stage := range outline.Stages {
+			if stage.Category != "" && !containsString(categories, stage.Category) {
+				categories = append(categories, stage.Category)
+			}
+		}
+	}
+
+	path := &model.LearningPath{
+		Title:       outline.Title,
+		Description: outline.Description,
+		Difficulty:  req.Difficulty,
+		Categories:  categories,
+		Status:      model.StatusDraft,
+		Stages:      stages,
+	}
+	meta := model.RevisionMeta{AuthorID: req.AuthorID, Note: "Generated for goal: " + req.Goal}
+	if err := s.pathService.Create(ctx, path, meta); err != nil {
+		s.discardExercises(ctx, result.CreatedExercises)
+		return nil, err
+	}
+
+	result.Path = path
+	return result, nil
+}
+
+// plannedStage is a stage of a generated path before anything is stored: the
+// existing exercises it reuses and the generated ones still to be created
+type plannedStage struct {
+	outline     llm.StageOutline
+	exerciseIDs []primitive.ObjectID
+	generated   []*model.Exercise
+}
+
+// planStages picks the exercises for every stage of the outline, generating the
+// ones that cannot be reused, and returns the plan and the number reused
+func (s *pathAuthoringService) planStages(ctx context.Context, outline *llm.PathOutline, req GeneratePathRequest) ([]*plannedStage, int, error) {
+	used := make(map[primitive.ObjectID]bool)
+	plan := make([]*plannedStage, 0, len(outline.Stages))
+	reused := 0
+
+	for _, stageOutline := range outline.Stages {
+		count := stageOutline.ExerciseCount
+		if count <= 0 {
+			count = defaultStageExercises
+		}
+		count = min(max(count, minStageExercises), maxStageExercises)
+
+		exerciseIDs, err := s.reuseExercises(ctx, stageOutline, req.Difficulty, count, used)
+		if err != nil {
+			return nil, 0, err
+		}
+		reused += len(exerciseIDs)
+
+		stage := &plannedStage{outline: stageOutline, exerciseIDs: exerciseIDs, generated: []*model.Exercise{}}
+		if missing := count - len(exerciseIDs); missing > 0 {
+			if stage.generated, err = s.generateExercises(ctx, stageOutline, req, missing); err != nil {
+				return nil, 0, err
+			}
+		}
+		plan = append(plan, stage)
+	}
+	return plan, reused, nil
+}
+
+// validatePlan checks a generated path before any of it is stored
+func validatePlan(outline *llm.PathOutline, plan []*plannedStage) error {
+	if strings.TrimSpace(outline.Title) == "" {
+		return fmt.Errorf("%w: the path has no title", ErrIncompleteDraft)
+	}
+	if len(plan) == 0 {
+		return fmt.Errorf("%w: the path has no stages", ErrIncompleteDraft)
+	}
+	for i, stage := range plan {
+		if strings.TrimSpace(stage.outline.Title) == "" {
+			return fmt.Errorf("%w: stage %d has no title", ErrIncompleteDraft, i+1)
+		}
+		if count := len(stage.exerciseIDs) + len(stage.generated); count < minStageExercises {
+			return fmt.Errorf("%w: stage %q has %d exercises", ErrIncompleteDraft, stage.outline.Title, count)
+		}
+		for _, exercise := range stage.generated {
+			if strings.TrimSpace(exercise.Content.Problem) == "" || strings.TrimSpace(exercise.Content.CorrectAnswer) == "" {
+				return fmt.Errorf("%w: stage %q has an exercise without a problem or answer", ErrIncompleteDraft, stage.outline.Title)
+			}
+		}
+	}
+	return nil
+}
+
+// discardExercises deletes the exercises a failed draft stored. They are new
+// drafts nothing else refers to, so they skip the trash.
+func (s *pathAuthoringService) discardExercises(ctx context.Context, exercises []*model.Exercise) {
+	for _, exercise := range exercises {
+		if err := s.exerciseRepo.Delete(ctx, exercise.ID); err != nil {
+			logger.Error("Failed to delete exercise generated for a failed draft", err)
+		}
+	}
+	s.content.InvalidateExercises(ctx)
+}
+
+// reuseExercises picks existing exercises in the stage's category and difficulty,
+// preferring those that share the most tags with the stage
+func (s *pathAuthoringService) reuseExercises(ctx context.Context, stage llm.StageOutline, difficulty string, count int, used map[primitive.ObjectID]bool) ([]primitive.ObjectID, error) {
+	if stage.Category == "" {
+		return []primitive.ObjectID{}, nil
+	}
+
+	candidates, err := s.exerciseRepo.GetByCategory(ctx, stage.Category, false, pagination.Params{Limit: candidateExerciseLimit})
+	if err != nil {
+		return nil, err
+	}
+
+	best := make([]*model.Exercise, 0, count)
+	bestOverlap := make([]int, 0, count)
+	for _, exercise := range candidates {
+		if exercise.Difficulty != difficulty || used[exercise.ID] {
+			continue
+		}
+
+		overlap := 0
+		for _, tag := range exercise.Tags {
+			if containsString(stage.Tags, tag) {
+				overlap++
+			}
+		}
+
+		// Keep the candidates ordered by tag overlap, stable for equal overlap
+		pos := len(best)
+		for pos > 0 && bestOverlap[pos-1] < overlap {
+			pos--
+		}
+		best = append(best[:pos], append([]*model.Exercise{exercise}, best[pos:]...)...)
+		bestOverlap = append(bestOverlap[:pos], append([]int{overlap}, bestOverlap[pos:]...)...)
+	}
+
+	if len(best) > count {
+		best = best[:count]
+	}
+
+	ids := make([]primitive.ObjectID, len(best))
+	for i, exercise := range best {
+		ids[i] = exercise.ID
+		used[exercise.ID] = true
+	}
+	return ids, nil
+}
+
+// generateExercises generates new exercises for a stage without storing them
+func (s *pathAuthoringService) generateExercises(ctx context.Context, stage llm.StageOutline, req GeneratePathRequest, count int) ([]*model.Exercise, error) {
+	category := stage.Category
+	if category == "" {
+		category = stage.Title
+	}
+
+	exercises, err := s.llmService.GenerateExerciseBatch(ctx, category, req.Difficulty, count)
+	if err != nil {
+		return nil, err
+	}
+	if len(exercises) < count {
+		return nil, fmt.Errorf("%w: generated %d of %d exercises for stage %q", ErrIncompleteDraft, len(exercises), count, stage.Title)
+	}
+
+	for _, exercise := range exercises[:count] {
+		for _, tag := range stage.Tags {
+			if !containsString(exercise.Tags, tag) {
+				exercise.Tags = append(exercise.Tags, tag)
+			}
+		}
+		exercise.Status = model.StatusDraft
+		exercise.Review.Comments = []model.ReviewComment{}
+		if exercise.Metadata.CreatedAt.IsZero() {
+			exercise.Metadata.CreatedAt = time.Now()
+		}
+	}
+
+	return exercises[:count], nil
+}
+
+// stageCriteria sets completion criteria for a generated stage. Learners must try
+// most of the stage's exercises, and the accuracy bar rises towards the last stage.
+func stageCriteria(difficulty string, exerciseCount, index, stageCount int) model.CompletionCriteria {
+	accuracy := 80.0
+	switch difficulty {
+	case "medium":
+		accuracy = 75
+	case "hard":
+		accuracy = 70
+	}
+	if stageCount > 1 {
+		accuracy += 10 * float64(index) / float64(stageCount-1)
+	}
+
+	return model.CompletionCriteria{
+		MinAccuracy:  math.Round(accuracy),
+		MinExercises: int(math.Ceil(float64(exerciseCount) * 0.8)),
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
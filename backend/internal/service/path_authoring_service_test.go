@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository/memory"
+	"github.com/flutterninja9/mental-math-app/internal/llm"
+)
+
+// fakeLLM outlines a fixed path and generates short exercise batches for the
+// categories listed in short
+type fakeLLM struct {
+	llm.Service
+	outline *llm.PathOutline
+	short   map[string]bool
+}
+
+func (f *fakeLLM) OutlineLearningPath(ctx context.Context, goal, difficulty string, stageCount int) (*llm.PathOutline, error) {
+	return f.outline, nil
+}
+
+func (f *fakeLLM) GenerateExerciseBatch(ctx context.Context, category, difficulty string, count int) ([]*model.Exercise, error) {
+	if f.short[category] {
+		count--
+	}
+	exercises := make([]*model.Exercise, count)
+	for i := range exercises {
+		exercises[i] = &model.Exercise{
+			Title:      fmt.Sprintf("%s %d", category, i+1),
+			Category:   category,
+			Difficulty: difficulty,
+			Content:    model.ExerciseContent{Problem: fmt.Sprintf("%d + 1 = ?", i), CorrectAnswer: fmt.Sprint(i + 1)},
+		}
+	}
+	return exercises, nil
+}
+
+// failingPaths rejects every learning path it is asked to create
+type failingPaths struct {
+	LearningPathService
+}
+
+func (failingPaths) Create(ctx context.Context, path *model.LearningPath, meta model.RevisionMeta) error {
+	return errors.New("database unavailable")
+}
+
+func TestGenerateDraft(t *testing.T) {
+	outline := &llm.PathOutline{
+		Title: "Mental addition",
+		Stages: []llm.StageOutline{
+			{Title: "Single digits", Category: "addition", ExerciseCount: 3},
+			{Title: "Carrying", Category: "carrying", ExerciseCount: 4},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		short   map[string]bool
+		title   string
+		failing bool
+		wantErr error
+	}{
+		{name: "stores the draft", title: outline.Title},
+		{name: "short batch in a later stage", short: map[string]bool{"carrying": true}, title: outline.Title, wantErr: ErrIncompleteDraft},
+		{name: "untitled path", title: " ", wantErr: ErrIncompleteDraft},
+		{name: "path not saved", title: outline.Title, failing: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			exercises := memory.NewExerciseRepository(nil)
+			pathRepo := memory.NewLearningPathRepository(nil)
+			var paths LearningPathService = NewLearningPathService(pathRepo, exercises, nil)
+			if tt.failing {
+				paths = failingPaths{paths}
+			}
+			drafted := *outline
+			drafted.Title = tt.title
+			authoring := NewPathAuthoringService(&fakeLLM{outline: &drafted, short: tt.short}, paths, exercises, nil)
+
+			generated, err := authoring.GenerateDraft(ctx, GeneratePathRequest{Goal: "add in your head", Difficulty: "easy"})
+			stored, countErr := exercises.Count(ctx, model.ExerciseQuery{IncludeUnpublished: true})
+			if countErr != nil {
+				t.Fatal(countErr)
+			}
+
+			if tt.wantErr == nil && !tt.failing {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(generated.CreatedExercises) != 7 || stored != 7 {
+					t.Errorf("created %d exercises, %d stored; want 7", len(generated.CreatedExercises), stored)
+				}
+				path, err := pathRepo.GetByID(ctx, generated.Path.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(path.Stages) != 2 || len(path.Stages[0].ExerciseIDs) != 3 || len(path.Stages[1].ExerciseIDs) != 4 {
+					t.Errorf("stored path has %d stages, want 2 with 3 and 4 exercises", len(path.Stages))
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("draft was generated, want an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if stored != 0 {
+				t.Errorf("%d exercises left behind by the failed draft, want none", stored)
+			}
+			if count, err := pathRepo.Count(ctx, model.PathQuery{IncludeUnpublished: true}); err != nil || count != 0 {
+				t.Errorf("paths stored = %d, %v, want none", count, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,448 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// A learning path package is a zip holding three JSON files: the manifest, the
+// path with its stages, and every exercise the stages reference. Stages refer
+// to exercises by their ID in the exporting environment; importing assigns new
+// IDs and reuses exercises that already exist with the same content hash.
+// Prerequisite paths are not part of a package and are dropped on export.
+const (
+	PathPackageFormat  = "mental-math-path"
+	PathPackageVersion = 1
+
+	packageManifestFile  = "manifest.json"
+	packagePathFile      = "path.json"
+	packageExercisesFile = "exercises.json"
+
+	// maxPathPackageSize caps the size of an uploaded package
+	maxPathPackageSize = 20 << 20
+)
+
+// Actions reported for each exercise of an imported package
+const (
+	PackageActionCreate = "create" // no exercise with the same content exists yet
+	PackageActionReuse  = "reuse"  // an exercise with the same content is used instead
+)
+
+// ErrInvalidPackage is returned for uploads that are not a readable learning path package
+var ErrInvalidPackage = errors.New("invalid learning path package")
+
+// PackageManifest identifies a package and lets imports check it is complete
+type PackageManifest struct {
+	Format     string            `json:"format"`
+	Version    int               `json:"version"`
+	ExportedAt time.Time         `json:"exported_at"`
+	PathTitle  string            `json:"path_title"`
+	Exercises  int               `json:"exercises"`
+	Checksums  map[string]string `json:"checksums"` // SHA-256 of the other files, by name
+}
+
+// PackagePath is a learning path without its environment-specific fields
+type PackagePath struct {
+	Title          string         `json:"title" validate:"required"`
+	Description    string         `json:"description" validate:"required"`
+	Difficulty     string         `json:"difficulty" validate:"required,oneof=easy medium hard"`
+	Categories     []string       `json:"categories" validate:"required,min=1"`
+	RequiredSkills []string       `json:"required_skills"`
+	Stages         []PackageStage `json:"stages" validate:"required,min=1,dive"`
+}
+
+// PackageStage is a stage whose exercises are given by package reference
+type PackageStage struct {
+	Title              string                   `json:"title" validate:"required"`
+	Description        string                   `json:"description"`
+	CompletionCriteria model.CompletionCriteria `json:"completion_criteria"`
+	Exercises          []string                 `json:"exercises" validate:"required,min=1"`
+}
+
+// PackageExercise is an exercise as stored in a package
+type PackageExercise struct {
+	Ref         string                `json:"ref" validate:"required"` // the exercise ID in the exporting environment
+	ContentHash string                `json:"content_hash"`
+	Title       string                `json:"title" validate:"required"`
+	Description string                `json:"description" validate:"required"`
+	Type        string                `json:"type" validate:"required,oneof=multiple_choice fill_in"`
+	Category    string                `json:"category" validate:"required"`
+	Difficulty  string                `json:"difficulty" validate:"required,oneof=easy medium hard"`
+	Content     model.ExerciseContent `json:"content"`
+	Tags        []string              `json:"tags"`
+}
+
+// PackageImportOptions controls how a package is imported
+type PackageImportOptions struct {
+	AuthorID primitive.ObjectID
+	DryRun   bool // report what would change without writing
+}
+
+// PackageImportResult describes what an import did, or for a dry run would do
+type PackageImportResult struct {
+	DryRun    bool                    `json:"dry_run"`
+	Manifest  PackageManifest         `json:"manifest"`
+	Path      *model.LearningPath     `json:"path,omitempty"` // nil for dry runs
+	Exercises []PackageExerciseResult `json:"exercises"`
+	Created   int                     `json:"created"`
+	Reused    int                     `json:"reused"`
+}
+
+// PackageExerciseResult is the outcome for one exercise of a package
+type PackageExerciseResult struct {
+	Ref        string `json:"ref"`
+	Title      string `json:"title"`
+	Action     string `json:"action"`
+	ExerciseID string `json:"exercise_id,omitempty"` // empty for exercises a dry run would create
+}
+
+type PathPackageService interface {
+	Export(ctx context.Context, pathID primitive.ObjectID, w io.Writer) error
+	Import(ctx context.Context, r io.Reader, opts PackageImportOptions) (*PackageImportResult, error)
+}
+
+type pathPackageService struct {
+	pathService     LearningPathService
+	exerciseService ExerciseService
+	exerciseRepo    repository.ExerciseRepository
+	validator       *utils.CustomValidator
+}
+
+// NewPathPackageService creates a new instance of the learning path package service
+func NewPathPackageService(pathService LearningPathService, exerciseService ExerciseService, exerciseRepo repository.ExerciseRepository) PathPackageService {
+	return &pathPackageService{
+		pathService:     pathService,
+		exerciseService: exerciseService,
+		exerciseRepo:    exerciseRepo,
+		validator:       utils.NewValidator(),
+	}
+}
+
+// Export writes a package holding a learning path and all of its exercises
+func (s *pathPackageService) Export(ctx context.Context, pathID primitive.ObjectID, w io.Writer) error {
+	path, err := s.pathService.GetByID(ctx, pathID)
+	if err != nil {
+		return err
+	}
+
+	// Exercises are packaged in the order the stages first use them
+	var exerciseIDs []primitive.ObjectID
+	seen := make(map[primitive.ObjectID]bool)
+	packagePath := PackagePath{
+		Title:          path.Title,
+		Description:    path.Description,
+		Difficulty:     path.Difficulty,
+		Categories:     path.Categories,
+		RequiredSkills: path.RequiredSkills,
+	}
+	for _, stage := range path.Stages {
+		packageStage := PackageStage{
+			Title:              stage.Title,
+			Description:        stage.Description,
+			CompletionCriteria: stage.CompletionCriteria,
+			Exercises:          hexIDs(stage.ExerciseIDs),
+		}
+		packagePath.Stages = append(packagePath.Stages, packageStage)
+		for _, id := range stage.ExerciseIDs {
+			if !seen[id] {
+				seen[id] = true
+				exerciseIDs = append(exerciseIDs, id)
+			}
+		}
+	}
+
+	exercises, err := s.exerciseRepo.GetByIDs(ctx, exerciseIDs)
+	if err != nil {
+		return err
+	}
+	byID := make(map[primitive.ObjectID]*model.Exercise, len(exercises))
+	for _, exercise := range exercises {
+		byID[exercise.ID] = exercise
+	}
+
+	var missing []primitive.ObjectID
+	packageExercises := make([]PackageExercise, 0, len(exerciseIDs))
+	for _, id := range exerciseIDs {
+		exercise, ok := byID[id]
+		if !ok || exercise.DeletedAt != nil {
+			missing = append(missing, id)
+			continue
+		}
+		packageExercises = append(packageExercises, PackageExercise{
+			Ref:         id.Hex(),
+			ContentHash: model.ContentHash(exercise),
+			Title:       exercise.Title,
+			Description: exercise.Description,
+			Type:        exercise.Type,
+			Category:    exercise.Category,
+			Difficulty:  exercise.Difficulty,
+			Content:     exercise.Content,
+			Tags:        exercise.Tags,
+		})
+	}
+	if len(missing) > 0 {
+		return &MissingExercisesError{IDs: missing}
+	}
+
+	files := map[string]interface{}{
+		packagePathFile:      packagePath,
+		packageExercisesFile: packageExercises,
+	}
+	manifest := PackageManifest{
+		Format:     PathPackageFormat,
+		Version:    PathPackageVersion,
+		ExportedAt: time.Now().UTC(),
+		PathTitle:  path.Title,
+		Exercises:  len(packageExercises),
+		Checksums:  make(map[string]string, len(files)),
+	}
+
+	archive := zip.NewWriter(w)
+	for _, name := range []string{packagePathFile, packageExercisesFile} {
+		data, err := json.MarshalIndent(files[name], "", "  ")
+		if err != nil {
+			return err
+		}
+		manifest.Checksums[name] = checksum(data)
+		if err := writeZipFile(archive, name, data); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeZipFile(archive, packageManifestFile, data); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// Import creates a draft learning path from a package. Package exercises whose
+// content hash matches an existing exercise reuse it; the others are created
+// as drafts. A dry run reports the same outcome without writing anything.
+func (s *pathPackageService) Import(ctx context.Context, r io.Reader, opts PackageImportOptions) (*PackageImportResult, error) {
+	manifest, packagePath, packageExercises, err := s.readPackage(r)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, len(packageExercises))
+	for i := range packageExercises {
+		exercise := packageExercises[i].toExercise()
+		hashes[i] = model.ContentHash(exercise)
+	}
+	existing, err := s.exerciseRepo.GetByContentHashes(ctx, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	// The oldest exercise wins when several share a hash
+	byHash := make(map[string]primitive.ObjectID, len(existing))
+	for i := len(existing) - 1; i >= 0; i-- {
+		byHash[existing[i].ContentHash] = existing[i].ID
+	}
+
+	result := &PackageImportResult{DryRun: opts.DryRun, Manifest: *manifest}
+	meta := model.RevisionMeta{AuthorID: opts.AuthorID, Note: "Imported from learning path package"}
+	idByRef := make(map[string]primitive.ObjectID, len(packageExercises))
+	for i, packageExercise := range packageExercises {
+		outcome := PackageExerciseResult{Ref: packageExercise.Ref, Title: packageExercise.Title}
+
+		if id, ok := byHash[hashes[i]]; ok {
+			outcome.Action = PackageActionReuse
+			idByRef[packageExercise.Ref] = id
+			if !id.IsZero() {
+				outcome.ExerciseID = id.Hex()
+			}
+			result.Reused++
+			result.Exercises = append(result.Exercises, outcome)
+			continue
+		}
+
+		outcome.Action = PackageActionCreate
+		result.Created++
+		if !opts.DryRun {
+			exercise := packageExercise.toExercise()
+			if err := s.exerciseService.Create(ctx, exercise, meta); err != nil {
+				return nil, fmt.Errorf("failed to create exercise %s: %w", packageExercise.Ref, err)
+			}
+			outcome.ExerciseID = exercise.ID.Hex()
+			idByRef[packageExercise.Ref] = exercise.ID
+		}
+		// Later copies of the same content in the package reuse this one; a dry
+		// run has no ID for it yet
+		byHash[hashes[i]] = idByRef[packageExercise.Ref]
+		result.Exercises = append(result.Exercises, outcome)
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+
+	path := &model.LearningPath{
+		Title:          packagePath.Title,
+		Description:    packagePath.Description,
+		Difficulty:     packagePath.Difficulty,
+		Categories:     packagePath.Categories,
+		RequiredSkills: packagePath.RequiredSkills,
+	}
+	for _, packageStage := range packagePath.Stages {
+		stage := model.PathStage{
+			Title:              packageStage.Title,
+			Description:        packageStage.Description,
+			CompletionCriteria: packageStage.CompletionCriteria,
+		}
+		for _, ref := range packageStage.Exercises {
+			if id := idByRef[ref]; !containsObjectID(stage.ExerciseIDs, id) {
+				stage.ExerciseIDs = append(stage.ExerciseIDs, id)
+			}
+		}
+		path.Stages = append(path.Stages, stage)
+	}
+
+	if err := s.pathService.Create(ctx, path, meta); err != nil {
+		return nil, err
+	}
+	result.Path = path
+	return result, nil
+}
+
+// readPackage unpacks and checks a package: the manifest must name a supported
+// format, every file must match its checksum and every stage reference must
+// name a packaged exercise
+func (s *pathPackageService) readPackage(r io.Reader) (*PackageManifest, *PackagePath, []PackageExercise, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxPathPackageSize+1))
+	if err != nil || len(data) > maxPathPackageSize {
+		return nil, nil, nil, fmt.Errorf("%w: package is too large", ErrInvalidPackage)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: not a zip file", ErrInvalidPackage)
+	}
+
+	contents := make(map[string][]byte)
+	for _, file := range archive.File {
+		switch file.Name {
+		case packageManifestFile, packagePathFile, packageExercisesFile:
+			content, err := readZipFile(file)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("%w: %s could not be read", ErrInvalidPackage, file.Name)
+			}
+			contents[file.Name] = content
+		}
+	}
+
+	var manifest PackageManifest
+	if err := unmarshalPackageFile(contents, packageManifestFile, &manifest); err != nil {
+		return nil, nil, nil, err
+	}
+	if manifest.Format != PathPackageFormat || manifest.Version != PathPackageVersion {
+		return nil, nil, nil, fmt.Errorf("%w: unsupported format %s version %d", ErrInvalidPackage, manifest.Format, manifest.Version)
+	}
+	for _, name := range []string{packagePathFile, packageExercisesFile} {
+		if content, ok := contents[name]; ok && manifest.Checksums[name] != checksum(content) {
+			return nil, nil, nil, fmt.Errorf("%w: checksum mismatch for %s", ErrInvalidPackage, name)
+		}
+	}
+
+	var path PackagePath
+	if err := unmarshalPackageFile(contents, packagePathFile, &path); err != nil {
+		return nil, nil, nil, err
+	}
+	var exercises []PackageExercise
+	if err := unmarshalPackageFile(contents, packageExercisesFile, &exercises); err != nil {
+		return nil, nil, nil, err
+	}
+
+	if valErrors := s.validator.Validate(path); valErrors.HasErrors() {
+		return nil, nil, nil, fmt.Errorf("%w: path: %s", ErrInvalidPackage, describeValidationErrors(valErrors))
+	}
+	refs := make(map[string]bool, len(exercises))
+	for _, exercise := range exercises {
+		if valErrors := s.validator.Validate(exercise); valErrors.HasErrors() {
+			return nil, nil, nil, fmt.Errorf("%w: exercise %s: %s", ErrInvalidPackage, exercise.Ref, describeValidationErrors(valErrors))
+		}
+		if refs[exercise.Ref] {
+			return nil, nil, nil, fmt.Errorf("%w: exercise %s is listed twice", ErrInvalidPackage, exercise.Ref)
+		}
+		refs[exercise.Ref] = true
+	}
+	for _, stage := range path.Stages {
+		for _, ref := range stage.Exercises {
+			if !refs[ref] {
+				return nil, nil, nil, fmt.Errorf("%w: stage %q references unknown exercise %s", ErrInvalidPackage, stage.Title, ref)
+			}
+		}
+	}
+
+	return &manifest, &path, exercises, nil
+}
+
+// toExercise builds a new draft exercise from a packaged one
+func (e PackageExercise) toExercise() *model.Exercise {
+	return &model.Exercise{
+		Title:       e.Title,
+		Description: e.Description,
+		Type:        e.Type,
+		Category:    e.Category,
+		Difficulty:  e.Difficulty,
+		Content:     e.Content,
+		Tags:        e.Tags,
+		Metadata: model.ExerciseMetadata{
+			GeneratedBy: "import",
+			CreatedAt:   time.Now(),
+		},
+	}
+}
+
+func unmarshalPackageFile(contents map[string][]byte, name string, v interface{}) error {
+	content, ok := contents[name]
+	if !ok {
+		return fmt.Errorf("%w: %s is missing", ErrInvalidPackage, name)
+	}
+	if err := json.Unmarshal(content, v); err != nil {
+		return fmt.Errorf("%w: %s is not valid JSON", ErrInvalidPackage, name)
+	}
+	return nil
+}
+
+func writeZipFile(archive *zip.Writer, name string, data []byte) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	return err
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// describeValidationErrors flattens validation errors into a stable message
+func describeValidationErrors(valErrors utils.ValidationErrors) string {
+	fields := make([]string, 0, len(valErrors.Errors))
+	for field, message := range valErrors.Errors {
+		fields = append(fields, field+": "+message)
+	}
+	sort.Strings(fields)
+	return strings.Join(fields, "; ")
+}
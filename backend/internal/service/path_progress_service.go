@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNotEnrolled is returned when a user asks for progress in a path they have not joined
+var ErrNotEnrolled = errors.New("not enrolled in learning path")
+
+type PathProgressService interface {
+	Enroll(ctx context.Context, userID, pathID primitive.ObjectID) (*PathProgress, error)
+	GetProgress(ctx context.Context, userID, pathID primitive.ObjectID) (*PathProgress, error)
+	HandleAttempt(ctx context.Context, userID, exerciseID primitive.ObjectID) error
+}
+
+// PathProgress is a user's progress through a learning path, stage by stage
+type PathProgress struct {
+	PathID          primitive.ObjectID  `json:"path_id"`
+	Title           string              `json:"title"`
+	EnrolledAt      time.Time           `json:"enrolled_at"`
+	CompletedAt     *time.Time          `json:"completed_at,omitempty"`
+	CompletedStages int                 `json:"completed_stages"`
+	TotalStages     int                 `json:"total_stages"`
+	Stages          []StageProgressView `json:"stages"`
+}
+
+// StageProgressView combines a stage's definition with the user's progress in it
+type StageProgressView struct {
+	model.StageProgress
+	StageNumber       int     `json:"stage_number"`
+	Title             string  `json:"title"`
+	RequiredExercises int     `json:"required_exercises"`
+	RequiredAccuracy  float64 `json:"required_accuracy"`
+}
+
+type pathProgressService struct {
+	enrollmentRepo repository.EnrollmentRepository
+	pathRepo       repository.LearningPathRepository
+	progressRepo   repository.ProgressRepository
+	content        *ContentCache
+}
+
+// NewPathProgressService creates a new instance of the path progress service
+func NewPathProgressService(
+	enrollmentRepo repository.EnrollmentRepository,
+	pathRepo repository.LearningPathRepository,
+	progressRepo repository.ProgressRepository,
+	content *ContentCache,
+) PathProgressService {
+	return &pathProgressService{
+		enrollmentRepo: enrollmentRepo,
+		pathRepo:       pathRepo,
+		progressRepo:   progressRepo,
+		content:        content,
+	}
+}
+
+func (s *pathProgressService) Enroll(ctx context.Context, userID, pathID primitive.ObjectID) (*PathProgress, error) {
+	path, err := s.pathRepo.GetByID(ctx, pathID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.ensureStageIDs(ctx, path); err != nil {
+		return nil, err
+	}
+
+	if enrollment, err := s.enrollmentRepo.GetByUserAndPath(ctx, userID, pathID); err == nil {
+		return buildPathProgress(path, enrollment), nil
+	}
+
+	enrollment := &model.PathEnrollment{
+		UserID: userID,
+		PathID: pathID,
+		Stages: []model.StageProgress{},
+	}
+	syncStages(enrollment, path, time.Now())
+
+	if err := s.enrollmentRepo.Create(ctx, enrollment); err != nil {
+		return nil, err
+	}
+
+	// Attempts made before enrolling count towards the stages
+	if err := s.evaluate(ctx, enrollment, path); err != nil {
+		return nil, err
+	}
+
+	return buildPathProgress(path, enrollment), nil
+}
+
+func (s *pathProgressService) GetProgress(ctx context.Context, userID, pathID primitive.ObjectID) (*PathProgress, error) {
+	path, err := s.pathRepo.GetByID(ctx, pathID)
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment, err := s.enrollmentRepo.GetByUserAndPath(ctx, userID, pathID)
+	if err != nil {
+		return nil, ErrNotEnrolled
+	}
+
+	// The path may have been edited since the last evaluation
+	if err := s.evaluate(ctx, enrollment, path); err != nil {
+		return nil, err
+	}
+
+	return buildPathProgress(path, enrollment), nil
+}
+
+// HandleAttempt re-evaluates every active enrollment whose path contains the exercise
+func (s *pathProgressService) HandleAttempt(ctx context.Context, userID, exerciseID primitive.ObjectID) error {
+	enrollments, err := s.enrollmentRepo.GetActiveByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, enrollment := range enrollments {
+		path, err := s.pathRepo.GetByID(ctx, enrollment.PathID)
+		if err != nil {
+			continue
+		}
+		if !pathContainsExercise(path, exerciseID) {
+			continue
+		}
+
+		if err := s.evaluate(ctx, enrollment, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensureStageIDs assigns identifiers to stages created before stages had them,
+// so enrollments can keep track of stages that are later renumbered
+func (s *pathProgressService) ensureStageIDs(ctx context.Context, path *model.LearningPath) error {
+	missing := false
+	for i := range path.Stages {
+		if path.Stages[i].ID.IsZero() {
+			path.Stages[i].ID = primitive.NewObjectID()
+			missing = true
+		}
+	}
+
+	if !missing {
+		return nil
+	}
+	err := s.pathRepo.Update(ctx, path, model.RevisionMeta{Note: "Assigned stage identifiers"})
+	s.content.InvalidatePaths(ctx)
+	return err
+}
+
+// evaluate recomputes stage metrics from the user's progress records, completes
+// every stage whose criteria are met and unlocks the stage after it
+func (s *pathProgressService) evaluate(ctx context.Context, enrollment *model.PathEnrollment, path *model.LearningPath) error {
+	now := time.Now()
+	syncStages(enrollment, path, now)
+
+	var exerciseIDs []primitive.ObjectID
+	for _, stage := range path.Stages {
+		exerciseIDs = append(exerciseIDs, stage.ExerciseIDs...)
+	}
+
+	progressByExercise := make(map[primitive.ObjectID]*model.UserProgress)
+	if len(exerciseIDs) > 0 {
+		progresses, err := s.progressRepo.GetByUserAndExercises(ctx, enrollment.UserID, exerciseIDs)
+		if err != nil {
+			return err
+		}
+		for _, progress := range progresses {
+			progressByExercise[progress.ExerciseID] = progress
+		}
+	}
+
+	for i, stage := range path.Stages {
+		stageProgress := &enrollment.Stages[i]
+		stageProgress.ExercisesAttempted, stageProgress.Accuracy = stageMetrics(stage, progressByExercise)
+
+		if stageProgress.Status != model.StageStatusInProgress || !criteriaMet(stage, stageProgress) {
+			continue
+		}
+
+		completedAt := now
+		stageProgress.Status = model.StageStatusCompleted
+		stageProgress.CompletedAt = &completedAt
+
+		if i+1 < len(enrollment.Stages) && enrollment.Stages[i+1].Status == model.StageStatusLocked {
+			unlockedAt := now
+			enrollment.Stages[i+1].Status = model.StageStatusInProgress
+			enrollment.Stages[i+1].UnlockedAt = &unlockedAt
+		}
+	}
+
+	if enrollment.CompletedAt == nil && len(enrollment.Stages) > 0 && completedStages(enrollment) == len(enrollment.Stages) {
+		completedAt := now
+		enrollment.CompletedAt = &completedAt
+	}
+
+	return s.enrollmentRepo.Update(ctx, enrollment)
+}
+
+// syncStages aligns the enrollment's stage records with the path's current stages.
+// New stages start locked unless every earlier stage is already complete.
+func syncStages(enrollment *model.PathEnrollment, path *model.LearningPath, now time.Time) {
+	existing := make(map[primitive.ObjectID]model.StageProgress, len(enrollment.Stages))
+	for _, stageProgress := range enrollment.Stages {
+		existing[stageProgress.StageID] = stageProgress
+	}
+
+	stages := make([]model.StageProgress, 0, len(path.Stages))
+	previousCompleted := true
+	for _, stage := range path.Stages {
+		stageProgress, ok := existing[stage.ID]
+		if !ok {
+			stageProgress = model.StageProgress{StageID: stage.ID, Status: model.StageStatusLocked}
+		}
+		if stageProgress.Status == model.StageStatusLocked && previousCompleted {
+			unlockedAt := now
+			stageProgress.Status = model.StageStatusInProgress
+			stageProgress.UnlockedAt = &unlockedAt
+		}
+
+		previousCompleted = stageProgress.Status == model.StageStatusCompleted
+		stages = append(stages, stageProgress)
+	}
+
+	enrollment.Stages = stages
+	if completedStages(enrollment) < len(stages) {
+		enrollment.CompletedAt = nil
+	}
+}
+
+// stageMetrics returns the distinct exercises attempted and the accuracy percentage
+// across the stage's exercises
+func stageMetrics(stage model.PathStage, progressByExercise map[primitive.ObjectID]*model.UserProgress) (int, float64) {
+	attempted, attempts, correct := 0, 0, 0
+	for _, exerciseID := range stage.ExerciseIDs {
+		progress, ok := progressByExercise[exerciseID]
+		if !ok || progress.AttemptCount == 0 {
+			continue
+		}
+		attempted++
+		attempts += progress.AttemptCount
+		correct += progress.CorrectCount
+	}
+
+	if attempts == 0 {
+		return attempted, 0
+	}
+	return attempted, float64(correct) / float64(attempts) * 100
+}
+
+// requiredExercises returns the number of distinct exercises a stage requires
+func requiredExercises(stage model.PathStage) int {
+	required := stage.CompletionCriteria.MinExercises
+	if required <= 0 || required > len(stage.ExerciseIDs) {
+		required = len(stage.ExerciseIDs)
+	}
+	return required
+}
+
+func criteriaMet(stage model.PathStage, stageProgress *model.StageProgress) bool {
+	return stageProgress.ExercisesAttempted >= requiredExercises(stage) &&
+		stageProgress.Accuracy >= stage.CompletionCriteria.MinAccuracy
+}
+
+func completedStages(enrollment *model.PathEnrollment) int {
+	completed := 0
+	for _, stageProgress := range enrollment.Stages {
+		if stageProgress.Status == model.StageStatusCompleted {
+			completed++
+		}
+	}
+	return completed
+}
+
+func pathContainsExercise(path *model.LearningPath, exerciseID primitive.ObjectID) bool {
+	for _, stage := range path.Stages {
+		for _, id := range stage.ExerciseIDs {
+			if id == exerciseID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func buildPathProgress(path *model.LearningPath, enrollment *model.PathEnrollment) *PathProgress {
+	progress := &PathProgress{
+		PathID:          path.ID,
+		Title:           path.Title,
+		EnrolledAt:      enrollment.EnrolledAt,
+		CompletedAt:     enrollment.CompletedAt,
+		CompletedStages: completedStages(enrollment),
+		TotalStages:     len(path.Stages),
+		Stages:          make([]StageProgressView, 0, len(path.Stages)),
+	}
+
+	for i, stage := range path.Stages {
+		if i >= len(enrollment.Stages) {
+			break
+		}
+		progress.Stages = append(progress.Stages, StageProgressView{
+			StageProgress:     enrollment.Stages[i],
+			StageNumber:       stage.StageNumber,
+			Title:             stage.Title,
+			RequiredExercises: requiredExercises(stage),
+			RequiredAccuracy:  stage.CompletionCriteria.MinAccuracy,
+		})
+	}
+
+	return progress
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository/memory"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPathProgressCompletesStagesInOrder(t *testing.T) {
+	ctx := context.Background()
+	revisions := memory.NewRevisionRepository()
+	pathRepo := memory.NewLearningPathRepository(revisions)
+	progressRepo := memory.NewProgressRepository()
+	enrollmentRepo := memory.NewEnrollmentRepository()
+	service := NewPathProgressService(enrollmentRepo, pathRepo, progressRepo, nil)
+
+	first, second := primitive.NewObjectID(), primitive.NewObjectID()
+	path := &model.LearningPath{
+		Title:  "Addition",
+		Status: model.StatusPublished,
+		Stages: []model.PathStage{
+			{StageNumber: 1, Title: "Single digits", ExerciseIDs: []primitive.ObjectID{first}, CompletionCriteria: model.CompletionCriteria{MinAccuracy: 50}},
+			{StageNumber: 2, Title: "Two digits", ExerciseIDs: []primitive.ObjectID{second}, CompletionCriteria: model.CompletionCriteria{MinAccuracy: 50}},
+		},
+	}
+	if err := pathRepo.Create(ctx, path, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+
+	userID := primitive.NewObjectID()
+	progress, err := service.Enroll(ctx, userID, path.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stageStatuses(progress); got != [2]string{model.StageStatusInProgress, model.StageStatusLocked} {
+		t.Fatalf("stages after enrolling = %v", got)
+	}
+	// Enrolling assigned the stages their identifiers as a new revision
+	if count, _ := revisions.CountByDocument(ctx, model.ContentKindLearningPath, path.ID); count != 2 {
+		t.Errorf("revisions = %d, want 2", count)
+	}
+
+	if _, err := progressRepo.RecordAttempt(ctx, model.Attempt{UserID: userID, ExerciseID: first, IsCorrect: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.HandleAttempt(ctx, userID, first); err != nil {
+		t.Fatal(err)
+	}
+
+	progress, err = service.GetProgress(ctx, userID, path.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stageStatuses(progress); got != [2]string{model.StageStatusCompleted, model.StageStatusInProgress} {
+		t.Errorf("stages after the first stage = %v", got)
+	}
+	if progress.CompletedStages != 1 || progress.CompletedAt != nil {
+		t.Errorf("completed stages = %d, completed at = %v", progress.CompletedStages, progress.CompletedAt)
+	}
+
+	active, err := enrollmentRepo.GetActiveByUserID(ctx, userID)
+	if err != nil || len(active) != 1 {
+		t.Fatalf("active enrollments = %d, %v", len(active), err)
+	}
+}
+
+func TestPathProgressRequiresEnrollment(t *testing.T) {
+	ctx := context.Background()
+	pathRepo := memory.NewLearningPathRepository(nil)
+	service := NewPathProgressService(memory.NewEnrollmentRepository(), pathRepo, memory.NewProgressRepository(), nil)
+
+	path := &model.LearningPath{Title: "Addition", Status: model.StatusPublished}
+	if err := pathRepo.Create(ctx, path, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.GetProgress(ctx, primitive.NewObjectID(), path.ID); err != ErrNotEnrolled {
+		t.Errorf("error = %v, want ErrNotEnrolled", err)
+	}
+}
+
+func stageStatuses(progress *PathProgress) [2]string {
+	var statuses [2]string
+	for i, stage := range progress.Stages {
+		if i < len(statuses) {
+			statuses[i] = stage.Status
+		}
+	}
+	return statuses
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PrerequisiteCycleError is returned when a write would make a learning path or
+// skill depend on itself, directly or through other prerequisites
+type PrerequisiteCycleError struct {
+	Cycle []string
+}
+
+func (e *PrerequisiteCycleError) Error() string {
+	return "prerequisite cycle: " + strings.Join(e.Cycle, " -> ")
+}
+
+// findCycle returns a cycle in the dependency graph, starting and ending at the same
+// node, or nil when the graph is acyclic. Nodes are visited in sorted order so the
+// reported cycle is stable.
+func findCycle(graph map[string][]string) []string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+
+	nodes := make([]string, 0, len(graph))
+	for node := range graph {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	state := make(map[string]int, len(graph))
+	var stack []string
+
+	var visit func(node string) []string
+	visit = func(node string) []string {
+		state[node] = visiting
+		stack = append(stack, node)
+
+		for _, next := range graph[node] {
+			switch state[next] {
+			case visiting:
+				for i, n := range stack {
+					if n == next {
+						cycle := append([]string{}, stack[i:]...)
+						return append(cycle, next)
+					}
+				}
+			case unvisited:
+				if cycle := visit(next); cycle != nil {
+					return cycle
+				}
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[node] = done
+		return nil
+	}
+
+	for _, node := range nodes {
+		if state[node] == unvisited {
+			if cycle := visit(node); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// pathPrerequisiteGraph walks a learning path's prerequisites breadth first and
+// returns the reachable part of the graph, keyed by hex ID, along with the
+// path's own prerequisites that were not found. Paths in the trash are not
+// walked, so links to them play no part until they are restored.
+func pathPrerequisiteGraph(ctx context.Context, pathRepo repository.LearningPathRepository, selfID primitive.ObjectID, prerequisites []primitive.ObjectID) (map[string][]string, []primitive.ObjectID, error) {
+	graph := map[string][]string{selfID.Hex(): hexIDs(prerequisites)}
+	var missing []primitive.ObjectID
+
+	frontier := prerequisites
+	for depth := 0; len(frontier) > 0; depth++ {
+		var pending []primitive.ObjectID
+		for _, id := range frontier {
+			if _, ok := graph[id.Hex()]; !ok {
+				pending = append(pending, id)
+			}
+		}
+		if len(pending) == 0 {
+			break
+		}
+
+		found, err := pathRepo.GetByIDs(ctx, pending)
+		if err != nil {
+			return nil, nil, err
+		}
+		seen := make(map[primitive.ObjectID]bool, len(found))
+
+		frontier = nil
+		for _, prerequisite := range found {
+			seen[prerequisite.ID] = true
+			graph[prerequisite.ID.Hex()] = hexIDs(prerequisite.PrerequisitePathIDs)
+			frontier = append(frontier, prerequisite.PrerequisitePathIDs...)
+		}
+
+		if depth == 0 {
+			for _, id := range pending {
+				if !seen[id] {
+					missing = append(missing, id)
+				}
+			}
+		}
+	}
+	return graph, missing, nil
+}
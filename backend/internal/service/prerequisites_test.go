@@ -0,0 +1,34 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindCycle(t *testing.T) {
+	tests := []struct {
+		name  string
+		graph map[string][]string
+		want  []string
+	}{
+		{"empty", map[string][]string{}, nil},
+		{"acyclic", map[string][]string{"a": {"b", "c"}, "b": {"c"}, "c": nil}, nil},
+		{"shared prerequisite", map[string][]string{"a": {"c"}, "b": {"c"}, "c": {"d"}}, nil},
+		{"self loop", map[string][]string{"a": {"a"}}, []string{"a", "a"}},
+		{"two nodes", map[string][]string{"a": {"b"}, "b": {"a"}}, []string{"a", "b", "a"}},
+		{"longer cycle", map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"d"}, "d": {"b"}}, []string{"b", "c", "d", "b"}},
+		{"unknown nodes", map[string][]string{"a": {"x"}, "b": {"y", "a"}}, nil},
+		// Nodes are visited in sorted order, so the same cycle is always reported from the same start
+		{"stable start", map[string][]string{"z": {"m"}, "m": {"k"}, "k": {"z"}}, []string{"k", "z", "m", "k"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 10; i++ {
+				if got := findCycle(tt.graph); !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("findCycle = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -3,131 +3,155 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/flutterninja9/mental-math-app/internal/domain/model"
 	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/logger"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type ProgressService interface {
-	RecordAttempt(ctx context.Context, userID, exerciseID primitive.ObjectID, userAnswer string, isCorrect bool, timeTaken int) error
-	GetUserProgress(ctx context.Context, userID primitive.ObjectID) ([]*model.UserProgress, error)
+	RecordAttempt(ctx context.Context, userID, exerciseID primitive.ObjectID, exerciseRevision int, userAnswer string, isCorrect bool, timeTaken int) error
+	GetUserProgress(ctx context.Context, userID primitive.ObjectID, page pagination.Params) (pagination.Page[*model.UserProgress], error)
 	GetProgressForExercise(ctx context.Context, userID, exerciseID primitive.ObjectID) (*model.UserProgress, error)
 	CalculateMasteryLevel(ctx context.Context, progressID primitive.ObjectID) (float64, error)
 	GetRecentPerformance(ctx context.Context, userID primitive.ObjectID, days int) (map[string]interface{}, error)
+	GetStreak(ctx context.Context, userID primitive.ObjectID) (*StreakSummary, error)
+	GetPerformanceReport(ctx context.Context, userID primitive.ObjectID, from, to time.Time, granularity string) (*model.PerformanceReport, error)
+	RecomputeStatistics(ctx context.Context, userID primitive.ObjectID) (*RecomputeResult, error)
+	RecomputeAllStatistics(ctx context.Context) (*RecomputeSummary, error)
 }
 
+// RecomputeResult reports what recomputing a user's statistics corrected
+type RecomputeResult struct {
+	UserID           primitive.ObjectID   `json:"user_id"`
+	SummariesUpdated int                  `json:"summaries_updated"` // progress summaries that were wrong or missing
+	Statistics       model.UserStatistics `json:"statistics"`
+}
+
+// RecomputeSummary reports what recomputing every user's statistics corrected
+type RecomputeSummary struct {
+	Users            int `json:"users"`
+	SummariesUpdated int `json:"summaries_updated"`
+}
+
+// Errors returned for invalid performance report requests
+var (
+	ErrInvalidGranularity = errors.New("granularity must be one of day, week or month")
+	ErrInvalidReportRange = errors.New("from must be before to and span at most a year")
+)
+
+// maxStatisticsRetries bounds the compare-and-swap loop when recomputing user statistics
+const maxStatisticsRetries = 10
+
+// Limits applied to performance report requests
+const (
+	defaultReportRange = 30 * 24 * time.Hour
+	maxReportRange     = 366 * 24 * time.Hour
+)
+
 type progressService struct {
 	progressRepo repository.ProgressRepository
+	attemptRepo  repository.AttemptRepository
 	exerciseRepo repository.ExerciseRepository
 	userRepo     repository.UserRepository
+	pathProgress PathProgressService
 }
 
 func NewProgressService(
 	progressRepo repository.ProgressRepository,
+	attemptRepo repository.AttemptRepository,
 	exerciseRepo repository.ExerciseRepository,
 	userRepo repository.UserRepository,
+	pathProgress PathProgressService,
 ) ProgressService {
 	return &progressService{
 		progressRepo: progressRepo,
+		attemptRepo:  attemptRepo,
 		exerciseRepo: exerciseRepo,
 		userRepo:     userRepo,
+		pathProgress: pathProgress,
 	}
 }
 
 func (s *progressService) RecordAttempt(
 	ctx context.Context,
 	userID, exerciseID primitive.ObjectID,
+	exerciseRevision int,
 	userAnswer string,
 	isCorrect bool,
 	timeTaken int,
 ) error {
 	// Verify that the user and exercise exist
-	_, err := s.userRepo.GetByID(ctx, userID)
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return errors.New("user not found")
 	}
 
-	_, err = s.exerciseRepo.GetByID(ctx, exerciseID)
+	exercise, err := s.exerciseRepo.GetByID(ctx, exerciseID)
 	if err != nil {
 		return errors.New("exercise not found")
 	}
 
-	// Create or get existing progress record
-	progress, err := s.progressRepo.GetByUserAndExercise(ctx, userID, exerciseID)
-	if err != nil {
-		// Create new progress record if not exists
-		progress = &model.UserProgress{
-			UserID:       userID,
-			ExerciseID:   exerciseID,
-			Attempts:     []model.Attempt{},
-			MasteryLevel: 0,
-		}
-		if err := s.progressRepo.Create(ctx, progress); err != nil {
-			return errors.New("failed to create progress record: " + err.Error())
-		}
+	// Link the attempt to the revision the learner saw, defaulting to the current one
+	if exerciseRevision <= 0 || exerciseRevision > exercise.Revision {
+		exerciseRevision = exercise.Revision
 	}
 
-	// Add new attempt
+	// Store the attempt and fold it into the progress summary
 	attempt := model.Attempt{
-		Timestamp:  time.Now(),
-		UserAnswer: userAnswer,
-		IsCorrect:  isCorrect,
-		TimeTaken:  timeTaken,
+		UserID:           userID,
+		ExerciseID:       exerciseID,
+		Timestamp:        time.Now(),
+		ExerciseRevision: exerciseRevision,
+		UserAnswer:       userAnswer,
+		IsCorrect:        isCorrect,
+		TimeTaken:        timeTaken,
 	}
 
-	if err := s.progressRepo.AddAttempt(ctx, progress.ID, attempt); err != nil {
+	if err := s.attemptRepo.Create(ctx, &attempt); err != nil {
 		return errors.New("failed to record attempt: " + err.Error())
 	}
 
-	// Update mastery level
-	masteryLevel, err := s.CalculateMasteryLevel(ctx, progress.ID)
-	if err != nil {
-		return errors.New("failed to calculate mastery level: " + err.Error())
+	// The attempt is stored, so failing the request now would only invite a
+	// duplicate retry. Summaries that miss it are repaired by recomputing them.
+	if _, err := s.progressRepo.RecordAttempt(ctx, attempt); err != nil {
+		logger.Error("Failed to update progress summary", err)
 	}
 
-	if err := s.progressRepo.UpdateMasteryLevel(ctx, progress.ID, masteryLevel); err != nil {
-		return errors.New("failed to update mastery level: " + err.Error())
-	}
-
-	// Update user statistics
-	user, err := s.userRepo.GetByID(ctx, userID)
+	// Count the attempt towards the totals, the daily goal and the streak
+	err = s.userRepo.ApplyAttemptStatistics(ctx, userID, model.StatisticsAttempt{
+		IsCorrect: isCorrect,
+		Timestamp: attempt.Timestamp,
+		Day:       attempt.Timestamp.In(userLocation(user)).Format(model.DayLayout),
+		DailyGoal: dailyGoal(user),
+	})
 	if err != nil {
-		return errors.New("failed to get user: " + err.Error())
+		logger.Error("Failed to update user statistics", err)
 	}
 
-	stats := user.Statistics
-	stats.TotalExercisesCompleted++
-	stats.LastActive = time.Now()
-
-	// Calculate new average accuracy
-	totalAttempts := float64(stats.TotalExercisesCompleted)
-	oldAccuracyWeight := (totalAttempts - 1) / totalAttempts
-	newAccuracyWeight := 1.0 / totalAttempts
-	var newAccuracyPart float64
-	if isCorrect {
-		newAccuracyPart = 100.0
-	}
-	stats.AverageAccuracy = (stats.AverageAccuracy * oldAccuracyWeight) + (newAccuracyPart * newAccuracyWeight)
-
-	// Update streak
-	if time.Since(stats.LastActive).Hours() > 24 {
-		stats.StreakDays = 1
-	} else {
-		stats.StreakDays++
-	}
-	if err := s.userRepo.UpdateStatistics(ctx, user.ID, stats); err != nil {
-		return errors.New("failed to update user statistics: " + err.Error())
+	// The attempt is recorded; a failure to advance learning paths is retried on the next read
+	if err := s.pathProgress.HandleAttempt(ctx, userID, exerciseID); err != nil {
+		logger.Error("Failed to evaluate learning path progress", err)
 	}
 	return nil
 }
-func (s *progressService) GetUserProgress(ctx context.Context, userID primitive.ObjectID) ([]*model.UserProgress, error) {
-	progresses, err := s.progressRepo.GetByUserID(ctx, userID)
+
+func (s *progressService) GetUserProgress(ctx context.Context, userID primitive.ObjectID, page pagination.Params) (pagination.Page[*model.UserProgress], error) {
+	progresses, err := s.progressRepo.ListByUserID(ctx, userID, page)
+	if err != nil {
+		return pagination.Page[*model.UserProgress]{}, errors.New("failed to get user progress: " + err.Error())
+	}
+	total, err := s.progressRepo.CountByUserID(ctx, userID)
 	if err != nil {
-		return nil, errors.New("failed to get user progress: " + err.Error())
+		return pagination.Page[*model.UserProgress]{}, errors.New("failed to count user progress: " + err.Error())
 	}
-	return progresses, nil
+	return pagination.NewPage(progresses, page, total, func(p *model.UserProgress) pagination.Cursor {
+		return pagination.IDCursor(p.ID)
+	}), nil
 }
 func (s *progressService) GetProgressForExercise(ctx context.Context, userID, exerciseID primitive.ObjectID) (*model.UserProgress, error) {
 	progress, err := s.progressRepo.GetByUserAndExercise(ctx, userID, exerciseID)
@@ -142,48 +166,327 @@ func (s *progressService) CalculateMasteryLevel(ctx context.Context, progressID
 		return 0, errors.New("failed to get progress record: " + err.Error())
 	}
 
-	if len(progress.Attempts) == 0 {
+	if progress.AttemptCount == 0 {
 		return 0, nil
 	}
 
-	correctAttempts := 0
-	for _, attempt := range progress.Attempts {
-		if attempt.IsCorrect {
-			correctAttempts++
-		}
-	}
-
-	masteryLevel := float64(correctAttempts) / float64(len(progress.Attempts))
+	masteryLevel := float64(progress.CorrectCount) / float64(progress.AttemptCount)
 	return masteryLevel, nil
 }
-func (s *progressService) GetRecentPerformance(ctx context.Context, userID primitive.ObjectID, days int) (map[string]interface{}, error) {
-	progresses, err := s.progressRepo.GetByUserID(ctx, userID)
+
+// RecomputeStatistics rebuilds a user's progress summaries and answer totals
+// from the attempts they made, correcting drift left by failed or partial
+// updates of the summaries and statistics. Summaries missing for an exercise
+// that still exists are created. Recent attempts, streaks and daily goal
+// progress are kept as they are.
+func (s *progressService) RecomputeStatistics(ctx context.Context, userID primitive.ObjectID) (*RecomputeResult, error) {
+	updated, totals, err := s.recomputeSummaries(ctx, userID)
 	if err != nil {
-		return nil, errors.New("failed to get user progress: " + err.Error())
+		return nil, err
 	}
+	result := &RecomputeResult{UserID: userID, SummariesUpdated: updated}
+
+	// The totals are swapped in only if no attempt was counted meanwhile, so
+	// attempts recorded during the recompute are not lost
+	for i := 0; i < maxStatisticsRetries; i++ {
+		user, err := s.userRepo.GetByID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			if totals, err = s.attemptRepo.TotalsByExercise(ctx, userID); err != nil {
+				return nil, errors.New("failed to count attempts: " + err.Error())
+			}
+		}
 
-	recentPerformance := make(map[string]interface{})
-	recentPerformance["totalAttempts"] = 0
-	recentPerformance["correctAttempts"] = 0
-	recentPerformance["averageTime"] = 0.0
+		stats := user.Statistics
+		stats.TotalExercisesCompleted = 0
+		stats.CorrectExercises = 0
+		for _, t := range totals {
+			stats.TotalExercisesCompleted += t.Attempts
+			stats.CorrectExercises += t.Correct
+			if t.LastAttempted.After(stats.LastActive) {
+				stats.LastActive = t.LastAttempted
+			}
+		}
+		stats.AverageAccuracy = 0
+		if stats.TotalExercisesCompleted > 0 {
+			stats.AverageAccuracy = float64(stats.CorrectExercises) / float64(stats.TotalExercisesCompleted) * 100
+		}
+		stats.Revision++
 
-	for _, progress := range progresses {
-		for _, attempt := range progress.Attempts {
-			if time.Since(attempt.Timestamp).Hours() <= float64(days*24) {
-				recentPerformance["totalAttempts"] = recentPerformance["totalAttempts"].(int) + 1
-				if attempt.IsCorrect {
-					recentPerformance["correctAttempts"] = recentPerformance["correctAttempts"].(int) + 1
+		swapped, err := s.userRepo.SwapStatistics(ctx, userID, user.Statistics.Revision, stats)
+		if err != nil {
+			return nil, err
+		}
+		if swapped {
+			result.Statistics = stats
+			return result, nil
+		}
+	}
+
+	return nil, errors.New("statistics were modified concurrently too many times")
+}
+
+// recomputeSummaries rewrites the progress summaries that disagree with the
+// attempt totals and returns how many it wrote along with the totals. A summary
+// that had an attempt folded in meanwhile is read again on the next pass.
+func (s *progressService) recomputeSummaries(ctx context.Context, userID primitive.ObjectID) (int, []model.AttemptTotals, error) {
+	updated := 0
+	for i := 0; i < maxStatisticsRetries; i++ {
+		progresses, err := s.progressRepo.GetByUserID(ctx, userID)
+		if err != nil {
+			return 0, nil, errors.New("failed to get user progress: " + err.Error())
+		}
+		totals, err := s.attemptRepo.TotalsByExercise(ctx, userID)
+		if err != nil {
+			return 0, nil, errors.New("failed to count attempts: " + err.Error())
+		}
+
+		stored := make(map[primitive.ObjectID]*model.UserProgress, len(progresses))
+		for _, progress := range progresses {
+			stored[progress.ExerciseID] = progress
+		}
+		byExercise := make(map[primitive.ObjectID]model.AttemptTotals, len(totals))
+		var unsummarized []primitive.ObjectID
+		for _, t := range totals {
+			byExercise[t.ExerciseID] = t
+			if stored[t.ExerciseID] == nil {
+				unsummarized = append(unsummarized, t.ExerciseID)
+			}
+		}
+
+		// Attempts at exercises that no longer exist do not get a summary
+		existing := make(map[primitive.ObjectID]bool, len(unsummarized))
+		if len(unsummarized) > 0 {
+			exercises, err := s.exerciseRepo.GetByIDs(ctx, unsummarized)
+			if err != nil {
+				return 0, nil, errors.New("failed to get exercises: " + err.Error())
+			}
+			for _, exercise := range exercises {
+				existing[exercise.ID] = true
+			}
+		}
+
+		var wanted []*model.UserProgress
+		for _, progress := range progresses {
+			want := summaryFromTotals(userID, progress.ExerciseID, byExercise[progress.ExerciseID])
+			if want.AttemptCount == 0 {
+				want.LastAttempted = progress.LastAttempted
+			}
+			wanted = append(wanted, want)
+		}
+		for _, id := range unsummarized {
+			if existing[id] {
+				wanted = append(wanted, summaryFromTotals(userID, id, byExercise[id]))
+			}
+		}
+
+		conflicts := 0
+		for _, want := range wanted {
+			expected := 0
+			if current := stored[want.ExerciseID]; current != nil {
+				if sameTotals(current, want) {
+					continue
 				}
-				recentPerformance["averageTime"] = recentPerformance["averageTime"].(float64) + float64(attempt.TimeTaken)
+				expected = current.AttemptCount
+			}
+			written, err := s.progressRepo.ReplaceTotals(ctx, want, expected)
+			if err != nil {
+				return 0, nil, errors.New("failed to update progress: " + err.Error())
 			}
+			if !written {
+				conflicts++
+				continue
+			}
+			updated++
+		}
+		if conflicts == 0 {
+			return updated, totals, nil
 		}
 	}
 
-	if recentPerformance["totalAttempts"].(int) > 0 {
-		recentPerformance["averageTime"] = recentPerformance["averageTime"].(float64) / float64(recentPerformance["totalAttempts"].(int))
-	} else {
-		recentPerformance["averageTime"] = 0.0
+	return 0, nil, errors.New("progress was modified concurrently too many times")
+}
+
+// summaryFromTotals is the progress summary the attempt totals for an exercise describe
+func summaryFromTotals(userID, exerciseID primitive.ObjectID, totals model.AttemptTotals) *model.UserProgress {
+	progress := &model.UserProgress{
+		UserID:         userID,
+		ExerciseID:     exerciseID,
+		AttemptCount:   totals.Attempts,
+		CorrectCount:   totals.Correct,
+		TotalTimeTaken: totals.TimeTaken,
+		LastAttempted:  totals.LastAttempted,
 	}
+	if totals.Attempts > 0 {
+		progress.MasteryLevel = float64(totals.Correct) / float64(totals.Attempts)
+	}
+	return progress
+}
 
-	return recentPerformance, nil
+// sameTotals reports whether a stored summary already matches the one rebuilt from attempts
+func sameTotals(stored, want *model.UserProgress) bool {
+	return stored.AttemptCount == want.AttemptCount &&
+		stored.CorrectCount == want.CorrectCount &&
+		stored.TotalTimeTaken == want.TotalTimeTaken &&
+		stored.MasteryLevel == want.MasteryLevel &&
+		stored.LastAttempted.Equal(want.LastAttempted)
+}
+
+// RecomputeAllStatistics recomputes the statistics of every user not in the trash
+func (s *progressService) RecomputeAllStatistics(ctx context.Context) (*RecomputeSummary, error) {
+	summary := &RecomputeSummary{}
+	err := s.userRepo.ForEach(ctx, func(user *model.User) error {
+		result, err := s.RecomputeStatistics(ctx, user.ID)
+		if err != nil {
+			return fmt.Errorf("user %s: %w", user.ID.Hex(), err)
+		}
+		summary.Users++
+		summary.SummariesUpdated += result.SummariesUpdated
+		return nil
+	})
+	return summary, err
+}
+
+func (s *progressService) GetRecentPerformance(ctx context.Context, userID primitive.ObjectID, days int) (map[string]interface{}, error) {
+	now := time.Now()
+	report, err := s.attemptRepo.AggregatePerformance(ctx, model.AnalyticsQuery{
+		UserID:      userID,
+		From:        now.Add(-time.Duration(days) * 24 * time.Hour),
+		To:          now,
+		Granularity: model.GranularityDay,
+		Timezone:    "UTC",
+	})
+	if err != nil {
+		return nil, errors.New("failed to aggregate performance: " + err.Error())
+	}
+
+	return map[string]interface{}{
+		"totalAttempts":   report.Summary.Attempts,
+		"correctAttempts": report.Summary.Correct,
+		"averageTime":     report.Summary.AverageTime,
+	}, nil
+}
+
+func (s *progressService) GetStreak(ctx context.Context, userID primitive.ObjectID) (*StreakSummary, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	return buildStreakSummary(user, time.Now()), nil
+}
+
+func (s *progressService) GetPerformanceReport(
+	ctx context.Context,
+	userID primitive.ObjectID,
+	from, to time.Time,
+	granularity string,
+) (*model.PerformanceReport, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	switch granularity {
+	case "":
+		granularity = model.GranularityDay
+	case model.GranularityDay, model.GranularityWeek, model.GranularityMonth:
+	default:
+		return nil, ErrInvalidGranularity
+	}
+
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultReportRange)
+	}
+	if !from.Before(to) || to.Sub(from) > maxReportRange {
+		return nil, ErrInvalidReportRange
+	}
+
+	report, err := s.attemptRepo.AggregatePerformance(ctx, model.AnalyticsQuery{
+		UserID:      userID,
+		From:        from,
+		To:          to,
+		Granularity: granularity,
+		Timezone:    userLocation(user).String(),
+	})
+	if err != nil {
+		return nil, errors.New("failed to aggregate performance: " + err.Error())
+	}
+
+	report.Trend = calculateTrend(report.Series, granularity)
+	return report, nil
+}
+
+// calculateTrend fits a least-squares line through the accuracy and median time
+// of each period to tell whether the user is improving. Periods are placed by
+// when they start, so periods without attempts still count as elapsed time.
+func calculateTrend(series []model.PerformanceBucket, granularity string) model.PerformanceTrend {
+	if len(series) < 2 {
+		return model.PerformanceTrend{Direction: "insufficient_data"}
+	}
+
+	length := periodLength(granularity)
+	elapsed := make([]float64, len(series))
+	accuracies := make([]float64, len(series))
+	times := make([]float64, len(series))
+	for i, bucket := range series {
+		elapsed[i] = float64(bucket.Period.Sub(series[0].Period)) / float64(length)
+		accuracies[i] = bucket.Accuracy
+		times[i] = bucket.MedianTime
+	}
+
+	trend := model.PerformanceTrend{
+		AccuracySlope:   slope(elapsed, accuracies),
+		MedianTimeSlope: slope(elapsed, times),
+	}
+
+	switch {
+	case trend.AccuracySlope >= 0 && trend.MedianTimeSlope <= 0 && (trend.AccuracySlope > 0 || trend.MedianTimeSlope < 0):
+		trend.Direction = "improving"
+	case trend.AccuracySlope <= 0 && trend.MedianTimeSlope >= 0 && (trend.AccuracySlope < 0 || trend.MedianTimeSlope > 0):
+		trend.Direction = "declining"
+	case trend.AccuracySlope == 0 && trend.MedianTimeSlope == 0:
+		trend.Direction = "steady"
+	default:
+		trend.Direction = "mixed"
+	}
+
+	return trend
+}
+
+// periodLength is the nominal length of a period of a granularity; months
+// average out leap years
+func periodLength(granularity string) time.Duration {
+	switch granularity {
+	case model.GranularityWeek:
+		return 7 * 24 * time.Hour
+	case model.GranularityMonth:
+		return time.Duration(365.25 / 12 * float64(24*time.Hour))
+	default:
+		return 24 * time.Hour
+	}
+}
+
+// slope returns the least-squares slope of ys plotted against xs
+func slope(xs, ys []float64) float64 {
+	n := float64(len(ys))
+	var sumX, sumY, sumXY, sumXX float64
+	for i, y := range ys {
+		x := xs[i]
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denominator
 }
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository/memory"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRecordAttemptCountsParallelAttempts(t *testing.T) {
+	ctx := context.Background()
+	users := memory.NewUserRepository()
+	exercises := memory.NewExerciseRepository(nil)
+	progress := memory.NewProgressRepository()
+	attempts := memory.NewAttemptRepository(exercises)
+	paths := memory.NewLearningPathRepository(nil)
+	pathProgress := NewPathProgressService(memory.NewEnrollmentRepository(), paths, progress, nil)
+	service := NewProgressService(progress, attempts, exercises, users, pathProgress)
+
+	user := &model.User{Email: "learner@example.com", Username: "learner", Preferences: model.UserPreferences{DailyGoal: 5}}
+	if err := users.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	exercise := &model.Exercise{Title: "2 + 2", Category: "addition", Difficulty: "easy", Status: model.StatusPublished}
+	if err := exercises.Create(ctx, exercise, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+
+	const parallel = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, parallel)
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func(correct bool) {
+			defer wg.Done()
+			errs <- service.RecordAttempt(ctx, user.ID, exercise.ID, 0, "4", correct, 3)
+		}(i%2 == 0)
+	}
+	// A profile edit made meanwhile must not overwrite the statistics
+	edited := *user
+	edited.FirstName = "Ada"
+	if err := users.Update(ctx, &edited); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stored, err := users.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := stored.Statistics
+	if stats.TotalExercisesCompleted != parallel || stats.CorrectExercises != parallel/2 || stats.AverageAccuracy != 50 {
+		t.Errorf("statistics = %d attempts, %d correct, %v%%; want %d, %d, 50%%",
+			stats.TotalExercisesCompleted, stats.CorrectExercises, stats.AverageAccuracy, parallel, parallel/2)
+	}
+	if stats.DailyProgressCount != parallel || stats.StreakDays != 1 {
+		t.Errorf("daily progress = %d, streak = %d; want %d, 1", stats.DailyProgressCount, stats.StreakDays, parallel)
+	}
+	if stored.FirstName != "Ada" {
+		t.Errorf("first name = %q, want the edit to be kept", stored.FirstName)
+	}
+
+	summary, err := progress.GetByUserAndExercise(ctx, user.ID, exercise.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.AttemptCount != parallel || summary.CorrectCount != parallel/2 {
+		t.Errorf("progress summary = %d attempts, %d correct", summary.AttemptCount, summary.CorrectCount)
+	}
+	recorded, err := attempts.GetByUser(ctx, user.ID, time.Time{}, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded) != parallel {
+		t.Errorf("recorded attempts = %d, want %d", len(recorded), parallel)
+	}
+}
+
+func TestCalculateTrendUsesElapsedTime(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	series := []model.PerformanceBucket{
+		{Period: start, Accuracy: 50, MedianTime: 10},
+		{Period: start.AddDate(0, 0, 1), Accuracy: 60, MedianTime: 9},
+		// Nothing was attempted for eight days
+		{Period: start.AddDate(0, 0, 10), Accuracy: 70, MedianTime: 8},
+	}
+
+	trend := calculateTrend(series, model.GranularityDay)
+	if trend.Direction != "improving" {
+		t.Fatalf("direction = %q, want improving", trend.Direction)
+	}
+	// Ten points over ten days rather than over two periods
+	if trend.AccuracySlope < 1 || trend.AccuracySlope > 2 {
+		t.Errorf("accuracy slope = %v, want between 1 and 2 points per day", trend.AccuracySlope)
+	}
+}
+
+func TestCalculateTrendInsufficientData(t *testing.T) {
+	trend := calculateTrend([]model.PerformanceBucket{{Accuracy: 100}}, model.GranularityWeek)
+	if trend.Direction != "insufficient_data" {
+		t.Errorf("direction = %q, want insufficient_data", trend.Direction)
+	}
+}
+
+func TestRecomputeStatisticsCountsAttempts(t *testing.T) {
+	ctx := context.Background()
+	users := memory.NewUserRepository()
+	exercises := memory.NewExerciseRepository(nil)
+	progress := memory.NewProgressRepository()
+	attempts := memory.NewAttemptRepository(exercises)
+	pathProgress := NewPathProgressService(memory.NewEnrollmentRepository(), memory.NewLearningPathRepository(nil), progress, nil)
+	service := NewProgressService(progress, attempts, exercises, users, pathProgress)
+
+	user := &model.User{Email: "learner@example.com", Username: "learner", Preferences: model.UserPreferences{DailyGoal: 5}}
+	if err := users.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	exercise := &model.Exercise{Title: "2 + 2", Category: "addition", Difficulty: "easy", Status: model.StatusPublished}
+	if err := exercises.Create(ctx, exercise, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, correct := range []bool{true, true, true, false} {
+		if err := service.RecordAttempt(ctx, user.ID, exercise.ID, 0, "4", correct, 3); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// An attempt whose summary was never written, and one at an exercise that is gone
+	unsummarized := &model.Exercise{Title: "3 + 3", Category: "addition", Difficulty: "easy", Status: model.StatusPublished}
+	if err := exercises.Create(ctx, unsummarized, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+	lastAttempt := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	for _, attempt := range []*model.Attempt{
+		{UserID: user.ID, ExerciseID: unsummarized.ID, UserAnswer: "6", IsCorrect: true, TimeTaken: 5, Timestamp: lastAttempt},
+		{UserID: user.ID, ExerciseID: primitive.NewObjectID(), UserAnswer: "1", TimeTaken: 1, Timestamp: lastAttempt},
+	} {
+		if err := attempts.Create(ctx, attempt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Drift the summaries away from the attempts, as a failed update would
+	summary, err := progress.GetByUserAndExercise(ctx, user.ID, exercise.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary.AttemptCount, summary.CorrectCount, summary.TotalTimeTaken = 9, 1, 40
+	if err := progress.Update(ctx, summary); err != nil {
+		t.Fatal(err)
+	}
+	if err := progress.UpdateMasteryLevel(ctx, summary.ID, 0.1); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := users.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	drifted := stored.Statistics
+	drifted.TotalExercisesCompleted, drifted.CorrectExercises = 9, 1
+	drifted.Revision++
+	if swapped, err := users.SwapStatistics(ctx, user.ID, stored.Statistics.Revision, drifted); err != nil || !swapped {
+		t.Fatalf("drifting statistics: %v, %v", swapped, err)
+	}
+
+	result, err := service.RecomputeStatistics(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SummariesUpdated != 2 {
+		t.Errorf("summaries updated = %d, want 2", result.SummariesUpdated)
+	}
+	stats := result.Statistics
+	if stats.TotalExercisesCompleted != 6 || stats.CorrectExercises != 4 {
+		t.Errorf("statistics = %d attempts, %d correct, %v%%; want 6 and 4",
+			stats.TotalExercisesCompleted, stats.CorrectExercises, stats.AverageAccuracy)
+	}
+	if stats.DailyProgressCount != 4 {
+		t.Errorf("daily progress = %d, want it kept at 4", stats.DailyProgressCount)
+	}
+	summary, err = progress.GetByUserAndExercise(ctx, user.ID, exercise.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.AttemptCount != 4 || summary.CorrectCount != 3 || summary.TotalTimeTaken != 12 || summary.MasteryLevel != 0.75 {
+		t.Errorf("summary = %d attempts, %d correct, %ds, mastery %v; want 4, 3, 12s, 0.75",
+			summary.AttemptCount, summary.CorrectCount, summary.TotalTimeTaken, summary.MasteryLevel)
+	}
+	if len(summary.RecentAttempts) != 4 {
+		t.Errorf("recent attempts = %d, want them kept", len(summary.RecentAttempts))
+	}
+
+	created, err := progress.GetByUserAndExercise(ctx, user.ID, unsummarized.ID)
+	if err != nil {
+		t.Fatalf("missing summary was not created: %v", err)
+	}
+	if created.AttemptCount != 1 || created.CorrectCount != 1 || created.MasteryLevel != 1 || !created.LastAttempted.Equal(lastAttempt) {
+		t.Errorf("created summary = %+v, want one correct attempt at %v", created, lastAttempt)
+	}
+	if count, err := progress.CountByUserID(ctx, user.ID); err != nil || count != 2 {
+		t.Errorf("summaries = %d, %v, want none for the exercise that is gone", count, err)
+	}
+
+	// A second run finds nothing to correct
+	again, err := service.RecomputeStatistics(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.SummariesUpdated != 0 {
+		t.Errorf("summaries updated on a second run = %d, want 0", again.SummariesUpdated)
+	}
+}
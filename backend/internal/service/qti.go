@@ -0,0 +1,607 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// IMS QTI 2.1 support covers the two interactions our exercise types map to:
+// multiple_choice exercises are single-answer choiceInteraction items and
+// fill_in exercises are textEntryInteraction items. Exports are IMS content
+// packages, a zip with one item file per exercise and an imsmanifest.xml;
+// imports accept such a package or a single item file.
+//
+// Items carry no metadata of their own, so the external key is the item
+// identifier and the fields QTI has no place for are IEEE LOM metadata on
+// the item's manifest resource:
+//
+//	<metadata><lom xmlns="http://ltsc.ieee.org/xsd/LOM">
+//	  <general>
+//	    <identifier><catalog>external_key</catalog><entry>add-1</entry></identifier>
+//	    <description><string>Adding single digits</string></description>
+//	    <keyword><string>addition</string></keyword>
+//	  </general>
+//	  <educational><difficulty><source>LOMv1.0</source><value>easy</value></difficulty></educational>
+//	  <classification>
+//	    <purpose><source>LOMv1.0</source><value>discipline</value></purpose>
+//	    <taxonPath><source><string>category</string></source><taxon><entry><string>arithmetic</string></entry></taxon></taxonPath>
+//	  </classification>
+//	</lom></metadata>
+
+const (
+	qtiNamespace      = "http://www.imsglobal.org/xsd/imsqti_v2p1"
+	qtiMatchCorrect   = "http://www.imsglobal.org/question/qti_v2p1/rptemplates/match_correct"
+	qtiItemType       = "imsqti_item_xmlv2p1"
+	qtiManifestName   = "imsmanifest.xml"
+	qtiResponseID     = "RESPONSE"
+	qtiFeedbackID     = "SOLUTION"
+	lomNamespace      = "http://ltsc.ieee.org/xsd/LOM"
+	lomSource         = "LOMv1.0"
+	lomKeyCatalog     = "external_key"
+	lomDiscipline     = "discipline"
+	maxQTIPackageSize = 50 << 20
+)
+
+var (
+	// zipSignature starts every zip file, telling packages apart from single items
+	zipSignature = []byte("PK\x03\x04")
+	// qtiIdentifierPattern matches the identifiers QTI allows
+	qtiIdentifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+)
+
+// qtiItem is an assessmentItem as written by exports and read by imports.
+// The item body is kept as raw XML because interactions may be nested in
+// arbitrary markup.
+type qtiItem struct {
+	XMLName       xml.Name                 `xml:"assessmentItem"`
+	Namespace     string                   `xml:"xmlns,attr,omitempty"`
+	Identifier    string                   `xml:"identifier,attr"`
+	Title         string                   `xml:"title,attr"`
+	Adaptive      bool                     `xml:"adaptive,attr"`
+	TimeDependent bool                     `xml:"timeDependent,attr"`
+	Responses     []qtiResponseDeclaration `xml:"responseDeclaration"`
+	Outcomes      []qtiOutcomeDeclaration  `xml:"outcomeDeclaration"`
+	Body          qtiItemBody              `xml:"itemBody"`
+	Processing    *qtiResponseProcessing   `xml:"responseProcessing,omitempty"`
+	Feedback      []qtiModalFeedback       `xml:"modalFeedback"`
+}
+
+type qtiResponseDeclaration struct {
+	Identifier  string   `xml:"identifier,attr"`
+	Cardinality string   `xml:"cardinality,attr"`
+	BaseType    string   `xml:"baseType,attr"`
+	Correct     []string `xml:"correctResponse>value"`
+}
+
+type qtiOutcomeDeclaration struct {
+	Identifier  string `xml:"identifier,attr"`
+	Cardinality string `xml:"cardinality,attr"`
+	BaseType    string `xml:"baseType,attr"`
+}
+
+type qtiItemBody struct {
+	Inner string `xml:",innerxml"`
+}
+
+type qtiResponseProcessing struct {
+	Template string `xml:"template,attr"`
+}
+
+type qtiModalFeedback struct {
+	OutcomeIdentifier string `xml:"outcomeIdentifier,attr"`
+	ShowHide          string `xml:"showHide,attr"`
+	Identifier        string `xml:"identifier,attr"`
+	Text              string `xml:",chardata"`
+}
+
+// qtiManifest is the imsmanifest.xml of a content package
+type qtiManifest struct {
+	XMLName    xml.Name      `xml:"manifest"`
+	Namespace  string        `xml:"xmlns,attr"`
+	Identifier string        `xml:"identifier,attr"`
+	Resources  []qtiResource `xml:"resources>resource"`
+}
+
+type qtiResource struct {
+	Identifier string       `xml:"identifier,attr"`
+	Type       string       `xml:"type,attr"`
+	Href       string       `xml:"href,attr"`
+	Metadata   *qtiMetadata `xml:"metadata,omitempty"`
+	File       struct {
+		Href string `xml:"href,attr"`
+	} `xml:"file"`
+}
+
+// qtiMetadata is the LOM record of a resource, covering the fields exports write
+type qtiMetadata struct {
+	LOM struct {
+		Namespace       string              `xml:"xmlns,attr,omitempty"`
+		Identifiers     []lomIdentifier     `xml:"general>identifier"`
+		Description     *lomString          `xml:"general>description,omitempty"`
+		Keywords        []lomString         `xml:"general>keyword"`
+		Difficulty      *lomVocabulary      `xml:"educational>difficulty,omitempty"`
+		Classifications []lomClassification `xml:"classification"`
+	} `xml:"lom"`
+}
+
+type lomIdentifier struct {
+	Catalog string `xml:"catalog"`
+	Entry   string `xml:"entry"`
+}
+
+type lomString struct {
+	String string `xml:"string"`
+}
+
+type lomVocabulary struct {
+	Source string `xml:"source"`
+	Value  string `xml:"value"`
+}
+
+type lomClassification struct {
+	Purpose lomVocabulary `xml:"purpose"`
+	Source  lomString     `xml:"taxonPath>source"`
+	Taxons  []lomString   `xml:"taxonPath>taxon>entry"`
+}
+
+// lomDifficulties maps exercise difficulties to the LOM vocabulary and
+// lomImportDifficulties maps the vocabulary back
+var (
+	lomDifficulties       = map[string]string{"easy": "easy", "medium": "medium", "hard": "difficult"}
+	lomImportDifficulties = map[string]string{
+		"very easy": "easy", "easy": "easy", "medium": "medium", "difficult": "hard", "very difficult": "hard",
+	}
+)
+
+// qtiPackageItem is an item file of a package and the metadata the manifest
+// gives it, if any
+type qtiPackageItem struct {
+	file     *zip.File
+	metadata *qtiMetadata
+}
+
+// decodeQTI reads a content package or a single assessmentItem. Items in a
+// package are numbered in the order the manifest lists them, or the order of
+// the archive when there is no manifest.
+func decodeQTI(r io.Reader) ([]numberedRecord, []RowError, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxQTIPackageSize+1))
+	if err != nil || len(data) > maxQTIPackageSize {
+		return nil, nil, ErrMalformedImport
+	}
+
+	if !bytes.HasPrefix(data, zipSignature) {
+		record, err := parseQTIItem(data)
+		if err != nil {
+			return nil, []RowError{{Row: 1, Errors: map[string]string{"row": err.Error()}}}, nil
+		}
+		return []numberedRecord{{row: 1, record: record}}, nil, nil
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, ErrMalformedImport
+	}
+	items, err := qtiItemFiles(archive)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(items) > maxImportRows {
+		return nil, nil, ErrTooManyRows
+	}
+
+	var records []numberedRecord
+	var rowErrors []RowError
+	for i, item := range items {
+		record, err := readQTIItem(item.file)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Row: i + 1, Errors: map[string]string{"row": item.file.Name + ": " + err.Error()}})
+			continue
+		}
+		applyQTIMetadata(&record, item.metadata)
+		records = append(records, numberedRecord{row: i + 1, record: record})
+	}
+	return records, rowErrors, nil
+}
+
+// qtiItemFiles lists the item files of a package
+func qtiItemFiles(archive *zip.Reader) ([]qtiPackageItem, error) {
+	byName := make(map[string]*zip.File, len(archive.File))
+	var manifestFile *zip.File
+	for _, file := range archive.File {
+		byName[file.Name] = file
+		if path.Base(file.Name) == qtiManifestName {
+			manifestFile = file
+		}
+	}
+
+	var items []qtiPackageItem
+	if manifestFile == nil {
+		for _, file := range archive.File {
+			if strings.HasSuffix(strings.ToLower(file.Name), ".xml") {
+				items = append(items, qtiPackageItem{file: file})
+			}
+		}
+		return items, nil
+	}
+
+	data, err := readZipFile(manifestFile)
+	if err != nil {
+		return nil, ErrMalformedImport
+	}
+	var manifest qtiManifest
+	if err := xml.Unmarshal(data, &manifest); err != nil {
+		return nil, ErrMalformedImport
+	}
+
+	base := path.Dir(manifestFile.Name)
+	for _, resource := range manifest.Resources {
+		if !strings.HasPrefix(resource.Type, "imsqti_item") {
+			continue
+		}
+		href := resource.Href
+		if href == "" {
+			href = resource.File.Href
+		}
+		if file, ok := byName[path.Join(base, href)]; ok {
+			items = append(items, qtiPackageItem{file: file, metadata: resource.Metadata})
+		}
+	}
+	return items, nil
+}
+
+func readQTIItem(file *zip.File) (ExerciseRecord, error) {
+	data, err := readZipFile(file)
+	if err != nil {
+		return ExerciseRecord{}, errors.New("file could not be read")
+	}
+	return parseQTIItem(data)
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(io.LimitReader(rc, maxJSONLineSize))
+}
+
+// parseQTIItem converts a single assessmentItem into a record keyed by the
+// item identifier. Items have no categories or difficulties, so without
+// manifest metadata those come from the import defaults.
+func parseQTIItem(data []byte) (ExerciseRecord, error) {
+	var item qtiItem
+	if err := xml.Unmarshal(data, &item); err != nil {
+		return ExerciseRecord{}, errors.New("invalid assessmentItem XML")
+	}
+
+	if len(item.Responses) != 1 {
+		return ExerciseRecord{}, errors.New("items must have exactly one response")
+	}
+	response := item.Responses[0]
+	if response.Cardinality != "single" || len(response.Correct) != 1 {
+		return ExerciseRecord{}, errors.New("items must have a single correct response")
+	}
+
+	body, err := parseQTIBody(item.Body.Inner)
+	if err != nil {
+		return ExerciseRecord{}, err
+	}
+
+	record := ExerciseRecord{
+		ExternalKey: strings.TrimSpace(item.Identifier),
+		Title:       strings.TrimSpace(item.Title),
+		Problem:     body.prompt,
+	}
+	for _, feedback := range item.Feedback {
+		if text := strings.TrimSpace(feedback.Text); text != "" {
+			record.Explanation = text
+			break
+		}
+	}
+
+	correct := strings.TrimSpace(response.Correct[0])
+	switch body.interaction {
+	case "choiceInteraction":
+		record.Type = "multiple_choice"
+		for _, choice := range body.choices {
+			record.Options = append(record.Options, choice.text)
+			if choice.identifier == correct {
+				record.CorrectAnswer = choice.text
+			}
+		}
+		if record.CorrectAnswer == "" {
+			return record, errors.New("correct response does not match a choice")
+		}
+	case "textEntryInteraction":
+		record.Type = "fill_in"
+		record.CorrectAnswer = correct
+	}
+
+	if record.Title == "" {
+		record.Title = truncateRunes(record.Problem, maxGIFTTitleLength)
+	}
+	record.Description = record.Problem
+	return record, nil
+}
+
+// applyQTIMetadata fills in a record from the LOM metadata of its resource.
+// Exports always write metadata and only list an external key the exercise
+// has, so a package item with metadata but no key is imported without one.
+func applyQTIMetadata(record *ExerciseRecord, metadata *qtiMetadata) {
+	if metadata == nil {
+		return
+	}
+	lom := metadata.LOM
+
+	record.ExternalKey = ""
+	for _, identifier := range lom.Identifiers {
+		if strings.TrimSpace(identifier.Catalog) == lomKeyCatalog {
+			record.ExternalKey = strings.TrimSpace(identifier.Entry)
+			break
+		}
+	}
+	if lom.Description != nil {
+		if description := strings.TrimSpace(lom.Description.String); description != "" {
+			record.Description = description
+		}
+	}
+	for _, keyword := range lom.Keywords {
+		if tag := strings.TrimSpace(keyword.String); tag != "" {
+			record.Tags = append(record.Tags, tag)
+		}
+	}
+	if lom.Difficulty != nil {
+		value := strings.ToLower(strings.TrimSpace(lom.Difficulty.Value))
+		if difficulty, ok := lomImportDifficulties[value]; ok {
+			record.Difficulty = difficulty
+		} else {
+			// Left for validation to reject
+			record.Difficulty = value
+		}
+	}
+	for _, classification := range lom.Classifications {
+		if strings.TrimSpace(classification.Purpose.Value) != lomDiscipline || len(classification.Taxons) == 0 {
+			continue
+		}
+		// Taxon paths run from the broadest taxon to the narrowest
+		record.Category = strings.TrimSpace(classification.Taxons[len(classification.Taxons)-1].String)
+		break
+	}
+}
+
+// encodeQTIMetadata describes a record as LOM metadata for its manifest resource
+func encodeQTIMetadata(record ExerciseRecord) *qtiMetadata {
+	metadata := &qtiMetadata{}
+	lom := &metadata.LOM
+	lom.Namespace = lomNamespace
+	if record.ExternalKey != "" {
+		lom.Identifiers = []lomIdentifier{{Catalog: lomKeyCatalog, Entry: record.ExternalKey}}
+	}
+	if record.Description != "" {
+		lom.Description = &lomString{String: record.Description}
+	}
+	for _, tag := range record.Tags {
+		lom.Keywords = append(lom.Keywords, lomString{String: tag})
+	}
+	if record.Difficulty != "" {
+		value, ok := lomDifficulties[record.Difficulty]
+		if !ok {
+			value = record.Difficulty
+		}
+		lom.Difficulty = &lomVocabulary{Source: lomSource, Value: value}
+	}
+	if record.Category != "" {
+		lom.Classifications = []lomClassification{{
+			Purpose: lomVocabulary{Source: lomSource, Value: lomDiscipline},
+			Source:  lomString{String: "category"},
+			Taxons:  []lomString{{String: record.Category}},
+		}}
+	}
+	return metadata
+}
+
+type qtiChoice struct {
+	identifier string
+	text       string
+}
+
+type qtiBody struct {
+	interaction string
+	prompt      string
+	choices     []qtiChoice
+}
+
+// parseQTIBody walks an item body for its single interaction, the choices it
+// offers and the question text. The text is the interaction's prompt or,
+// without one, all text outside the interaction.
+func parseQTIBody(inner string) (qtiBody, error) {
+	var body qtiBody
+	var prompt, outside strings.Builder
+	inPrompt, inChoice, inInteraction := false, false, false
+
+	decoder := xml.NewDecoder(strings.NewReader(inner))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return body, errors.New("invalid itemBody XML")
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch name := t.Name.Local; {
+			case name == "choiceInteraction" || name == "textEntryInteraction":
+				if body.interaction != "" {
+					return body, errors.New("items with several interactions are not supported")
+				}
+				body.interaction = name
+				inInteraction = name == "choiceInteraction"
+				if name == "textEntryInteraction" {
+					outside.WriteString(" _____ ")
+				}
+			case strings.HasSuffix(name, "Interaction"):
+				return body, fmt.Errorf("%s items are not supported", name)
+			case name == "prompt":
+				inPrompt = true
+			case name == "simpleChoice":
+				inChoice = true
+				identifier := ""
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "identifier" {
+						identifier = attr.Value
+					}
+				}
+				body.choices = append(body.choices, qtiChoice{identifier: identifier})
+			case name == "p" || name == "div" || name == "br":
+				outside.WriteString(" ")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "choiceInteraction":
+				inInteraction = false
+			case "prompt":
+				inPrompt = false
+			case "simpleChoice":
+				inChoice = false
+			}
+		case xml.CharData:
+			switch {
+			case inChoice:
+				body.choices[len(body.choices)-1].text += string(t)
+			case inPrompt:
+				prompt.Write(t)
+			case !inInteraction:
+				outside.Write(t)
+			}
+		}
+	}
+
+	if body.interaction == "" {
+		return body, errors.New("item has no choice or text entry interaction")
+	}
+	for i := range body.choices {
+		body.choices[i].text = strings.Join(strings.Fields(body.choices[i].text), " ")
+	}
+
+	body.prompt = strings.TrimSpace(prompt.String())
+	if body.prompt == "" {
+		body.prompt = strings.TrimSuffix(strings.Join(strings.Fields(outside.String()), " "), " _____")
+	}
+	return body, nil
+}
+
+// qtiPackageWriter writes exercises as a content package. Items are written as
+// they arrive; the manifest listing them is written by Close.
+type qtiPackageWriter struct {
+	archive   *zip.Writer
+	resources []qtiResource
+}
+
+func newQTIPackageWriter(w io.Writer) *qtiPackageWriter {
+	return &qtiPackageWriter{archive: zip.NewWriter(w)}
+}
+
+// Write adds an item file for a record
+func (q *qtiPackageWriter) Write(record ExerciseRecord) error {
+	identifier := record.ExternalKey
+	if !qtiIdentifierPattern.MatchString(identifier) {
+		identifier = "exercise-" + record.ID
+	}
+	href := "items/" + identifier + ".xml"
+
+	data, err := encodeQTIItem(identifier, record)
+	if err != nil {
+		return err
+	}
+	file, err := q.archive.Create(href)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		return err
+	}
+
+	resource := qtiResource{Identifier: identifier, Type: qtiItemType, Href: href, Metadata: encodeQTIMetadata(record)}
+	resource.File.Href = href
+	q.resources = append(q.resources, resource)
+	return nil
+}
+
+// Close writes the manifest and finishes the archive
+func (q *qtiPackageWriter) Close() error {
+	manifest := qtiManifest{
+		Namespace:  "http://www.imsglobal.org/xsd/imscp_v1p1",
+		Identifier: "MANIFEST-exercises",
+		Resources:  q.resources,
+	}
+	data, err := xml.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file, err := q.archive.Create(qtiManifestName)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append([]byte(xml.Header), data...)); err != nil {
+		return err
+	}
+	return q.archive.Close()
+}
+
+// encodeQTIItem renders a record as an assessmentItem document
+func encodeQTIItem(identifier string, record ExerciseRecord) ([]byte, error) {
+	item := qtiItem{
+		Namespace:  qtiNamespace,
+		Identifier: identifier,
+		Title:      record.Title,
+		Outcomes:   []qtiOutcomeDeclaration{{Identifier: "SCORE", Cardinality: "single", BaseType: "float"}},
+		Processing: &qtiResponseProcessing{Template: qtiMatchCorrect},
+	}
+
+	var body strings.Builder
+	if record.Type == "multiple_choice" {
+		correct := ""
+		body.WriteString(`<choiceInteraction responseIdentifier="` + qtiResponseID + `" shuffle="false" maxChoices="1"><prompt>`)
+		xml.EscapeText(&body, []byte(record.Problem))
+		body.WriteString(`</prompt>`)
+		for i, option := range record.Options {
+			choiceID := fmt.Sprintf("choice_%d", i+1)
+			if option == record.CorrectAnswer {
+				correct = choiceID
+			}
+			body.WriteString(`<simpleChoice identifier="` + choiceID + `">`)
+			xml.EscapeText(&body, []byte(option))
+			body.WriteString(`</simpleChoice>`)
+		}
+		body.WriteString(`</choiceInteraction>`)
+		item.Responses = []qtiResponseDeclaration{{Identifier: qtiResponseID, Cardinality: "single", BaseType: "identifier", Correct: []string{correct}}}
+	} else {
+		body.WriteString(`<p>`)
+		xml.EscapeText(&body, []byte(record.Problem))
+		body.WriteString(`</p><p><textEntryInteraction responseIdentifier="` + qtiResponseID + `"/></p>`)
+		item.Responses = []qtiResponseDeclaration{{Identifier: qtiResponseID, Cardinality: "single", BaseType: "string", Correct: []string{record.CorrectAnswer}}}
+	}
+	item.Body.Inner = body.String()
+
+	if record.Explanation != "" {
+		item.Outcomes = append(item.Outcomes, qtiOutcomeDeclaration{Identifier: "FEEDBACK", Cardinality: "single", BaseType: "identifier"})
+		item.Feedback = []qtiModalFeedback{{OutcomeIdentifier: "FEEDBACK", ShowHide: "show", Identifier: qtiFeedbackID, Text: record.Explanation}}
+	}
+
+	data, err := xml.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
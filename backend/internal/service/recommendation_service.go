@@ -0,0 +1,339 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// defaultRecommendationLimit is the number of paths recommended when no limit is given
+const defaultRecommendationLimit = 3
+
+type RecommendationService interface {
+	GetPathAccess(ctx context.Context, userID primitive.ObjectID, paths []*model.LearningPath) (map[primitive.ObjectID]PathAccess, error)
+	RecommendPaths(ctx context.Context, userID primitive.ObjectID, limit int) ([]*PathRecommendation, error)
+}
+
+// PathAccess describes whether a user has unlocked a learning path and what is still missing
+type PathAccess struct {
+	Locked               bool                 `json:"locked"`
+	MissingPrerequisites []primitive.ObjectID `json:"missing_prerequisites,omitempty"`
+	MissingSkills        []string             `json:"missing_skills,omitempty"`
+}
+
+// PathRecommendation is an unlocked learning path suggested as the user's next step
+type PathRecommendation struct {
+	Path    *model.LearningPath `json:"path"`
+	Score   float64             `json:"score"`
+	Reasons []string            `json:"reasons"`
+}
+
+// learnerState is what the prerequisite checks need to know about a user
+type learnerState struct {
+	completedPaths map[primitive.ObjectID]bool
+	activePaths    map[primitive.ObjectID]bool
+	skillMastery   map[string]float64
+	skills         map[string]*model.Skill
+	// prerequisites holds the titles of the prerequisite paths outside the
+	// trash; links to trashed paths are ignored until they are restored
+	prerequisites map[primitive.ObjectID]string
+}
+
+type recommendationService struct {
+	pathRepo       repository.LearningPathRepository
+	enrollmentRepo repository.EnrollmentRepository
+	progressRepo   repository.ProgressRepository
+	exerciseRepo   repository.ExerciseRepository
+	skillRepo      repository.SkillRepository
+	userRepo       repository.UserRepository
+}
+
+// NewRecommendationService creates a new instance of the recommendation service
+func NewRecommendationService(
+	pathRepo repository.LearningPathRepository,
+	enrollmentRepo repository.EnrollmentRepository,
+	progressRepo repository.ProgressRepository,
+	exerciseRepo repository.ExerciseRepository,
+	skillRepo repository.SkillRepository,
+	userRepo repository.UserRepository,
+) RecommendationService {
+	return &recommendationService{
+		pathRepo:       pathRepo,
+		enrollmentRepo: enrollmentRepo,
+		progressRepo:   progressRepo,
+		exerciseRepo:   exerciseRepo,
+		skillRepo:      skillRepo,
+		userRepo:       userRepo,
+	}
+}
+
+// GetPathAccess reports for each path whether the user has met its prerequisites
+func (s *recommendationService) GetPathAccess(ctx context.Context, userID primitive.ObjectID, paths []*model.LearningPath) (map[primitive.ObjectID]PathAccess, error) {
+	state, err := s.loadLearnerState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loadPrerequisites(ctx, state, paths); err != nil {
+		return nil, err
+	}
+
+	access := make(map[primitive.ObjectID]PathAccess, len(paths))
+	for _, path := range paths {
+		access[path.ID] = state.pathAccess(path)
+	}
+	return access, nil
+}
+
+// RecommendPaths ranks the unlocked paths the user has not completed yet
+func (s *recommendationService) RecommendPaths(ctx context.Context, userID primitive.ObjectID, limit int) ([]*PathRecommendation, error) {
+	if limit <= 0 {
+		limit = defaultRecommendationLimit
+	}
+	if limit > pagination.MaxLimit {
+		limit = pagination.MaxLimit
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := s.loadLearnerState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var published []*model.LearningPath
+	for offset := 0; ; offset += integrityBatchSize {
+		paths, err := s.pathRepo.GetAll(ctx, false, pagination.Params{Limit: integrityBatchSize, Offset: offset})
+		if err != nil {
+			return nil, err
+		}
+		published = append(published, paths...)
+		if len(paths) < integrityBatchSize {
+			break
+		}
+	}
+	if err := s.loadPrerequisites(ctx, state, published); err != nil {
+		return nil, err
+	}
+
+	recommendations := make([]*PathRecommendation, 0, len(published))
+	for _, path := range published {
+		if !state.completedPaths[path.ID] && !state.pathAccess(path).Locked {
+			recommendations = append(recommendations, state.score(path, user.Preferences))
+		}
+	}
+
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		return recommendations[i].Score > recommendations[j].Score
+	})
+	if len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+
+	return recommendations, nil
+}
+
+// loadLearnerState collects the user's path completions and per-skill mastery
+func (s *recommendationService) loadLearnerState(ctx context.Context, userID primitive.ObjectID) (*learnerState, error) {
+	state := &learnerState{
+		completedPaths: make(map[primitive.ObjectID]bool),
+		activePaths:    make(map[primitive.ObjectID]bool),
+		skillMastery:   make(map[string]float64),
+		skills:         make(map[string]*model.Skill),
+	}
+
+	completed, err := s.enrollmentRepo.GetCompletedByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, enrollment := range completed {
+		state.completedPaths[enrollment.PathID] = true
+	}
+
+	active, err := s.enrollmentRepo.GetActiveByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, enrollment := range active {
+		state.activePaths[enrollment.PathID] = true
+	}
+
+	skills, err := s.skillRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, skill := range skills {
+		state.skills[skill.Tag] = skill
+	}
+
+	progresses, err := s.progressRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(progresses) == 0 {
+		return state, nil
+	}
+
+	exerciseIDs := make([]primitive.ObjectID, len(progresses))
+	for i, progress := range progresses {
+		exerciseIDs[i] = progress.ExerciseID
+	}
+	exercises, err := s.exerciseRepo.GetByIDs(ctx, exerciseIDs)
+	if err != nil {
+		return nil, err
+	}
+	tagsByExercise := make(map[primitive.ObjectID][]string, len(exercises))
+	for _, exercise := range exercises {
+		tagsByExercise[exercise.ID] = exercise.Tags
+	}
+
+	// Skill mastery is the share of correct attempts across exercises carrying the tag
+	attempts := make(map[string]int)
+	correct := make(map[string]int)
+	for _, progress := range progresses {
+		for _, tag := range tagsByExercise[progress.ExerciseID] {
+			attempts[tag] += progress.AttemptCount
+			correct[tag] += progress.CorrectCount
+		}
+	}
+	for tag, count := range attempts {
+		if count > 0 {
+			state.skillMastery[tag] = float64(correct[tag]) / float64(count)
+		}
+	}
+
+	return state, nil
+}
+
+// loadPrerequisites looks up the prerequisite paths of the given paths, leaving
+// out those in the trash
+func (s *recommendationService) loadPrerequisites(ctx context.Context, state *learnerState, paths []*model.LearningPath) error {
+	state.prerequisites = make(map[primitive.ObjectID]string)
+
+	var ids []primitive.ObjectID
+	seen := make(map[primitive.ObjectID]bool)
+	for _, path := range paths {
+		for _, id := range path.PrerequisitePathIDs {
+			if !seen[id] {
+				ids = append(ids, id)
+				seen[id] = true
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	prerequisites, err := s.pathRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, prerequisite := range prerequisites {
+		state.prerequisites[prerequisite.ID] = prerequisite.Title
+	}
+	return nil
+}
+
+// pathAccess checks a path's prerequisite paths and required skills
+func (state *learnerState) pathAccess(path *model.LearningPath) PathAccess {
+	access := PathAccess{}
+
+	for _, id := range path.PrerequisitePathIDs {
+		if _, ok := state.prerequisites[id]; ok && !state.completedPaths[id] {
+			access.MissingPrerequisites = append(access.MissingPrerequisites, id)
+		}
+	}
+
+	mastered := make(map[string]bool)
+	for _, tag := range path.RequiredSkills {
+		if !state.skillMastered(tag, mastered, map[string]bool{}) {
+			access.MissingSkills = append(access.MissingSkills, tag)
+		}
+	}
+
+	access.Locked = len(access.MissingPrerequisites) > 0 || len(access.MissingSkills) > 0
+	return access
+}
+
+// skillMastered reports whether the user has mastered a skill and, transitively,
+// every skill it depends on. Tags that were never declared as skills use the
+// default threshold and have no prerequisites.
+func (state *learnerState) skillMastered(tag string, memo, visiting map[string]bool) bool {
+	if result, ok := memo[tag]; ok {
+		return result
+	}
+	if visiting[tag] {
+		return false
+	}
+	visiting[tag] = true
+
+	threshold := model.DefaultMasteryThreshold
+	var prerequisites []string
+	if skill, ok := state.skills[tag]; ok {
+		if skill.MasteryThreshold > 0 {
+			threshold = skill.MasteryThreshold
+		}
+		prerequisites = skill.Prerequisites
+	}
+
+	result := state.skillMastery[tag] >= threshold
+	for _, prerequisite := range prerequisites {
+		if !result {
+			break
+		}
+		result = state.skillMastered(prerequisite, memo, visiting)
+	}
+
+	memo[tag] = result
+	return result
+}
+
+// score ranks an unlocked path for the user and explains why it was suggested
+func (state *learnerState) score(path *model.LearningPath, preferences model.UserPreferences) *PathRecommendation {
+	recommendation := &PathRecommendation{Path: path, Reasons: []string{}}
+
+	if state.activePaths[path.ID] {
+		recommendation.Score += 3
+		recommendation.Reasons = append(recommendation.Reasons, "You are already enrolled in this path")
+	}
+
+	// Paths that build directly on completed work are the natural next step
+	for _, id := range path.PrerequisitePathIDs {
+		title, ok := state.prerequisites[id]
+		if !ok {
+			continue
+		}
+		recommendation.Score += 2
+		recommendation.Reasons = append(recommendation.Reasons, fmt.Sprintf("Builds on %q, which you completed", title))
+	}
+
+	for _, tag := range path.RequiredSkills {
+		recommendation.Score += state.skillMastery[tag]
+	}
+	if len(path.RequiredSkills) > 0 {
+		recommendation.Reasons = append(recommendation.Reasons, "You have mastered the required skills")
+	}
+
+	if preferences.DifficultyPreference != "" && path.Difficulty == preferences.DifficultyPreference {
+		recommendation.Score++
+		recommendation.Reasons = append(recommendation.Reasons, "Matches your preferred difficulty")
+	}
+
+	for _, category := range path.Categories {
+		for _, preferred := range preferences.Categories {
+			if category == preferred {
+				recommendation.Score++
+				recommendation.Reasons = append(recommendation.Reasons, fmt.Sprintf("Covers %s, one of your preferred categories", category))
+			}
+		}
+	}
+
+	return recommendation
+}
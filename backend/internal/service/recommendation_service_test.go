@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository/memory"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRecommendPathsRanksUnlockedPaths(t *testing.T) {
+	ctx := context.Background()
+	pathRepo := memory.NewLearningPathRepository(nil)
+	enrollmentRepo := memory.NewEnrollmentRepository()
+	userRepo := memory.NewUserRepository()
+	recommendations := NewRecommendationService(pathRepo, enrollmentRepo, memory.NewProgressRepository(),
+		memory.NewExerciseRepository(nil), memory.NewSkillRepository(), userRepo)
+
+	user := &model.User{Email: "ada@example.com", Username: "ada", Preferences: model.UserPreferences{DifficultyPreference: "medium"}}
+	if err := userRepo.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	create := func(title, difficulty string, prerequisites ...primitive.ObjectID) *model.LearningPath {
+		t.Helper()
+		path := &model.LearningPath{Title: title, Difficulty: difficulty, Status: model.StatusPublished, PrerequisitePathIDs: prerequisites}
+		if err := pathRepo.Create(ctx, path, model.RevisionMeta{}); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	enroll := func(path *model.LearningPath, completed bool) {
+		t.Helper()
+		enrollment := &model.PathEnrollment{UserID: user.ID, PathID: path.ID}
+		if completed {
+			now := time.Now()
+			enrollment.CompletedAt = &now
+		}
+		if err := enrollmentRepo.Create(ctx, enrollment); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	basics := create("Basics", "easy")
+	trashed := create("Retired", "easy")
+	plain := create("Plain", "hard")
+	orphaned := create("Orphaned", "hard", trashed.ID)
+	preferred := create("Preferred", "medium")
+	next := create("Next", "hard", basics.ID)
+	enrolled := create("Enrolled", "hard")
+	create("Locked", "hard", plain.ID)
+	enroll(basics, true)
+	enroll(enrolled, false)
+	if err := pathRepo.SoftDelete(ctx, trashed.ID, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ties keep the order paths are listed in; a trashed prerequisite neither locks nor scores
+	got, err := recommendations.RecommendPaths(ctx, user.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*model.LearningPath{enrolled, next, preferred, plain, orphaned}
+	if len(got) != len(want) {
+		t.Fatalf("recommended %d paths, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Path.ID != want[i].ID {
+			t.Errorf("recommendation %d = %q (score %v), want %q", i, got[i].Path.Title, got[i].Score, want[i].Title)
+		}
+	}
+	if reasons := strings.Join(got[1].Reasons, "; "); !strings.Contains(reasons, `Builds on "Basics"`) {
+		t.Errorf("reasons for the next path = %q, want it to build on Basics", reasons)
+	}
+	if got[4].Score != 0 || len(got[4].Reasons) != 0 {
+		t.Errorf("path requiring a trashed path = score %v, reasons %v; want nothing for the trashed link", got[4].Score, got[4].Reasons)
+	}
+
+	limited, err := recommendations.RecommendPaths(ctx, user.ID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limited) != 2 || limited[0].Path.ID != enrolled.ID || limited[1].Path.ID != next.ID {
+		t.Errorf("limited recommendations = %d paths, want the top 2", len(limited))
+	}
+
+	access, err := recommendations.GetPathAccess(ctx, user.ID, []*model.LearningPath{orphaned})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if access[orphaned.ID].Locked {
+		t.Errorf("access to a path requiring a trashed path = %+v, want unlocked", access[orphaned.ID])
+	}
+}
@@ -0,0 +1,375 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrUnknownContentKind is returned for content kinds the review workflow does not handle
+	ErrUnknownContentKind = errors.New("unknown content kind")
+
+	// ErrInvalidTransition is returned when content is not in a status that allows the action
+	ErrInvalidTransition = errors.New("action not allowed in the current status")
+
+	// ErrNotAssignedReviewer is returned when someone other than the assigned reviewer approves
+	ErrNotAssignedReviewer = errors.New("content is assigned to another reviewer")
+
+	// ErrInvalidReviewer is returned when assigning a reviewer who is not an editor
+	ErrInvalidReviewer = errors.New("reviewer must be an editor or admin")
+)
+
+type ReviewService interface {
+	Submit(ctx context.Context, kind string, id, actorID primitive.ObjectID) (*ReviewItem, error)
+	AssignReviewer(ctx context.Context, kind string, id, actorID, reviewerID primitive.ObjectID) (*ReviewItem, error)
+	Comment(ctx context.Context, kind string, id, authorID primitive.ObjectID, body string) (*ReviewItem, error)
+	Approve(ctx context.Context, kind string, id, actorID primitive.ObjectID, isAdmin bool) (*ReviewItem, error)
+	RequestChanges(ctx context.Context, kind string, id, actorID primitive.ObjectID, body string) (*ReviewItem, error)
+	Archive(ctx context.Context, kind string, id, actorID primitive.ObjectID) (*ReviewItem, error)
+	Reopen(ctx context.Context, kind string, id, actorID primitive.ObjectID) (*ReviewItem, error)
+	GetQueue(ctx context.Context, kind string, page pagination.Params) (pagination.Page[*ReviewItem], error)
+}
+
+// ReviewItem is the review state of an exercise or learning path
+type ReviewItem struct {
+	Kind   string             `json:"kind"`
+	ID     primitive.ObjectID `json:"id"`
+	Title  string             `json:"title"`
+	Status string             `json:"status"`
+	Review model.Review       `json:"review"`
+}
+
+// reviewTarget gives the workflow uniform access to the status and review of
+// either kind of content
+type reviewTarget struct {
+	item   *ReviewItem
+	status *string
+	review *model.Review
+	path   *model.LearningPath
+	save   func(ctx context.Context, meta model.RevisionMeta) error
+}
+
+type reviewService struct {
+	exerciseRepo repository.ExerciseRepository
+	pathRepo     repository.LearningPathRepository
+	userRepo     repository.UserRepository
+	content      *ContentCache
+}
+
+// NewReviewService creates a new instance of the review service
+func NewReviewService(
+	exerciseRepo repository.ExerciseRepository,
+	pathRepo repository.LearningPathRepository,
+	userRepo repository.UserRepository,
+	content *ContentCache,
+) ReviewService {
+	return &reviewService{
+		exerciseRepo: exerciseRepo,
+		pathRepo:     pathRepo,
+		userRepo:     userRepo,
+		content:      content,
+	}
+}
+
+// Submit sends a draft for review
+func (s *reviewService) Submit(ctx context.Context, kind string, id, actorID primitive.ObjectID) (*ReviewItem, error) {
+	return s.transition(ctx, kind, id, model.RevisionMeta{AuthorID: actorID, Note: "Submitted for review"}, func(target *reviewTarget, now time.Time) error {
+		if *target.status != model.StatusDraft {
+			return ErrInvalidTransition
+		}
+
+		*target.status = model.StatusInReview
+		target.review.SubmittedBy = &actorID
+		target.review.SubmittedAt = &now
+		target.review.ApprovedBy = nil
+		target.review.ApprovedAt = nil
+		return nil
+	})
+}
+
+// AssignReviewer makes an editor responsible for approving content in review
+func (s *reviewService) AssignReviewer(ctx context.Context, kind string, id, actorID, reviewerID primitive.ObjectID) (*ReviewItem, error) {
+	reviewer, err := s.userRepo.GetByID(ctx, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+	if reviewer.Role != model.RoleEditor && reviewer.Role != model.RoleAdmin {
+		return nil, ErrInvalidReviewer
+	}
+
+	return s.transition(ctx, kind, id, model.RevisionMeta{AuthorID: actorID, Note: "Assigned reviewer"}, func(target *reviewTarget, now time.Time) error {
+		if *target.status != model.StatusInReview {
+			return ErrInvalidTransition
+		}
+
+		target.review.ReviewerID = &reviewerID
+		target.review.AssignedAt = &now
+		return nil
+	})
+}
+
+// Comment adds a review comment without changing the status
+func (s *reviewService) Comment(ctx context.Context, kind string, id, authorID primitive.ObjectID, body string) (*ReviewItem, error) {
+	return s.transition(ctx, kind, id, model.RevisionMeta{AuthorID: authorID, Note: "Added review comment"}, func(target *reviewTarget, now time.Time) error {
+		addComment(target.review, authorID, body, now)
+		return nil
+	})
+}
+
+// Approve publishes content in review. Only the assigned reviewer or an admin may
+// approve. Approving a learning path also publishes the unpublished exercises it
+// introduces, since they were reviewed as part of the path.
+func (s *reviewService) Approve(ctx context.Context, kind string, id, actorID primitive.ObjectID, isAdmin bool) (*ReviewItem, error) {
+	return s.transition(ctx, kind, id, model.RevisionMeta{AuthorID: actorID, Note: "Approved"}, func(target *reviewTarget, now time.Time) error {
+		if *target.status != model.StatusInReview {
+			return ErrInvalidTransition
+		}
+		if target.review.ReviewerID != nil && *target.review.ReviewerID != actorID && !isAdmin {
+			return ErrNotAssignedReviewer
+		}
+
+		if target.path != nil {
+			if err := s.approvePathExercises(ctx, target.path, actorID, now); err != nil {
+				return err
+			}
+		}
+
+		*target.status = model.StatusPublished
+		target.review.ApprovedBy = &actorID
+		target.review.ApprovedAt = &now
+		return nil
+	})
+}
+
+// RequestChanges sends content in review back to draft with a comment explaining why
+func (s *reviewService) RequestChanges(ctx context.Context, kind string, id, actorID primitive.ObjectID, body string) (*ReviewItem, error) {
+	return s.transition(ctx, kind, id, model.RevisionMeta{AuthorID: actorID, Note: "Requested changes"}, func(target *reviewTarget, now time.Time) error {
+		if *target.status != model.StatusInReview {
+			return ErrInvalidTransition
+		}
+
+		*target.status = model.StatusDraft
+		addComment(target.review, actorID, body, now)
+		return nil
+	})
+}
+
+// Archive retires content so it is no longer shown to learners
+func (s *reviewService) Archive(ctx context.Context, kind string, id, actorID primitive.ObjectID) (*ReviewItem, error) {
+	return s.transition(ctx, kind, id, model.RevisionMeta{AuthorID: actorID, Note: "Archived"}, func(target *reviewTarget, now time.Time) error {
+		if *target.status == model.StatusArchived {
+			return ErrInvalidTransition
+		}
+
+		*target.status = model.StatusArchived
+		return nil
+	})
+}
+
+// Reopen moves archived content back to draft so it can be reviewed again
+func (s *reviewService) Reopen(ctx context.Context, kind string, id, actorID primitive.ObjectID) (*ReviewItem, error) {
+	return s.transition(ctx, kind, id, model.RevisionMeta{AuthorID: actorID, Note: "Reopened"}, func(target *reviewTarget, now time.Time) error {
+		if *target.status != model.StatusArchived {
+			return ErrInvalidTransition
+		}
+
+		*target.status = model.StatusDraft
+		return nil
+	})
+}
+
+// queueSort names the order of the review queue in its cursors
+const queueSort = "submitted_at"
+
+// GetQueue lists content of a kind that is waiting for review, oldest submission first
+func (s *reviewService) GetQueue(ctx context.Context, kind string, page pagination.Params) (pagination.Page[*ReviewItem], error) {
+	var after *time.Time
+	if page.After != nil {
+		after = new(time.Time)
+		if page.After.Sort != queueSort || json.Unmarshal(page.After.Value, after) != nil {
+			return pagination.Page[*ReviewItem]{}, pagination.ErrInvalidCursor
+		}
+	}
+
+	items := []*ReviewItem{}
+	var total int64
+	switch kind {
+	case model.ContentKindExercise:
+		exercises, err := s.exerciseRepo.GetByStatus(ctx, model.StatusInReview, page, after)
+		if err != nil {
+			return pagination.Page[*ReviewItem]{}, err
+		}
+		for _, exercise := range exercises {
+			items = append(items, exerciseTarget(s.exerciseRepo, s.content, exercise).item)
+		}
+		if total, err = s.exerciseRepo.Count(ctx, model.ExerciseQuery{Status: model.StatusInReview, IncludeUnpublished: true}); err != nil {
+			return pagination.Page[*ReviewItem]{}, err
+		}
+	case model.ContentKindLearningPath:
+		paths, err := s.pathRepo.GetByStatus(ctx, model.StatusInReview, page, after)
+		if err != nil {
+			return pagination.Page[*ReviewItem]{}, err
+		}
+		for _, path := range paths {
+			items = append(items, pathTarget(s.pathRepo, s.content, path).item)
+		}
+		if total, err = s.pathRepo.Count(ctx, model.PathQuery{Status: model.StatusInReview, IncludeUnpublished: true}); err != nil {
+			return pagination.Page[*ReviewItem]{}, err
+		}
+	default:
+		return pagination.Page[*ReviewItem]{}, ErrUnknownContentKind
+	}
+
+	return pagination.NewPage(items, page, total, func(item *ReviewItem) pagination.Cursor {
+		raw, _ := json.Marshal(item.Review.SubmittedAt)
+		return pagination.Cursor{Sort: queueSort, Value: raw, ID: item.ID}
+	}), nil
+}
+
+// transition loads the content, applies a change to its status and review, and saves it
+func (s *reviewService) transition(ctx context.Context, kind string, id primitive.ObjectID, meta model.RevisionMeta, change func(target *reviewTarget, now time.Time) error) (*ReviewItem, error) {
+	var target *reviewTarget
+	switch kind {
+	case model.ContentKindExercise:
+		exercise, err := s.exerciseRepo.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		target = exerciseTarget(s.exerciseRepo, s.content, exercise)
+	case model.ContentKindLearningPath:
+		path, err := s.pathRepo.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		target = pathTarget(s.pathRepo, s.content, path)
+	default:
+		return nil, ErrUnknownContentKind
+	}
+
+	// Content stored before statuses existed is published
+	if *target.status == "" {
+		*target.status = model.StatusPublished
+	}
+
+	if err := change(target, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := target.save(ctx, meta); err != nil {
+		return nil, err
+	}
+
+	target.item.Status = *target.status
+	target.item.Review = *target.review
+	return target.item, nil
+}
+
+// approvePathExercises publishes the draft and in-review exercises used by a path.
+// Archived or deleted exercises block the approval.
+func (s *reviewService) approvePathExercises(ctx context.Context, path *model.LearningPath, actorID primitive.ObjectID, now time.Time) error {
+	var exerciseIDs []primitive.ObjectID
+	for _, stage := range path.Stages {
+		exerciseIDs = append(exerciseIDs, stage.ExerciseIDs...)
+	}
+	if len(exerciseIDs) == 0 {
+		return nil
+	}
+
+	exercises, err := s.exerciseRepo.GetByIDs(ctx, exerciseIDs)
+	if err != nil {
+		return err
+	}
+
+	found := make(map[primitive.ObjectID]bool, len(exercises))
+	var unavailable, pending []*model.Exercise
+	for _, exercise := range exercises {
+		found[exercise.ID] = true
+		switch {
+		case exercise.DeletedAt != nil || exercise.Status == model.StatusArchived:
+			unavailable = append(unavailable, exercise)
+		case !model.IsPublished(exercise.Status):
+			pending = append(pending, exercise)
+		}
+	}
+
+	missing := &MissingExercisesError{}
+	for _, id := range exerciseIDs {
+		if !found[id] {
+			missing.IDs = append(missing.IDs, id)
+		}
+	}
+	for _, exercise := range unavailable {
+		missing.IDs = append(missing.IDs, exercise.ID)
+	}
+	if len(missing.IDs) > 0 {
+		return missing
+	}
+
+	if len(pending) > 0 {
+		defer s.content.InvalidateExercises(ctx)
+	}
+	for _, exercise := range pending {
+		exercise.Status = model.StatusPublished
+		exercise.Review.ApprovedBy = &actorID
+		exercise.Review.ApprovedAt = &now
+		meta := model.RevisionMeta{AuthorID: actorID, Note: "Approved with learning path " + path.Title}
+		if err := s.exerciseRepo.Update(ctx, exercise, meta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exerciseTarget(repo repository.ExerciseRepository, content *ContentCache, exercise *model.Exercise) *reviewTarget {
+	return &reviewTarget{
+		item: &ReviewItem{
+			Kind:   model.ContentKindExercise,
+			ID:     exercise.ID,
+			Title:  exercise.Title,
+			Status: exercise.Status,
+			Review: exercise.Review,
+		},
+		status: &exercise.Status,
+		review: &exercise.Review,
+		save: func(ctx context.Context, meta model.RevisionMeta) error {
+			err := repo.Update(ctx, exercise, meta)
+			content.InvalidateExercises(ctx)
+			return err
+		},
+	}
+}
+
+func pathTarget(repo repository.LearningPathRepository, content *ContentCache, path *model.LearningPath) *reviewTarget {
+	return &reviewTarget{
+		item: &ReviewItem{
+			Kind:   model.ContentKindLearningPath,
+			ID:     path.ID,
+			Title:  path.Title,
+			Status: path.Status,
+			Review: path.Review,
+		},
+		status: &path.Status,
+		review: &path.Review,
+		path:   path,
+		save: func(ctx context.Context, meta model.RevisionMeta) error {
+			err := repo.Update(ctx, path, meta)
+			content.InvalidatePaths(ctx)
+			return err
+		},
+	}
+}
+
+func addComment(review *model.Review, authorID primitive.ObjectID, body string, now time.Time) {
+	review.Comments = append(review.Comments, model.ReviewComment{
+		ID:        primitive.NewObjectID(),
+		AuthorID:  authorID,
+		Body:      body,
+		CreatedAt: now,
+	})
+}
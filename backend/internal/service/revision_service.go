@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrVersionConflict is returned when content changed between being read and written
+var ErrVersionConflict = repository.ErrVersionConflict
+
+type RevisionService interface {
+	List(ctx context.Context, kind string, id primitive.ObjectID, page pagination.Params) (pagination.Page[*model.Revision], error)
+	Get(ctx context.Context, kind string, id primitive.ObjectID, number int) (*RevisionView, error)
+	Diff(ctx context.Context, kind string, id primitive.ObjectID, from, to int) ([]FieldChange, error)
+	Restore(ctx context.Context, kind string, id primitive.ObjectID, number int, meta model.RevisionMeta) (interface{}, error)
+}
+
+// RevisionView is a revision together with the document as it was at that revision
+type RevisionView struct {
+	*model.Revision
+	Document interface{} `json:"document"`
+}
+
+// FieldChange is a single field that differs between two revisions. Nested fields
+// are named with dotted paths; lists are compared as a whole.
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+type revisionService struct {
+	revisionRepo    repository.RevisionRepository
+	exerciseService ExerciseService
+	pathService     LearningPathService
+}
+
+// NewRevisionService creates a new instance of the revision service
+func NewRevisionService(
+	revisionRepo repository.RevisionRepository,
+	exerciseService ExerciseService,
+	pathService LearningPathService,
+) RevisionService {
+	return &revisionService{
+		revisionRepo:    revisionRepo,
+		exerciseService: exerciseService,
+		pathService:     pathService,
+	}
+}
+
+func (s *revisionService) List(ctx context.Context, kind string, id primitive.ObjectID, page pagination.Params) (pagination.Page[*model.Revision], error) {
+	revisions, err := s.revisionRepo.GetByDocument(ctx, kind, id, page)
+	if err != nil {
+		return pagination.Page[*model.Revision]{}, err
+	}
+	total, err := s.revisionRepo.CountByDocument(ctx, kind, id)
+	if err != nil {
+		return pagination.Page[*model.Revision]{}, err
+	}
+
+	return pagination.NewPage(revisions, page, total, func(r *model.Revision) pagination.Cursor {
+		return pagination.IDCursor(r.ID)
+	}), nil
+}
+
+func (s *revisionService) Get(ctx context.Context, kind string, id primitive.ObjectID, number int) (*RevisionView, error) {
+	revision, err := s.revisionRepo.GetByNumber(ctx, kind, id, number)
+	if err != nil {
+		return nil, err
+	}
+
+	document, err := decodeSnapshot(revision)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RevisionView{Revision: revision, Document: document}, nil
+}
+
+// Diff compares two revisions of a document field by field
+func (s *revisionService) Diff(ctx context.Context, kind string, id primitive.ObjectID, from, to int) ([]FieldChange, error) {
+	fromFields, err := s.snapshotFields(ctx, kind, id, from)
+	if err != nil {
+		return nil, err
+	}
+	toFields, err := s.snapshotFields(ctx, kind, id, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// The revision number always differs and is not a change in itself
+	delete(fromFields, "revision")
+	delete(toFields, "revision")
+
+	fields := make(map[string]bool, len(fromFields)+len(toFields))
+	for field := range fromFields {
+		fields[field] = true
+	}
+	for field := range toFields {
+		fields[field] = true
+	}
+
+	changes := []FieldChange{}
+	for field := range fields {
+		if !reflect.DeepEqual(fromFields[field], toFields[field]) {
+			changes = append(changes, FieldChange{Field: field, From: fromFields[field], To: toFields[field]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+
+	return changes, nil
+}
+
+// Restore copies the content of an older revision onto the current document and
+// saves it as a new revision. Status, review and deletion state are left alone so
+// a restore never bypasses the review workflow.
+func (s *revisionService) Restore(ctx context.Context, kind string, id primitive.ObjectID, number int, meta model.RevisionMeta) (interface{}, error) {
+	revision, err := s.revisionRepo.GetByNumber(ctx, kind, id, number)
+	if err != nil {
+		return nil, err
+	}
+
+	note := fmt.Sprintf("Restored revision %d", number)
+	if meta.Note != "" {
+		note += ": " + meta.Note
+	}
+	meta.Note = note
+
+	switch kind {
+	case model.ContentKindExercise:
+		var old model.Exercise
+		if err := revision.DecodeSnapshot(&old); err != nil {
+			return nil, err
+		}
+		current, err := s.exerciseService.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		current.Title = old.Title
+		current.Description = old.Description
+		current.Type = old.Type
+		current.Category = old.Category
+		current.Difficulty = old.Difficulty
+		current.Content = old.Content
+		current.Tags = old.Tags
+
+		if err := s.exerciseService.Update(ctx, current, meta); err != nil {
+			return nil, err
+		}
+		return current, nil
+	case model.ContentKindLearningPath:
+		var old model.LearningPath
+		if err := revision.DecodeSnapshot(&old); err != nil {
+			return nil, err
+		}
+		current, err := s.pathService.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		// Stages removed since the revision come back as new stages
+		kept := make(map[primitive.ObjectID]bool, len(current.Stages))
+		for _, stage := range current.Stages {
+			kept[stage.ID] = true
+		}
+		for i := range old.Stages {
+			if !kept[old.Stages[i].ID] {
+				old.Stages[i].ID = primitive.NilObjectID
+			}
+		}
+
+		current.Title = old.Title
+		current.Description = old.Description
+		current.Difficulty = old.Difficulty
+		current.Categories = old.Categories
+		current.Stages = old.Stages
+		current.PrerequisitePathIDs = old.PrerequisitePathIDs
+		current.RequiredSkills = old.RequiredSkills
+
+		if err := s.pathService.Update(ctx, current, meta); err != nil {
+			return nil, err
+		}
+		return current, nil
+	}
+
+	return nil, ErrUnknownContentKind
+}
+
+// snapshotFields flattens a revision's document into its JSON fields
+func (s *revisionService) snapshotFields(ctx context.Context, kind string, id primitive.ObjectID, number int) (map[string]interface{}, error) {
+	revision, err := s.revisionRepo.GetByNumber(ctx, kind, id, number)
+	if err != nil {
+		return nil, err
+	}
+
+	document, err := decodeSnapshot(revision)
+	if err != nil {
+		return nil, err
+	}
+
+	// Round-trip through JSON so fields carry their API names and values compare cleanly
+	data, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	flat := make(map[string]interface{})
+	flattenFields("", fields, flat)
+	return flat, nil
+}
+
+// decodeSnapshot decodes a revision snapshot into the model for its kind
+func decodeSnapshot(revision *model.Revision) (interface{}, error) {
+	switch revision.Kind {
+	case model.ContentKindExercise:
+		var exercise model.Exercise
+		if err := revision.DecodeSnapshot(&exercise); err != nil {
+			return nil, err
+		}
+		return &exercise, nil
+	case model.ContentKindLearningPath:
+		var path model.LearningPath
+		if err := revision.DecodeSnapshot(&path); err != nil {
+			return nil, err
+		}
+		return &path, nil
+	}
+	return nil, ErrUnknownContentKind
+}
+
+// flattenFields names nested object fields with dotted paths
+func flattenFields(prefix string, fields map[string]interface{}, flat map[string]interface{}) {
+	for key, value := range fields {
+		name := key
+		if prefix != "" {
+			name = prefix + "." + key
+		}
+
+		if nested, ok := value.(map[string]interface{}); ok {
+			flattenFields(name, nested, flat)
+			continue
+		}
+		flat[name] = value
+	}
+}
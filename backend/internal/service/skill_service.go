@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+)
+
+var (
+	// ErrUnknownSkill is returned when a skill lists a prerequisite that has not been declared
+	ErrUnknownSkill = errors.New("unknown skill")
+
+	// ErrSkillInUse is returned when deleting a skill that other skills depend on
+	ErrSkillInUse = errors.New("skill is a prerequisite of other skills")
+)
+
+type SkillService interface {
+	Save(ctx context.Context, skill *model.Skill) error
+	GetByTag(ctx context.Context, tag string) (*model.Skill, error)
+	GetAll(ctx context.Context) ([]*model.Skill, error)
+	Delete(ctx context.Context, tag string) error
+}
+
+type skillService struct {
+	skillRepo repository.SkillRepository
+}
+
+// NewSkillService creates a new instance of the skill service
+func NewSkillService(skillRepo repository.SkillRepository) SkillService {
+	return &skillService{
+		skillRepo: skillRepo,
+	}
+}
+
+// Save creates or replaces a skill after checking that its prerequisites are
+// declared and do not form a cycle
+func (s *skillService) Save(ctx context.Context, skill *model.Skill) error {
+	skill.Tag = strings.TrimSpace(skill.Tag)
+	if skill.MasteryThreshold <= 0 {
+		skill.MasteryThreshold = model.DefaultMasteryThreshold
+	}
+	if skill.Prerequisites == nil {
+		skill.Prerequisites = []string{}
+	}
+
+	skills, err := s.skillRepo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	graph := make(map[string][]string, len(skills)+1)
+	for _, existing := range skills {
+		graph[existing.Tag] = existing.Prerequisites
+	}
+	graph[skill.Tag] = skill.Prerequisites
+
+	for _, prerequisite := range skill.Prerequisites {
+		if _, ok := graph[prerequisite]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownSkill, prerequisite)
+		}
+	}
+
+	if cycle := findCycle(graph); cycle != nil {
+		return &PrerequisiteCycleError{Cycle: cycle}
+	}
+
+	return s.skillRepo.Upsert(ctx, skill)
+}
+
+func (s *skillService) GetByTag(ctx context.Context, tag string) (*model.Skill, error) {
+	return s.skillRepo.GetByTag(ctx, tag)
+}
+
+func (s *skillService) GetAll(ctx context.Context) ([]*model.Skill, error) {
+	return s.skillRepo.GetAll(ctx)
+}
+
+func (s *skillService) Delete(ctx context.Context, tag string) error {
+	if _, err := s.skillRepo.GetByTag(ctx, tag); err != nil {
+		return err
+	}
+
+	skills, err := s.skillRepo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, skill := range skills {
+		for _, prerequisite := range skill.Prerequisites {
+			if prerequisite == tag {
+				return ErrSkillInUse
+			}
+		}
+	}
+
+	return s.skillRepo.Delete(ctx, tag)
+}
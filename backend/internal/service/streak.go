@@ -0,0 +1,88 @@
+package service
+
+import (
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+)
+
+// StreakSummary describes a user's streak and today's daily goal progress
+type StreakSummary struct {
+	CurrentStreak    int               `json:"current_streak"`
+	LongestStreak    int               `json:"longest_streak"`
+	FreezesAvailable int               `json:"freezes_available"`
+	Timezone         string            `json:"timezone"`
+	Today            DailyGoalProgress `json:"today"`
+}
+
+// DailyGoalProgress describes progress towards the daily goal for a calendar day
+type DailyGoalProgress struct {
+	Date      string `json:"date"`
+	Completed int    `json:"completed"`
+	Goal      int    `json:"goal"`
+	GoalMet   bool   `json:"goal_met"`
+}
+
+// userLocation returns the user's timezone, falling back to UTC when unset or invalid
+func userLocation(user *model.User) *time.Location {
+	if user.Timezone == "" {
+		return time.UTC
+	}
+
+	loc, err := time.LoadLocation(user.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// dailyGoal returns the user's daily goal, treating unset goals as a single exercise
+func dailyGoal(user *model.User) int {
+	if user.Preferences.DailyGoal < 1 {
+		return 1
+	}
+	return user.Preferences.DailyGoal
+}
+
+// currentStreak returns the streak as of now without modifying the statistics.
+// A streak stays alive while the missed days can still be covered by freezes.
+func currentStreak(stats model.UserStatistics, now time.Time, loc *time.Location) int {
+	if stats.LastGoalDate == "" {
+		return 0
+	}
+
+	gap, ok := model.DaysBetween(stats.LastGoalDate, now.In(loc).Format(model.DayLayout))
+	if !ok {
+		return 0
+	}
+	if gap <= 1 || gap-1 <= stats.StreakFreezes {
+		return stats.StreakDays
+	}
+	return 0
+}
+
+// buildStreakSummary assembles the streak summary for a user as of now
+func buildStreakSummary(user *model.User, now time.Time) *StreakSummary {
+	loc := userLocation(user)
+	stats := user.Statistics
+	today := now.In(loc).Format(model.DayLayout)
+	goal := dailyGoal(user)
+
+	completed := 0
+	if stats.DailyProgressDate == today {
+		completed = stats.DailyProgressCount
+	}
+
+	return &StreakSummary{
+		CurrentStreak:    currentStreak(stats, now, loc),
+		LongestStreak:    stats.LongestStreak,
+		FreezesAvailable: stats.StreakFreezes,
+		Timezone:         loc.String(),
+		Today: DailyGoalProgress{
+			Date:      today,
+			Completed: completed,
+			Goal:      goal,
+			GoalMet:   completed >= goal,
+		},
+	}
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository"
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrNotInTrash is returned when restoring a record that is not in the trash
+	ErrNotInTrash = errors.New("not in the trash")
+	// ErrRestoreConflict is returned when restoring a record whose email,
+	// username or external key has since been taken by another one
+	ErrRestoreConflict = errors.New("taken by a record outside the trash")
+	// ErrUserNotFound is returned when deleting a user that does not exist or is already in the trash
+	ErrUserNotFound = repository.ErrUserNotFound
+)
+
+// TrashService manages deleted exercises, learning paths and users until they
+// are restored or, once the retention period has passed, purged
+type TrashService interface {
+	DeleteUser(ctx context.Context, id, deletedBy primitive.ObjectID) error
+	ListExercises(ctx context.Context, page pagination.Params) (pagination.Page[*model.Exercise], error)
+	ListPaths(ctx context.Context, page pagination.Params) (pagination.Page[*model.LearningPath], error)
+	ListUsers(ctx context.Context, page pagination.Params) (pagination.Page[*model.User], error)
+	RestoreExercise(ctx context.Context, id primitive.ObjectID) error
+	RestorePath(ctx context.Context, id primitive.ObjectID) error
+	RestoreUser(ctx context.Context, id primitive.ObjectID) error
+	Purge(ctx context.Context) (PurgeResult, error)
+}
+
+// PurgeResult counts the records a purge removed for good
+type PurgeResult struct {
+	Exercises int `json:"exercises"`
+	Paths     int `json:"paths"`
+	Users     int `json:"users"`
+}
+
+// Total is the number of records purged
+func (r PurgeResult) Total() int {
+	return r.Exercises + r.Paths + r.Users
+}
+
+type trashService struct {
+	exerciseRepo   repository.ExerciseRepository
+	pathRepo       repository.LearningPathRepository
+	enrollmentRepo repository.EnrollmentRepository
+	userRepo       repository.UserRepository
+	sessionRepo    repository.SessionRepository
+	accountService AccountService
+	sessions       SessionInvalidator
+	content        *ContentCache
+	retention      time.Duration
+}
+
+// NewTrashService creates a new instance of the trash service. Deleted records
+// are purged once they have been in the trash for longer than retention.
+func NewTrashService(
+	exerciseRepo repository.ExerciseRepository,
+	pathRepo repository.LearningPathRepository,
+	enrollmentRepo repository.EnrollmentRepository,
+	userRepo repository.UserRepository,
+	sessionRepo repository.SessionRepository,
+	accountService AccountService,
+	sessions SessionInvalidator,
+	content *ContentCache,
+	retention time.Duration,
+) TrashService {
+	return &trashService{
+		exerciseRepo:   exerciseRepo,
+		pathRepo:       pathRepo,
+		enrollmentRepo: enrollmentRepo,
+		userRepo:       userRepo,
+		sessionRepo:    sessionRepo,
+		accountService: accountService,
+		sessions:       sessions,
+		content:        content,
+		retention:      retention,
+	}
+}
+
+// DeleteUser moves a user to the trash and signs them out everywhere. Their
+// data is kept until the user is purged.
+func (s *trashService) DeleteUser(ctx context.Context, id, deletedBy primitive.ObjectID) error {
+	if err := s.userRepo.SoftDelete(ctx, id, deletedBy); err != nil {
+		return err
+	}
+	if _, err := s.sessionRepo.DeleteAllForUser(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	if err := s.sessions.InvalidateUser(ctx, id); err != nil {
+		return fmt.Errorf("failed to invalidate sessions: %w", err)
+	}
+	return nil
+}
+
+func (s *trashService) ListExercises(ctx context.Context, page pagination.Params) (pagination.Page[*model.Exercise], error) {
+	exercises, err := s.exerciseRepo.GetDeleted(ctx, time.Now(), page)
+	if err != nil {
+		return pagination.Page[*model.Exercise]{}, err
+	}
+	total, err := s.exerciseRepo.CountDeleted(ctx)
+	if err != nil {
+		return pagination.Page[*model.Exercise]{}, err
+	}
+	return pagination.NewPage(exercises, page, total, func(e *model.Exercise) pagination.Cursor {
+		return pagination.IDCursor(e.ID)
+	}), nil
+}
+
+func (s *trashService) ListPaths(ctx context.Context, page pagination.Params) (pagination.Page[*model.LearningPath], error) {
+	paths, err := s.pathRepo.GetDeleted(ctx, time.Now(), page)
+	if err != nil {
+		return pagination.Page[*model.LearningPath]{}, err
+	}
+	total, err := s.pathRepo.CountDeleted(ctx)
+	if err != nil {
+		return pagination.Page[*model.LearningPath]{}, err
+	}
+	return pagination.NewPage(paths, page, total, func(p *model.LearningPath) pagination.Cursor {
+		return pagination.IDCursor(p.ID)
+	}), nil
+}
+
+func (s *trashService) ListUsers(ctx context.Context, page pagination.Params) (pagination.Page[*model.User], error) {
+	users, err := s.userRepo.GetDeleted(ctx, time.Now(), page)
+	if err != nil {
+		return pagination.Page[*model.User]{}, err
+	}
+	total, err := s.userRepo.CountDeleted(ctx)
+	if err != nil {
+		return pagination.Page[*model.User]{}, err
+	}
+	return pagination.NewPage(users, page, total, func(u *model.User) pagination.Cursor {
+		return pagination.IDCursor(u.ID)
+	}), nil
+}
+
+// RestoreExercise takes an exercise out of the trash. Stage references removed
+// by the cascade delete policy are not restored.
+func (s *trashService) RestoreExercise(ctx context.Context, id primitive.ObjectID) error {
+	defer s.content.InvalidateExercises(ctx)
+	return notInTrash(s.exerciseRepo.Restore(ctx, id), repository.ErrExerciseNotFound)
+}
+
+// RestorePath takes a learning path out of the trash. Prerequisite links to and
+// from it were kept while it was there, so it is refused when they would now
+// close a cycle.
+func (s *trashService) RestorePath(ctx context.Context, id primitive.ObjectID) error {
+	defer s.content.InvalidatePaths(ctx)
+
+	path, err := s.trashedPath(ctx, id)
+	if err != nil {
+		return err
+	}
+	graph, _, err := pathPrerequisiteGraph(ctx, s.pathRepo, path.ID, path.PrerequisitePathIDs)
+	if err != nil {
+		return err
+	}
+	if cycle := findCycle(graph); cycle != nil {
+		return &PrerequisiteCycleError{Cycle: cycle}
+	}
+
+	return notInTrash(s.pathRepo.Restore(ctx, id), repository.ErrPathNotFound)
+}
+
+// trashedPath finds a learning path in the trash, a page at a time
+func (s *trashService) trashedPath(ctx context.Context, id primitive.ObjectID) (*model.LearningPath, error) {
+	page := pagination.Params{Limit: purgeBatchSize, UseCursor: true}
+	for {
+		paths, err := s.pathRepo.GetDeleted(ctx, time.Now(), page)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			if path.ID == id {
+				return path, nil
+			}
+		}
+		if len(paths) < purgeBatchSize {
+			return nil, ErrNotInTrash
+		}
+		cursor := pagination.IDCursor(paths[len(paths)-1].ID)
+		page.After = &cursor
+	}
+}
+
+// RestoreUser takes a user out of the trash; they have to sign in again
+func (s *trashService) RestoreUser(ctx context.Context, id primitive.ObjectID) error {
+	return notInTrash(s.userRepo.Restore(ctx, id), repository.ErrUserNotFound)
+}
+
+// Purge permanently removes the records that have been in the trash for longer
+// than the retention period. Exercises are first taken out of any stages still
+// using them, learning paths are dropped from the prerequisites of other paths
+// and take their enrollments with them, and users are
+// erased the way account deletion erases them.
+func (s *trashService) Purge(ctx context.Context) (PurgeResult, error) {
+	var result PurgeResult
+	before := time.Now().Add(-s.retention)
+	defer s.content.InvalidatePaths(ctx)
+	defer s.content.InvalidateExercises(ctx)
+
+	err := purgeExpired(ctx, before, s.exerciseRepo.GetDeleted, func(e *model.Exercise) error {
+		if _, err := s.pathRepo.RemoveExercise(ctx, e.ID, model.RevisionMeta{Note: "Removed purged exercise"}); err != nil {
+			return err
+		}
+		if err := s.exerciseRepo.Delete(ctx, e.ID); err != nil {
+			return err
+		}
+		result.Exercises++
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to purge exercises: %w", err)
+	}
+
+	err = purgeExpired(ctx, before, s.pathRepo.GetDeleted, func(p *model.LearningPath) error {
+		if _, err := s.pathRepo.RemovePrerequisite(ctx, p.ID, model.RevisionMeta{Note: "Removed purged prerequisite"}); err != nil {
+			return err
+		}
+		if _, err := s.enrollmentRepo.DeleteByPathID(ctx, p.ID); err != nil {
+			return err
+		}
+		if err := s.pathRepo.Delete(ctx, p.ID); err != nil {
+			return err
+		}
+		result.Paths++
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to purge learning paths: %w", err)
+	}
+
+	err = purgeExpired(ctx, before, s.userRepo.GetDeleted, func(u *model.User) error {
+		if err := s.accountService.Delete(ctx, u.ID); err != nil {
+			return err
+		}
+		result.Users++
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to purge users: %w", err)
+	}
+
+	return result, nil
+}
+
+// notInTrash reports a restore that found nothing to restore as ErrNotInTrash,
+// and one that would break a uniqueness constraint as ErrRestoreConflict
+func notInTrash(err, notFound error) error {
+	switch {
+	case errors.Is(err, notFound):
+		return ErrNotInTrash
+	case errors.Is(err, repository.ErrDuplicateKey):
+		return ErrRestoreConflict
+	}
+	return err
+}
+
+// purgeExpired removes, in batches, the records that went to the trash before
+// the given time. Each batch is read from the start, since purged records no
+// longer match.
+func purgeExpired[T any](ctx context.Context, before time.Time, list func(context.Context, time.Time, pagination.Params) ([]*T, error), purge func(*T) error) error {
+	for {
+		records, err := list(ctx, before, pagination.Params{Limit: purgeBatchSize})
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if err := purge(record); err != nil {
+				return err
+			}
+		}
+		if len(records) < purgeBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/flutterninja9/mental-math-app/internal/domain/model"
+	"github.com/flutterninja9/mental-math-app/internal/domain/repository/memory"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPurgeRemovesPathEnrollments(t *testing.T) {
+	ctx := context.Background()
+	pathRepo := memory.NewLearningPathRepository(nil)
+	enrollmentRepo := memory.NewEnrollmentRepository()
+	trash := NewTrashService(memory.NewExerciseRepository(nil), pathRepo, enrollmentRepo, memory.NewUserRepository(), nil, nil, nil, nil, 0)
+
+	purged := &model.LearningPath{Title: "Addition", Status: model.StatusPublished}
+	kept := &model.LearningPath{Title: "Subtraction", Status: model.StatusPublished}
+	for _, path := range []*model.LearningPath{purged, kept} {
+		if err := pathRepo.Create(ctx, path, model.RevisionMeta{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	userID := primitive.NewObjectID()
+	for _, pathID := range []primitive.ObjectID{purged.ID, kept.ID} {
+		if err := enrollmentRepo.Create(ctx, &model.PathEnrollment{UserID: userID, PathID: pathID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pathRepo.SoftDelete(ctx, purged.ID, userID); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := trash.Purge(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Paths != 1 {
+		t.Errorf("purged paths = %d, want 1", result.Paths)
+	}
+	enrollments, err := enrollmentRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enrollments) != 1 || enrollments[0].PathID != kept.ID {
+		t.Errorf("enrollments left = %+v, want only the one in the kept path", enrollments)
+	}
+}
+
+func TestRestoreReportsTakenExternalKeys(t *testing.T) {
+	ctx := context.Background()
+	exerciseRepo := memory.NewExerciseRepository(nil)
+	trash := NewTrashService(exerciseRepo, memory.NewLearningPathRepository(nil), memory.NewEnrollmentRepository(), memory.NewUserRepository(), nil, nil, nil, nil, 0)
+
+	newExercise := func() *model.Exercise {
+		exercise := &model.Exercise{Title: "Addition", ExternalKey: "addition-1"}
+		if err := exerciseRepo.Create(ctx, exercise, model.RevisionMeta{}); err != nil {
+			t.Fatal(err)
+		}
+		return exercise
+	}
+	trashed := newExercise()
+	if err := exerciseRepo.SoftDelete(ctx, trashed.ID, primitive.NewObjectID()); err != nil {
+		t.Fatal(err)
+	}
+	newExercise()
+
+	if err := trash.RestoreExercise(ctx, trashed.ID); !errors.Is(err, ErrRestoreConflict) {
+		t.Errorf("error = %v, want ErrRestoreConflict", err)
+	}
+}
+
+func TestTrashedPrerequisiteKeptUntilPurge(t *testing.T) {
+	ctx := context.Background()
+	pathRepo := memory.NewLearningPathRepository(nil)
+	paths := NewLearningPathService(pathRepo, memory.NewExerciseRepository(nil), nil)
+	trash := NewTrashService(memory.NewExerciseRepository(nil), pathRepo, memory.NewEnrollmentRepository(), memory.NewUserRepository(), nil, nil, nil, nil, 0)
+
+	prerequisite := &model.LearningPath{Title: "Addition"}
+	if err := paths.Create(ctx, prerequisite, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+	dependent := &model.LearningPath{Title: "Subtraction", PrerequisitePathIDs: []primitive.ObjectID{prerequisite.ID}}
+	if err := paths.Create(ctx, dependent, model.RevisionMeta{}); err != nil {
+		t.Fatal(err)
+	}
+
+	prerequisiteOf := func() []primitive.ObjectID {
+		t.Helper()
+		stored, err := pathRepo.GetByID(ctx, dependent.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stored.PrerequisitePathIDs
+	}
+
+	// The link survives the trash and a round trip through the trash
+	if err := paths.Delete(ctx, prerequisite.ID, primitive.NewObjectID()); err != nil {
+		t.Fatal(err)
+	}
+	if ids := prerequisiteOf(); len(ids) != 1 || ids[0] != prerequisite.ID {
+		t.Fatalf("prerequisites while trashed = %v, want the link kept", ids)
+	}
+
+	// Updating the dependent keeps a link it already had, but cannot add new trashed ones
+	dependent.Description = "Taking away"
+	if err := paths.Update(ctx, dependent, model.RevisionMeta{}); err != nil {
+		t.Fatalf("updating a path whose prerequisite is trashed: %v", err)
+	}
+	other := &model.LearningPath{Title: "Multiplication", PrerequisitePathIDs: []primitive.ObjectID{prerequisite.ID}}
+	if err := paths.Create(ctx, other, model.RevisionMeta{}); !errors.Is(err, ErrUnknownPrerequisite) {
+		t.Errorf("new link to a trashed path: %v, want ErrUnknownPrerequisite", err)
+	}
+
+	if err := trash.RestorePath(ctx, prerequisite.ID); err != nil {
+		t.Fatal(err)
+	}
+	if ids := prerequisiteOf(); len(ids) != 1 || ids[0] != prerequisite.ID {
+		t.Fatalf("prerequisites after restore = %v, want the link back", ids)
+	}
+
+	// Purging removes the link for good
+	if err := paths.Delete(ctx, prerequisite.ID, primitive.NewObjectID()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := trash.Purge(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ids := prerequisiteOf(); len(ids) != 0 {
+		t.Errorf("prerequisites after purge = %v, want none", ids)
+	}
+}
+
+func TestRestorePathRejectsCycles(t *testing.T) {
+	ctx := context.Background()
+	pathRepo := memory.NewLearningPathRepository(nil)
+	paths := NewLearningPathService(pathRepo, memory.NewExerciseRepository(nil), nil)
+	trash := NewTrashService(memory.NewExerciseRepository(nil), pathRepo, memory.NewEnrollmentRepository(), memory.NewUserRepository(), nil, nil, nil, nil, 0)
+
+	create := func(title string, prerequisites ...primitive.ObjectID) *model.LearningPath {
+		t.Helper()
+		path := &model.LearningPath{Title: title, PrerequisitePathIDs: prerequisites}
+		if err := paths.Create(ctx, path, model.RevisionMeta{}); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	b := create("B")
+	a := create("A", b.ID)
+	c := create("C", a.ID)
+
+	// With A in the trash, B may require C; bringing A back would close A -> B -> C -> A
+	if err := paths.Delete(ctx, a.ID, primitive.NewObjectID()); err != nil {
+		t.Fatal(err)
+	}
+	b.PrerequisitePathIDs = []primitive.ObjectID{c.ID}
+	if err := paths.Update(ctx, b, model.RevisionMeta{}); err != nil {
+		t.Fatalf("requiring a path whose prerequisite is trashed: %v", err)
+	}
+
+	var cycle *PrerequisiteCycleError
+	if err := trash.RestorePath(ctx, a.ID); !errors.As(err, &cycle) {
+		t.Fatalf("restoring into a cycle: %v, want a PrerequisiteCycleError", err)
+	}
+	if _, err := pathRepo.GetByID(ctx, a.ID); err == nil {
+		t.Error("path was restored despite the cycle")
+	}
+
+	if err := trash.RestorePath(ctx, primitive.NewObjectID()); !errors.Is(err, ErrNotInTrash) {
+		t.Errorf("restoring an unknown path: %v, want ErrNotInTrash", err)
+	}
+}
@@ -12,16 +12,20 @@ import (
 )
 
 type UserService interface {
-	Register(ctx context.Context, email, username, password, firstName, lastName string) (*model.User, error)
+	Register(ctx context.Context, email, username, password, firstName, lastName, timezone string) (*model.User, error)
 	Login(ctx context.Context, email, password string) (*model.User, error)
 	GetByID(ctx context.Context, id primitive.ObjectID) (*model.User, error)
-	UpdateProfile(ctx context.Context, id primitive.ObjectID, firstName, lastName string) (*model.User, error)
+	GetByEmail(ctx context.Context, email string) (*model.User, error)
+	UpdateProfile(ctx context.Context, id primitive.ObjectID, firstName, lastName, timezone string) (*model.User, error)
 	UpdatePreferences(ctx context.Context, id primitive.ObjectID, preferences model.UserPreferences) (*model.User, error)
 	UpdatePassword(ctx context.Context, id primitive.ObjectID, oldPassword, newPassword string) error
+	UpdateRole(ctx context.Context, id primitive.ObjectID, role string) (*model.User, error)
 	UpdateStatistics(ctx context.Context, id primitive.ObjectID, stats model.UserStatistics) error
-	Delete(ctx context.Context, id primitive.ObjectID) error
 }
 
+// ErrInvalidRole is returned when setting a role other than user, editor or admin
+var ErrInvalidRole = errors.New("role must be user, editor or admin")
+
 type userService struct {
 	userRepo repository.UserRepository
 }
@@ -34,7 +38,7 @@ func NewUserService(userRepo repository.UserRepository) UserService {
 
 func (s *userService) Register(
 	ctx context.Context,
-	email, username, password, firstName, lastName string,
+	email, username, password, firstName, lastName, timezone string,
 ) (*model.User, error) {
 	// Check if email already exists
 	existingUser, err := s.userRepo.GetByEmail(ctx, email)
@@ -54,6 +58,10 @@ func (s *userService) Register(
 		return nil, errors.New("failed to hash password")
 	}
 
+	if timezone == "" {
+		timezone = "UTC"
+	}
+
 	// Create user
 	user := &model.User{
 		Email:        email,
@@ -61,6 +69,8 @@ func (s *userService) Register(
 		PasswordHash: passwordHash,
 		FirstName:    firstName,
 		LastName:     lastName,
+		Timezone:     timezone,
+		Role:         model.RoleUser,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 		LastLogin:    time.Now(),
@@ -111,10 +121,14 @@ func (s *userService) GetByID(ctx context.Context, id primitive.ObjectID) (*mode
 	return s.userRepo.GetByID(ctx, id)
 }
 
+func (s *userService) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	return s.userRepo.GetByEmail(ctx, email)
+}
+
 func (s *userService) UpdateProfile(
 	ctx context.Context,
 	id primitive.ObjectID,
-	firstName, lastName string,
+	firstName, lastName, timezone string,
 ) (*model.User, error) {
 	user, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
@@ -123,6 +137,9 @@ func (s *userService) UpdateProfile(
 
 	user.FirstName = firstName
 	user.LastName = lastName
+	if timezone != "" {
+		user.Timezone = timezone
+	}
 	user.UpdatedAt = time.Now()
 
 	if err := s.userRepo.Update(ctx, user); err != nil {
@@ -183,10 +200,30 @@ func (s *userService) UpdatePassword(
 	return nil
 }
 
-func (s *userService) UpdateStatistics(ctx context.Context, id primitive.ObjectID, stats model.UserStatistics) error {
-	return s.userRepo.UpdateStatistics(ctx, id, stats)
+// UpdateRole changes a user's role. Sessions keep the role they were created
+// with, so the change applies from the user's next sign in.
+func (s *userService) UpdateRole(ctx context.Context, id primitive.ObjectID, role string) (*model.User, error) {
+	switch role {
+	case model.RoleUser, model.RoleEditor, model.RoleAdmin:
+	default:
+		return nil, ErrInvalidRole
+	}
+
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Role = role
+	user.UpdatedAt = time.Now()
+
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, errors.New("failed to update role")
+	}
+
+	return user, nil
 }
 
-func (s *userService) Delete(ctx context.Context, id primitive.ObjectID) error {
-	return s.userRepo.Delete(ctx, id)
+func (s *userService) UpdateStatistics(ctx context.Context, id primitive.ObjectID, stats model.UserStatistics) error {
+	return s.userRepo.UpdateStatistics(ctx, id, stats)
 }
@@ -0,0 +1,82 @@
+// Package cache stores short-lived copies of values under string keys, in
+// memory or in Redis. A cache is an optimisation: callers fall back to the
+// source of a value on a miss or an error.
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrMiss is returned by Get for a key that is not cached or has expired
+var ErrMiss = errors.New("cache miss")
+
+// Cache stores values under keys until their time to live passes. A ttl of
+// zero keeps a value until it is deleted or evicted.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// GetValue decodes the value cached under a key into v
+func GetValue(ctx context.Context, c Cache, key string, v interface{}) error {
+	data, err := c.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(data, v)
+}
+
+// SetValue caches v under a key. Values are stored as BSON so they keep every
+// field the repositories store, including those hidden from JSON.
+func SetValue(ctx context.Context, c Cache, key string, v interface{}, ttl time.Duration) error {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Set(ctx, key, data, ttl)
+}
+
+// Generation returns the generation stored under a key, starting a new one when
+// there is none. Keys that embed a generation are all invalidated at once by
+// NextGeneration, and by the generation expiring or being evicted.
+func Generation(ctx context.Context, c Cache, key string, ttl time.Duration) (string, error) {
+	data, err := c.Get(ctx, key)
+	if err == nil {
+		return string(data), nil
+	}
+	if !errors.Is(err, ErrMiss) {
+		return "", err
+	}
+	return NextGeneration(ctx, c, key, ttl)
+}
+
+// NextGeneration starts a new generation under a key, leaving the values cached
+// under the previous one to expire
+func NextGeneration(ctx context.Context, c Cache, key string, ttl time.Duration) (string, error) {
+	generation := primitive.NewObjectID().Hex()
+	if err := c.Set(ctx, key, []byte(generation), ttl); err != nil {
+		return "", err
+	}
+	return generation, nil
+}
+
+// Nop caches nothing; every Get misses
+type Nop struct{}
+
+func (Nop) Get(ctx context.Context, key string) ([]byte, error) {
+	return nil, ErrMiss
+}
+
+func (Nop) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return nil
+}
+
+func (Nop) Delete(ctx context.Context, keys ...string) error {
+	return nil
+}
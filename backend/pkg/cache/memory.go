@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is the number of writes between sweeps of expired entries
+const sweepInterval = 1000
+
+// Memory is a cache local to the process. With several instances running,
+// invalidations on one do not reach the others; use Redis instead.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]entry
+	writes  int
+}
+
+type entry struct {
+	value   []byte
+	expires time.Time // zero for no expiry
+}
+
+func (e entry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && now.After(e.expires)
+}
+
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]entry)}
+}
+
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, ErrMiss
+	}
+	if e.expired(time.Now()) {
+		delete(m.entries, key)
+		return nil, ErrMiss
+	}
+	return append([]byte(nil), e.value...), nil
+}
+
+func (m *Memory) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := entry{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+	m.entries[key] = e
+
+	// Entries that are never read again are only removed by a sweep
+	m.writes++
+	if m.writes >= sweepInterval {
+		m.writes = 0
+		now := time.Now()
+		for key, e := range m.entries {
+			if e.expired(now) {
+				delete(m.entries, key)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Delete(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		delete(m.entries, key)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedis returns a cache on an in-process Redis server
+func newTestRedis(t *testing.T) (*Redis, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedis(client), server
+}
+
+func TestRedisStoresValues(t *testing.T) {
+	ctx := context.Background()
+	c, server := newTestRedis(t)
+
+	if _, err := c.Get(ctx, "answer"); !errors.Is(err, ErrMiss) {
+		t.Fatalf("get before set: %v, want ErrMiss", err)
+	}
+	if err := c.Set(ctx, "answer", []byte("42"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := c.Get(ctx, "answer"); err != nil || string(value) != "42" {
+		t.Fatalf("get = %q, %v", value, err)
+	}
+
+	server.FastForward(2 * time.Minute)
+	if _, err := c.Get(ctx, "answer"); !errors.Is(err, ErrMiss) {
+		t.Errorf("get after the ttl: %v, want ErrMiss", err)
+	}
+
+	if err := c.Set(ctx, "kept", []byte("1"), 0); err != nil {
+		t.Fatal(err)
+	}
+	server.FastForward(24 * time.Hour)
+	if _, err := c.Get(ctx, "kept"); err != nil {
+		t.Errorf("get without a ttl: %v", err)
+	}
+	if err := c.Delete(ctx); err != nil {
+		t.Errorf("deleting no keys: %v", err)
+	}
+	if err := c.Delete(ctx, "kept", "missing"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, "kept"); !errors.Is(err, ErrMiss) {
+		t.Errorf("get after delete: %v, want ErrMiss", err)
+	}
+}
+
+func TestRedisGenerations(t *testing.T) {
+	ctx := context.Background()
+	c, server := newTestRedis(t)
+
+	first, err := Generation(ctx, c, "exercises:generation", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := Generation(ctx, c, "exercises:generation", time.Hour); err != nil || again != first {
+		t.Fatalf("generation read again = %q, %v, want %q", again, err, first)
+	}
+
+	next, err := NextGeneration(ctx, c, "exercises:generation", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current, _ := Generation(ctx, c, "exercises:generation", time.Hour); next == first || current != next {
+		t.Errorf("generation after NextGeneration = %q, want a new one (%q, was %q)", current, next, first)
+	}
+
+	// An expired generation starts a new one, invalidating the values under the old one
+	server.FastForward(2 * time.Hour)
+	if expired, _ := Generation(ctx, c, "exercises:generation", time.Hour); expired == next {
+		t.Error("generation survived its ttl")
+	}
+
+	server.SetError("LOADING")
+	if _, err := Generation(ctx, c, "exercises:generation", time.Hour); err == nil || errors.Is(err, ErrMiss) {
+		t.Errorf("generation with the server failing: %v, want the server error", err)
+	}
+}
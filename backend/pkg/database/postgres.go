@@ -0,0 +1,40 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Postgres struct {
+	Pool *pgxpool.Pool
+}
+
+func NewPostgres(url string) (*Postgres, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+
+	// Ping the database to verify connection
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping PostgreSQL: %w", err)
+	}
+
+	log.Printf("Connected to PostgreSQL: %s", pool.Config().ConnConfig.Database)
+
+	return &Postgres{Pool: pool}, nil
+}
+
+func (db *Postgres) Close() error {
+	db.Pool.Close()
+	log.Println("Disconnected from PostgreSQL")
+	return nil
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"io"
 	"os"
 	"time"
 
@@ -10,9 +11,14 @@ import (
 
 var Logger zerolog.Logger
 
-// Initialize sets up the logger
+// Initialize sets up the logger to write to standard output
 func Initialize(appEnv string) {
-	output := zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}
+	InitializeOutput(appEnv, os.Stdout)
+}
+
+// InitializeOutput sets up the logger to write to out
+func InitializeOutput(appEnv string, out io.Writer) {
+	output := zerolog.ConsoleWriter{Out: out, TimeFormat: time.RFC3339}
 
 	// Set global log level based on environment
 	var logLevel zerolog.Level
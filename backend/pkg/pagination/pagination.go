@@ -0,0 +1,203 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// DefaultLimit is the page size when a request does not ask for one
+	DefaultLimit = 10
+	// MaxLimit caps the page size a request can ask for
+	MaxLimit = 100
+)
+
+// ErrInvalidCursor is returned for a cursor token that cannot be decoded
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// Params selects a page of a list, either by offset or, when UseCursor is set,
+// by continuing after the last item of the previous page
+type Params struct {
+	Limit     int
+	Offset    int
+	UseCursor bool
+	After     *Cursor // nil for the first page
+}
+
+// Cursor marks the last item of a page. Lists ordered by _id only need the ID;
+// other orders also carry the sort they were issued for and the item's sort value.
+type Cursor struct {
+	Sort  string             `json:"s,omitempty"`
+	Value json.RawMessage    `json:"v,omitempty"`
+	ID    primitive.ObjectID `json:"id"`
+}
+
+// Meta is the pagination metadata returned with every list response. Page
+// numbers are only set for offset pagination, cursors only for cursor pagination.
+type Meta struct {
+	Total       int64  `json:"total"`
+	PerPage     int    `json:"per_page"`
+	CurrentPage int    `json:"current_page,omitempty"`
+	LastPage    int    `json:"last_page,omitempty"`
+	NextCursor  string `json:"next_cursor,omitempty"`
+	HasMore     bool   `json:"has_more"`
+}
+
+// Page is one page of a list together with its metadata
+type Page[T any] struct {
+	Items []T
+	Meta  Meta
+}
+
+// New returns offset params for a 1-based page number, applying the default and
+// maximum limits
+func New(page, limit int) Params {
+	limit = clampLimit(limit)
+	if page < 1 {
+		page = 1
+	}
+	return Params{Limit: limit, Offset: (page - 1) * limit}
+}
+
+// FromQuery reads limit, page, offset and cursor from the query string. A cursor
+// parameter, empty for the first page, selects cursor pagination; otherwise page
+// takes precedence over offset.
+func FromQuery(c *fiber.Ctx) (Params, error) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	params := Params{Limit: clampLimit(limit)}
+
+	args := c.Context().QueryArgs()
+	switch {
+	case args.Has("cursor"):
+		params.UseCursor = true
+		if token := c.Query("cursor"); token != "" {
+			cursor, err := DecodeCursor(token)
+			if err != nil {
+				return Params{}, err
+			}
+			params.After = cursor
+		}
+	case args.Has("page"):
+		page, _ := strconv.Atoi(c.Query("page"))
+		params = New(page, params.Limit)
+	default:
+		offset, _ := strconv.Atoi(c.Query("offset"))
+		if offset > 0 {
+			params.Offset = offset
+		}
+	}
+	return params, nil
+}
+
+// Apply orders a find by _id and limits it to the page. In cursor mode the filter
+// is extended to start after the cursor and one extra item is fetched so
+// NewPage can tell whether there is another page.
+func (p Params) Apply(filter bson.M, findOptions *options.FindOptions) bson.M {
+	return p.ApplySorted(filter, findOptions, "", nil, false)
+}
+
+// ApplySorted is Apply for lists ordered by a field, with _id breaking ties.
+// after is the cursor's sort value decoded into the field's type.
+func (p Params) ApplySorted(filter bson.M, findOptions *options.FindOptions, field string, after interface{}, descending bool) bson.M {
+	direction, compare := 1, "$gt"
+	if descending {
+		direction, compare = -1, "$lt"
+	}
+
+	sort := bson.D{{Key: "_id", Value: direction}}
+	if field != "" {
+		sort = append(bson.D{{Key: field, Value: direction}}, sort...)
+	}
+	findOptions.SetSort(sort)
+
+	if !p.UseCursor {
+		findOptions.SetLimit(int64(p.Limit))
+		findOptions.SetSkip(int64(p.Offset))
+		return filter
+	}
+
+	findOptions.SetLimit(int64(p.Limit + 1))
+	if p.After == nil {
+		return filter
+	}
+
+	scoped := bson.M{}
+	for key, value := range filter {
+		scoped[key] = value
+	}
+	if field == "" {
+		scoped["_id"] = bson.M{compare: p.After.ID}
+	} else {
+		scoped["$or"] = bson.A{
+			bson.M{field: bson.M{compare: after}},
+			bson.M{field: after, "_id": bson.M{compare: p.After.ID}},
+		}
+	}
+	return scoped
+}
+
+// NewPage builds a page from the items fetched with Apply. In cursor mode the
+// extra item is dropped and cursorOf gives the cursor continuing after the last
+// item kept.
+func NewPage[T any](items []T, params Params, total int64, cursorOf func(T) Cursor) Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+
+	meta := Meta{Total: total, PerPage: params.Limit}
+	if params.UseCursor {
+		if len(items) > params.Limit {
+			items = items[:params.Limit]
+			meta.HasMore = true
+			meta.NextCursor = cursorOf(items[len(items)-1]).Encode()
+		}
+		return Page[T]{Items: items, Meta: meta}
+	}
+
+	meta.CurrentPage = params.Offset/params.Limit + 1
+	meta.LastPage = int((total + int64(params.Limit) - 1) / int64(params.Limit))
+	meta.HasMore = int64(params.Offset+len(items)) < total
+	return Page[T]{Items: items, Meta: meta}
+}
+
+// Encode returns the cursor as an opaque token
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor reads a token returned by Encode
+func DecodeCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// IDCursor is the cursor for lists ordered by _id
+func IDCursor(id primitive.ObjectID) Cursor {
+	return Cursor{ID: id}
+}
+
+func clampLimit(limit int) int {
+	if limit < 1 {
+		return DefaultLimit
+	}
+	if limit > MaxLimit {
+		return MaxLimit
+	}
+	return limit
+}
@@ -0,0 +1,283 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// fromQuery runs FromQuery on a request with the given query string
+func fromQuery(t *testing.T, query string) (Params, error) {
+	t.Helper()
+	var params Params
+	var err error
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		params, err = FromQuery(c)
+		return nil
+	})
+	resp, testErr := app.Test(httptest.NewRequest("GET", "/?"+query, nil))
+	if testErr != nil {
+		t.Fatal(testErr)
+	}
+	io.Copy(io.Discard, resp.Body)
+	return params, err
+}
+
+func TestFromQuery(t *testing.T) {
+	after := Cursor{ID: primitive.NewObjectID()}
+
+	tests := []struct {
+		name  string
+		query string
+		want  Params
+	}{
+		{"defaults", "", Params{Limit: DefaultLimit}},
+		{"limit", "limit=25", Params{Limit: 25}},
+		{"limit above the maximum", "limit=500", Params{Limit: MaxLimit}},
+		{"zero limit", "limit=0", Params{Limit: DefaultLimit}},
+		{"negative limit", "limit=-3", Params{Limit: DefaultLimit}},
+		{"unparsable limit", "limit=ten", Params{Limit: DefaultLimit}},
+		{"offset", "limit=5&offset=15", Params{Limit: 5, Offset: 15}},
+		{"negative offset", "offset=-5", Params{Limit: DefaultLimit}},
+		{"page", "limit=5&page=3", Params{Limit: 5, Offset: 10}},
+		{"page before the first", "limit=5&page=0", Params{Limit: 5}},
+		{"page takes precedence over offset", "limit=5&page=2&offset=40", Params{Limit: 5, Offset: 5}},
+		{"first cursor page", "cursor=&page=2", Params{Limit: DefaultLimit, UseCursor: true}},
+		{"cursor", "limit=5&cursor=" + after.Encode(), Params{Limit: 5, UseCursor: true, After: &after}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fromQuery(t, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FromQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := fromQuery(t, "cursor=not-a-cursor"); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("bad cursor: %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestApplySorted(t *testing.T) {
+	id := primitive.NewObjectID()
+	cursor := &Cursor{Sort: "title", ID: id}
+
+	tests := []struct {
+		name       string
+		params     Params
+		field      string
+		after      interface{}
+		descending bool
+		wantFilter bson.M
+		wantSort   bson.D
+		wantLimit  int64
+		wantSkip   int64
+	}{
+		{
+			name:       "offset by id",
+			params:     Params{Limit: 10, Offset: 20},
+			wantFilter: bson.M{"status": "published"},
+			wantSort:   bson.D{{Key: "_id", Value: 1}},
+			wantLimit:  10,
+			wantSkip:   20,
+		},
+		{
+			name:       "first cursor page fetches one extra",
+			params:     Params{Limit: 10, UseCursor: true},
+			field:      "title",
+			wantFilter: bson.M{"status": "published"},
+			wantSort:   bson.D{{Key: "title", Value: 1}, {Key: "_id", Value: 1}},
+			wantLimit:  11,
+		},
+		{
+			name:       "cursor by id",
+			params:     Params{Limit: 10, UseCursor: true, After: cursor},
+			wantFilter: bson.M{"status": "published", "_id": bson.M{"$gt": id}},
+			wantSort:   bson.D{{Key: "_id", Value: 1}},
+			wantLimit:  11,
+		},
+		{
+			name:   "cursor by field breaks ties on id",
+			params: Params{Limit: 10, UseCursor: true, After: cursor},
+			field:  "title",
+			after:  "Sums",
+			wantFilter: bson.M{"status": "published", "$or": bson.A{
+				bson.M{"title": bson.M{"$gt": "Sums"}},
+				bson.M{"title": "Sums", "_id": bson.M{"$gt": id}},
+			}},
+			wantSort:  bson.D{{Key: "title", Value: 1}, {Key: "_id", Value: 1}},
+			wantLimit: 11,
+		},
+		{
+			name:       "descending",
+			params:     Params{Limit: 10, UseCursor: true, After: cursor},
+			field:      "title",
+			after:      "Sums",
+			descending: true,
+			wantFilter: bson.M{"status": "published", "$or": bson.A{
+				bson.M{"title": bson.M{"$lt": "Sums"}},
+				bson.M{"title": "Sums", "_id": bson.M{"$lt": id}},
+			}},
+			wantSort:  bson.D{{Key: "title", Value: -1}, {Key: "_id", Value: -1}},
+			wantLimit: 11,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := bson.M{"status": "published"}
+			findOptions := options.Find()
+			got := tt.params.ApplySorted(filter, findOptions, tt.field, tt.after, tt.descending)
+
+			if !reflect.DeepEqual(got, tt.wantFilter) {
+				t.Errorf("filter = %v, want %v", got, tt.wantFilter)
+			}
+			if !reflect.DeepEqual(findOptions.Sort, tt.wantSort) {
+				t.Errorf("sort = %v, want %v", findOptions.Sort, tt.wantSort)
+			}
+			if findOptions.Limit == nil || *findOptions.Limit != tt.wantLimit {
+				t.Errorf("limit = %v, want %d", findOptions.Limit, tt.wantLimit)
+			}
+			if tt.params.UseCursor && findOptions.Skip != nil {
+				t.Errorf("cursor page skips %d items", *findOptions.Skip)
+			}
+			if !tt.params.UseCursor && (findOptions.Skip == nil || *findOptions.Skip != tt.wantSkip) {
+				t.Errorf("skip = %v, want %d", findOptions.Skip, tt.wantSkip)
+			}
+			if len(filter) != 1 {
+				t.Errorf("caller's filter was changed to %v", filter)
+			}
+		})
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	cursorOf := func(n int) Cursor {
+		return Cursor{Sort: "n", Value: json.RawMessage(strconv.Itoa(n)), ID: primitive.NewObjectID()}
+	}
+
+	tests := []struct {
+		name     string
+		items    []int
+		params   Params
+		total    int64
+		want     []int
+		wantMeta Meta
+	}{
+		{
+			name:     "cursor page with one extra item",
+			items:    []int{1, 2, 3},
+			params:   Params{Limit: 2, UseCursor: true},
+			total:    5,
+			want:     []int{1, 2},
+			wantMeta: Meta{Total: 5, PerPage: 2, HasMore: true},
+		},
+		{
+			name:     "last cursor page",
+			items:    []int{1, 2},
+			params:   Params{Limit: 2, UseCursor: true},
+			total:    2,
+			want:     []int{1, 2},
+			wantMeta: Meta{Total: 2, PerPage: 2},
+		},
+		{
+			name:     "empty cursor page",
+			params:   Params{Limit: 2, UseCursor: true},
+			want:     []int{},
+			wantMeta: Meta{PerPage: 2},
+		},
+		{
+			name:     "offset page",
+			items:    []int{3, 4},
+			params:   Params{Limit: 2, Offset: 2},
+			total:    5,
+			want:     []int{3, 4},
+			wantMeta: Meta{Total: 5, PerPage: 2, CurrentPage: 2, LastPage: 3, HasMore: true},
+		},
+		{
+			name:     "last offset page",
+			items:    []int{5},
+			params:   Params{Limit: 2, Offset: 4},
+			total:    5,
+			want:     []int{5},
+			wantMeta: Meta{Total: 5, PerPage: 2, CurrentPage: 3, LastPage: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := NewPage(tt.items, tt.params, tt.total, cursorOf)
+			if !reflect.DeepEqual(page.Items, tt.want) {
+				t.Errorf("items = %v, want %v", page.Items, tt.want)
+			}
+			// The next cursor continues after the last item kept, not the extra one
+			if tt.params.UseCursor && page.Meta.HasMore {
+				next, err := DecodeCursor(page.Meta.NextCursor)
+				if err != nil || string(next.Value) != strconv.Itoa(tt.want[len(tt.want)-1]) {
+					t.Errorf("next cursor = %+v, %v, want one after item %d", next, err, tt.want[len(tt.want)-1])
+				}
+			} else if page.Meta.NextCursor != "" {
+				t.Errorf("next cursor = %q without another page", page.Meta.NextCursor)
+			}
+			page.Meta.NextCursor = ""
+			if page.Meta != tt.wantMeta {
+				t.Errorf("meta = %+v, want %+v", page.Meta, tt.wantMeta)
+			}
+		})
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	id := primitive.NewObjectID()
+	sorted := Cursor{Sort: "-created_at", Value: json.RawMessage(`"2026-03-02T10:00:00Z"`), ID: id}
+
+	decoded, err := DecodeCursor(sorted.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*decoded, sorted) {
+		t.Errorf("decoded cursor = %+v, want %+v", *decoded, sorted)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"not base64", "%%%"},
+		{"padded base64", "eyJpZCI6IjEifQ=="},
+		{"not json", encodeRaw("cursor")},
+		{"json without an id", encodeRaw(`{"s":"title","v":"Sums"}`)},
+		{"zero id", IDCursor(primitive.NilObjectID).Encode()},
+		{"malformed id", encodeRaw(`{"id":"not-an-id"}`)},
+		{"array", encodeRaw(`[1,2]`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if cursor, err := DecodeCursor(tt.token); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodeCursor(%q) = %+v, %v; want ErrInvalidCursor", tt.token, cursor, err)
+			}
+		})
+	}
+}
+
+// encodeRaw encodes arbitrary data the way Encode encodes a cursor
+func encodeRaw(data string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(data))
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"github.com/flutterninja9/mental-math-app/pkg/pagination"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -13,29 +14,6 @@ type Response struct {
 	Meta    interface{} `json:"meta,omitempty"`
 }
 
-// Pagination represents metadata for paginated responses
-type Pagination struct {
-	Total       int64 `json:"total"`
-	PerPage     int   `json:"per_page"`
-	CurrentPage int   `json:"current_page"`
-	LastPage    int   `json:"last_page"`
-}
-
-// NewPagination creates a new pagination object
-func NewPagination(total int64, perPage, currentPage int) Pagination {
-	lastPage := int(total) / perPage
-	if int(total)%perPage > 0 {
-		lastPage++
-	}
-
-	return Pagination{
-		Total:       total,
-		PerPage:     perPage,
-		CurrentPage: currentPage,
-		LastPage:    lastPage,
-	}
-}
-
 // SuccessResponse returns a success response
 func SuccessResponse(c *fiber.Ctx, data interface{}, message string, statusCode int) error {
 	if message == "" {
@@ -80,7 +58,7 @@ func ValidationErrorResponse(c *fiber.Ctx, errors ValidationErrors) error {
 }
 
 // PaginatedResponse returns a paginated response
-func PaginatedResponse(c *fiber.Ctx, data interface{}, pagination Pagination, message string) error {
+func PaginatedResponse(c *fiber.Ctx, data interface{}, pagination pagination.Meta, message string) error {
 	if message == "" {
 		message = "Success"
 	}
@@ -57,6 +57,8 @@ func getErrorMsg(err validator.FieldError) string {
 		return "This field is required"
 	case "email":
 		return "Invalid email format"
+	case "timezone":
+		return "Invalid IANA timezone"
 	case "min":
 		if err.Type().Kind().String() == "string" {
 			return "Must be at least " + err.Param() + " characters long"
@@ -0,0 +1,223 @@
+// Package schemas holds the $jsonSchema validators of the Mongo collections
+// and checks them against the structs stored in those collections.
+package schemas
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//go:embed *.json
+var files embed.FS
+
+// Files maps each validated collection to its schema file
+var Files = map[string]string{
+	"attempts":         "attempt_schema.json",
+	"exercises":        "excercises_schema.json",
+	"learning_paths":   "learning_path_schema.json",
+	"path_enrollments": "path_enrollment_schema.json",
+	"revisions":        "revision_schema.json",
+	"skills":           "skill_schema.json",
+	"user_progress":    "user_progress_schema.json",
+	"users":            "user_schema.json",
+	"user_sessions":    "user_session_schema.json",
+}
+
+// Schema is a $jsonSchema document, or one of its property schemas
+type Schema struct {
+	BSONType   interface{}        `json:"bsonType"`
+	Required   []string           `json:"required,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+}
+
+// Load reads the schema of a collection
+func Load(collection string) (*Schema, error) {
+	name, ok := Files[collection]
+	if !ok {
+		return nil, fmt.Errorf("no schema for collection %s", collection)
+	}
+	data, err := files.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var schema Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %w", name, err)
+	}
+	return &schema, nil
+}
+
+// Validator returns the validator document of a collection, for collMod
+func Validator(collection string) (bson.M, error) {
+	data, err := files.ReadFile(Files[collection])
+	if err != nil {
+		return nil, fmt.Errorf("no schema for collection %s: %w", collection, err)
+	}
+
+	var schema bson.M
+	if err := bson.UnmarshalExtJSON(data, false, &schema); err != nil {
+		return nil, fmt.Errorf("invalid schema for collection %s: %w", collection, err)
+	}
+	return bson.M{"$jsonSchema": schema}, nil
+}
+
+// types returns the BSON types a schema allows
+func (s *Schema) types() []string {
+	switch t := s.BSONType.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, v := range t {
+			if name, ok := v.(string); ok {
+				types = append(types, name)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func (s *Schema) allows(bsonType string) bool {
+	for _, t := range s.types() {
+		if t == bsonType {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+	rawType      = reflect.TypeOf(bson.Raw{})
+)
+
+// Check compares a schema with the struct stored in its collection and returns
+// an error listing every difference: fields missing from either side and
+// fields whose Go type the schema does not allow
+func Check(schema *Schema, model interface{}) error {
+	var problems []string
+	checkStruct(schema, reflect.TypeOf(model), "", &problems)
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("schema does not match %T: %s", model, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func checkStruct(schema *Schema, t reflect.Type, path string, problems *[]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	fields := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := bsonName(field)
+		if name == "" {
+			continue
+		}
+		fields[name] = true
+
+		property, ok := schema.Properties[name]
+		if !ok {
+			*problems = append(*problems, path+name+" is not in the schema")
+			continue
+		}
+		checkField(property, field.Type, path+name, problems)
+	}
+
+	for name := range schema.Properties {
+		if !fields[name] {
+			*problems = append(*problems, path+name+" is not in the model")
+		}
+	}
+}
+
+func checkField(schema *Schema, t reflect.Type, path string, problems *[]string) {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+	if t.Kind() == reflect.Slice && t != rawType {
+		nullable = true
+	}
+
+	expected := bsonType(t)
+	if !schema.allows(expected) {
+		*problems = append(*problems, fmt.Sprintf("%s is %s in the model but %v in the schema", path, expected, schema.types()))
+		return
+	}
+	if nullable && !schema.allows("null") {
+		*problems = append(*problems, path+" can be null in the model but not in the schema")
+	}
+
+	switch {
+	case expected == "object" && t.Kind() == reflect.Struct:
+		checkStruct(schema, t, path+".", problems)
+	case expected == "array":
+		if schema.Items == nil {
+			*problems = append(*problems, path+" has no item schema")
+			return
+		}
+		checkField(schema.Items, t.Elem(), path+"[]", problems)
+	}
+}
+
+// bsonType is the BSON type the driver stores a Go type as
+func bsonType(t reflect.Type) string {
+	switch t {
+	case timeType:
+		return "date"
+	case objectIDType:
+		return "objectId"
+	case rawType:
+		return "object"
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return "int"
+	case reflect.Int64:
+		return "long"
+	case reflect.Float32, reflect.Float64:
+		return "double"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	}
+	return t.Kind().String()
+}
+
+// bsonName is the name a struct field is stored under, or "" if it is not stored
+func bsonName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	tag := field.Tag.Get("bson")
+	name := strings.Split(tag, ",")[0]
+	switch {
+	case name == "-":
+		return ""
+	case name == "":
+		return strings.ToLower(field.Name)
+	}
+	return name
+}